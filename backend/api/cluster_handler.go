@@ -0,0 +1,61 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"eflo/backend/engine"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type ClusterHandler struct {
+	Elector *engine.LeaderElector
+	Engine  *engine.Engine
+}
+
+// Leader reports which instance holds the trigger lease. Without leader election every
+// instance runs the triggers and reports itself as leader.
+func (h *ClusterHandler) Leader(w http.ResponseWriter, r *http.Request) {
+	if h.Elector == nil {
+		writeJSON(w, http.StatusOK, engine.LeaderStatus{
+			InstanceID: engine.InstanceID(),
+			IsLeader:   true,
+			Backend:    "none",
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, h.Elector.Status(r.Context()))
+}
+
+// Queue reports execution queue counts by status ({"enabled": false} when runs are in-process).
+func (h *ClusterHandler) Queue(w http.ResponseWriter, r *http.Request) {
+	if h.Engine.Queue == nil {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"enabled": false})
+		return
+	}
+	stats, err := h.Engine.Queue.Stats(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"enabled": true, "jobs": stats})
+}
+
+func (h *ClusterHandler) QueueJob(w http.ResponseWriter, r *http.Request) {
+	if h.Engine.Queue == nil {
+		http.Error(w, "execution queue is not enabled", http.StatusNotFound)
+		return
+	}
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	job, err := h.Engine.Queue.Get(r.Context(), id)
+	if err != nil {
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
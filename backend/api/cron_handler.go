@@ -2,8 +2,10 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"eflo/backend/engine"
@@ -15,8 +17,9 @@ import (
 )
 
 type CronHandler struct {
-	Repo      *repository.CronScheduleRepo
-	Scheduler *engine.Scheduler
+	Repo         *repository.CronScheduleRepo
+	CalendarRepo *repository.ScheduleCalendarRepo
+	Scheduler    *engine.Scheduler
 }
 
 func (h *CronHandler) List(w http.ResponseWriter, r *http.Request) {
@@ -52,18 +55,20 @@ func (h *CronHandler) Create(w http.ResponseWriter, r *http.Request) {
 	if s.Timezone == "" {
 		s.Timezone = "UTC"
 	}
+	if msg := normalizeSchedulePolicies(&s); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
 
-	// Validate cron expression
-	parser := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
-	schedule, err := parser.Parse(s.Expression)
+	// Validate cron expression, timezone and calendars
+	schedule, status, err := h.buildSchedule(s.Expression, s.Timezone, s.CalendarIDs)
 	if err != nil {
-		http.Error(w, "invalid cron expression: "+err.Error(), http.StatusBadRequest)
+		http.Error(w, err.Error(), status)
 		return
 	}
 
-	// Calculate next run
-	nextRun := schedule.Next(time.Now())
-	s.NextRunAt = &nextRun
+	// Calculate next run in the schedule's timezone
+	s.NextRunAt = nextRunAfter(schedule, time.Now())
 
 	id, err := h.Repo.Create(&s)
 	if err != nil {
@@ -74,7 +79,7 @@ func (h *CronHandler) Create(w http.ResponseWriter, r *http.Request) {
 
 	// If enabled, add to the live scheduler
 	if s.Enabled && h.Scheduler != nil {
-		if err := h.Scheduler.AddJob(s.ID, s.WorkflowID, s.Expression); err != nil {
+		if err := h.Scheduler.AddJob(&s); err != nil {
 			http.Error(w, "schedule created but failed to activate: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -118,17 +123,19 @@ func (h *CronHandler) Update(w http.ResponseWriter, r *http.Request) {
 	if s.Timezone == "" {
 		s.Timezone = "UTC"
 	}
+	if msg := normalizeSchedulePolicies(&s); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
 
-	// Validate cron expression
-	parser := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
-	schedule, err := parser.Parse(s.Expression)
+	// Validate cron expression, timezone and calendars
+	schedule, status, err := h.buildSchedule(s.Expression, s.Timezone, s.CalendarIDs)
 	if err != nil {
-		http.Error(w, "invalid cron expression: "+err.Error(), http.StatusBadRequest)
+		http.Error(w, err.Error(), status)
 		return
 	}
 
-	nextRun := schedule.Next(time.Now())
-	s.NextRunAt = &nextRun
+	s.NextRunAt = nextRunAfter(schedule, time.Now())
 
 	if err := h.Repo.Update(&s); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -138,7 +145,7 @@ func (h *CronHandler) Update(w http.ResponseWriter, r *http.Request) {
 	// Update the live scheduler
 	if h.Scheduler != nil {
 		if s.Enabled {
-			_ = h.Scheduler.AddJob(s.ID, s.WorkflowID, s.Expression)
+			_ = h.Scheduler.AddJob(&s)
 		} else {
 			h.Scheduler.RemoveJob(s.ID)
 		}
@@ -165,3 +172,160 @@ func (h *CronHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	}
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// Events lists recent scheduler decisions (skipped/queued/cancelled ticks, misfire handling).
+func (h *CronHandler) Events(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	limit := 50
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if n, err := strconv.Atoi(l); err == nil && n > 0 && n <= 1000 {
+			limit = n
+		}
+	}
+	events, err := h.Repo.ListEvents(id, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, events)
+}
+
+// schedulePreviewRequest is the body of POST /api/schedules/preview.
+type schedulePreviewRequest struct {
+	Expression  string     `json:"expression"`
+	Timezone    string     `json:"timezone"`
+	CalendarIDs []int64    `json:"calendarIds"`
+	Count       int        `json:"count"`
+	From        *time.Time `json:"from"`
+}
+
+// Preview returns the upcoming fire times of a stored schedule (?count=N, optional ?from=RFC3339).
+func (h *CronHandler) Preview(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	s, err := h.Repo.GetByID(id)
+	if err != nil {
+		http.Error(w, "schedule not found", http.StatusNotFound)
+		return
+	}
+	count, _ := strconv.Atoi(r.URL.Query().Get("count"))
+	from := time.Now()
+	if f := r.URL.Query().Get("from"); f != "" {
+		if from, err = time.Parse(time.RFC3339, f); err != nil {
+			http.Error(w, "invalid from: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	h.writePreview(w, s.Expression, s.Timezone, s.CalendarIDs, count, from)
+}
+
+// PreviewExpression returns the upcoming fire times for an expression without storing anything.
+func (h *CronHandler) PreviewExpression(w http.ResponseWriter, r *http.Request) {
+	var req schedulePreviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Timezone == "" {
+		req.Timezone = "UTC"
+	}
+	from := time.Now()
+	if req.From != nil {
+		from = *req.From
+	}
+	h.writePreview(w, req.Expression, req.Timezone, req.CalendarIDs, req.Count, from)
+}
+
+func (h *CronHandler) writePreview(w http.ResponseWriter, expression, timezone string, calendarIDs []int64, count int, from time.Time) {
+	if count <= 0 {
+		count = 10
+	}
+	if count > 500 {
+		count = 500
+	}
+	schedule, status, err := h.buildSchedule(expression, timezone, calendarIDs)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"expression": expression,
+		"timezone":   timezone,
+		"times":      engine.PreviewSchedule(schedule, timezone, from, count),
+	})
+}
+
+// buildSchedule parses expression in timezone and applies the referenced exclusion calendars.
+// The returned status is the HTTP code to report on error.
+func (h *CronHandler) buildSchedule(expression, timezone string, calendarIDs []int64) (cron.Schedule, int, error) {
+	if expression == "" {
+		return nil, http.StatusBadRequest, fmt.Errorf("expression is required")
+	}
+	schedule, err := engine.ParseCronSchedule(expression, timezone)
+	if err != nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("invalid cron expression: %w", err)
+	}
+	if len(calendarIDs) == 0 || h.CalendarRepo == nil {
+		return schedule, 0, nil
+	}
+	calendars, err := h.CalendarRepo.GetByIDs(calendarIDs)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	if len(calendars) != len(calendarIDs) {
+		return nil, http.StatusBadRequest, fmt.Errorf("calendarIds references an unknown calendar")
+	}
+	return engine.ApplyCalendars(schedule, timezone, calendars), 0, nil
+}
+
+// nextRunAfter returns the schedule's next fire time, or nil if it never fires again.
+func nextRunAfter(schedule cron.Schedule, t time.Time) *time.Time {
+	next := schedule.Next(t)
+	if next.IsZero() {
+		return nil
+	}
+	return &next
+}
+
+// normalizeSchedulePolicies fills defaults and validates overlap/misfire/jitter/timeout settings.
+// Hyphenated spellings (cancel-previous, run-once) are accepted.
+func normalizeSchedulePolicies(s *models.CronSchedule) string {
+	s.OverlapPolicy = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(s.OverlapPolicy)), "-", "_")
+	s.MisfirePolicy = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(s.MisfirePolicy)), "-", "_")
+	if s.OverlapPolicy == "" {
+		s.OverlapPolicy = models.OverlapAllow
+	}
+	if s.MisfirePolicy == "" {
+		s.MisfirePolicy = models.MisfireSkip
+	}
+	switch s.OverlapPolicy {
+	case models.OverlapAllow, models.OverlapSkip, models.OverlapQueue, models.OverlapCancelPrevious:
+	default:
+		return "overlapPolicy must be one of allow, skip, queue, cancel_previous"
+	}
+	switch s.MisfirePolicy {
+	case models.MisfireSkip, models.MisfireRunOnce, models.MisfireRunAll:
+	default:
+		return "misfirePolicy must be one of skip, run_once, run_all"
+	}
+	if s.MisfireMax <= 0 {
+		s.MisfireMax = 10
+	}
+	if s.JitterSec < 0 {
+		return "jitterSec must not be negative"
+	}
+	if s.ExecTimeoutSec < 0 {
+		return "execTimeoutSec must not be negative"
+	}
+	if s.ExecTimeoutSec == 0 {
+		s.ExecTimeoutSec = models.DefaultTriggerExecTimeoutSec
+	}
+	return ""
+}
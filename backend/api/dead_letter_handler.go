@@ -0,0 +1,225 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"eflo/backend/engine"
+	"eflo/backend/models"
+	"eflo/backend/repository"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// DeadLetterHandler exposes trigger events whose workflow run failed, for inspection, replay
+// and purging.
+type DeadLetterHandler struct {
+	Repo   *repository.DeadLetterRepo
+	Engine *engine.Engine
+}
+
+// maxBulkReplay caps how many dead letters one bulk replay request may start.
+const maxBulkReplay = 1000
+
+// List returns dead letters, newest first. Filters: ?triggerType, triggerId, workflowId,
+// status, since, before (RFC3339); paging with ?limit (default 50, max 1000) and ?offset.
+func (h *DeadLetterHandler) List(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f, msg := parseDeadLetterFilter(q)
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	limit := 50
+	if l := q.Get("limit"); l != "" {
+		if n, err := strconv.Atoi(l); err == nil && n > 0 && n <= 1000 {
+			limit = n
+		}
+	}
+	offset := 0
+	if o := q.Get("offset"); o != "" {
+		if n, err := strconv.Atoi(o); err == nil && n > 0 {
+			offset = n
+		}
+	}
+	list, err := h.Repo.List(f, limit, offset)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	total, err := h.Repo.Count(f)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("X-Total-Count", strconv.FormatInt(total, 10))
+	if list == nil {
+		list = []*models.DeadLetter{}
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (h *DeadLetterHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	d, err := h.Repo.GetByID(id)
+	if err != nil {
+		http.Error(w, "dead letter not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, d)
+}
+
+// Replay runs the dead letter's workflow again with its stored payload and waits for the result.
+func (h *DeadLetterHandler) Replay(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	d, err := h.Repo.GetByID(id)
+	if err != nil {
+		http.Error(w, "dead letter not found", http.StatusNotFound)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), models.DefaultTriggerExecTimeoutSec*time.Second)
+	defer cancel()
+	execID, runErr := h.Engine.ReplayDeadLetter(ctx, d)
+	resp := map[string]interface{}{
+		"deadLetterId": id,
+		"executionId":  execID,
+		"status":       "completed",
+	}
+	if runErr != nil {
+		resp["status"] = "failed"
+		resp["error"] = runErr.Error()
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// bulkReplayRequest is the body of POST /api/dead-letters/replay: explicit IDs, or a filter
+// (pending dead letters by default) with an optional limit.
+type bulkReplayRequest struct {
+	IDs []int64 `json:"ids"`
+	models.DeadLetterFilter
+	Limit int `json:"limit"`
+}
+
+// ReplayBulk replays several dead letters one after another in the background, oldest first,
+// and answers 202 with the IDs it will replay. Each outcome is recorded on its dead letter.
+func (h *DeadLetterHandler) ReplayBulk(w http.ResponseWriter, r *http.Request) {
+	var req bulkReplayRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Limit <= 0 || req.Limit > maxBulkReplay {
+		req.Limit = maxBulkReplay
+	}
+
+	ids := req.IDs
+	if len(ids) == 0 {
+		if req.Status == "" {
+			req.Status = models.DeadLetterPending
+		}
+		var err error
+		if ids, err = h.Repo.IDs(req.DeadLetterFilter, req.Limit); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	} else if len(ids) > maxBulkReplay {
+		http.Error(w, "too many ids (max "+strconv.Itoa(maxBulkReplay)+")", http.StatusBadRequest)
+		return
+	}
+	if ids == nil {
+		ids = []int64{}
+	}
+
+	go h.replayAll(ids)
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{"count": len(ids), "ids": ids})
+}
+
+func (h *DeadLetterHandler) replayAll(ids []int64) {
+	failed := 0
+	for _, id := range ids {
+		d, err := h.Repo.GetByID(id)
+		if err != nil {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), models.DefaultTriggerExecTimeoutSec*time.Second)
+		if _, err := h.Engine.ReplayDeadLetter(ctx, d); err != nil {
+			failed++
+		}
+		cancel()
+	}
+	log.Printf("[DeadLetter] Bulk replay of %d dead letters finished (%d failed)", len(ids), failed)
+}
+
+func (h *DeadLetterHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	if err := h.Repo.Delete(id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Purge deletes the dead letters matching the List filters. Without any filter ?all=true is
+// required, so an unqualified request cannot wipe the table by accident.
+func (h *DeadLetterHandler) Purge(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f, msg := parseDeadLetterFilter(q)
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if f == (models.DeadLetterFilter{}) && q.Get("all") != "true" {
+		http.Error(w, "specify a filter or all=true", http.StatusBadRequest)
+		return
+	}
+	n, err := h.Repo.Purge(f)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"deleted": n})
+}
+
+func parseDeadLetterFilter(q url.Values) (models.DeadLetterFilter, string) {
+	f := models.DeadLetterFilter{
+		TriggerType: q.Get("triggerType"),
+		Status:      q.Get("status"),
+	}
+	for name, dst := range map[string]*int64{"triggerId": &f.TriggerID, "workflowId": &f.WorkflowID} {
+		if v := q.Get(name); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return f, "invalid " + name
+			}
+			*dst = n
+		}
+	}
+	for name, dst := range map[string]**time.Time{"since": &f.Since, "before": &f.Before} {
+		if v := q.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return f, "invalid " + name + ": " + err.Error()
+			}
+			*dst = &t
+		}
+	}
+	return f, ""
+}
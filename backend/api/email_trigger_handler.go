@@ -6,6 +6,7 @@ import (
 	"strconv"
 
 	"eflo/backend/engine"
+	"eflo/backend/imaputil"
 	"eflo/backend/models"
 	"eflo/backend/repository"
 
@@ -41,6 +42,24 @@ func (h *EmailTriggerHandler) Create(w http.ResponseWriter, r *http.Request) {
 	if t.MaxFetch <= 0 {
 		t.MaxFetch = 10
 	}
+	if t.MaxMessageBytes <= 0 {
+		t.MaxMessageBytes = models.DefaultEmailMaxMessageBytes
+	}
+	if t.MaxAttachmentBytes <= 0 {
+		t.MaxAttachmentBytes = models.DefaultEmailMaxAttachmentBytes
+	}
+	if msg := normalizeTriggerLimits(&t.TriggerLimits); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if msg := normalizeEmailStartFrom(&t); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if msg := validateEmailActions(&t); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
 
 	id, err := h.Repo.Create(&t)
 	if err != nil {
@@ -50,7 +69,7 @@ func (h *EmailTriggerHandler) Create(w http.ResponseWriter, r *http.Request) {
 	t.ID = id
 
 	if t.Enabled && h.Poller != nil {
-		if err := h.Poller.AddTrigger(t.ID, t.WorkflowID, t.ConfigID, t.Mailbox, t.PollIntervalSec, t.MarkSeen, t.MaxFetch); err != nil {
+		if err := h.Poller.AddTrigger(&t); err != nil {
 			http.Error(w, "trigger created but failed to start poller: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -86,6 +105,24 @@ func (h *EmailTriggerHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	t.ID = id
+	if t.MaxMessageBytes <= 0 {
+		t.MaxMessageBytes = models.DefaultEmailMaxMessageBytes
+	}
+	if t.MaxAttachmentBytes <= 0 {
+		t.MaxAttachmentBytes = models.DefaultEmailMaxAttachmentBytes
+	}
+	if msg := normalizeTriggerLimits(&t.TriggerLimits); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if msg := normalizeEmailStartFrom(&t); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if msg := validateEmailActions(&t); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
 
 	if err := h.Repo.Update(&t); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -94,7 +131,7 @@ func (h *EmailTriggerHandler) Update(w http.ResponseWriter, r *http.Request) {
 
 	if h.Poller != nil {
 		if t.Enabled {
-			_ = h.Poller.AddTrigger(t.ID, t.WorkflowID, t.ConfigID, t.Mailbox, t.PollIntervalSec, t.MarkSeen, t.MaxFetch)
+			_ = h.Poller.AddTrigger(&t)
 		} else {
 			h.Poller.RemoveTrigger(t.ID)
 		}
@@ -120,3 +157,25 @@ func (h *EmailTriggerHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	}
 	w.WriteHeader(http.StatusNoContent)
 }
+
+func validateEmailActions(t *models.EmailTrigger) string {
+	if err := imaputil.ValidateActions(t.OnSuccess); err != nil {
+		return "onSuccess: " + err.Error()
+	}
+	if err := imaputil.ValidateActions(t.OnFailure); err != nil {
+		return "onFailure: " + err.Error()
+	}
+	return ""
+}
+
+// normalizeEmailStartFrom defaults StartFrom to new and rejects unknown values.
+func normalizeEmailStartFrom(t *models.EmailTrigger) string {
+	switch t.StartFrom {
+	case "":
+		t.StartFrom = models.EmailStartNew
+	case models.EmailStartNew, models.EmailStartUnseen:
+	default:
+		return "startFrom must be new or unseen"
+	}
+	return ""
+}
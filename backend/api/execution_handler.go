@@ -6,6 +6,7 @@ import (
 	"strconv"
 
 	"eflo/backend/engine"
+	"eflo/backend/engine/nodes"
 	"eflo/backend/repository"
 
 	"github.com/go-chi/chi/v5"
@@ -33,7 +34,7 @@ func (h *ExecutionHandler) Execute(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	execID, err := h.Engine.RunWorkflow(r.Context(), wf)
+	execID, err := h.Engine.Execute(r.Context(), wf, nil, "api")
 	if err != nil {
 		// Return the execution ID even on failure so user can see logs
 		writeJSON(w, http.StatusOK, map[string]interface{}{
@@ -134,6 +135,11 @@ func (h *ExecutionHandler) Stats(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(resp)
 }
 
+// FunctionRuntimeStats returns function node isolate pool and script cache metrics.
+func (h *ExecutionHandler) FunctionRuntimeStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, nodes.GetFunctionRuntimeStats())
+}
+
 // ExecuteDebug runs the workflow and streams real-time execution events via Server-Sent Events (SSE).
 func (h *ExecutionHandler) ExecuteDebug(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
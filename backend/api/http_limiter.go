@@ -0,0 +1,283 @@
+package api
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"eflo/backend/models"
+)
+
+// normalizeHttpTriggerLimits validates an HTTP trigger's limits and fills defaults.
+func normalizeHttpTriggerLimits(l *models.HttpTriggerLimits) string {
+	if l.MaxBodyBytes < 0 || l.RatePerSec < 0 || l.Burst < 0 || l.MaxConcurrent < 0 {
+		return "limits.maxBodyBytes, ratePerSec, burst and maxConcurrent must not be negative"
+	}
+	if l.MaxBodyBytes == 0 {
+		l.MaxBodyBytes = models.DefaultHttpMaxBodyBytes
+	}
+	l.RateKey = strings.ToLower(strings.TrimSpace(l.RateKey))
+	if l.RateKey == "" {
+		l.RateKey = models.RateKeyGlobal
+	}
+	switch l.RateKey {
+	case models.RateKeyGlobal, models.RateKeyIP:
+	case models.RateKeyHeader:
+		if l.RateKeyHeader = strings.TrimSpace(l.RateKeyHeader); l.RateKeyHeader == "" {
+			return "limits.rateKeyHeader is required with rateKey header"
+		}
+	default:
+		return "limits.rateKey must be one of global, ip, header"
+	}
+	if l.RatePerSec > 0 && l.Burst == 0 {
+		l.Burst = int(math.Max(1, math.Ceil(l.RatePerSec)))
+	}
+	for _, list := range []*[]string{&l.AllowCIDRs, &l.DenyCIDRs} {
+		for i, s := range *list {
+			p, err := parseCIDR(s)
+			if err != nil {
+				return "invalid CIDR " + strconv.Quote(s) + " in limits"
+			}
+			(*list)[i] = p.String()
+		}
+	}
+	l.ClientIPHeader = strings.TrimSpace(l.ClientIPHeader)
+	return ""
+}
+
+// parseCIDR parses a prefix such as 10.0.0.0/8, or a single address.
+func parseCIDR(s string) (netip.Prefix, error) {
+	s = strings.TrimSpace(s)
+	if !strings.Contains(s, "/") {
+		addr, err := netip.ParseAddr(s)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		addr = addr.Unmap()
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+	p, err := netip.ParsePrefix(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	if p.Addr().Is4In6() && p.Bits() >= 96 {
+		p = netip.PrefixFrom(p.Addr().Unmap(), p.Bits()-96)
+	}
+	return p.Masked(), nil
+}
+
+// clientIP returns the request's client address: the last entry of the trusted header when set
+// (the one added by the proxy in front of eflo), else the connection's address.
+func clientIP(r *http.Request, trustedHeader string) (netip.Addr, bool) {
+	if trustedHeader != "" {
+		if v := r.Header.Get(trustedHeader); v != "" {
+			entries := strings.Split(v, ",")
+			if addr, err := netip.ParseAddr(strings.TrimSpace(entries[len(entries)-1])); err == nil {
+				return addr.Unmap(), true
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// ipAllowed applies a trigger's deny list, then its allow list. Addresses that can't be
+// determined are refused when either list is set.
+func ipAllowed(l *models.HttpTriggerLimits, addr netip.Addr, ok bool) bool {
+	if len(l.AllowCIDRs) == 0 && len(l.DenyCIDRs) == 0 {
+		return true
+	}
+	if !ok {
+		return false
+	}
+	for _, s := range l.DenyCIDRs {
+		if p, err := parseCIDR(s); err == nil && p.Contains(addr) {
+			return false
+		}
+	}
+	if len(l.AllowCIDRs) == 0 {
+		return true
+	}
+	for _, s := range l.AllowCIDRs {
+		if p, err := parseCIDR(s); err == nil && p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// httpLimiter enforces the rate and concurrency limits of HTTP triggers within this instance.
+type httpLimiter struct {
+	mu       sync.Mutex
+	triggers map[int64]*httpTriggerState
+	sweptAt  time.Time
+}
+
+type httpTriggerState struct {
+	rate     float64
+	burst    int
+	buckets  map[string]*tokenBucket // rate key -> bucket
+	sweptAt  time.Time
+	inFlight int
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Full buckets are dropped every bucketSweepInterval, so per-IP keys don't accumulate, and so is
+// the state of triggers left without buckets or running requests (e.g. deleted ones).
+const bucketSweepInterval = time.Minute
+
+// maxRateBuckets caps the buckets of a trigger. Once reached, new keys share overflowBucketKey,
+// so a client cycling through IPs or header values cannot grow the map without bound.
+const maxRateBuckets = 10000
+
+// overflowBucketKey is the shared bucket beyond maxRateBuckets. Header values and IP addresses
+// cannot contain NUL, so no request key collides with it.
+const overflowBucketKey = "\x00overflow"
+
+func (l *httpLimiter) state(id int64) *httpTriggerState {
+	if l.triggers == nil {
+		l.triggers = make(map[int64]*httpTriggerState)
+	}
+	s, ok := l.triggers[id]
+	if !ok {
+		s = &httpTriggerState{buckets: make(map[string]*tokenBucket)}
+		l.triggers[id] = s
+	}
+	return s
+}
+
+// allow takes a token from the bucket of key, or reports how long until one is available.
+func (l *httpLimiter) allow(t *models.HttpTrigger, key string, now time.Time) (bool, time.Duration) {
+	rate, burst := t.Limits.RatePerSec, t.Limits.Burst
+	if rate <= 0 {
+		return true, 0
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+	s := l.state(t.ID)
+	if s.rate != rate || s.burst != burst {
+		s.rate, s.burst = rate, burst
+		s.buckets = make(map[string]*tokenBucket)
+	}
+
+	b, ok := s.buckets[key]
+	if !ok && len(s.buckets) >= maxRateBuckets && now.Sub(s.sweptAt) > time.Second {
+		s.sweepBuckets(now)
+	}
+	if !ok && len(s.buckets) >= maxRateBuckets {
+		key = overflowBucketKey
+		b, ok = s.buckets[key]
+	}
+	if !ok {
+		b = &tokenBucket{tokens: float64(burst), last: now}
+		s.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / rate * float64(time.Second))
+}
+
+// sweep drops full buckets and the state of idle triggers every bucketSweepInterval; l.mu must
+// be held.
+func (l *httpLimiter) sweep(now time.Time) {
+	if now.Sub(l.sweptAt) <= bucketSweepInterval {
+		return
+	}
+	l.sweptAt = now
+	for id, s := range l.triggers {
+		s.sweepBuckets(now)
+		if len(s.buckets) == 0 && s.inFlight == 0 {
+			delete(l.triggers, id)
+		}
+	}
+}
+
+// sweepBuckets drops the buckets that have refilled, which behave like new ones.
+func (s *httpTriggerState) sweepBuckets(now time.Time) {
+	for k, b := range s.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*s.rate >= float64(s.burst) {
+			delete(s.buckets, k)
+		}
+	}
+	s.sweptAt = now
+}
+
+// forget drops a trigger's state, e.g. after it was deleted.
+func (l *httpLimiter) forget(id int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.triggers, id)
+}
+
+// acquire reserves one of the trigger's execution slots; release must follow when it was granted.
+func (l *httpLimiter) acquire(t *models.HttpTrigger) bool {
+	if t.Limits.MaxConcurrent <= 0 {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s := l.state(t.ID)
+	if s.inFlight >= t.Limits.MaxConcurrent {
+		return false
+	}
+	s.inFlight++
+	return true
+}
+
+func (l *httpLimiter) release(t *models.HttpTrigger) {
+	if t.Limits.MaxConcurrent <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if s := l.state(t.ID); s.inFlight > 0 {
+		s.inFlight--
+	}
+}
+
+// rateKey returns the bucket a request counts against.
+func rateKey(l *models.HttpTriggerLimits, r *http.Request, addr netip.Addr, ok bool) string {
+	switch l.RateKey {
+	case models.RateKeyIP:
+		if ok {
+			return addr.String()
+		}
+	case models.RateKeyHeader:
+		return r.Header.Get(l.RateKeyHeader)
+	}
+	return ""
+}
+
+// writeRetryAfter answers a throttled request with 429 and a whole-second Retry-After.
+func writeRetryAfter(w http.ResponseWriter, wait time.Duration, msg string) {
+	secs := int(math.Ceil(wait.Seconds()))
+	if secs < 1 {
+		secs = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(secs))
+	http.Error(w, msg, http.StatusTooManyRequests)
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"net/url"
+	"strings"
+
+	"eflo/backend/authutil"
+	"eflo/backend/models"
+)
+
+// normalizeHttpTriggerAuth validates an HTTP trigger's auth policy and fills defaults. A policy
+// of type none is dropped.
+func normalizeHttpTriggerAuth(t *models.HttpTrigger) string {
+	a := t.Auth
+	if a == nil {
+		return ""
+	}
+	a.Type = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(a.Type)), "-", "_")
+	if a.Type == "" || a.Type == models.HttpAuthNone {
+		t.Auth = nil
+		return ""
+	}
+	a.SecretKey = strings.TrimSpace(a.SecretKey)
+	a.Header = strings.TrimSpace(a.Header)
+	if a.ToleranceSec < 0 {
+		return "auth.toleranceSec must not be negative"
+	}
+
+	switch a.Type {
+	case models.HttpAuthAPIKey:
+		if a.SecretKey == "" {
+			return "auth.secretKey (config store key holding the API key) is required"
+		}
+		if a.Header == "" && a.QueryParam == "" {
+			a.Header = models.DefaultHttpAPIKeyHeader
+		}
+
+	case models.HttpAuthHMAC:
+		if a.SecretKey == "" {
+			return "auth.secretKey (config store key holding the HMAC secret) is required"
+		}
+		if a.Header == "" {
+			return "auth.header (signature header) is required"
+		}
+		a.Algorithm = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(a.Algorithm), "-", ""))
+		if a.Algorithm == "" {
+			a.Algorithm = models.DefaultHmacAlgorithm
+		}
+		if _, err := authutil.HashFunc(a.Algorithm); err != nil {
+			return "auth.algorithm must be one of sha1, sha256, sha512"
+		}
+		a.Encoding = strings.ToLower(strings.TrimSpace(a.Encoding))
+		if a.Encoding == "" {
+			a.Encoding = models.DefaultHmacSignatureEncoding
+		}
+		if a.Encoding != "hex" && a.Encoding != "base64" {
+			return "auth.encoding must be hex or base64"
+		}
+		a.Format = strings.ToLower(strings.TrimSpace(a.Format))
+		if a.Format == "" {
+			a.Format = models.HmacFormatPlain
+		}
+		if a.Format != models.HmacFormatPlain && a.Format != models.HmacFormatStripe {
+			return "auth.format must be plain or stripe"
+		}
+		if a.SignedPayload == "" {
+			a.SignedPayload = models.DefaultHmacSignedPayload
+		}
+		if !strings.Contains(a.SignedPayload, "{body}") {
+			return "auth.signedPayload must contain {body}"
+		}
+		if a.ToleranceSec == 0 {
+			a.ToleranceSec = models.DefaultHmacToleranceSec
+		}
+
+	case models.HttpAuthBasic:
+		if a.Username == "" {
+			return "auth.username is required"
+		}
+		if a.SecretKey == "" {
+			return "auth.secretKey (config store key holding the password) is required"
+		}
+
+	case models.HttpAuthJWT:
+		a.JWKSURL = strings.TrimSpace(a.JWKSURL)
+		if (a.SecretKey == "") == (a.JWKSURL == "") {
+			return "auth needs exactly one of secretKey (HS* shared secret) or jwksUrl"
+		}
+		if a.JWKSURL != "" {
+			if u, err := url.Parse(a.JWKSURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+				return "auth.jwksUrl must be an http(s) URL"
+			}
+		}
+		if a.ToleranceSec == 0 {
+			a.ToleranceSec = models.DefaultJWTLeewaySec
+		}
+
+	default:
+		return "auth.type must be one of none, api_key, hmac, basic, jwt"
+	}
+	return ""
+}
+
+// redactedValue replaces credentials in stored request data.
+const redactedValue = "[redacted]"
+
+// redactHttpInput returns a copy of an HTTP trigger's workflow input that is safe to store, e.g.
+// as a dead letter: the Authorization, Proxy-Authorization and Cookie headers and the header or
+// query parameter carrying the trigger's credential are redacted.
+func redactHttpInput(t *models.HttpTrigger, input map[string]interface{}) map[string]interface{} {
+	secretHeaders := []string{"Authorization", "Proxy-Authorization", "Cookie"}
+	secretParam := ""
+	if t.Auth != nil {
+		if t.Auth.Header != "" {
+			secretHeaders = append(secretHeaders, t.Auth.Header)
+		}
+		secretParam = t.Auth.QueryParam
+	}
+
+	out := make(map[string]interface{}, len(input))
+	for k, v := range input {
+		out[k] = v
+	}
+	if headers, ok := input["headers"].(map[string]string); ok {
+		redacted := make(map[string]string, len(headers))
+		for k, v := range headers {
+			for _, h := range secretHeaders {
+				if strings.EqualFold(k, h) {
+					v = redactedValue
+					break
+				}
+			}
+			redacted[k] = v
+		}
+		out["headers"] = redacted
+	}
+	if query, ok := input["query"].(url.Values); ok && secretParam != "" {
+		if _, present := query[secretParam]; present {
+			redacted := make(url.Values, len(query))
+			for k, v := range query {
+				redacted[k] = v
+			}
+			redacted[secretParam] = []string{redactedValue}
+			out["query"] = redacted
+		}
+	}
+	return out
+}
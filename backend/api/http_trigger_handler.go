@@ -2,14 +2,20 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
+	"log"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
+	"eflo/backend/authutil"
 	"eflo/backend/engine"
 	"eflo/backend/models"
 	"eflo/backend/repository"
+	"eflo/backend/routeutil"
+	"eflo/backend/schemautil"
 
 	"github.com/go-chi/chi/v5"
 )
@@ -18,6 +24,8 @@ type HttpTriggerHandler struct {
 	Repo         *repository.HttpTriggerRepo
 	WorkflowRepo *repository.WorkflowRepo
 	Engine       *engine.Engine
+	Auth         *authutil.Verifier
+	limiter      httpLimiter
 }
 
 func (h *HttpTriggerHandler) List(w http.ResponseWriter, r *http.Request) {
@@ -43,6 +51,24 @@ func (h *HttpTriggerHandler) Create(w http.ResponseWriter, r *http.Request) {
 	if t.Method == "" {
 		t.Method = "POST"
 	}
+	if status, msg := h.validateRoute(&t); status != 0 {
+		http.Error(w, msg, status)
+		return
+	}
+	if msg := normalizeHttpTriggerAuth(&t); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if msg := normalizeHttpTriggerLimits(&t.Limits); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if len(t.RequestSchema) > 0 {
+		if _, err := schemautil.Parse(t.RequestSchema); err != nil {
+			http.Error(w, "invalid requestSchema: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
 
 	id, err := h.Repo.Create(&t)
 	if err != nil {
@@ -85,6 +111,24 @@ func (h *HttpTriggerHandler) Update(w http.ResponseWriter, r *http.Request) {
 	if t.Method == "" {
 		t.Method = "POST"
 	}
+	if status, msg := h.validateRoute(&t); status != 0 {
+		http.Error(w, msg, status)
+		return
+	}
+	if msg := normalizeHttpTriggerAuth(&t); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if msg := normalizeHttpTriggerLimits(&t.Limits); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if len(t.RequestSchema) > 0 {
+		if _, err := schemautil.Parse(t.RequestSchema); err != nil {
+			http.Error(w, "invalid requestSchema: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
 	if err := h.Repo.Update(&t); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -92,6 +136,32 @@ func (h *HttpTriggerHandler) Update(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, t)
 }
 
+// validateRoute checks the trigger's path pattern and rejects one that would match the same
+// requests as another trigger's route with the same method and precedence.
+func (h *HttpTriggerHandler) validateRoute(t *models.HttpTrigger) (int, string) {
+	route, err := routeutil.Parse(t.Path)
+	if err != nil {
+		return http.StatusBadRequest, "invalid path: " + err.Error()
+	}
+	existing, err := h.Repo.List()
+	if err != nil {
+		return http.StatusInternalServerError, err.Error()
+	}
+	for _, other := range existing {
+		if other.ID == t.ID || other.Method != t.Method {
+			continue
+		}
+		otherRoute, err := routeutil.Parse(other.Path)
+		if err != nil {
+			continue
+		}
+		if routeutil.Conflicts(route, otherRoute) {
+			return http.StatusConflict, "path " + t.Path + " conflicts with " + other.Path + " of HTTP trigger " + strconv.FormatInt(other.ID, 10)
+		}
+	}
+	return 0, ""
+}
+
 func (h *HttpTriggerHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
@@ -102,11 +172,13 @@ func (h *HttpTriggerHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	h.limiter.forget(id)
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// HandleHTTPIn is the catch-all handler for /api/in/*. It looks up the trigger by path and method,
-// builds input from the request (body, headers, query), and runs the workflow; http_out node sends the response.
+// HandleHTTPIn is the catch-all handler for /api/in/*. It looks up the trigger whose route matches the path and method,
+// applies its IP, rate, body size and auth checks, builds input from the request (body, headers, query, route params),
+// and runs the workflow within its concurrency limit; http_out node sends the response.
 func (h *HttpTriggerHandler) HandleHTTPIn(w http.ResponseWriter, r *http.Request) {
 	path := strings.TrimPrefix(r.URL.Path, "/api/in")
 	path = strings.TrimPrefix(path, "/")
@@ -115,12 +187,61 @@ func (h *HttpTriggerHandler) HandleHTTPIn(w http.ResponseWriter, r *http.Request
 	}
 
 	method := r.Method
-	trigger, err := h.Repo.FindByPathAndMethod(path, method)
+	trigger, params, err := h.Repo.FindByPathAndMethod(path, method)
 	if err != nil || trigger == nil {
 		http.Error(w, "not found", http.StatusNotFound)
 		return
 	}
 
+	limits := &trigger.Limits
+	addr, addrOK := clientIP(r, limits.ClientIPHeader)
+	if !ipAllowed(limits, addr, addrOK) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	if ok, wait := h.limiter.allow(trigger, rateKey(limits, r, addr, addrOK), time.Now()); !ok {
+		writeRetryAfter(w, wait, "rate limit exceeded")
+		return
+	}
+
+	maxBody := limits.MaxBodyBytes
+	if maxBody <= 0 {
+		maxBody = models.DefaultHttpMaxBodyBytes
+	}
+	if r.ContentLength > maxBody {
+		http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	var body []byte
+	if r.Body != nil {
+		body, err = io.ReadAll(http.MaxBytesReader(w, r.Body, maxBody))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			} else {
+				http.Error(w, "failed to read request body", http.StatusBadRequest)
+			}
+			return
+		}
+	}
+
+	// Authenticate against the raw body (HMAC signatures) before any execution is created
+	authInfo, err := h.Auth.Verify(r.Context(), trigger.Auth, r, body)
+	if err != nil {
+		var authErr *authutil.Error
+		if errors.As(err, &authErr) {
+			if authErr.Challenge != "" {
+				w.Header().Set("WWW-Authenticate", authErr.Challenge)
+			}
+			http.Error(w, "unauthorized: "+authErr.Reason, http.StatusUnauthorized)
+			return
+		}
+		log.Printf("[HttpIn] Trigger %d auth check failed: %v", trigger.ID, err)
+		http.Error(w, "authentication unavailable", http.StatusInternalServerError)
+		return
+	}
+
 	wf, err := h.WorkflowRepo.GetByID(trigger.WorkflowID)
 	if err != nil || wf == nil {
 		http.Error(w, "workflow not found", http.StatusInternalServerError)
@@ -132,6 +253,10 @@ func (h *HttpTriggerHandler) HandleHTTPIn(w http.ResponseWriter, r *http.Request
 	input["method"] = r.Method
 	input["path"] = path
 	input["query"] = r.URL.Query()
+	input["params"] = params
+	if authInfo != nil {
+		input["auth"] = authInfo
+	}
 
 	headers := make(map[string]string)
 	for k, v := range r.Header {
@@ -141,22 +266,37 @@ func (h *HttpTriggerHandler) HandleHTTPIn(w http.ResponseWriter, r *http.Request
 	}
 	input["headers"] = headers
 
-	if r.Body != nil {
-		body, _ := io.ReadAll(r.Body)
-		if len(body) > 0 {
-			var jsonBody interface{}
-			if err := json.Unmarshal(body, &jsonBody); err == nil {
-				input["payload"] = jsonBody
-				input["body"] = jsonBody
-			} else {
-				input["body"] = string(body)
-				input["payload"] = string(body)
-			}
+	if len(body) > 0 {
+		var jsonBody interface{}
+		if err := json.Unmarshal(body, &jsonBody); err == nil {
+			input["payload"] = jsonBody
+			input["body"] = jsonBody
+		} else {
+			input["body"] = string(body)
+			input["payload"] = string(body)
 		}
 	}
 
+	// Reject requests that don't match the trigger's schema before any execution is created
+	if len(trigger.RequestSchema) > 0 {
+		if violations := schemautil.Validate(trigger.RequestSchema, input["body"]); len(violations) > 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+				"error":      "request body does not match schema",
+				"violations": violations,
+			})
+			return
+		}
+	}
+
+	if !h.limiter.acquire(trigger) {
+		writeRetryAfter(w, time.Second, "too many concurrent executions")
+		return
+	}
+	defer h.limiter.release(trigger)
+
 	execID, responseSent, err := h.Engine.RunWorkflowForHTTP(r.Context(), wf, input, w)
 	if err != nil {
+		h.Engine.RecordDeadLetter(models.TriggerHTTP, trigger.ID, wf.ID, execID, redactHttpInput(trigger, input), err)
 		if !responseSent {
 			writeJSON(w, http.StatusInternalServerError, map[string]interface{}{
 				"error":       err.Error(),
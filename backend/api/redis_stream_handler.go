@@ -0,0 +1,157 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"eflo/backend/engine"
+	"eflo/backend/models"
+	"eflo/backend/repository"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type RedisStreamHandler struct {
+	Repo     *repository.RedisStreamTriggerRepo
+	Consumer *engine.RedisStreamConsumer
+}
+
+func (h *RedisStreamHandler) List(w http.ResponseWriter, r *http.Request) {
+	triggers, err := h.Repo.List()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, triggers)
+}
+
+func (h *RedisStreamHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var t models.RedisStreamTrigger
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if msg := validateRedisStreamTrigger(&t); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	id, err := h.Repo.Create(&t)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	t.ID = id
+
+	// If enabled, start consuming immediately
+	if t.Enabled && h.Consumer != nil {
+		if err := h.Consumer.AddTrigger(&t); err != nil {
+			http.Error(w, "stream trigger created but failed to start consumer: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	writeJSON(w, http.StatusCreated, t)
+}
+
+func (h *RedisStreamHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	t, err := h.Repo.GetByID(id)
+	if err != nil {
+		http.Error(w, "stream trigger not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+func (h *RedisStreamHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	var t models.RedisStreamTrigger
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	t.ID = id
+	if msg := validateRedisStreamTrigger(&t); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Repo.Update(&t); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Sync with live consumer
+	if h.Consumer != nil {
+		if t.Enabled {
+			_ = h.Consumer.AddTrigger(&t)
+		} else {
+			h.Consumer.RemoveTrigger(t.ID)
+		}
+	}
+
+	writeJSON(w, http.StatusOK, t)
+}
+
+func (h *RedisStreamHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	if h.Consumer != nil {
+		h.Consumer.RemoveTrigger(id)
+	}
+
+	if err := h.Repo.Delete(id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// validateRedisStreamTrigger checks required fields and applies defaults.
+func validateRedisStreamTrigger(t *models.RedisStreamTrigger) string {
+	t.Stream = strings.TrimSpace(t.Stream)
+	t.Group = strings.TrimSpace(t.Group)
+	t.Consumer = strings.TrimSpace(t.Consumer)
+	if t.Stream == "" {
+		return "stream is required"
+	}
+	if t.Group == "" {
+		return "group is required"
+	}
+	if t.StartID == "" {
+		t.StartID = "$"
+	}
+	if t.Concurrency <= 0 {
+		t.Concurrency = 1
+	}
+	if t.Concurrency > engine.RedisStreamMaxConcurrency {
+		return fmt.Sprintf("concurrency must be at most %d", engine.RedisStreamMaxConcurrency)
+	}
+	if t.ClaimIdleSec <= 0 {
+		t.ClaimIdleSec = 300
+	}
+	if t.ExecTimeoutSec <= 0 {
+		t.ExecTimeoutSec = models.DefaultTriggerExecTimeoutSec
+	}
+	if t.MaxDeliveries <= 0 {
+		t.MaxDeliveries = engine.RedisStreamDefaultMaxDeliveries
+	}
+	return ""
+}
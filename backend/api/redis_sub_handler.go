@@ -1,6 +1,7 @@
 package api
 
 import (
+	"database/sql"
 	"encoding/json"
 	"net/http"
 	"strconv"
@@ -36,6 +37,10 @@ func (h *RedisSubHandler) Create(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "channel is required", http.StatusBadRequest)
 		return
 	}
+	if msg := normalizeTriggerLimits(&s.TriggerLimits); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
 
 	id, err := h.Repo.Create(&s)
 	if err != nil {
@@ -46,7 +51,7 @@ func (h *RedisSubHandler) Create(w http.ResponseWriter, r *http.Request) {
 
 	// If enabled, start listening immediately
 	if s.Enabled && h.Subscriber != nil {
-		if err := h.Subscriber.AddSubscription(s.ID, s.WorkflowID, s.ConfigID, s.Channel, s.IsPattern); err != nil {
+		if err := h.Subscriber.AddSubscription(s.ID, s.WorkflowID, s.ConfigID, s.Channel, s.IsPattern, s.TriggerLimits); err != nil {
 			http.Error(w, "subscription created but failed to start listener: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -82,6 +87,10 @@ func (h *RedisSubHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	s.ID = id
+	if msg := normalizeTriggerLimits(&s.TriggerLimits); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
 
 	if err := h.Repo.Update(&s); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -91,7 +100,7 @@ func (h *RedisSubHandler) Update(w http.ResponseWriter, r *http.Request) {
 	// Sync with live subscriber
 	if h.Subscriber != nil {
 		if s.Enabled {
-			_ = h.Subscriber.AddSubscription(s.ID, s.WorkflowID, s.ConfigID, s.Channel, s.IsPattern)
+			_ = h.Subscriber.AddSubscription(s.ID, s.WorkflowID, s.ConfigID, s.Channel, s.IsPattern, s.TriggerLimits)
 		} else {
 			h.Subscriber.RemoveSubscription(s.ID)
 		}
@@ -117,3 +126,36 @@ func (h *RedisSubHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	}
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// Status reports the listener health of a subscription: connection state, last error and
+// reconnect count. It is served from this instance when it runs the listener, otherwise from
+// the status the running instance last stored.
+func (h *RedisSubHandler) Status(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	s, err := h.Repo.GetByID(id)
+	if err != nil {
+		http.Error(w, "subscription not found", http.StatusNotFound)
+		return
+	}
+	if h.Subscriber != nil {
+		if st, ok := h.Subscriber.Status(id); ok && st.State != models.RedisSubStopped {
+			writeJSON(w, http.StatusOK, st)
+			return
+		}
+	}
+	st, err := h.Repo.GetStatus(id)
+	if err == sql.ErrNoRows {
+		st = &models.RedisSubscriptionStatus{SubscriptionID: id, State: models.RedisSubStopped}
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !s.Enabled {
+		st.State = models.RedisSubDisabled
+	}
+	writeJSON(w, http.StatusOK, st)
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"net/http"
 
+	"eflo/backend/authutil"
 	"eflo/backend/engine"
 	"eflo/backend/repository"
 
@@ -20,13 +21,20 @@ func NewRouter(
 	configStoreRepo *repository.ConfigStoreRepo,
 	cronRepo *repository.CronScheduleRepo,
 	redisSubRepo *repository.RedisSubscriptionRepo,
+	redisStreamRepo *repository.RedisStreamTriggerRepo,
 	emailTriggerRepo *repository.EmailTriggerRepo,
+	smtpTriggerRepo *repository.SmtpTriggerRepo,
 	httpTriggerRepo *repository.HttpTriggerRepo,
 	kbArticleRepo *repository.KBArticleRepo,
+	scriptLibRepo *repository.ScriptLibraryRepo,
+	calendarRepo *repository.ScheduleCalendarRepo,
+	deadLetterRepo *repository.DeadLetterRepo,
 	eng *engine.Engine,
 	scheduler *engine.Scheduler,
 	redisSub *engine.RedisSubscriber,
+	redisStreams *engine.RedisStreamConsumer,
 	emailPoller *engine.EmailPoller,
+	elector *engine.LeaderElector,
 ) http.Handler {
 	r := chi.NewRouter()
 
@@ -53,11 +61,17 @@ func NewRouter(
 	}
 	ch := &ConfigHandler{Repo: configRepo}
 	csh := &ConfigStoreHandler{Repo: configStoreRepo}
-	crh := &CronHandler{Repo: cronRepo, Scheduler: scheduler}
+	crh := &CronHandler{Repo: cronRepo, CalendarRepo: calendarRepo, Scheduler: scheduler}
+	sch := &ScheduleCalendarHandler{Repo: calendarRepo, Scheduler: scheduler}
 	rsh := &RedisSubHandler{Repo: redisSubRepo, Subscriber: redisSub}
+	rst := &RedisStreamHandler{Repo: redisStreamRepo, Consumer: redisStreams}
 	eth := &EmailTriggerHandler{Repo: emailTriggerRepo, Poller: emailPoller}
-	hth := &HttpTriggerHandler{Repo: httpTriggerRepo, WorkflowRepo: workflowRepo, Engine: eng}
+	smh := &SmtpTriggerHandler{Repo: smtpTriggerRepo}
+	hth := &HttpTriggerHandler{Repo: httpTriggerRepo, WorkflowRepo: workflowRepo, Engine: eng, Auth: authutil.NewVerifier(configStoreRepo)}
 	kbh := &KBHandler{Repo: kbArticleRepo}
+	slh := &ScriptLibraryHandler{Repo: scriptLibRepo}
+	clh := &ClusterHandler{Elector: elector, Engine: eng}
+	dlh := &DeadLetterHandler{Repo: deadLetterRepo, Engine: eng}
 
 	r.Route("/api", func(r chi.Router) {
 		// Workflow folders (tree)
@@ -85,6 +99,12 @@ func NewRouter(
 		r.Get("/executions/{id}", eh.GetExecution)
 		r.Get("/executions/{id}/logs", eh.GetExecutionLogs)
 		r.Get("/stats/executions", eh.Stats)
+		r.Get("/stats/function-runtime", eh.FunctionRuntimeStats)
+
+		// Cluster
+		r.Get("/cluster/leader", clh.Leader)
+		r.Get("/cluster/queue", clh.Queue)
+		r.Get("/cluster/queue/jobs/{id}", clh.QueueJob)
 
 		// Node Configs
 		r.Get("/configs", ch.List)
@@ -104,9 +124,19 @@ func NewRouter(
 		// Cron Schedules
 		r.Get("/schedules", crh.List)
 		r.Post("/schedules", crh.Create)
+		r.Post("/schedules/preview", crh.PreviewExpression)
 		r.Get("/schedules/{id}", crh.GetByID)
 		r.Put("/schedules/{id}", crh.Update)
 		r.Delete("/schedules/{id}", crh.Delete)
+		r.Get("/schedules/{id}/events", crh.Events)
+		r.Get("/schedules/{id}/preview", crh.Preview)
+
+		// Schedule exclusion calendars
+		r.Get("/schedule-calendars", sch.List)
+		r.Post("/schedule-calendars", sch.Create)
+		r.Get("/schedule-calendars/{id}", sch.GetByID)
+		r.Put("/schedule-calendars/{id}", sch.Update)
+		r.Delete("/schedule-calendars/{id}", sch.Delete)
 
 		// Redis Subscriptions
 		r.Get("/redis-subscriptions", rsh.List)
@@ -114,6 +144,14 @@ func NewRouter(
 		r.Get("/redis-subscriptions/{id}", rsh.GetByID)
 		r.Put("/redis-subscriptions/{id}", rsh.Update)
 		r.Delete("/redis-subscriptions/{id}", rsh.Delete)
+		r.Get("/redis-subscriptions/{id}/status", rsh.Status)
+
+		// Redis Streams (consumer-group triggers, at-least-once)
+		r.Get("/redis-streams", rst.List)
+		r.Post("/redis-streams", rst.Create)
+		r.Get("/redis-streams/{id}", rst.GetByID)
+		r.Put("/redis-streams/{id}", rst.Update)
+		r.Delete("/redis-streams/{id}", rst.Delete)
 
 		// Email Triggers
 		r.Get("/email-triggers", eth.List)
@@ -122,6 +160,13 @@ func NewRouter(
 		r.Put("/email-triggers/{id}", eth.Update)
 		r.Delete("/email-triggers/{id}", eth.Delete)
 
+		// SMTP Triggers (recipients routed by the embedded SMTP receiver)
+		r.Get("/smtp-triggers", smh.List)
+		r.Post("/smtp-triggers", smh.Create)
+		r.Get("/smtp-triggers/{id}", smh.GetByID)
+		r.Put("/smtp-triggers/{id}", smh.Update)
+		r.Delete("/smtp-triggers/{id}", smh.Delete)
+
 		// HTTP Triggers (HTTP-in / HTTP-out like Node-RED)
 		r.Get("/http-triggers", hth.List)
 		r.Post("/http-triggers", hth.Create)
@@ -129,6 +174,23 @@ func NewRouter(
 		r.Put("/http-triggers/{id}", hth.Update)
 		r.Delete("/http-triggers/{id}", hth.Delete)
 
+		// Dead letters (failed trigger events: inspect, replay, purge)
+		r.Get("/dead-letters", dlh.List)
+		r.Delete("/dead-letters", dlh.Purge)
+		r.Post("/dead-letters/replay", dlh.ReplayBulk)
+		r.Get("/dead-letters/{id}", dlh.GetByID)
+		r.Delete("/dead-letters/{id}", dlh.Delete)
+		r.Post("/dead-letters/{id}/replay", dlh.Replay)
+
+		// Script libraries (JS modules for require() in function nodes)
+		r.Get("/script-libraries", slh.List)
+		r.Post("/script-libraries", slh.Create)
+		r.Get("/script-libraries/{id}", slh.GetByID)
+		r.Put("/script-libraries/{id}", slh.Update)
+		r.Delete("/script-libraries/{id}", slh.Delete)
+		r.Get("/script-libraries/{id}/versions", slh.ListVersions)
+		r.Get("/script-libraries/{id}/versions/{version}", slh.GetVersion)
+
 		// Knowledge base (Confluence-style)
 		r.Get("/kb/articles", kbh.List)
 		r.Get("/kb/articles/tree", kbh.ListAll)
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"eflo/backend/engine"
+	"eflo/backend/models"
+	"eflo/backend/repository"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ScheduleCalendarHandler manages exclusion calendars referenced by cron schedules.
+type ScheduleCalendarHandler struct {
+	Repo      *repository.ScheduleCalendarRepo
+	Scheduler *engine.Scheduler
+}
+
+func (h *ScheduleCalendarHandler) List(w http.ResponseWriter, r *http.Request) {
+	list, err := h.Repo.List()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (h *ScheduleCalendarHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var c models.ScheduleCalendar
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if msg := validateScheduleCalendar(&c); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	id, err := h.Repo.Create(&c)
+	if err != nil {
+		writeScheduleCalendarError(w, err)
+		return
+	}
+	created, err := h.Repo.GetByID(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusCreated, created)
+}
+
+func (h *ScheduleCalendarHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	c, err := h.Repo.GetByID(id)
+	if err != nil {
+		http.Error(w, "calendar not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
+
+// Update saves the calendar and reloads the scheduler so schedules referencing it pick up
+// the new exclusions.
+func (h *ScheduleCalendarHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	var c models.ScheduleCalendar
+	if err := json.NewDecoder(r.Body).Decode(&c); err != nil {
+		http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.ID = id
+	if msg := validateScheduleCalendar(&c); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if err := h.Repo.Update(&c); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "calendar not found", http.StatusNotFound)
+			return
+		}
+		writeScheduleCalendarError(w, err)
+		return
+	}
+	h.reloadScheduler()
+	updated, err := h.Repo.GetByID(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, updated)
+}
+
+// Delete removes the calendar. Schedules still referencing it simply stop excluding its dates.
+func (h *ScheduleCalendarHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	if err := h.Repo.Delete(id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.reloadScheduler()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ScheduleCalendarHandler) reloadScheduler() {
+	if h.Scheduler != nil {
+		_ = h.Scheduler.Reload()
+	}
+}
+
+func validateScheduleCalendar(c *models.ScheduleCalendar) string {
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Name == "" {
+		return "name is required"
+	}
+	for i, d := range c.Dates {
+		c.Dates[i] = strings.TrimSpace(d)
+	}
+	if err := engine.ValidateCalendar(c); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+func writeScheduleCalendarError(w http.ResponseWriter, err error) {
+	if strings.Contains(err.Error(), "Duplicate entry") {
+		http.Error(w, "a calendar with this name already exists", http.StatusConflict)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
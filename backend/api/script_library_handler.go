@@ -0,0 +1,169 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"eflo/backend/models"
+	"eflo/backend/repository"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// scriptLibraryNamePattern keeps names usable in require('name') / require('name@3').
+var scriptLibraryNamePattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.\-/]*$`)
+
+type ScriptLibraryHandler struct {
+	Repo *repository.ScriptLibraryRepo
+}
+
+func (h *ScriptLibraryHandler) List(w http.ResponseWriter, r *http.Request) {
+	list, err := h.Repo.List()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (h *ScriptLibraryHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var l models.ScriptLibrary
+	if err := json.NewDecoder(r.Body).Decode(&l); err != nil {
+		http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if msg := validateScriptLibrary(&l); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	id, err := h.Repo.Create(&l)
+	if err != nil {
+		writeScriptLibraryError(w, err)
+		return
+	}
+	created, err := h.Repo.GetByID(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusCreated, created)
+}
+
+func (h *ScriptLibraryHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	l, err := h.Repo.GetByID(id)
+	if err != nil {
+		http.Error(w, "script library not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, l)
+}
+
+// Update saves the library; a code change creates a new version.
+func (h *ScriptLibraryHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	var l models.ScriptLibrary
+	if err := json.NewDecoder(r.Body).Decode(&l); err != nil {
+		http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	l.ID = id
+	if msg := validateScriptLibrary(&l); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if err := h.Repo.Update(&l); err != nil {
+		if err == sql.ErrNoRows {
+			http.Error(w, "script library not found", http.StatusNotFound)
+			return
+		}
+		writeScriptLibraryError(w, err)
+		return
+	}
+	updated, err := h.Repo.GetByID(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, updated)
+}
+
+func (h *ScriptLibraryHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	if err := h.Repo.Delete(id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ScriptLibraryHandler) ListVersions(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	list, err := h.Repo.ListVersions(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (h *ScriptLibraryHandler) GetVersion(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	version, err := strconv.Atoi(chi.URLParam(r, "version"))
+	if err != nil {
+		http.Error(w, "invalid version", http.StatusBadRequest)
+		return
+	}
+	v, err := h.Repo.GetVersion(id, version)
+	if err != nil {
+		http.Error(w, "version not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+func validateScriptLibrary(l *models.ScriptLibrary) string {
+	l.Name = strings.TrimSpace(l.Name)
+	if l.Name == "" {
+		return "name is required"
+	}
+	if !scriptLibraryNamePattern.MatchString(l.Name) {
+		return "name may only contain letters, digits, '_', '-', '.' and '/'"
+	}
+	if strings.TrimSpace(l.Code) == "" {
+		return "code is required"
+	}
+	return ""
+}
+
+func writeScriptLibraryError(w http.ResponseWriter, err error) {
+	if strings.Contains(err.Error(), "Duplicate entry") {
+		http.Error(w, "a script library with this name already exists", http.StatusConflict)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
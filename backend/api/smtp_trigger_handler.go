@@ -0,0 +1,165 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+
+	"eflo/backend/models"
+	"eflo/backend/repository"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type SmtpTriggerHandler struct {
+	Repo *repository.SmtpTriggerRepo
+}
+
+func (h *SmtpTriggerHandler) List(w http.ResponseWriter, r *http.Request) {
+	triggers, err := h.Repo.List()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, triggers)
+}
+
+func (h *SmtpTriggerHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var t models.SmtpTrigger
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if status, msg := h.validate(&t); msg != "" {
+		http.Error(w, msg, status)
+		return
+	}
+
+	id, err := h.Repo.Create(&t)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	t.ID = id
+	writeJSON(w, http.StatusCreated, t)
+}
+
+func (h *SmtpTriggerHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	t, err := h.Repo.GetByID(id)
+	if err != nil {
+		http.Error(w, "trigger not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+func (h *SmtpTriggerHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	var t models.SmtpTrigger
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		http.Error(w, "invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	t.ID = id
+	if status, msg := h.validate(&t); msg != "" {
+		http.Error(w, msg, status)
+		return
+	}
+	if err := h.Repo.Update(&t); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+func (h *SmtpTriggerHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	if err := h.Repo.Delete(id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// validate normalizes the recipients, fills defaults and rejects a recipient already routed by
+// another trigger (409), since each address or domain may map to one workflow only.
+func (h *SmtpTriggerHandler) validate(t *models.SmtpTrigger) (int, string) {
+	if t.WorkflowID == 0 {
+		return http.StatusBadRequest, "workflowId is required"
+	}
+	seen := make(map[string]bool)
+	var recipients []string
+	for _, r := range t.Recipients {
+		norm, err := normalizeSmtpRecipient(r)
+		if err != nil {
+			return http.StatusBadRequest, err.Error()
+		}
+		if norm != "" && !seen[norm] {
+			seen[norm] = true
+			recipients = append(recipients, norm)
+		}
+	}
+	if len(recipients) == 0 {
+		return http.StatusBadRequest, "at least one recipient address or @domain is required"
+	}
+	t.Recipients = recipients
+	if t.MaxAttachmentBytes <= 0 {
+		t.MaxAttachmentBytes = models.DefaultEmailMaxAttachmentBytes
+	}
+	if msg := normalizeTriggerLimits(&t.TriggerLimits); msg != "" {
+		return http.StatusBadRequest, msg
+	}
+
+	existing, err := h.Repo.List()
+	if err != nil {
+		return http.StatusInternalServerError, err.Error()
+	}
+	for _, other := range existing {
+		if other.ID == t.ID {
+			continue
+		}
+		for _, r := range other.Recipients {
+			if seen[strings.ToLower(r)] {
+				return http.StatusConflict, fmt.Sprintf("recipient %s is already routed by SMTP trigger %d", r, other.ID)
+			}
+		}
+	}
+	return 0, ""
+}
+
+// normalizeSmtpRecipient lowercases an address (orders@example.com) or domain pattern
+// (@example.com), rejecting anything else.
+func normalizeSmtpRecipient(r string) (string, error) {
+	r = strings.ToLower(strings.TrimSpace(r))
+	if r == "" {
+		return "", nil
+	}
+	if strings.HasPrefix(r, "@") {
+		domain := r[1:]
+		if domain == "" || strings.ContainsAny(domain, "@ <>,;") || strings.HasPrefix(domain, ".") {
+			return "", fmt.Errorf("invalid recipient domain %q", r)
+		}
+		return r, nil
+	}
+	addr, err := mail.ParseAddress(r)
+	if err != nil || addr.Address != r {
+		return "", fmt.Errorf("invalid recipient address %q (use name@domain or @domain)", r)
+	}
+	return r, nil
+}
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"strings"
+
+	"eflo/backend/models"
+)
+
+// normalizeTriggerLimits validates an event trigger's concurrency settings and fills defaults.
+func normalizeTriggerLimits(l *models.TriggerLimits) string {
+	l.OverflowPolicy = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(l.OverflowPolicy)), "-", "_")
+	if l.OverflowPolicy == "" {
+		l.OverflowPolicy = models.OverflowBlock
+	}
+	switch l.OverflowPolicy {
+	case models.OverflowBlock, models.OverflowDropOldest, models.OverflowReject:
+	default:
+		return "overflowPolicy must be one of block, drop_oldest, reject"
+	}
+	if l.MaxInFlight < 0 || l.BufferSize < 0 || l.ExecTimeoutSec < 0 {
+		return "maxInFlight, bufferSize and execTimeoutSec must not be negative"
+	}
+	if l.MaxInFlight == 0 {
+		l.MaxInFlight = models.DefaultTriggerMaxInFlight
+	}
+	if l.BufferSize == 0 {
+		l.BufferSize = models.DefaultTriggerBufferSize
+	}
+	if l.ExecTimeoutSec == 0 {
+		l.ExecTimeoutSec = models.DefaultTriggerExecTimeoutSec
+	}
+	return ""
+}
@@ -0,0 +1,328 @@
+package authutil
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"eflo/backend/models"
+)
+
+// JWKS caching: key sets are refetched after jwksTTL, or sooner when a token names an unknown
+// kid (keys were rotated), but at most once per jwksMinRefresh.
+const (
+	jwksTTL        = 10 * time.Minute
+	jwksMinRefresh = 30 * time.Second
+	jwksMaxBytes   = 1 << 20
+)
+
+type jwk struct {
+	kid string
+	alg string
+	key crypto.PublicKey // *rsa.PublicKey or *ecdsa.PublicKey
+}
+
+type keySet struct {
+	keys      []jwk
+	fetchedAt time.Time // last successful fetch
+	triedAt   time.Time // last fetch attempt
+}
+
+// verifyJWT checks a bearer token's signature and its exp, nbf, iss and aud claims. HS* tokens
+// are accepted only with a shared secret and RS*, PS*, ES* only with a JWKS, so a token can't
+// pick a weaker algorithm than the policy intends.
+func (v *Verifier) verifyJWT(ctx context.Context, a *models.HttpTriggerAuth, r *http.Request) (map[string]interface{}, error) {
+	challenge := `Bearer realm="eflo"`
+	bad := func(format string, args ...interface{}) *Error {
+		return &Error{Reason: fmt.Sprintf(format, args...), Challenge: challenge + `, error="invalid_token"`}
+	}
+
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return nil, &Error{Reason: "missing bearer token", Challenge: challenge}
+	}
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 3 {
+		return nil, bad("malformed token")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, bad("malformed token header")
+	}
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, bad("malformed token claims")
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, bad("malformed token signature")
+	}
+	signingInput := []byte(parts[0] + "." + parts[1])
+
+	if strings.HasPrefix(header.Alg, "HS") {
+		if a.JWKSURL != "" {
+			return nil, bad("algorithm %q not allowed", header.Alg)
+		}
+		secret, err := v.secret(a.SecretKey)
+		if err != nil {
+			return nil, err
+		}
+		if !verifyHMACJWT(header.Alg, []byte(secret), signingInput, sig) {
+			return nil, bad("invalid signature")
+		}
+	} else {
+		if a.JWKSURL == "" {
+			return nil, bad("algorithm %q not allowed", header.Alg)
+		}
+		keys, err := v.jwksKeys(ctx, a.JWKSURL, header.Kid)
+		if err != nil {
+			return nil, err
+		}
+		verified := false
+		for _, k := range keys {
+			if (header.Kid == "" || k.kid == header.Kid) && (k.alg == "" || k.alg == header.Alg) &&
+				verifyPublicKeyJWT(header.Alg, k.key, signingInput, sig) {
+				verified = true
+				break
+			}
+		}
+		if !verified {
+			return nil, bad("invalid signature")
+		}
+	}
+
+	leeway := time.Duration(a.ToleranceSec) * time.Second
+	now := time.Now()
+	if exp, ok := numericClaim(claims, "exp"); ok && now.After(exp.Add(leeway)) {
+		return nil, bad("token expired")
+	}
+	if nbf, ok := numericClaim(claims, "nbf"); ok && now.Add(leeway).Before(nbf) {
+		return nil, bad("token not valid yet")
+	}
+	if a.Issuer != "" && claims["iss"] != a.Issuer {
+		return nil, bad("unexpected issuer")
+	}
+	if a.Audience != "" && !hasAudience(claims["aud"], a.Audience) {
+		return nil, bad("unexpected audience")
+	}
+
+	info := map[string]interface{}{"type": models.HttpAuthJWT, "claims": claims}
+	if sub, ok := claims["sub"].(string); ok {
+		info["subject"] = sub
+	}
+	return info, nil
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+func numericClaim(claims map[string]interface{}, name string) (time.Time, bool) {
+	f, ok := claims[name].(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(f), 0), true
+}
+
+func hasAudience(aud interface{}, want string) bool {
+	switch a := aud.(type) {
+	case string:
+		return a == want
+	case []interface{}:
+		for _, s := range a {
+			if s == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func jwtHash(alg string) (crypto.Hash, bool) {
+	if len(alg) != 5 {
+		return 0, false
+	}
+	switch alg[2:] {
+	case "256":
+		return crypto.SHA256, true
+	case "384":
+		return crypto.SHA384, true
+	case "512":
+		return crypto.SHA512, true
+	}
+	return 0, false
+}
+
+func verifyHMACJWT(alg string, secret, signingInput, sig []byte) bool {
+	h, ok := jwtHash(alg)
+	if !ok {
+		return false
+	}
+	mac := hmac.New(h.New, secret)
+	mac.Write(signingInput)
+	return hmac.Equal(sig, mac.Sum(nil))
+}
+
+func verifyPublicKeyJWT(alg string, key crypto.PublicKey, signingInput, sig []byte) bool {
+	h, ok := jwtHash(alg)
+	if !ok {
+		return false
+	}
+	hasher := h.New()
+	hasher.Write(signingInput)
+	digest := hasher.Sum(nil)
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		switch alg[:2] {
+		case "RS":
+			return rsa.VerifyPKCS1v15(k, h, digest, sig) == nil
+		case "PS":
+			return rsa.VerifyPSS(k, h, digest, sig, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}) == nil
+		}
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if alg[:2] != "ES" || len(sig) != 2*size {
+			return false
+		}
+		// ES256 pairs with P-256, ES384 with P-384 and ES512 with P-521
+		if want := map[string]int{"256": 256, "384": 384, "512": 521}[alg[2:]]; k.Curve.Params().BitSize != want {
+			return false
+		}
+		rv := new(big.Int).SetBytes(sig[:size])
+		sv := new(big.Int).SetBytes(sig[size:])
+		return ecdsa.Verify(k, digest, rv, sv)
+	}
+	return false
+}
+
+// jwksKeys returns the cached keys of a JWKS URL, fetching them when missing, stale, or when kid
+// is not among them. While fetching fails the last known keys stay in use.
+func (v *Verifier) jwksKeys(ctx context.Context, url, kid string) ([]jwk, error) {
+	v.mu.Lock()
+	ks := v.keySets[url]
+	if ks != nil {
+		fresh := time.Since(ks.fetchedAt) < jwksTTL && (kid == "" || ks.has(kid))
+		if fresh || time.Since(ks.triedAt) < jwksMinRefresh {
+			v.mu.Unlock()
+			return ks.keys, nil
+		}
+		ks.triedAt = time.Now()
+	}
+	v.mu.Unlock()
+
+	keys, err := v.fetchJWKS(ctx, url)
+	if err != nil {
+		if ks != nil {
+			return ks.keys, nil
+		}
+		return nil, err
+	}
+	now := time.Now()
+	v.mu.Lock()
+	v.keySets[url] = &keySet{keys: keys, fetchedAt: now, triedAt: now}
+	v.mu.Unlock()
+	return keys, nil
+}
+
+func (ks *keySet) has(kid string) bool {
+	for _, k := range ks.keys {
+		if k.kid == kid {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *Verifier) fetchJWKS(ctx context.Context, url string) ([]jwk, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := v.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch JWKS: %s", resp.Status)
+	}
+	var doc struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Alg string `json:"alg"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, jwksMaxBytes)).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("parse JWKS: %w", err)
+	}
+
+	var keys []jwk
+	for _, k := range doc.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		var pub crypto.PublicKey
+		switch k.Kty {
+		case "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(k.N)
+			e, errE := base64.RawURLEncoding.DecodeString(k.E)
+			if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 {
+				continue
+			}
+			pub = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case "EC":
+			var curve elliptic.Curve
+			switch k.Crv {
+			case "P-256":
+				curve = elliptic.P256()
+			case "P-384":
+				curve = elliptic.P384()
+			case "P-521":
+				curve = elliptic.P521()
+			default:
+				continue
+			}
+			x, errX := base64.RawURLEncoding.DecodeString(k.X)
+			y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+			if errX != nil || errY != nil {
+				continue
+			}
+			pub = &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		default:
+			continue
+		}
+		keys = append(keys, jwk{kid: k.Kid, alg: k.Alg, key: pub})
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("JWKS at %s has no usable signing keys", url)
+	}
+	return keys, nil
+}
@@ -0,0 +1,337 @@
+package authutil
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"eflo/backend/models"
+)
+
+// mapSecrets is a SecretStore backed by a map.
+type mapSecrets map[string]string
+
+func (m mapSecrets) Get(key string) (string, bool, error) {
+	s, ok := m[key]
+	return s, ok, nil
+}
+
+func b64(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+func jsonSegment(t *testing.T, v interface{}) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b64(b)
+}
+
+// signingInput encodes the header and claims of a token.
+func signingInput(t *testing.T, alg, kid string, claims map[string]interface{}) string {
+	header := map[string]string{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	return jsonSegment(t, header) + "." + jsonSegment(t, claims)
+}
+
+func hsToken(t *testing.T, alg string, secret []byte, claims map[string]interface{}) string {
+	t.Helper()
+	h, _ := jwtHash(alg)
+	input := signingInput(t, alg, "", claims)
+	mac := hmac.New(h.New, secret)
+	mac.Write([]byte(input))
+	return input + "." + b64(mac.Sum(nil))
+}
+
+func rsToken(t *testing.T, alg, kid string, key *rsa.PrivateKey, claims map[string]interface{}) string {
+	t.Helper()
+	h, _ := jwtHash(alg)
+	input := signingInput(t, alg, kid, claims)
+	hasher := h.New()
+	hasher.Write([]byte(input))
+	var sig []byte
+	var err error
+	if strings.HasPrefix(alg, "PS") {
+		sig, err = rsa.SignPSS(rand.Reader, key, h, hasher.Sum(nil), &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	} else {
+		sig, err = rsa.SignPKCS1v15(rand.Reader, key, h, hasher.Sum(nil))
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return input + "." + b64(sig)
+}
+
+// esToken signs with key using alg's hash, whatever the key's curve, and encodes the signature
+// as JWS r||s padded to the curve size.
+func esToken(t *testing.T, alg, kid string, key *ecdsa.PrivateKey, claims map[string]interface{}) string {
+	t.Helper()
+	h, _ := jwtHash(alg)
+	input := signingInput(t, alg, kid, claims)
+	hasher := h.New()
+	hasher.Write([]byte(input))
+	r, s, err := ecdsa.Sign(rand.Reader, key, hasher.Sum(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := (key.Curve.Params().BitSize + 7) / 8
+	sig := make([]byte, 2*size)
+	r.FillBytes(sig[:size])
+	s.FillBytes(sig[size:])
+	return input + "." + b64(sig)
+}
+
+func rsaJWK(kid, alg string, key *rsa.PublicKey) map[string]string {
+	return map[string]string{"kty": "RSA", "kid": kid, "alg": alg, "use": "sig",
+		"n": b64(key.N.Bytes()), "e": b64(big.NewInt(int64(key.E)).Bytes())}
+}
+
+func ecJWK(kid string, key *ecdsa.PublicKey) map[string]string {
+	size := (key.Curve.Params().BitSize + 7) / 8
+	x, y := make([]byte, size), make([]byte, size)
+	key.X.FillBytes(x)
+	key.Y.FillBytes(y)
+	return map[string]string{"kty": "EC", "kid": kid, "crv": key.Curve.Params().Name, "x": b64(x), "y": b64(y)}
+}
+
+// jwksServer serves the current keys and counts the fetches.
+type jwksServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	keys    []map[string]string
+	fetches atomic.Int32
+}
+
+func newJWKSServer(t *testing.T, keys ...map[string]string) *jwksServer {
+	s := &jwksServer{keys: keys}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.fetches.Add(1)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": s.keys})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) setKeys(keys ...map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = keys
+}
+
+func bearer(token string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/hook", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	return r
+}
+
+// wantAuthError checks that err is a policy failure mentioning reason ("" = success).
+func wantAuthError(t *testing.T, err error, reason string) {
+	t.Helper()
+	if reason == "" {
+		if err != nil {
+			t.Fatalf("verify failed: %v", err)
+		}
+		return
+	}
+	var authErr *Error
+	if !errors.As(err, &authErr) || !strings.Contains(authErr.Reason, reason) {
+		t.Fatalf("error = %v, want a policy failure mentioning %q", err, reason)
+	}
+}
+
+func TestVerifyJWTAlgorithms(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	jwks := newJWKSServer(t, rsaJWK("rsa", "", &rsaKey.PublicKey), ecJWK("p256", &p256.PublicKey), ecJWK("p384", &p384.PublicKey))
+	secret := []byte("s3cret")
+	pubDER, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	claims := map[string]interface{}{"sub": "user-1"}
+
+	hsPolicy := &models.HttpTriggerAuth{Type: models.HttpAuthJWT, SecretKey: "jwt.secret"}
+	jwksPolicy := &models.HttpTriggerAuth{Type: models.HttpAuthJWT, JWKSURL: jwks.URL}
+
+	// esDER signs like esToken but with the ASN.1 signature encoding JWS does not use
+	esDER := func() string {
+		input := signingInput(t, "ES256", "p256", claims)
+		h := crypto.SHA256.New()
+		h.Write([]byte(input))
+		sig, err := ecdsa.SignASN1(rand.Reader, p256, h.Sum(nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return input + "." + b64(sig)
+	}
+
+	tests := []struct {
+		name   string
+		policy *models.HttpTriggerAuth
+		token  string
+		reason string // "" = accepted
+	}{
+		{"HS256 with secret", hsPolicy, hsToken(t, "HS256", secret, claims), ""},
+		{"HS512 with secret", hsPolicy, hsToken(t, "HS512", secret, claims), ""},
+		{"HS256 wrong secret", hsPolicy, hsToken(t, "HS256", []byte("other"), claims), "invalid signature"},
+		{"RS256 with JWKS", jwksPolicy, rsToken(t, "RS256", "rsa", rsaKey, claims), ""},
+		{"PS384 with JWKS", jwksPolicy, rsToken(t, "PS384", "rsa", rsaKey, claims), ""},
+		{"ES256 with JWKS", jwksPolicy, esToken(t, "ES256", "p256", p256, claims), ""},
+		{"ES384 with JWKS", jwksPolicy, esToken(t, "ES384", "p384", p384, claims), ""},
+		{"no kid tries every key", jwksPolicy, esToken(t, "ES256", "", p256, claims), ""},
+
+		// alg confusion: an HS token keyed with the public key must not pass a JWKS policy, and a
+		// public-key token must not pass a secret policy
+		{"HS256 keyed with the RSA public key against JWKS", jwksPolicy, hsToken(t, "HS256", pubDER, claims), `"HS256" not allowed`},
+		{"HS256 against JWKS", jwksPolicy, hsToken(t, "HS256", secret, claims), `"HS256" not allowed`},
+		{"RS256 against secret", hsPolicy, rsToken(t, "RS256", "rsa", rsaKey, claims), `"RS256" not allowed`},
+
+		// alg none
+		{"none against secret", hsPolicy, signingInput(t, "none", "", claims) + ".", `"none" not allowed`},
+		{"none against JWKS", jwksPolicy, signingInput(t, "none", "", claims) + ".", "invalid signature"},
+
+		// ES curve and signature shape
+		{"ES384 signed with a P-256 key", jwksPolicy, esToken(t, "ES384", "p256", p256, claims), "invalid signature"},
+		{"ES256 signed with a P-384 key", jwksPolicy, esToken(t, "ES256", "p384", p384, claims), "invalid signature"},
+		{"ES256 with a DER signature", jwksPolicy, esDER(), "invalid signature"},
+		{"ES256 with a truncated signature", jwksPolicy, truncated(esToken(t, "ES256", "p256", p256, claims)), "invalid signature"},
+		{"RS256 signed by another key", jwksPolicy, rsToken(t, "RS256", "rsa", mustRSAKey(t), claims), "invalid signature"},
+
+		// shape
+		{"two segments", hsPolicy, "abc.def", "malformed token"},
+		{"bad header", hsPolicy, "!!.e30.sig", "malformed token header"},
+	}
+	v := NewVerifier(mapSecrets{"jwt.secret": string(secret)})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := v.Verify(context.Background(), tt.policy, bearer(tt.token), nil)
+			wantAuthError(t, err, tt.reason)
+			if tt.reason == "" && info["subject"] != "user-1" {
+				t.Errorf("info = %v, want subject user-1", info)
+			}
+		})
+	}
+}
+
+// truncated drops the last bytes of a token's signature.
+func truncated(token string) string {
+	return token[:len(token)-4]
+}
+
+func mustRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	k, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestVerifyJWTClaims(t *testing.T) {
+	secret := []byte("s3cret")
+	now := time.Now().Unix()
+	policy := &models.HttpTriggerAuth{Type: models.HttpAuthJWT, SecretKey: "jwt.secret", ToleranceSec: 30,
+		Issuer: "https://issuer.example", Audience: "eflo"}
+	base := func(extra map[string]interface{}) map[string]interface{} {
+		c := map[string]interface{}{"iss": "https://issuer.example", "aud": "eflo"}
+		for k, v := range extra {
+			c[k] = v
+		}
+		return c
+	}
+
+	tests := []struct {
+		name   string
+		claims map[string]interface{}
+		reason string
+	}{
+		{"valid", base(map[string]interface{}{"exp": now + 60, "nbf": now - 60}), ""},
+		{"expired within leeway", base(map[string]interface{}{"exp": now - 20}), ""},
+		{"expired beyond leeway", base(map[string]interface{}{"exp": now - 40}), "token expired"},
+		{"not yet valid within leeway", base(map[string]interface{}{"nbf": now + 20}), ""},
+		{"not yet valid beyond leeway", base(map[string]interface{}{"nbf": now + 40}), "not valid yet"},
+		{"audience array", base(map[string]interface{}{"aud": []string{"other", "eflo"}}), ""},
+		{"audience array without ours", base(map[string]interface{}{"aud": []string{"other"}}), "unexpected audience"},
+		{"wrong audience", base(map[string]interface{}{"aud": "other"}), "unexpected audience"},
+		{"missing audience", map[string]interface{}{"iss": "https://issuer.example"}, "unexpected audience"},
+		{"wrong issuer", base(map[string]interface{}{"iss": "https://evil.example"}), "unexpected issuer"},
+	}
+	v := NewVerifier(mapSecrets{"jwt.secret": string(secret)})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := v.Verify(context.Background(), policy, bearer(hsToken(t, "HS256", secret, tt.claims)), nil)
+			wantAuthError(t, err, tt.reason)
+		})
+	}
+}
+
+func TestJWKSRefetchOnUnknownKid(t *testing.T) {
+	old, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	rotated, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	jwks := newJWKSServer(t, ecJWK("k1", &old.PublicKey))
+	policy := &models.HttpTriggerAuth{Type: models.HttpAuthJWT, JWKSURL: jwks.URL}
+	v := NewVerifier(nil)
+	verify := func(kid string, key *ecdsa.PrivateKey) error {
+		_, err := v.Verify(context.Background(), policy, bearer(esToken(t, "ES256", kid, key, map[string]interface{}{})), nil)
+		return err
+	}
+
+	wantAuthError(t, verify("k1", old), "")
+	wantAuthError(t, verify("k1", old), "")
+	if n := jwks.fetches.Load(); n != 1 {
+		t.Fatalf("%d fetches, want the key set cached after the first", n)
+	}
+
+	// The issuer rotates its keys; the new kid is unknown, but the last fetch was too recent
+	jwks.setKeys(ecJWK("k1", &old.PublicKey), ecJWK("k2", &rotated.PublicKey))
+	wantAuthError(t, verify("k2", rotated), "invalid signature")
+	if n := jwks.fetches.Load(); n != 1 {
+		t.Fatalf("%d fetches, want no refetch within %v", n, jwksMinRefresh)
+	}
+
+	// Once the minimum refresh interval has passed, an unknown kid triggers a refetch
+	v.mu.Lock()
+	v.keySets[jwks.URL].triedAt = time.Now().Add(-jwksMinRefresh)
+	v.mu.Unlock()
+	wantAuthError(t, verify("k2", rotated), "")
+	if n := jwks.fetches.Load(); n != 2 {
+		t.Fatalf("%d fetches, want a refetch for the unknown kid", n)
+	}
+
+	// Tokens naming kids the issuer never published don't cause more fetches
+	for i := 0; i < 5; i++ {
+		wantAuthError(t, verify("bogus", rotated), "invalid signature")
+	}
+	if n := jwks.fetches.Load(); n != 2 {
+		t.Errorf("%d fetches, want unknown kids rate limited", n)
+	}
+
+	// While the JWKS is unreachable the last known keys stay in use
+	v.mu.Lock()
+	v.keySets[jwks.URL].triedAt = time.Now().Add(-jwksMinRefresh)
+	v.keySets[jwks.URL].fetchedAt = time.Now().Add(-jwksTTL)
+	v.mu.Unlock()
+	jwks.Close()
+	wantAuthError(t, verify("k1", old), "")
+}
@@ -0,0 +1,252 @@
+// Package authutil verifies the authentication policies of inbound HTTP triggers: static API
+// keys, HMAC signatures of the raw body, Basic auth and JWT bearer tokens.
+package authutil
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"eflo/backend/models"
+)
+
+// SecretStore looks up secrets by key; the config store implements it.
+type SecretStore interface {
+	Get(key string) (string, bool, error)
+}
+
+// Error is a request failing its trigger's policy. It is answered with 401 and, when set,
+// Challenge as the WWW-Authenticate header.
+type Error struct {
+	Reason    string
+	Challenge string
+}
+
+func (e *Error) Error() string { return e.Reason }
+
+func fail(format string, args ...interface{}) *Error {
+	return &Error{Reason: fmt.Sprintf(format, args...)}
+}
+
+// Verifier checks requests against HttpTriggerAuth policies. It caches the key sets of JWKS
+// URLs, so one Verifier should serve all triggers.
+type Verifier struct {
+	Secrets SecretStore
+	Client  *http.Client // fetches JWKS
+
+	mu      sync.Mutex
+	keySets map[string]*keySet // JWKS URL -> keys
+}
+
+func NewVerifier(secrets SecretStore) *Verifier {
+	return &Verifier{
+		Secrets: secrets,
+		Client:  &http.Client{Timeout: 10 * time.Second},
+		keySets: make(map[string]*keySet),
+	}
+}
+
+// Verify authenticates a request whose raw body has already been read. It returns what the
+// workflow learns about the caller (nil without a policy). An *Error means the request failed
+// the policy; any other error that the policy could not be checked, e.g. a missing secret or an
+// unreachable JWKS.
+func (v *Verifier) Verify(ctx context.Context, a *models.HttpTriggerAuth, r *http.Request, body []byte) (map[string]interface{}, error) {
+	if a == nil {
+		return nil, nil
+	}
+	switch a.Type {
+	case "", models.HttpAuthNone:
+		return nil, nil
+	case models.HttpAuthAPIKey:
+		return v.verifyAPIKey(a, r)
+	case models.HttpAuthHMAC:
+		return v.verifyHMAC(a, r, body)
+	case models.HttpAuthBasic:
+		return v.verifyBasic(a, r)
+	case models.HttpAuthJWT:
+		return v.verifyJWT(ctx, a, r)
+	}
+	return nil, fmt.Errorf("unknown auth type %q", a.Type)
+}
+
+func (v *Verifier) secret(key string) (string, error) {
+	if key == "" {
+		return "", fmt.Errorf("auth secretKey is not set")
+	}
+	if v.Secrets == nil {
+		return "", fmt.Errorf("config store unavailable")
+	}
+	s, ok, err := v.Secrets.Get(key)
+	if err != nil {
+		return "", fmt.Errorf("read config store key %q: %w", key, err)
+	}
+	if !ok || s == "" {
+		return "", fmt.Errorf("config store key %q is empty", key)
+	}
+	return s, nil
+}
+
+func (v *Verifier) verifyAPIKey(a *models.HttpTriggerAuth, r *http.Request) (map[string]interface{}, error) {
+	want, err := v.secret(a.SecretKey)
+	if err != nil {
+		return nil, err
+	}
+	var got string
+	if a.QueryParam != "" {
+		got = r.URL.Query().Get(a.QueryParam)
+	}
+	if got == "" && a.Header != "" {
+		got = r.Header.Get(a.Header)
+		if strings.EqualFold(a.Header, "Authorization") {
+			if scheme, token, ok := strings.Cut(got, " "); ok && strings.EqualFold(scheme, "Bearer") {
+				got = token
+			}
+		}
+	}
+	if got == "" {
+		return nil, fail("missing API key")
+	}
+	if subtle.ConstantTimeCompare([]byte(got), []byte(want)) != 1 {
+		return nil, fail("invalid API key")
+	}
+	return map[string]interface{}{"type": models.HttpAuthAPIKey}, nil
+}
+
+func (v *Verifier) verifyBasic(a *models.HttpTriggerAuth, r *http.Request) (map[string]interface{}, error) {
+	password, err := v.secret(a.SecretKey)
+	if err != nil {
+		return nil, err
+	}
+	challenge := `Basic realm="eflo", charset="UTF-8"`
+	user, pass, ok := r.BasicAuth()
+	if !ok {
+		return nil, &Error{Reason: "missing Basic credentials", Challenge: challenge}
+	}
+	userOK := subtle.ConstantTimeCompare([]byte(user), []byte(a.Username))
+	passOK := subtle.ConstantTimeCompare([]byte(pass), []byte(password))
+	if userOK&passOK != 1 {
+		return nil, &Error{Reason: "invalid credentials", Challenge: challenge}
+	}
+	return map[string]interface{}{"type": models.HttpAuthBasic, "username": user}, nil
+}
+
+// verifyHMAC checks a signature of the raw body, optionally bound to a timestamp that must be
+// within the tolerance so captured requests can't be replayed later.
+func (v *Verifier) verifyHMAC(a *models.HttpTriggerAuth, r *http.Request, body []byte) (map[string]interface{}, error) {
+	secret, err := v.secret(a.SecretKey)
+	if err != nil {
+		return nil, err
+	}
+	newHash, err := HashFunc(a.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	header := strings.TrimSpace(r.Header.Get(a.Header))
+	if header == "" {
+		return nil, fail("missing %s header", a.Header)
+	}
+
+	var timestamp string
+	var signatures []string
+	if a.Format == models.HmacFormatStripe {
+		for _, part := range strings.Split(header, ",") {
+			k, val, _ := strings.Cut(strings.TrimSpace(part), "=")
+			switch k {
+			case "t":
+				timestamp = val
+			case "v1":
+				signatures = append(signatures, val)
+			}
+		}
+		if timestamp == "" || len(signatures) == 0 {
+			return nil, fail("malformed %s header", a.Header)
+		}
+	} else {
+		if !strings.HasPrefix(header, a.Prefix) {
+			return nil, fail("malformed %s header", a.Header)
+		}
+		signatures = []string{strings.TrimPrefix(header, a.Prefix)}
+		if a.TimestampHeader != "" {
+			if timestamp = strings.TrimSpace(r.Header.Get(a.TimestampHeader)); timestamp == "" {
+				return nil, fail("missing %s header", a.TimestampHeader)
+			}
+		}
+	}
+
+	signed := body
+	if timestamp != "" {
+		ts, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			return nil, fail("invalid signature timestamp")
+		}
+		age := time.Since(time.Unix(ts, 0))
+		if tolerance := time.Duration(a.ToleranceSec) * time.Second; age > tolerance || age < -tolerance {
+			return nil, fail("signature timestamp outside the %ds tolerance", a.ToleranceSec)
+		}
+		signed = signedPayload(a.SignedPayload, timestamp, body)
+	}
+
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(signed)
+	expected := mac.Sum(nil)
+	for _, s := range signatures {
+		if got, err := decodeSignature(s, a.Encoding); err == nil && hmac.Equal(got, expected) {
+			info := map[string]interface{}{"type": models.HttpAuthHMAC}
+			if timestamp != "" {
+				info["timestamp"] = timestamp
+			}
+			return info, nil
+		}
+	}
+	return nil, fail("signature mismatch")
+}
+
+// signedPayload fills the {timestamp} and {body} placeholders of tpl; the body is inserted
+// verbatim, so placeholders in it are not expanded.
+func signedPayload(tpl, timestamp string, body []byte) []byte {
+	before, after, found := strings.Cut(tpl, "{body}")
+	before = strings.ReplaceAll(before, "{timestamp}", timestamp)
+	if !found {
+		return []byte(before)
+	}
+	after = strings.ReplaceAll(after, "{timestamp}", timestamp)
+	out := make([]byte, 0, len(before)+len(body)+len(after))
+	out = append(out, before...)
+	out = append(out, body...)
+	return append(out, after...)
+}
+
+func decodeSignature(s, encoding string) ([]byte, error) {
+	if encoding == "base64" {
+		if b, err := base64.StdEncoding.DecodeString(s); err == nil {
+			return b, nil
+		}
+		return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	}
+	return hex.DecodeString(strings.ToLower(s))
+}
+
+// HashFunc returns the hash of an HMAC algorithm name: sha1, sha256 or sha512.
+func HashFunc(algorithm string) (func() hash.Hash, error) {
+	switch algorithm {
+	case "sha1":
+		return sha1.New, nil
+	case "sha256":
+		return sha256.New, nil
+	case "sha512":
+		return sha512.New, nil
+	}
+	return nil, fmt.Errorf("unsupported HMAC algorithm %q (want sha1, sha256 or sha512)", algorithm)
+}
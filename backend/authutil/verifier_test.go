@@ -0,0 +1,59 @@
+package authutil
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"eflo/backend/models"
+)
+
+func itoa(n int64) string { return strconv.FormatInt(n, 10) }
+
+func TestVerifyHMACTimestamp(t *testing.T) {
+	secret := "whsec"
+	body := []byte(`{"event":"paid"}`)
+	sign := func(ts int64, payload []byte) string {
+		mac := hmac.New(crypto.SHA256.New, []byte(secret))
+		mac.Write([]byte(itoa(ts) + "." + string(payload)))
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+	stripe := &models.HttpTriggerAuth{Type: models.HttpAuthHMAC, SecretKey: "hmac.secret", Header: "Stripe-Signature",
+		Algorithm: "sha256", Encoding: "hex", Format: models.HmacFormatStripe, SignedPayload: "{timestamp}.{body}", ToleranceSec: 300}
+	now := time.Now().Unix()
+
+	tests := []struct {
+		name   string
+		header string
+		body   []byte
+		reason string
+	}{
+		{"fresh", "t=" + itoa(now) + ",v1=" + sign(now, body), body, ""},
+		{"within tolerance", "t=" + itoa(now-290) + ",v1=" + sign(now-290, body), body, ""},
+		{"clock ahead within tolerance", "t=" + itoa(now+290) + ",v1=" + sign(now+290, body), body, ""},
+		{"several signatures, one valid", "t=" + itoa(now) + ",v1=00ff,v1=" + sign(now, body), body, ""},
+		// A request captured earlier can't be replayed once the window has passed
+		{"replayed after the window", "t=" + itoa(now-310) + ",v1=" + sign(now-310, body), body, "outside the 300s tolerance"},
+		{"too far in the future", "t=" + itoa(now+310) + ",v1=" + sign(now+310, body), body, "outside the 300s tolerance"},
+		// Moving a captured signature into the window breaks it, since the timestamp is signed
+		{"replay with a fresh timestamp", "t=" + itoa(now) + ",v1=" + sign(now-310, body), body, "signature mismatch"},
+		{"tampered body", "t=" + itoa(now) + ",v1=" + sign(now, body), []byte(`{"event":"refund"}`), "signature mismatch"},
+		{"non-numeric timestamp", "t=yesterday,v1=" + sign(now, body), body, "invalid signature timestamp"},
+		{"missing timestamp", "v1=" + sign(now, body), body, "malformed"},
+	}
+	v := NewVerifier(mapSecrets{"hmac.secret": secret})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/hook", nil)
+			r.Header.Set("Stripe-Signature", tt.header)
+			_, err := v.Verify(context.Background(), stripe, r, tt.body)
+			wantAuthError(t, err, tt.reason)
+		})
+	}
+}
@@ -1,6 +1,11 @@
 package config
 
-import "os"
+import (
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+)
 
 type Config struct {
 	DBHost     string
@@ -9,6 +14,53 @@ type Config struct {
 	DBPassword string
 	DBName     string
 	ServerPort string
+
+	// Function node runtime: warm V8 isolates kept in the pool (0 = one per CPU).
+	FunctionPoolSize int
+	// Hosts and CIDRs function-node fetch may reach although they are internal (loopback,
+	// private, link-local); empty denies all of them.
+	FunctionFetchAllowPrivate []string
+
+	// Leader election for cron, Redis and email triggers: "mysql" (lease table, default),
+	// "redis" or "none" (every instance runs them). InstanceID defaults to hostname-pid.
+	LeaderElection      string
+	LeaderLeaseTTLSec   int
+	InstanceID          string
+	LeaderRedisAddr     string
+	LeaderRedisPassword string
+	LeaderRedisDB       int
+
+	// Roles this process runs: any of "api" (REST + HTTP-in), "scheduler" (cron, Redis and
+	// email triggers) and "worker" (runs queued executions). Default: all three.
+	Roles []string
+	// ExecutionQueue hands trigger-started runs to workers: "none" (run in-process, default),
+	// "mysql" or "redis".
+	ExecutionQueue     string
+	QueueRedisAddr     string
+	QueueRedisPassword string
+	QueueRedisDB       int
+	QueueVisibilitySec int
+	QueueMaxAttempts   int
+	WorkerConcurrency  int
+
+	// Embedded SMTP receiver for SMTP triggers, run by API instances when a port is set.
+	// STARTTLS is offered when a certificate and key are configured.
+	SmtpReceiverPort        string
+	SmtpReceiverHostname    string
+	SmtpReceiverMaxBytes    int
+	SmtpReceiverTLSCert     string
+	SmtpReceiverTLSKey      string
+	SmtpReceiverMaxBuffered int64 // bytes of accepted mail waiting for runs; more is deferred
+}
+
+// HasRole reports whether role is enabled for this process.
+func (c *Config) HasRole(role string) bool {
+	for _, r := range c.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
 }
 
 func Load() *Config {
@@ -19,6 +71,32 @@ func Load() *Config {
 		DBPassword: getEnv("DB_PASSWORD", "rootpass"),
 		DBName:     getEnv("DB_NAME", "eflo"),
 		ServerPort: getEnv("SERVER_PORT", "8080"),
+
+		FunctionPoolSize:          getEnvInt("FUNCTION_POOL_SIZE", 0),
+		FunctionFetchAllowPrivate: getEnvList("FUNCTION_FETCH_ALLOW_PRIVATE", ""),
+
+		LeaderElection:      getEnv("LEADER_ELECTION", "mysql"),
+		LeaderLeaseTTLSec:   getEnvInt("LEADER_LEASE_TTL_SEC", 15),
+		InstanceID:          getEnv("INSTANCE_ID", ""),
+		LeaderRedisAddr:     getEnv("LEADER_REDIS_ADDR", "127.0.0.1:6379"),
+		LeaderRedisPassword: getEnv("LEADER_REDIS_PASSWORD", ""),
+		LeaderRedisDB:       getEnvInt("LEADER_REDIS_DB", 0),
+
+		Roles:              getEnvList("ROLES", "api,scheduler,worker"),
+		ExecutionQueue:     getEnv("EXECUTION_QUEUE", "none"),
+		QueueRedisAddr:     getEnv("QUEUE_REDIS_ADDR", "127.0.0.1:6379"),
+		QueueRedisPassword: getEnv("QUEUE_REDIS_PASSWORD", ""),
+		QueueRedisDB:       getEnvInt("QUEUE_REDIS_DB", 0),
+		QueueVisibilitySec: getEnvInt("QUEUE_VISIBILITY_SEC", 30),
+		QueueMaxAttempts:   getEnvInt("QUEUE_MAX_ATTEMPTS", 3),
+		WorkerConcurrency:  getEnvInt("WORKER_CONCURRENCY", runtime.NumCPU()),
+
+		SmtpReceiverPort:        getEnv("SMTP_RECEIVER_PORT", ""),
+		SmtpReceiverHostname:    getEnv("SMTP_RECEIVER_HOSTNAME", ""),
+		SmtpReceiverMaxBytes:    getEnvInt("SMTP_RECEIVER_MAX_BYTES", 25<<20),
+		SmtpReceiverTLSCert:     getEnv("SMTP_RECEIVER_TLS_CERT", ""),
+		SmtpReceiverTLSKey:      getEnv("SMTP_RECEIVER_TLS_KEY", ""),
+		SmtpReceiverMaxBuffered: int64(getEnvInt("SMTP_RECEIVER_MAX_BUFFERED_BYTES", 256<<20)),
 	}
 }
 
@@ -32,3 +110,23 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+func getEnvInt(key string, fallback int) int {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+	}
+	return fallback
+}
+
+// getEnvList splits a comma-separated variable, trimming and lowercasing each entry.
+func getEnvList(key, fallback string) []string {
+	var list []string
+	for _, v := range strings.Split(getEnv(key, fallback), ",") {
+		if v = strings.ToLower(strings.TrimSpace(v)); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
@@ -90,6 +90,25 @@ func RunMigrations(db *sql.DB) error {
 			FOREIGN KEY (config_id) REFERENCES node_configs(id) ON DELETE CASCADE
 		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`,
 
+		`CREATE TABLE IF NOT EXISTS redis_stream_triggers (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			workflow_id BIGINT NOT NULL,
+			config_id BIGINT NOT NULL,
+			stream VARCHAR(500) NOT NULL,
+			group_name VARCHAR(255) NOT NULL,
+			consumer VARCHAR(255) NOT NULL DEFAULT '',
+			start_id VARCHAR(64) NOT NULL DEFAULT '$',
+			concurrency INT NOT NULL DEFAULT 1,
+			claim_idle_sec INT NOT NULL DEFAULT 300,
+			enabled BOOLEAN NOT NULL DEFAULT TRUE,
+			last_msg_at TIMESTAMP NULL,
+			msg_count BIGINT NOT NULL DEFAULT 0,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			FOREIGN KEY (workflow_id) REFERENCES workflows(id) ON DELETE CASCADE,
+			FOREIGN KEY (config_id) REFERENCES node_configs(id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`,
+
 		`CREATE TABLE IF NOT EXISTS email_triggers (
 			id BIGINT AUTO_INCREMENT PRIMARY KEY,
 			workflow_id BIGINT NOT NULL,
@@ -148,6 +167,140 @@ func RunMigrations(db *sql.DB) error {
 			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
 			FOREIGN KEY (parent_id) REFERENCES kb_articles(id) ON DELETE CASCADE
 		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`,
+
+		`CREATE TABLE IF NOT EXISTS cron_schedule_events (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			schedule_id BIGINT NOT NULL,
+			event VARCHAR(50) NOT NULL,
+			scheduled_at TIMESTAMP NULL,
+			execution_id BIGINT NULL,
+			detail TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (schedule_id) REFERENCES cron_schedules(id) ON DELETE CASCADE,
+			KEY idx_cron_schedule_events_schedule (schedule_id, id)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`,
+
+		`CREATE TABLE IF NOT EXISTS schedule_calendars (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			description VARCHAR(1000) DEFAULT '',
+			timezone VARCHAR(100) NOT NULL DEFAULT '',
+			dates JSON,
+			windows JSON,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			UNIQUE KEY uq_schedule_calendar_name (name)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`,
+
+		`CREATE TABLE IF NOT EXISTS leader_leases (
+			name VARCHAR(100) PRIMARY KEY,
+			holder VARCHAR(255) NOT NULL DEFAULT '',
+			acquired_at TIMESTAMP(6) NULL,
+			renewed_at TIMESTAMP(6) NULL,
+			expires_at TIMESTAMP(6) NULL,
+			version BIGINT NOT NULL DEFAULT 0
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`,
+
+		`CREATE TABLE IF NOT EXISTS execution_queue (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			workflow_id BIGINT NOT NULL,
+			input JSON,
+			source VARCHAR(50) NOT NULL DEFAULT '',
+			status VARCHAR(20) NOT NULL DEFAULT 'pending',
+			attempts INT NOT NULL DEFAULT 0,
+			max_attempts INT NOT NULL DEFAULT 3,
+			worker_id VARCHAR(255) NULL,
+			visible_at TIMESTAMP(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+			timeout_ms BIGINT NOT NULL DEFAULT 0,
+			execution_id BIGINT NULL,
+			last_error TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			KEY idx_execution_queue_claim (status, visible_at, id),
+			KEY idx_execution_queue_updated (updated_at)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`,
+
+		`CREATE TABLE IF NOT EXISTS redis_subscription_status (
+			subscription_id BIGINT PRIMARY KEY,
+			state VARCHAR(20) NOT NULL,
+			instance VARCHAR(255) NOT NULL DEFAULT '',
+			connected_at TIMESTAMP NULL,
+			last_error TEXT,
+			last_error_at TIMESTAMP NULL,
+			reconnect_count BIGINT NOT NULL DEFAULT 0,
+			next_retry_at TIMESTAMP NULL,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			FOREIGN KEY (subscription_id) REFERENCES redis_subscriptions(id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`,
+
+		`CREATE TABLE IF NOT EXISTS dead_letters (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			trigger_type VARCHAR(20) NOT NULL,
+			trigger_id BIGINT NOT NULL,
+			workflow_id BIGINT NOT NULL,
+			payload JSON NULL,
+			execution_id BIGINT NULL,
+			error TEXT,
+			status VARCHAR(20) NOT NULL DEFAULT 'pending',
+			replay_count INT NOT NULL DEFAULT 0,
+			replay_execution_id BIGINT NULL,
+			replayed_at TIMESTAMP NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			INDEX idx_dead_letters_trigger (trigger_type, trigger_id),
+			INDEX idx_dead_letters_status (status, created_at),
+			FOREIGN KEY (workflow_id) REFERENCES workflows(id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`,
+
+		`CREATE TABLE IF NOT EXISTS script_libraries (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			description VARCHAR(1000) DEFAULT '',
+			code MEDIUMTEXT NOT NULL,
+			version INT NOT NULL DEFAULT 1,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			UNIQUE KEY uq_script_library_name (name)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`,
+
+		`CREATE TABLE IF NOT EXISTS script_library_versions (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			library_id BIGINT NOT NULL,
+			version INT NOT NULL,
+			code MEDIUMTEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (library_id) REFERENCES script_libraries(id) ON DELETE CASCADE,
+			UNIQUE KEY uq_script_library_version (library_id, version)
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`,
+
+		`CREATE TABLE IF NOT EXISTS smtp_triggers (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			workflow_id BIGINT NOT NULL,
+			recipients JSON NOT NULL,
+			enabled BOOLEAN NOT NULL DEFAULT TRUE,
+			max_attachment_bytes INT NOT NULL DEFAULT 10485760,
+			max_in_flight INT NOT NULL DEFAULT 1,
+			buffer_size INT NOT NULL DEFAULT 100,
+			overflow_policy VARCHAR(20) NOT NULL DEFAULT 'block',
+			exec_timeout_sec INT NOT NULL DEFAULT 300,
+			last_received_at TIMESTAMP NULL,
+			msg_count BIGINT NOT NULL DEFAULT 0,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			FOREIGN KEY (workflow_id) REFERENCES workflows(id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`,
+
+		`CREATE TABLE IF NOT EXISTS smtp_spool (
+			id BIGINT AUTO_INCREMENT PRIMARY KEY,
+			trigger_id BIGINT NOT NULL,
+			workflow_id BIGINT NOT NULL,
+			payload JSON NULL,
+			owner VARCHAR(255) NOT NULL,
+			leased_until TIMESTAMP(6) NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			KEY idx_smtp_spool_lease (leased_until, id),
+			FOREIGN KEY (workflow_id) REFERENCES workflows(id) ON DELETE CASCADE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;`,
 	}
 
 	for _, q := range queries {
@@ -156,10 +309,53 @@ func RunMigrations(db *sql.DB) error {
 		}
 	}
 
-	// Add folder_id to workflows if not present (for existing DBs)
+	// Add columns introduced after the initial schema if not present (for existing DBs)
 	alterQueries := []string{
 		"ALTER TABLE workflows ADD COLUMN folder_id BIGINT NULL",
 		"ALTER TABLE workflows ADD CONSTRAINT fk_workflow_folder FOREIGN KEY (folder_id) REFERENCES workflow_folders(id) ON DELETE SET NULL",
+		// Optional JSON Schema the HTTP-in request body must satisfy
+		"ALTER TABLE http_triggers ADD COLUMN request_schema JSON NULL",
+		// Cron overlap / misfire / jitter settings
+		"ALTER TABLE cron_schedules ADD COLUMN overlap_policy VARCHAR(20) NOT NULL DEFAULT 'allow'",
+		"ALTER TABLE cron_schedules ADD COLUMN misfire_policy VARCHAR(20) NOT NULL DEFAULT 'skip'",
+		"ALTER TABLE cron_schedules ADD COLUMN misfire_max INT NOT NULL DEFAULT 10",
+		"ALTER TABLE cron_schedules ADD COLUMN jitter_sec INT NOT NULL DEFAULT 0",
+		// Exclusion calendars referenced by a schedule (JSON array of schedule_calendars ids)
+		"ALTER TABLE cron_schedules ADD COLUMN calendar_ids JSON NULL",
+		// Static JSON payload injected into each run of a schedule
+		"ALTER TABLE cron_schedules ADD COLUMN payload JSON NULL",
+		// Per-execution timeout of scheduled runs
+		"ALTER TABLE cron_schedules ADD COLUMN exec_timeout_sec INT NOT NULL DEFAULT 300",
+		// Concurrency, buffering and timeout of event-driven triggers
+		"ALTER TABLE redis_subscriptions ADD COLUMN max_in_flight INT NOT NULL DEFAULT 1",
+		"ALTER TABLE redis_subscriptions ADD COLUMN buffer_size INT NOT NULL DEFAULT 100",
+		"ALTER TABLE redis_subscriptions ADD COLUMN overflow_policy VARCHAR(20) NOT NULL DEFAULT 'block'",
+		"ALTER TABLE redis_subscriptions ADD COLUMN exec_timeout_sec INT NOT NULL DEFAULT 300",
+		"ALTER TABLE email_triggers ADD COLUMN max_in_flight INT NOT NULL DEFAULT 1",
+		"ALTER TABLE email_triggers ADD COLUMN buffer_size INT NOT NULL DEFAULT 100",
+		"ALTER TABLE email_triggers ADD COLUMN overflow_policy VARCHAR(20) NOT NULL DEFAULT 'block'",
+		"ALTER TABLE email_triggers ADD COLUMN exec_timeout_sec INT NOT NULL DEFAULT 300",
+		"ALTER TABLE redis_stream_triggers ADD COLUMN exec_timeout_sec INT NOT NULL DEFAULT 300",
+		// Size limits for fetched emails and their attachments
+		"ALTER TABLE email_triggers ADD COLUMN max_message_bytes INT NOT NULL DEFAULT 26214400",
+		"ALTER TABLE email_triggers ADD COLUMN max_attachment_bytes INT NOT NULL DEFAULT 10485760",
+		// IMAP UID cursor and IDLE push for email triggers
+		"ALTER TABLE email_triggers ADD COLUMN use_idle BOOLEAN NOT NULL DEFAULT TRUE",
+		"ALTER TABLE email_triggers ADD COLUMN uid_validity BIGINT NOT NULL DEFAULT 0",
+		"ALTER TABLE email_triggers ADD COLUMN last_uid BIGINT NOT NULL DEFAULT 0",
+		// Where an email trigger's cursor starts. Triggers from before the cursor fetched unread
+		// mail, so they start at the oldest unread message; new triggers skip existing mail.
+		"ALTER TABLE email_triggers ADD COLUMN start_from VARCHAR(10) NOT NULL DEFAULT 'unseen'",
+		"ALTER TABLE email_triggers ALTER COLUMN start_from SET DEFAULT 'new'",
+		// Deliveries of a stream entry that could not be run before it becomes a dead letter
+		"ALTER TABLE redis_stream_triggers ADD COLUMN max_deliveries INT NOT NULL DEFAULT 5",
+		// Email post-processing actions
+		"ALTER TABLE email_triggers ADD COLUMN on_success JSON NULL",
+		"ALTER TABLE email_triggers ADD COLUMN on_failure JSON NULL",
+		// Authentication policy of HTTP triggers
+		"ALTER TABLE http_triggers ADD COLUMN auth JSON NULL",
+		// Body size, rate, concurrency and IP limits of HTTP triggers
+		"ALTER TABLE http_triggers ADD COLUMN limits JSON NULL",
 	}
 	for _, q := range alterQueries {
 		if _, err := db.Exec(q); err != nil {
@@ -0,0 +1,154 @@
+package engine
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"eflo/backend/models"
+
+	"github.com/robfig/cron/v3"
+)
+
+// maxCalendarSkips bounds how many consecutive excluded ticks Next steps over before giving up
+// (each step jumps past a whole excluded day or window).
+const maxCalendarSkips = 1000
+
+// calendarDate is a parsed exclusion date; year 0 matches every year.
+type calendarDate struct {
+	year  int
+	month time.Month
+	day   int
+}
+
+// parseCalendarDate parses an exclusion date, either "2006-01-02" or a yearly "01-02".
+func parseCalendarDate(s string) (calendarDate, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return calendarDate{year: t.Year(), month: t.Month(), day: t.Day()}, nil
+	}
+	// Parse against a leap year so "02-29" is accepted as a yearly date
+	if t, err := time.Parse("2006-01-02", "2000-"+s); err == nil && len(s) == 5 {
+		return calendarDate{month: t.Month(), day: t.Day()}, nil
+	}
+	return calendarDate{}, fmt.Errorf("invalid date %q (want YYYY-MM-DD or MM-DD)", s)
+}
+
+// ValidateCalendar checks a calendar's timezone, dates and windows.
+func ValidateCalendar(c *models.ScheduleCalendar) error {
+	if _, err := LoadTimezone(c.Timezone); err != nil {
+		return err
+	}
+	for _, d := range c.Dates {
+		if _, err := parseCalendarDate(d); err != nil {
+			return err
+		}
+	}
+	for i, w := range c.Windows {
+		if w.Start.IsZero() || w.End.IsZero() || !w.End.After(w.Start) {
+			return fmt.Errorf("window %d: end must be after start", i)
+		}
+	}
+	return nil
+}
+
+// compiledCalendar is a calendar with its dates parsed and timezone resolved.
+type compiledCalendar struct {
+	loc     *time.Location
+	dates   []calendarDate
+	windows []models.CalendarWindow
+}
+
+// calendarSchedule skips the ticks of a schedule that fall on an excluded date or window.
+type calendarSchedule struct {
+	base      cron.Schedule
+	calendars []compiledCalendar
+}
+
+// ApplyCalendars wraps sched so it never fires inside the calendars' exclusions. Dates are
+// whole days in the calendar's timezone, or in timezone (the schedule's) when the calendar
+// has none. Invalid entries are logged and ignored.
+func ApplyCalendars(sched cron.Schedule, timezone string, calendars []*models.ScheduleCalendar) cron.Schedule {
+	if len(calendars) == 0 {
+		return sched
+	}
+	defaultLoc, err := LoadTimezone(timezone)
+	if err != nil {
+		defaultLoc = time.UTC
+	}
+	cs := &calendarSchedule{base: sched}
+	for _, c := range calendars {
+		cc := compiledCalendar{loc: defaultLoc, windows: c.Windows}
+		if c.Timezone != "" {
+			if loc, err := LoadTimezone(c.Timezone); err == nil {
+				cc.loc = loc
+			} else {
+				log.Printf("[Scheduler] Calendar %q: %v", c.Name, err)
+			}
+		}
+		for _, d := range c.Dates {
+			pd, err := parseCalendarDate(d)
+			if err != nil {
+				log.Printf("[Scheduler] Calendar %q: %v", c.Name, err)
+				continue
+			}
+			cc.dates = append(cc.dates, pd)
+		}
+		cs.calendars = append(cs.calendars, cc)
+	}
+	return cs
+}
+
+func (c *calendarSchedule) Next(t time.Time) time.Time {
+	for i := 0; i < maxCalendarSkips; i++ {
+		next := c.base.Next(t)
+		if next.IsZero() {
+			return next
+		}
+		until, excluded := c.excludedUntil(next)
+		if !excluded {
+			return next
+		}
+		// Resume just before the end of the exclusion so a tick exactly at its end still fires
+		t = until.Add(-time.Nanosecond)
+	}
+	return time.Time{}
+}
+
+// excludedUntil reports whether t is excluded and, if so, when the exclusion ends.
+func (c *calendarSchedule) excludedUntil(t time.Time) (time.Time, bool) {
+	var until time.Time
+	for _, cal := range c.calendars {
+		local := t.In(cal.loc)
+		for _, d := range cal.dates {
+			if (d.year == 0 || d.year == local.Year()) && d.month == local.Month() && d.day == local.Day() {
+				end := time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, cal.loc)
+				if end.After(until) {
+					until = end
+				}
+			}
+		}
+		for _, w := range cal.windows {
+			if !t.Before(w.Start) && t.Before(w.End) && w.End.After(until) {
+				until = w.End
+			}
+		}
+	}
+	return until, !until.IsZero()
+}
+
+// PreviewSchedule returns up to count fire times of sched after from, in the schedule's timezone.
+func PreviewSchedule(sched cron.Schedule, timezone string, from time.Time, count int) []time.Time {
+	loc, err := LoadTimezone(timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	times := make([]time.Time, 0, count)
+	for t := from; len(times) < count; {
+		t = sched.Next(t)
+		if t.IsZero() {
+			break
+		}
+		times = append(times, t.In(loc))
+	}
+	return times
+}
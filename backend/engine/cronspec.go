@@ -0,0 +1,122 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// cronParser accepts standard five-field specs, an optional leading seconds field
+// ("0 30 9 * * MON-FRI"), descriptors (@daily, @every 5m) and a CRON_TZ=/TZ= prefix.
+var cronParser = cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// ParseCronSchedule parses expression evaluated in the IANA timezone (default UTC). An explicit
+// CRON_TZ=/TZ= prefix in the expression wins over timezone. Fixed-hour specs follow the usual
+// cron DST rules: a time that falls in a spring-forward gap fires when the gap ends, and a time
+// in a repeated fall-back hour fires once. Specs with a "*" hour run on every real hour.
+func ParseCronSchedule(expression, timezone string) (cron.Schedule, error) {
+	expression = strings.TrimSpace(expression)
+	if expression == "" {
+		return nil, fmt.Errorf("expression is required")
+	}
+	if _, err := LoadTimezone(timezone); err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(expression, "CRON_TZ=") && !strings.HasPrefix(expression, "TZ=") {
+		if timezone == "" {
+			timezone = "UTC"
+		}
+		expression = "CRON_TZ=" + timezone + " " + expression
+	}
+	sched, err := cronParser.Parse(expression)
+	if err != nil {
+		return nil, err
+	}
+	if spec, ok := sched.(*cron.SpecSchedule); ok && spec.Hour&starBit == 0 && spec.Location != time.UTC {
+		return &dstSchedule{spec: spec}, nil
+	}
+	return sched, nil
+}
+
+// starBit marks a "*" field in cron.SpecSchedule (mirrors robfig/cron's unexported constant).
+const starBit = 1 << 63
+
+// dstSchedule adjusts a fixed-hour SpecSchedule around DST transitions, where SpecSchedule
+// alone skips times inside a gap and fires twice in a repeated hour.
+type dstSchedule struct {
+	spec *cron.SpecSchedule
+}
+
+func (d *dstSchedule) Next(t time.Time) time.Time {
+	loc := d.spec.Location
+	next := d.spec.Next(t)
+	if next.IsZero() {
+		return next
+	}
+
+	// Spring forward: evaluate with the offset in effect at t. If that yields an earlier wall
+	// time that does not exist in loc, fire at the end of the gap instead.
+	_, offset := t.In(loc).Zone()
+	shifted := *d.spec
+	shifted.Location = time.FixedZone("", offset)
+	if alt := shifted.Next(t); !alt.IsZero() && alt.Before(next) && !wallTimeExists(alt.In(shifted.Location), loc) {
+		if gapEnd := offsetChange(t, alt, loc); gapEnd.After(t) {
+			return gapEnd.In(t.Location())
+		}
+	}
+
+	// Fall back: skip the second occurrence of a repeated wall time.
+	_, offNext := next.In(loc).Zone()
+	_, offBefore := next.Add(-3 * time.Hour).In(loc).Zone()
+	if offBefore > offNext {
+		first := next.Add(-time.Duration(offBefore-offNext) * time.Second)
+		if first.After(t) || !d.spec.Next(first.Add(-time.Second)).Equal(first) {
+			return next
+		}
+		if sameWallClock(first.In(loc), next.In(loc)) {
+			return d.Next(next)
+		}
+	}
+	return next
+}
+
+// wallTimeExists reports whether w's wall-clock time occurs in loc.
+func wallTimeExists(w time.Time, loc *time.Location) bool {
+	local := time.Date(w.Year(), w.Month(), w.Day(), w.Hour(), w.Minute(), w.Second(), 0, loc)
+	return sameWallClock(local, w)
+}
+
+func sameWallClock(a, b time.Time) bool {
+	return a.Year() == b.Year() && a.YearDay() == b.YearDay() &&
+		a.Hour() == b.Hour() && a.Minute() == b.Minute() && a.Second() == b.Second()
+}
+
+// offsetChange returns the first second in (from, to] whose UTC offset in loc differs from
+// the offset at from.
+func offsetChange(from, to time.Time, loc *time.Location) time.Time {
+	_, base := from.In(loc).Zone()
+	lo, hi := from, to
+	for hi.Sub(lo) > time.Second {
+		mid := lo.Add(hi.Sub(lo) / 2).Truncate(time.Second)
+		if _, off := mid.In(loc).Zone(); off == base {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return hi
+}
+
+// LoadTimezone resolves an IANA timezone name; empty means UTC.
+func LoadTimezone(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", name, err)
+	}
+	return loc, nil
+}
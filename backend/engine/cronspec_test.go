@@ -0,0 +1,153 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+// In America/New_York, 2026 springs forward on March 8 (02:00 EST -> 03:00 EDT) and falls back
+// on November 1 (02:00 EDT -> 01:00 EST). Expected fire times are in UTC: EST is UTC-5, EDT UTC-4.
+func TestCronScheduleAcrossDST(t *testing.T) {
+	utc := func(s string) time.Time {
+		t.Helper()
+		v, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	tests := []struct {
+		name string
+		spec string
+		from string // UTC
+		want []string
+	}{
+		{
+			name: "daily 02:30 in the spring-forward gap fires when the gap ends",
+			spec: "30 2 * * *",
+			from: "2026-03-06 12:00",
+			want: []string{
+				"2026-03-07 07:30", // 02:30 EST
+				"2026-03-08 07:00", // 02:30 does not exist: 03:00 EDT
+				"2026-03-09 06:30", // 02:30 EDT
+			},
+		},
+		{
+			name: "daily 02:30 after fall-back fires once",
+			spec: "30 2 * * *",
+			from: "2026-10-31 00:00",
+			want: []string{
+				"2026-10-31 06:30", // 02:30 EDT
+				"2026-11-01 07:30", // 02:30 EST, after the repeated hour
+				"2026-11-02 07:30",
+			},
+		},
+		{
+			name: "daily 01:30 in the repeated hour fires on the first occurrence only",
+			spec: "30 1 * * *",
+			from: "2026-10-31 00:00",
+			want: []string{
+				"2026-10-31 05:30", // 01:30 EDT
+				"2026-11-01 05:30", // 01:30 EDT; 01:30 EST an hour later is skipped
+				"2026-11-02 06:30", // 01:30 EST
+			},
+		},
+		{
+			name: "from inside the repeated hour the second 01:30 is skipped",
+			spec: "30 1 * * *",
+			from: "2026-11-01 05:45", // 01:45 EDT
+			want: []string{
+				"2026-11-02 06:30",
+			},
+		},
+		{
+			name: "hourly runs on every real hour across spring-forward",
+			spec: "0 * * * *",
+			from: "2026-03-08 05:30", // 00:30 EST
+			want: []string{
+				"2026-03-08 06:00", // 01:00 EST
+				"2026-03-08 07:00", // 03:00 EDT
+				"2026-03-08 08:00", // 04:00 EDT
+			},
+		},
+		{
+			name: "hourly runs on every real hour across fall-back",
+			spec: "0 * * * *",
+			from: "2026-11-01 04:30", // 00:30 EDT
+			want: []string{
+				"2026-11-01 05:00", // 01:00 EDT
+				"2026-11-01 06:00", // 01:00 EST
+				"2026-11-01 07:00", // 02:00 EST
+			},
+		},
+		{
+			name: "@every keeps its interval across spring-forward",
+			spec: "@every 1h",
+			from: "2026-03-08 05:30",
+			want: []string{
+				"2026-03-08 06:30",
+				"2026-03-08 07:30",
+				"2026-03-08 08:30",
+			},
+		},
+		{
+			name: "@every keeps its interval across fall-back",
+			spec: "@every 90m",
+			from: "2026-11-01 04:30",
+			want: []string{
+				"2026-11-01 06:00",
+				"2026-11-01 07:30",
+				"2026-11-01 09:00",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sched, err := ParseCronSchedule(tt.spec, "America/New_York")
+			if err != nil {
+				t.Fatal(err)
+			}
+			at := utc(tt.from)
+			for i, w := range tt.want {
+				next := sched.Next(at)
+				if want := utc(w); !next.Equal(want) {
+					t.Fatalf("fire %d after %s = %s, want %s", i+1, at.UTC().Format(time.DateTime), next.UTC().Format(time.DateTime), want.Format(time.DateTime))
+				}
+				at = next
+			}
+		})
+	}
+}
+
+func TestParseCronScheduleTimezone(t *testing.T) {
+	tests := []struct {
+		spec, tz string
+		want     string // first fire after 2026-01-15 00:00 UTC
+		wantErr  bool
+	}{
+		{spec: "0 9 * * *", tz: "", want: "2026-01-15 09:00"},
+		{spec: "0 9 * * *", tz: "America/New_York", want: "2026-01-15 14:00"},
+		{spec: "CRON_TZ=Asia/Tokyo 0 9 * * *", tz: "America/New_York", want: "2026-01-16 00:00"},
+		{spec: "0 9 * * *", tz: "Mars/Olympus", wantErr: true},
+		{spec: "", tz: "UTC", wantErr: true},
+	}
+	from := time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.spec+" "+tt.tz, func(t *testing.T) {
+			sched, err := ParseCronSchedule(tt.spec, tt.tz)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := sched.Next(from).UTC().Format("2006-01-02 15:04"); got != tt.want {
+				t.Errorf("Next = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
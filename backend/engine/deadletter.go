@@ -0,0 +1,55 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"eflo/backend/models"
+)
+
+// RecordDeadLetter keeps a trigger event whose workflow run failed (runErr) so it can be
+// replayed later. execID is 0 when no execution was created. It is a no-op without a
+// dead-letter store.
+func (e *Engine) RecordDeadLetter(triggerType string, triggerID, workflowID, execID int64, payload map[string]interface{}, runErr error) {
+	if e.DeadLetters == nil || runErr == nil {
+		return
+	}
+	d := &models.DeadLetter{
+		TriggerType: triggerType,
+		TriggerID:   triggerID,
+		WorkflowID:  workflowID,
+		Payload:     payload,
+		Error:       runErr.Error(),
+	}
+	if execID != 0 {
+		d.ExecutionID = &execID
+	}
+	if _, err := e.DeadLetters.Create(d); err != nil {
+		log.Printf("[DeadLetter] Failed to store %s event of trigger %d: %v", triggerType, triggerID, err)
+	}
+}
+
+// ReplayDeadLetter runs the dead letter's workflow again with the stored payload and records
+// the outcome on the dead letter. The run goes through Execute, so it uses the execution queue
+// when one is configured.
+func (e *Engine) ReplayDeadLetter(ctx context.Context, d *models.DeadLetter) (int64, error) {
+	wf, err := e.WorkflowRepo.GetByID(d.WorkflowID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load workflow %d: %w", d.WorkflowID, err)
+	}
+
+	execID, runErr := e.Execute(ctx, wf, d.Payload, "replay")
+	var execRef *int64
+	if execID != 0 {
+		execRef = &execID
+	}
+	errMsg := ""
+	if runErr != nil {
+		errMsg = runErr.Error()
+	}
+	if err := e.DeadLetters.RecordReplay(d.ID, execRef, errMsg); err != nil {
+		log.Printf("[DeadLetter] Failed to record replay of %d: %v", d.ID, err)
+	}
+	return execID, runErr
+}
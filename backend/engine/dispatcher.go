@@ -0,0 +1,162 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"eflo/backend/models"
+)
+
+// ErrTriggerOverflow is returned by triggerDispatcher.Submit when the buffer is full and the
+// overflow policy is reject.
+var ErrTriggerOverflow = errors.New("trigger buffer full")
+
+// triggerDispatcher runs a trigger's events on a bounded pool of executions. Events wait in a
+// bounded FIFO buffer; when it is full the overflow policy blocks the submitter, drops the
+// oldest waiting event or rejects the new one. Each run gets the configured timeout and is
+// cancelled when the dispatcher's parent context is done.
+type triggerDispatcher struct {
+	name    string // log prefix, e.g. "[RedisSubscriber] sub 3"
+	limits  models.TriggerLimits
+	timeout time.Duration
+	parent  context.Context
+
+	mu      sync.Mutex
+	cond    *sync.Cond // signals buffer changes to runners and blocked submitters
+	buffer  []dispatchJob
+	closed  bool
+	running sync.WaitGroup
+}
+
+// dispatchJob is a buffered event. dropped, if set, is called when drop_oldest discards it.
+type dispatchJob struct {
+	run     func(ctx context.Context)
+	dropped func()
+}
+
+// newTriggerDispatcher applies defaults to limits and starts MaxInFlight runners whose runs
+// are derived from parent.
+func newTriggerDispatcher(parent context.Context, name string, limits models.TriggerLimits) *triggerDispatcher {
+	limits = normalizeTriggerLimits(limits)
+	d := &triggerDispatcher{
+		name:    name,
+		limits:  limits,
+		timeout: time.Duration(limits.ExecTimeoutSec) * time.Second,
+		parent:  parent,
+	}
+	d.cond = sync.NewCond(&d.mu)
+	for i := 0; i < limits.MaxInFlight; i++ {
+		d.running.Add(1)
+		go d.run()
+	}
+	return d
+}
+
+// normalizeTriggerLimits fills unset fields with their defaults.
+func normalizeTriggerLimits(l models.TriggerLimits) models.TriggerLimits {
+	if l.MaxInFlight <= 0 {
+		l.MaxInFlight = models.DefaultTriggerMaxInFlight
+	}
+	if l.BufferSize <= 0 {
+		l.BufferSize = models.DefaultTriggerBufferSize
+	}
+	switch l.OverflowPolicy {
+	case models.OverflowBlock, models.OverflowDropOldest, models.OverflowReject:
+	default:
+		l.OverflowPolicy = models.OverflowBlock
+	}
+	if l.ExecTimeoutSec <= 0 {
+		l.ExecTimeoutSec = models.DefaultTriggerExecTimeoutSec
+	}
+	return l
+}
+
+// Submit queues an event for execution. With the block policy it waits for buffer space until
+// ctx is done; with reject it returns ErrTriggerOverflow when the buffer is full.
+func (d *triggerDispatcher) Submit(ctx context.Context, job func(ctx context.Context)) error {
+	return d.SubmitDroppable(ctx, job, nil)
+}
+
+// SubmitDroppable is Submit with a callback for when the drop_oldest policy discards the job
+// before it ran. dropped is called with the dispatcher locked and must not call back into it.
+func (d *triggerDispatcher) SubmitDroppable(ctx context.Context, job func(ctx context.Context), dropped func()) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.limits.OverflowPolicy == models.OverflowBlock && len(d.buffer) >= d.limits.BufferSize && !d.closed {
+		// Wake the waiting submitter when ctx is done so it does not block forever
+		stop := context.AfterFunc(ctx, func() {
+			d.mu.Lock()
+			d.cond.Broadcast()
+			d.mu.Unlock()
+		})
+		defer stop()
+		for len(d.buffer) >= d.limits.BufferSize && !d.closed && ctx.Err() == nil {
+			d.cond.Wait()
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+	if d.closed {
+		return errors.New("trigger dispatcher closed")
+	}
+
+	if len(d.buffer) >= d.limits.BufferSize {
+		switch d.limits.OverflowPolicy {
+		case models.OverflowReject:
+			return ErrTriggerOverflow
+		case models.OverflowDropOldest:
+			if d.buffer[0].dropped != nil {
+				d.buffer[0].dropped()
+			}
+			d.buffer[0] = dispatchJob{}
+			d.buffer = d.buffer[1:]
+			log.Printf("%s: buffer full (%d), dropped oldest event", d.name, d.limits.BufferSize)
+		}
+	}
+	d.buffer = append(d.buffer, dispatchJob{run: job, dropped: dropped})
+	d.cond.Broadcast()
+	return nil
+}
+
+// run executes buffered events until the dispatcher is closed and drained.
+func (d *triggerDispatcher) run() {
+	defer d.running.Done()
+	for {
+		d.mu.Lock()
+		for len(d.buffer) == 0 && !d.closed {
+			d.cond.Wait()
+		}
+		if len(d.buffer) == 0 {
+			d.mu.Unlock()
+			return
+		}
+		job := d.buffer[0].run
+		d.buffer[0] = dispatchJob{}
+		d.buffer = d.buffer[1:]
+		d.cond.Broadcast()
+		d.mu.Unlock()
+
+		ctx, cancel := context.WithTimeout(d.parent, d.timeout)
+		job(ctx)
+		cancel()
+	}
+}
+
+// Close stops accepting events and waits until the buffered and running ones are done, so
+// events already taken from the source (e.g. emails marked seen) are not lost.
+func (d *triggerDispatcher) Close() {
+	d.mu.Lock()
+	d.closed = true
+	pending := len(d.buffer)
+	d.cond.Broadcast()
+	d.mu.Unlock()
+	if pending > 0 {
+		log.Printf("%s: draining %d buffered events", d.name, pending)
+	}
+	d.running.Wait()
+}
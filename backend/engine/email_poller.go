@@ -2,23 +2,46 @@ package engine
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"sync"
 	"time"
 
 	"eflo/backend/imaputil"
+	"eflo/backend/models"
 	"eflo/backend/repository"
 )
 
-// EmailPoller periodically checks IMAP mailboxes and triggers workflows for new emails.
+// The parts of the repositories the poller uses (see repository.WorkflowRepo, NodeConfigRepo
+// and EmailTriggerRepo).
+type (
+	workflowGetter interface {
+		GetByID(id int64) (*models.Workflow, error)
+	}
+	nodeConfigGetter interface {
+		GetByID(id int64) (*models.NodeConfig, error)
+	}
+	emailTriggerStore interface {
+		ListEnabled() ([]*models.EmailTrigger, error)
+		GetByID(id int64) (*models.EmailTrigger, error)
+		IncrementMsgCount(id int64) error
+		SaveCursor(id, uidValidity, lastUID int64) error
+	}
+)
+
+// EmailPoller watches IMAP mailboxes (IDLE or periodic polling) and triggers workflows for new emails.
 type EmailPoller struct {
 	engine       *Engine
-	workflowRepo *repository.WorkflowRepo
-	configRepo   *repository.NodeConfigRepo
-	triggerRepo  *repository.EmailTriggerRepo
+	workflowRepo workflowGetter
+	configRepo   nodeConfigGetter
+	triggerRepo  emailTriggerStore
 	mu           sync.Mutex
 	cancels      map[int64]context.CancelFunc // triggerID -> cancel func
 	wg           sync.WaitGroup
+	active       bool            // started (this instance is the trigger leader)
+	notify       func()          // signals the leader about changes made while inactive
+	runCtx       context.Context // parent of the runs; cancelled when the lease is lost
 }
 
 func NewEmailPoller(
@@ -36,13 +59,27 @@ func NewEmailPoller(
 	}
 }
 
-func (ep *EmailPoller) Start() error {
+// SetChangeNotifier sets the callback used when triggers change while this poller is not
+// running (another instance is the leader).
+func (ep *EmailPoller) SetChangeNotifier(fn func()) {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	ep.notify = fn
+}
+
+// Start loads all enabled triggers and begins watching their mailboxes. Runs are cancelled when
+// ctx is done.
+func (ep *EmailPoller) Start(ctx context.Context) error {
 	triggers, err := ep.triggerRepo.ListEnabled()
 	if err != nil {
 		return err
 	}
+	ep.mu.Lock()
+	ep.active = true
+	ep.runCtx = ctx
+	ep.mu.Unlock()
 	for _, t := range triggers {
-		if err := ep.startPoller(t.ID, t.WorkflowID, t.ConfigID, t.Mailbox, t.PollIntervalSec, t.MarkSeen, t.MaxFetch); err != nil {
+		if err := ep.startPoller(t); err != nil {
 			log.Printf("[EmailPoller] Failed to start trigger %d: %v", t.ID, err)
 		}
 	}
@@ -51,6 +88,23 @@ func (ep *EmailPoller) Start() error {
 }
 
 func (ep *EmailPoller) Stop() {
+	ep.stopAll()
+	ep.mu.Lock()
+	ep.active = false
+	ep.mu.Unlock()
+	log.Println("[EmailPoller] Stopped")
+}
+
+// Reload restarts all pollers from the DB (after another instance changed triggers).
+func (ep *EmailPoller) Reload() error {
+	if !ep.isActive() {
+		return nil
+	}
+	ep.stopAll()
+	return ep.Start(ep.runContext())
+}
+
+func (ep *EmailPoller) stopAll() {
 	ep.mu.Lock()
 	for id, cancel := range ep.cancels {
 		cancel()
@@ -58,11 +112,34 @@ func (ep *EmailPoller) Stop() {
 	}
 	ep.mu.Unlock()
 	ep.wg.Wait()
-	log.Println("[EmailPoller] Stopped")
 }
 
-func (ep *EmailPoller) AddTrigger(triggerID, workflowID, configID int64, mailbox string, intervalSec int, markSeen bool, maxFetch int) error {
-	return ep.startPoller(triggerID, workflowID, configID, mailbox, intervalSec, markSeen, maxFetch)
+// runContext returns the parent of the runs, set by Start.
+func (ep *EmailPoller) runContext() context.Context {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	if ep.runCtx == nil {
+		return context.Background()
+	}
+	return ep.runCtx
+}
+
+// isActive reports whether the poller is running; if not, the leader is signalled instead.
+func (ep *EmailPoller) isActive() bool {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	if !ep.active && ep.notify != nil {
+		go ep.notify()
+	}
+	return ep.active
+}
+
+// AddTrigger starts (or restarts) polling for an email trigger.
+func (ep *EmailPoller) AddTrigger(t *models.EmailTrigger) error {
+	if !ep.isActive() {
+		return nil
+	}
+	return ep.startPoller(t)
 }
 
 func (ep *EmailPoller) RemoveTrigger(triggerID int64) {
@@ -72,16 +149,28 @@ func (ep *EmailPoller) RemoveTrigger(triggerID int64) {
 		cancel()
 		delete(ep.cancels, triggerID)
 	}
+	if !ep.active && ep.notify != nil {
+		go ep.notify()
+	}
 }
 
-func (ep *EmailPoller) startPoller(triggerID, workflowID, configID int64, mailbox string, intervalSec int, markSeen bool, maxFetch int) error {
+// startPoller watches the mailbox for messages with a UID above the trigger's stored cursor:
+// with IMAP IDLE when enabled and supported by the server, otherwise by polling. Fetched emails
+// are executed by a dispatcher bounded by the trigger limits.
+func (ep *EmailPoller) startPoller(trigger *models.EmailTrigger) error {
+	t := *trigger
+	triggerID := t.ID
 	ep.RemoveTrigger(triggerID)
 
-	if intervalSec < 10 {
-		intervalSec = 60
+	if t.PollIntervalSec < 10 {
+		t.PollIntervalSec = 60
 	}
-	if maxFetch <= 0 {
-		maxFetch = 10
+	if t.MaxFetch <= 0 {
+		t.MaxFetch = 10
+	}
+	// The cursor is only ever advanced here, so the stored one wins over the caller's copy
+	if stored, err := ep.triggerRepo.GetByID(triggerID); err == nil {
+		t.UIDValidity, t.LastUID = stored.UIDValidity, stored.LastUID
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -89,17 +178,25 @@ func (ep *EmailPoller) startPoller(triggerID, workflowID, configID int64, mailbo
 	ep.cancels[triggerID] = cancel
 	ep.mu.Unlock()
 
+	d := newTriggerDispatcher(ep.runContext(), fmt.Sprintf("[EmailPoller] Trigger %d", triggerID), t.TriggerLimits)
+	cur := &emailCursor{validity: t.UIDValidity, lastUID: t.LastUID}
 	ep.wg.Add(1)
 	go func() {
 		defer ep.wg.Done()
+		defer d.Close()
+
+		if t.UseIdle && ep.watchIdle(ctx, d, cur, &t) {
+			log.Printf("[EmailPoller] Stopped trigger %d", triggerID)
+			return
+		}
 
-		ticker := time.NewTicker(time.Duration(intervalSec) * time.Second)
+		ticker := time.NewTicker(time.Duration(t.PollIntervalSec) * time.Second)
 		defer ticker.Stop()
 
-		log.Printf("[EmailPoller] Polling every %ds for trigger %d (workflow %d, mailbox=%s)", intervalSec, triggerID, workflowID, mailbox)
+		log.Printf("[EmailPoller] Polling every %ds for trigger %d (workflow %d, mailbox=%s)", t.PollIntervalSec, triggerID, t.WorkflowID, t.Mailbox)
 
 		// Do an initial poll immediately
-		ep.poll(triggerID, workflowID, configID, mailbox, markSeen, uint32(maxFetch))
+		ep.poll(ctx, d, cur, &t)
 
 		for {
 			select {
@@ -107,7 +204,7 @@ func (ep *EmailPoller) startPoller(triggerID, workflowID, configID int64, mailbo
 				log.Printf("[EmailPoller] Stopped trigger %d", triggerID)
 				return
 			case <-ticker.C:
-				ep.poll(triggerID, workflowID, configID, mailbox, markSeen, uint32(maxFetch))
+				ep.poll(ctx, d, cur, &t)
 			}
 		}
 	}()
@@ -115,45 +212,277 @@ func (ep *EmailPoller) startPoller(triggerID, workflowID, configID int64, mailbo
 	return nil
 }
 
-func (ep *EmailPoller) poll(triggerID, workflowID, configID int64, mailbox string, markSeen bool, maxFetch uint32) {
-	cfg, err := ep.configRepo.GetByID(configID)
+// emailIdleRefresh is how long one IDLE command lasts; servers may drop clients idle for 30 minutes.
+const emailIdleRefresh = 25 * time.Minute
+
+// watchIdle keeps an IDLE session open, reconnecting after errors. It returns true once ctx is
+// done, and false when the server does not support IDLE so the caller falls back to polling.
+func (ep *EmailPoller) watchIdle(ctx context.Context, d *triggerDispatcher, cur *emailCursor, t *models.EmailTrigger) bool {
+	retry := time.Duration(t.PollIntervalSec) * time.Second
+	for {
+		err := ep.idleSession(ctx, d, cur, t)
+		if ctx.Err() != nil {
+			return true
+		}
+		if errors.Is(err, imaputil.ErrIdleUnsupported) {
+			log.Printf("[EmailPoller] Trigger %d: server does not support IDLE, falling back to polling", t.ID)
+			return false
+		}
+		log.Printf("[EmailPoller] IDLE session for trigger %d failed: %v; reconnecting in %s", t.ID, err, retry)
+		select {
+		case <-ctx.Done():
+			return true
+		case <-time.After(retry):
+		}
+	}
+}
+
+// idleSession fetches new mail, then waits in IDLE for the server to announce more. While the
+// trigger's buffer is full it retries every poll interval instead.
+func (ep *EmailPoller) idleSession(ctx context.Context, d *triggerDispatcher, cur *emailCursor, t *models.EmailTrigger) error {
+	c, err := ep.dial(t)
 	if err != nil {
-		log.Printf("[EmailPoller] Failed to resolve config %d: %v", configID, err)
-		return
+		return err
+	}
+	defer c.Close()
+	if !c.HasCapability("IDLE") {
+		return imaputil.ErrIdleUnsupported
+	}
+	log.Printf("[EmailPoller] IDLE on %s for trigger %d (workflow %d)", t.Mailbox, t.ID, t.WorkflowID)
+
+	for {
+		more, err := ep.fetchNew(ctx, d, cur, t, c)
+		if errors.Is(err, ErrTriggerOverflow) {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Duration(t.PollIntervalSec) * time.Second):
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if more {
+			continue
+		}
+		if _, err := c.Idle(ctx, emailIdleRefresh); err != nil {
+			return err
+		}
+	}
+}
+
+func (ep *EmailPoller) dial(t *models.EmailTrigger) (*imaputil.Client, error) {
+	cfg, err := ep.configRepo.GetByID(t.ConfigID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve config %d: %w", t.ConfigID, err)
 	}
+	// OAuth2 access tokens (imapTokenKey) are read from the config store
+	var secrets imaputil.SecretStore
+	if ep.engine.ConfigStoreRepo != nil {
+		secrets = ep.engine.ConfigStoreRepo
+	}
+	return imaputil.Dial(cfg, secrets)
+}
 
-	emails, err := imaputil.FetchNewEmails(cfg, mailbox, markSeen, maxFetch)
+func (ep *EmailPoller) poll(ctx context.Context, d *triggerDispatcher, cur *emailCursor, t *models.EmailTrigger) {
+	c, err := ep.dial(t)
 	if err != nil {
-		log.Printf("[EmailPoller] Fetch failed for trigger %d: %v", triggerID, err)
+		log.Printf("[EmailPoller] Fetch failed for trigger %d: %v", t.ID, err)
 		return
 	}
+	defer c.Close()
+	if _, err := ep.fetchNew(ctx, d, cur, t, c); err != nil && !errors.Is(err, ErrTriggerOverflow) {
+		log.Printf("[EmailPoller] Fetch failed for trigger %d: %v", t.ID, err)
+	}
+}
+
+// startUID returns where a new cursor starts: before the oldest unread message for
+// EmailStartUnseen (triggers from before the cursor processed unread mail), otherwise after the
+// newest message. Without unread mail both are the same.
+func startUID(c *imaputil.Client, st *imaputil.MailboxStatus, startFrom string) (int64, error) {
+	if startFrom == models.EmailStartUnseen {
+		uid, ok, err := c.OldestUnseen()
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			return int64(uid) - 1, nil
+		}
+	}
+	if st.UIDNext > 0 {
+		return int64(st.UIDNext) - 1, nil
+	}
+	if uids, err := c.UIDsAfter(0, 0); err == nil && len(uids) > 0 {
+		return int64(uids[len(uids)-1]), nil
+	}
+	return 0, nil
+}
+
+// emailCursor is a trigger's stored UID cursor. It only advances when a dispatcher runner takes
+// a message from the buffer and hands it to Execute, so mail still waiting in the in-memory
+// buffer is fetched again after a crash. Runners can start out of UID order, so it never moves
+// back.
+type emailCursor struct {
+	mu       sync.Mutex
+	validity int64
+	lastUID  int64
+}
+
+// resetCursor stores a new cursor after the first run or a UIDVALIDITY change.
+func (ep *EmailPoller) resetCursor(cur *emailCursor, triggerID, validity, lastUID int64) error {
+	cur.mu.Lock()
+	defer cur.mu.Unlock()
+	if err := ep.triggerRepo.SaveCursor(triggerID, validity, lastUID); err != nil {
+		return err
+	}
+	cur.validity, cur.lastUID = validity, lastUID
+	return nil
+}
 
-	if len(emails) == 0 {
+// advanceCursor stores uid as handled unless the cursor is already past it or was reset to a
+// different UIDVALIDITY meanwhile.
+func (ep *EmailPoller) advanceCursor(cur *emailCursor, triggerID, validity, uid int64) {
+	cur.mu.Lock()
+	defer cur.mu.Unlock()
+	if validity != cur.validity || uid <= cur.lastUID {
 		return
 	}
+	if err := ep.triggerRepo.SaveCursor(triggerID, validity, uid); err != nil {
+		log.Printf("[EmailPoller] Trigger %d: failed to store UID cursor %d: %v", triggerID, uid, err)
+		return
+	}
+	cur.lastUID = uid
+}
 
-	log.Printf("[EmailPoller] Found %d new email(s) for trigger %d", len(emails), triggerID)
+// fetchNew hands up to MaxFetch messages above t.LastUID to the dispatcher, oldest first, so every
+// message is handled once whatever its flags. t.LastUID is the fetch position and moves as soon
+// as a message is buffered; the stored cursor follows when the message starts (see emailCursor).
+// On the first run both start where t.StartFrom says (see startUID); when UIDVALIDITY changed
+// (the mailbox was recreated) they start after the newest message. more reports that further
+// messages are waiting. When the buffer is full and the
+// overflow policy is reject, the batch stops before the rejected message and ErrTriggerOverflow
+// is returned, so the message is fetched again on the next attempt.
+func (ep *EmailPoller) fetchNew(ctx context.Context, d *triggerDispatcher, cur *emailCursor, t *models.EmailTrigger, c *imaputil.Client) (more bool, err error) {
+	triggerID, workflowID := t.ID, t.WorkflowID
+	st, err := c.Select(t.Mailbox)
+	if err != nil {
+		return false, err
+	}
+	if t.UIDValidity == 0 || uint32(t.UIDValidity) != st.UIDValidity {
+		startFrom := t.StartFrom
+		if t.UIDValidity != 0 {
+			log.Printf("[EmailPoller] Trigger %d: UIDVALIDITY of %s changed (%d -> %d), skipping existing messages",
+				triggerID, t.Mailbox, t.UIDValidity, st.UIDValidity)
+			startFrom = models.EmailStartNew
+		}
+		last, err := startUID(c, st, startFrom)
+		if err != nil {
+			return false, err
+		}
+		if t.UIDValidity == 0 {
+			if startFrom == models.EmailStartUnseen {
+				log.Printf("[EmailPoller] Trigger %d: first poll of %s starts after UID %d (before the oldest unread message)", triggerID, t.Mailbox, last)
+			} else {
+				log.Printf("[EmailPoller] Trigger %d: first poll of %s starts after UID %d (existing messages are skipped)", triggerID, t.Mailbox, last)
+			}
+		}
+		if err := ep.resetCursor(cur, triggerID, int64(st.UIDValidity), last); err != nil {
+			return false, fmt.Errorf("store UID cursor: %w", err)
+		}
+		t.UIDValidity, t.LastUID = int64(st.UIDValidity), last
+	}
+
+	uids, err := c.UIDsAfter(uint32(t.LastUID), t.MaxFetch+1)
+	if err != nil {
+		return false, err
+	}
+	if len(uids) > t.MaxFetch {
+		uids, more = uids[:t.MaxFetch], true
+	}
+	if len(uids) == 0 {
+		return false, nil
+	}
 
+	log.Printf("[EmailPoller] Found %d new email(s) for trigger %d", len(uids), triggerID)
+
+	// Without the workflow the cursor stays put, so the messages are picked up once it loads
 	wf, err := ep.workflowRepo.GetByID(workflowID)
 	if err != nil {
-		log.Printf("[EmailPoller] Failed to load workflow %d: %v", workflowID, err)
-		return
+		return false, fmt.Errorf("failed to load workflow %d: %w", workflowID, err)
 	}
 
-	for _, emailData := range emails {
+	opts := imaputil.FetchOptions{MaxMessageBytes: t.MaxMessageBytes, MaxAttachmentBytes: t.MaxAttachmentBytes}
+	trig := *t // for post-processing in the dispatcher, while t's cursor moves on
+	for _, uid := range uids {
+		emailData, err := c.FetchMessage(uid, opts)
+		if err != nil {
+			return false, fmt.Errorf("fetch UID %d: %w", uid, err)
+		}
+		emailData["mailbox"] = t.Mailbox
+		emailData["uidValidity"] = st.UIDValidity
 		emailData["triggerId"] = triggerID
+		emailData["fetchedAt"] = time.Now().Format(time.RFC3339)
 		emailData["receivedAt"] = time.Now().Format(time.RFC3339)
 
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-		execID, err := ep.engine.RunWorkflowWithInput(ctx, wf, emailData, nil, nil)
-		cancel()
+		validity := t.UIDValidity
+		err = d.Submit(ctx, func(execCtx context.Context) {
+			ep.advanceCursor(cur, triggerID, validity, int64(uid))
+			execID, err := ep.engine.Execute(execCtx, wf, emailData, "email")
 
-		_ = ep.triggerRepo.IncrementMsgCount(triggerID)
+			_ = ep.triggerRepo.IncrementMsgCount(triggerID)
 
-		if err != nil {
-			log.Printf("[EmailPoller] Workflow %d exec failed (exec %d): %v", workflowID, execID, err)
-		} else {
-			log.Printf("[EmailPoller] Workflow %d exec completed (exec %d) for email: %s", workflowID, execID, emailData["subject"])
+			if err != nil {
+				log.Printf("[EmailPoller] Workflow %d exec failed (exec %d): %v", workflowID, execID, err)
+				ep.engine.RecordDeadLetter(models.TriggerEmail, triggerID, workflowID, execID, emailData, err)
+			} else {
+				log.Printf("[EmailPoller] Workflow %d exec completed (exec %d) for email: %s", workflowID, execID, emailData["subject"])
+			}
+
+			actions := trig.OnSuccess
+			if err != nil {
+				actions = trig.OnFailure
+			}
+			ep.postProcess(&trig, uid, actions)
+		})
+		if errors.Is(err, ErrTriggerOverflow) {
+			log.Printf("[EmailPoller] Trigger %d buffer full; UID %d will be fetched again", triggerID, uid)
+			return false, err
+		} else if err != nil {
+			// Stopped while waiting for buffer space: the cursor stays before this message
+			return false, err
+		}
+		t.LastUID = int64(uid)
+
+		if t.MarkSeen {
+			if err := c.MarkSeen(uid); err != nil {
+				log.Printf("[EmailPoller] Failed to mark UID %d seen (trigger %d): %v", uid, triggerID, err)
+			}
+		}
+	}
+	return more, nil
+}
+
+// postProcess applies a trigger's on-success or on-failure actions to a message. It uses its own
+// connection, as the fetching one may be idling or closed by the time the run finishes.
+func (ep *EmailPoller) postProcess(t *models.EmailTrigger, uid uint32, actions []models.EmailAction) {
+	if len(actions) == 0 {
+		return
+	}
+	c, err := ep.dial(t)
+	if err != nil {
+		log.Printf("[EmailPoller] Post-processing UID %d (trigger %d) failed: %v", uid, t.ID, err)
+		return
+	}
+	defer c.Close()
+	if err := c.SelectUIDs(t.Mailbox, uint32(t.UIDValidity)); err != nil {
+		log.Printf("[EmailPoller] Post-processing UID %d (trigger %d) failed: %v", uid, t.ID, err)
+		return
+	}
+	for _, a := range actions {
+		if err := c.Apply(uid, a); err != nil {
+			log.Printf("[EmailPoller] Post-processing UID %d (trigger %d): %s failed: %v", uid, t.ID, a.Type, err)
+			return
 		}
 	}
 }
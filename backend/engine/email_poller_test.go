@@ -0,0 +1,559 @@
+package engine
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"eflo/backend/models"
+)
+
+// imapStandIn is an in-process IMAP server holding one mailbox, speaking just the commands the
+// poller sends.
+type imapStandIn struct {
+	ln       net.Listener
+	idle     bool          // advertise IDLE
+	idling   chan struct{} // signalled when a client enters IDLE
+	mu       sync.Mutex
+	validity uint32
+	uidNext  uint32
+	msgs     map[uint32][]byte
+	seen     map[uint32]bool
+	changed  chan struct{} // closed when a message is added
+	commands []string
+}
+
+func newIMAPStandIn(t *testing.T, idle bool, validity uint32) *imapStandIn {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &imapStandIn{
+		ln:       ln,
+		idle:     idle,
+		idling:   make(chan struct{}, 16),
+		validity: validity,
+		uidNext:  1,
+		msgs:     map[uint32][]byte{},
+		seen:     map[uint32]bool{},
+		changed:  make(chan struct{}),
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { ln.Close() })
+	return s
+}
+
+func (s *imapStandIn) config() *models.NodeConfig {
+	host, port, _ := net.SplitHostPort(s.ln.Addr().String())
+	return &models.NodeConfig{Type: "email", Config: map[string]interface{}{
+		"imapHost": host, "imapPort": port, "imapSecurity": "none", "username": "u", "password": "p",
+	}}
+}
+
+// add delivers a message and returns its UID.
+func (s *imapStandIn) add(subject string) uint32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	uid := s.uidNext
+	s.uidNext++
+	s.msgs[uid] = []byte("From: a@example.com\r\nTo: b@example.com\r\nSubject: " + subject + "\r\n\r\nHello\r\n")
+	close(s.changed)
+	s.changed = make(chan struct{})
+	return uid
+}
+
+// recreate empties the mailbox under a new UIDVALIDITY, as when it is deleted and created again.
+func (s *imapStandIn) recreate(validity uint32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.validity, s.uidNext = validity, 1
+	s.msgs, s.seen = map[uint32][]byte{}, map[uint32]bool{}
+}
+
+func (s *imapStandIn) isSeen(uid uint32) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.seen[uid]
+}
+
+func (s *imapStandIn) received(command string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.commands {
+		if c == command {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *imapStandIn) serve(conn net.Conn) {
+	defer conn.Close()
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			lines <- strings.TrimRight(line, "\r\n")
+		}
+	}()
+	w := bufio.NewWriter(conn)
+	reply := func(format string, args ...interface{}) {
+		fmt.Fprintf(w, format+"\r\n", args...)
+	}
+	reply("* OK stand-in ready")
+	w.Flush()
+
+	for line := range lines {
+		tag, cmd, _ := strings.Cut(line, " ")
+		verb := strings.ToUpper(cmd)
+		s.mu.Lock()
+		s.commands = append(s.commands, verb)
+		s.mu.Unlock()
+		switch {
+		case verb == "CAPABILITY":
+			caps := "IMAP4rev1"
+			if s.idle {
+				caps += " IDLE"
+			}
+			reply("* CAPABILITY %s", caps)
+		case strings.HasPrefix(verb, "LOGIN "):
+		case strings.HasPrefix(verb, "SELECT "):
+			s.mu.Lock()
+			reply("* %d EXISTS", len(s.msgs))
+			reply("* OK [UIDVALIDITY %d] UIDs valid", s.validity)
+			reply("* OK [UIDNEXT %d] Predicted next UID", s.uidNext)
+			s.mu.Unlock()
+		case strings.HasPrefix(verb, "UID SEARCH UID "):
+			from, _ := strconv.ParseUint(strings.TrimSuffix(strings.Fields(verb)[3], ":*"), 10, 32)
+			s.mu.Lock()
+			var uids []string
+			for _, uid := range s.sortedUIDs() {
+				if uint64(uid) >= from {
+					uids = append(uids, strconv.Itoa(int(uid)))
+				}
+			}
+			s.mu.Unlock()
+			reply("* SEARCH %s", strings.Join(uids, " "))
+		case verb == "UID SEARCH UNSEEN":
+			s.mu.Lock()
+			var uids []string
+			for _, uid := range s.sortedUIDs() {
+				if !s.seen[uid] {
+					uids = append(uids, strconv.Itoa(int(uid)))
+				}
+			}
+			s.mu.Unlock()
+			reply("* SEARCH %s", strings.Join(uids, " "))
+		case strings.HasPrefix(verb, "UID FETCH "):
+			f := strings.Fields(cmd)
+			uid64, _ := strconv.ParseUint(f[2], 10, 32)
+			uid := uint32(uid64)
+			s.mu.Lock()
+			raw, ok := s.msgs[uid]
+			s.mu.Unlock()
+			if !ok {
+				break
+			}
+			if strings.Contains(verb, "RFC822.SIZE") {
+				reply("* 1 FETCH (UID %d FLAGS () RFC822.SIZE %d)", uid, len(raw))
+			} else {
+				fmt.Fprintf(w, "* 1 FETCH (UID %d BODY[] {%d}\r\n%s)\r\n", uid, len(raw), raw)
+			}
+		case strings.HasPrefix(verb, "UID STORE "):
+			uid, _ := strconv.ParseUint(strings.Fields(verb)[2], 10, 32)
+			s.mu.Lock()
+			s.seen[uint32(uid)] = true
+			s.mu.Unlock()
+		case verb == "IDLE" && s.idle:
+			s.mu.Lock()
+			changed := s.changed
+			s.mu.Unlock()
+			reply("+ idling")
+			w.Flush()
+			s.idling <- struct{}{}
+			select {
+			case <-changed:
+				s.mu.Lock()
+				reply("* %d EXISTS", len(s.msgs))
+				s.mu.Unlock()
+				w.Flush()
+				if <-lines != "DONE" {
+					return
+				}
+			case done := <-lines:
+				// A client stopping without DONE goes on with LOGOUT: hang up
+				if done != "DONE" {
+					return
+				}
+			}
+		case verb == "LOGOUT":
+			reply("* BYE")
+			reply("%s OK LOGOUT completed", tag)
+			w.Flush()
+			return
+		default:
+			reply("%s BAD unknown command", tag)
+			w.Flush()
+			continue
+		}
+		reply("%s OK completed", tag)
+		w.Flush()
+	}
+}
+
+func (s *imapStandIn) sortedUIDs() []uint32 {
+	uids := make([]uint32, 0, len(s.msgs))
+	for uid := range s.msgs {
+		uids = append(uids, uid)
+	}
+	sort.Slice(uids, func(i, j int) bool { return uids[i] < uids[j] })
+	return uids
+}
+
+// fakeTriggerStore keeps one email trigger and its stored cursor in memory.
+type fakeTriggerStore struct {
+	mu      sync.Mutex
+	trigger models.EmailTrigger
+}
+
+func (f *fakeTriggerStore) ListEnabled() ([]*models.EmailTrigger, error) {
+	t, _ := f.GetByID(f.trigger.ID)
+	return []*models.EmailTrigger{t}, nil
+}
+
+func (f *fakeTriggerStore) GetByID(id int64) (*models.EmailTrigger, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := f.trigger
+	return &t, nil
+}
+
+func (f *fakeTriggerStore) IncrementMsgCount(id int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.trigger.MsgCount++
+	return nil
+}
+
+func (f *fakeTriggerStore) SaveCursor(id, uidValidity, lastUID int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.trigger.UIDValidity, f.trigger.LastUID = uidValidity, lastUID
+	return nil
+}
+
+func (f *fakeTriggerStore) cursor() (int64, int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.trigger.UIDValidity, f.trigger.LastUID
+}
+
+// fakeQueue completes every enqueued execution at once, optionally holding Enqueue until gate
+// is closed, and reports the subjects it received.
+type fakeQueue struct {
+	gate     chan struct{}
+	mu       sync.Mutex
+	subjects []string
+	jobs     chan string
+}
+
+func newFakeQueue() *fakeQueue {
+	return &fakeQueue{jobs: make(chan string, 100)}
+}
+
+func (q *fakeQueue) Enqueue(ctx context.Context, job *models.QueuedJob) (int64, error) {
+	if q.gate != nil {
+		select {
+		case <-q.gate:
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+	subject, _ := job.Input["subject"].(string)
+	q.mu.Lock()
+	q.subjects = append(q.subjects, subject)
+	id := int64(len(q.subjects))
+	q.mu.Unlock()
+	q.jobs <- subject
+	return id, nil
+}
+
+func (q *fakeQueue) Get(ctx context.Context, id int64) (*models.QueuedJob, error) {
+	return &models.QueuedJob{ID: id, Status: models.JobCompleted}, nil
+}
+
+func (q *fakeQueue) Claim(context.Context, string, time.Duration) (*models.QueuedJob, error) {
+	return nil, nil
+}
+
+func (q *fakeQueue) Heartbeat(context.Context, int64, string, time.Duration) (bool, error) {
+	return true, nil
+}
+
+func (q *fakeQueue) Complete(context.Context, int64, string, int64, string) error { return nil }
+func (q *fakeQueue) Cancel(context.Context, int64) error                          { return nil }
+func (q *fakeQueue) Stats(context.Context) (map[string]int64, error)              { return nil, nil }
+
+func (q *fakeQueue) received() []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]string(nil), q.subjects...)
+}
+
+func (q *fakeQueue) wait(t *testing.T, subject string) {
+	t.Helper()
+	select {
+	case got := <-q.jobs:
+		if got != subject {
+			t.Fatalf("executed %q, want %q", got, subject)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("timed out waiting for %q to be executed", subject)
+	}
+}
+
+type fakeWorkflows struct{}
+
+func (fakeWorkflows) GetByID(id int64) (*models.Workflow, error) {
+	return &models.Workflow{ID: id}, nil
+}
+
+type fakeConfigs struct{ cfg *models.NodeConfig }
+
+func (f fakeConfigs) GetByID(id int64) (*models.NodeConfig, error) { return f.cfg, nil }
+
+func newTestEmailPoller(srv *imapStandIn, q *fakeQueue, t models.EmailTrigger) (*EmailPoller, *fakeTriggerStore) {
+	store := &fakeTriggerStore{trigger: t}
+	ep := &EmailPoller{
+		engine:       &Engine{Queue: q},
+		workflowRepo: fakeWorkflows{},
+		configRepo:   fakeConfigs{srv.config()},
+		triggerRepo:  store,
+		cancels:      make(map[int64]context.CancelFunc),
+	}
+	return ep, store
+}
+
+// pollOnce runs one poll of t and waits for the runs it started.
+func pollOnce(ep *EmailPoller, cur *emailCursor, t *models.EmailTrigger) {
+	d := newTriggerDispatcher(context.Background(), "test", t.TriggerLimits)
+	ep.poll(context.Background(), d, cur, t)
+	d.Close()
+}
+
+func TestEmailPollerCursor(t *testing.T) {
+	srv := newIMAPStandIn(t, false, 7)
+	srv.add("old 1")
+	srv.add("old 2")
+	q := newFakeQueue()
+	trig := models.EmailTrigger{ID: 1, WorkflowID: 2, Mailbox: "INBOX", MaxFetch: 10, MarkSeen: true}
+	ep, store := newTestEmailPoller(srv, q, trig)
+	cur := &emailCursor{}
+
+	// First run: existing mail is skipped and the cursor starts after it
+	pollOnce(ep, cur, &trig)
+	if got := q.received(); len(got) != 0 {
+		t.Fatalf("first poll executed %v, want nothing", got)
+	}
+	if v, last := store.cursor(); v != 7 || last != 2 {
+		t.Fatalf("cursor = (%d, %d), want (7, 2)", v, last)
+	}
+
+	uid := srv.add("new 3")
+	pollOnce(ep, cur, &trig)
+	if got := q.received(); len(got) != 1 || got[0] != "new 3" {
+		t.Fatalf("executed %v, want [new 3]", got)
+	}
+	if v, last := store.cursor(); v != 7 || last != 3 {
+		t.Fatalf("cursor = (%d, %d), want (7, 3)", v, last)
+	}
+	if !srv.isSeen(uid) {
+		t.Errorf("UID %d not marked seen", uid)
+	}
+
+	// Nothing new: nothing runs and the cursor stays
+	pollOnce(ep, cur, &trig)
+	if got := q.received(); len(got) != 1 {
+		t.Fatalf("executed %v after an empty poll", got)
+	}
+
+	// The mailbox was recreated: its mail is skipped under the new UIDVALIDITY
+	srv.recreate(9)
+	srv.add("recreated 1")
+	pollOnce(ep, cur, &trig)
+	if got := q.received(); len(got) != 1 {
+		t.Fatalf("executed %v after UIDVALIDITY reset, want no new runs", got)
+	}
+	if v, last := store.cursor(); v != 9 || last != 1 {
+		t.Fatalf("cursor = (%d, %d), want (9, 1)", v, last)
+	}
+
+	srv.add("recreated 2")
+	pollOnce(ep, cur, &trig)
+	if got := q.received(); len(got) != 2 || got[1] != "recreated 2" {
+		t.Fatalf("executed %v, want [new 3 recreated 2]", got)
+	}
+	if v, last := store.cursor(); v != 9 || last != 2 {
+		t.Fatalf("cursor = (%d, %d), want (9, 2)", v, last)
+	}
+}
+
+func TestEmailPollerStartsAtOldestUnseen(t *testing.T) {
+	// A trigger from before the UID cursor: it processed unread mail, so the unread messages
+	// waiting when it is upgraded still run
+	srv := newIMAPStandIn(t, false, 7)
+	srv.add("read 1")
+	srv.add("unread 2")
+	srv.add("read 3")
+	srv.add("unread 4")
+	srv.seen[1], srv.seen[3] = true, true
+	q := newFakeQueue()
+	trig := models.EmailTrigger{ID: 1, WorkflowID: 2, Mailbox: "INBOX", MaxFetch: 10, MarkSeen: true, StartFrom: models.EmailStartUnseen}
+	ep, store := newTestEmailPoller(srv, q, trig)
+	cur := &emailCursor{}
+
+	pollOnce(ep, cur, &trig)
+	if got := q.received(); strings.Join(got, ",") != "unread 2,read 3,unread 4" {
+		t.Fatalf("executed %v, want everything from the oldest unread message on", got)
+	}
+	if v, last := store.cursor(); v != 7 || last != 4 {
+		t.Fatalf("cursor = (%d, %d), want (7, 4)", v, last)
+	}
+
+	// Without unread mail it starts after the newest message, like a new trigger
+	srv2 := newIMAPStandIn(t, false, 8)
+	srv2.add("read 1")
+	srv2.seen[1] = true
+	q2 := newFakeQueue()
+	ep2, store2 := newTestEmailPoller(srv2, q2, trig)
+	pollOnce(ep2, &emailCursor{}, &models.EmailTrigger{ID: 1, WorkflowID: 2, Mailbox: "INBOX", MaxFetch: 10, StartFrom: models.EmailStartUnseen})
+	if got := q2.received(); len(got) != 0 {
+		t.Fatalf("executed %v, want nothing", got)
+	}
+	if v, last := store2.cursor(); v != 8 || last != 1 {
+		t.Fatalf("cursor = (%d, %d), want (8, 1)", v, last)
+	}
+}
+
+func TestEmailPollerOverflowKeepsCursor(t *testing.T) {
+	srv := newIMAPStandIn(t, false, 7)
+	for i := 1; i <= 3; i++ {
+		srv.add(fmt.Sprintf("msg %d", i))
+	}
+	q := newFakeQueue()
+	q.gate = make(chan struct{})
+	trig := models.EmailTrigger{ID: 1, WorkflowID: 2, Mailbox: "INBOX", MaxFetch: 10, MarkSeen: true, UIDValidity: 7,
+		TriggerLimits: models.TriggerLimits{MaxInFlight: 1, BufferSize: 1, OverflowPolicy: models.OverflowReject}}
+	ep, store := newTestEmailPoller(srv, q, trig)
+	cur := &emailCursor{validity: 7}
+
+	c, err := ep.dial(&trig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := newTriggerDispatcher(context.Background(), "test", trig.TriggerLimits)
+	_, err = ep.fetchNew(context.Background(), d, cur, &trig, c)
+	c.Close()
+	if !errors.Is(err, ErrTriggerOverflow) {
+		t.Fatalf("fetchNew error = %v, want ErrTriggerOverflow", err)
+	}
+	// One message is running and at most one is buffered; the rejected one stays unseen
+	buffered := trig.LastUID
+	if buffered < 1 || buffered > 2 {
+		t.Fatalf("fetch position = %d, want 1 or 2", buffered)
+	}
+	if srv.isSeen(uint32(buffered) + 1) {
+		t.Errorf("rejected UID %d marked seen", buffered+1)
+	}
+	if _, last := store.cursor(); last > 1 {
+		t.Fatalf("stored cursor = %d while only UID 1 was handed to a run", last)
+	}
+
+	close(q.gate)
+	d.Close()
+	if _, last := store.cursor(); last != buffered {
+		t.Fatalf("stored cursor = %d after the buffer drained, want %d", last, buffered)
+	}
+
+	// The rejected messages are fetched again, each run exactly once
+	trig.TriggerLimits = models.TriggerLimits{}
+	pollOnce(ep, cur, &trig)
+	if got := q.received(); strings.Join(got, ",") != "msg 1,msg 2,msg 3" {
+		t.Fatalf("executed %v, want each message once in order", got)
+	}
+	if _, last := store.cursor(); last != 3 {
+		t.Fatalf("stored cursor = %d, want 3", last)
+	}
+}
+
+func TestEmailPollerIdle(t *testing.T) {
+	srv := newIMAPStandIn(t, true, 7)
+	srv.add("old 1")
+	q := newFakeQueue()
+	trig := models.EmailTrigger{ID: 1, WorkflowID: 2, Mailbox: "INBOX", MaxFetch: 10, UseIdle: true, UIDValidity: 7, LastUID: 1}
+	ep, store := newTestEmailPoller(srv, q, trig)
+
+	if err := ep.startPoller(&trig); err != nil {
+		t.Fatal(err)
+	}
+	defer ep.stopAll()
+
+	select {
+	case <-srv.idling:
+	case <-time.After(10 * time.Second):
+		t.Fatal("poller did not enter IDLE")
+	}
+	srv.add("pushed 2")
+	q.wait(t, "pushed 2")
+
+	// Back in IDLE for the next message
+	select {
+	case <-srv.idling:
+	case <-time.After(10 * time.Second):
+		t.Fatal("poller did not return to IDLE")
+	}
+	if _, last := store.cursor(); last != 2 {
+		t.Fatalf("stored cursor = %d, want 2", last)
+	}
+}
+
+func TestEmailPollerIdleFallback(t *testing.T) {
+	srv := newIMAPStandIn(t, false, 7)
+	srv.add("old 1")
+	srv.add("new 2")
+	q := newFakeQueue()
+	trig := models.EmailTrigger{ID: 1, WorkflowID: 2, Mailbox: "INBOX", MaxFetch: 10, UseIdle: true, UIDValidity: 7, LastUID: 1}
+	ep, _ := newTestEmailPoller(srv, q, trig)
+
+	if err := ep.startPoller(&trig); err != nil {
+		t.Fatal(err)
+	}
+	defer ep.stopAll()
+
+	// Without IDLE the poller falls back to polling, which starts right away
+	q.wait(t, "new 2")
+	if srv.received("IDLE") {
+		t.Error("IDLE sent to a server that does not advertise it")
+	}
+}
@@ -17,10 +17,18 @@ type Engine struct {
 	ConfigRepo      *repository.NodeConfigRepo
 	ConfigStoreRepo *repository.ConfigStoreRepo
 	WorkflowRepo    *repository.WorkflowRepo
+	ScriptLibRepo   *repository.ScriptLibraryRepo
+
+	// Queue, when set, makes Execute hand trigger-started runs to workers (see ExecutionQueue).
+	Queue            ExecutionQueue
+	QueueMaxAttempts int
+
+	// DeadLetters, when set, keeps trigger events whose run failed (see RecordDeadLetter).
+	DeadLetters *repository.DeadLetterRepo
 }
 
-func NewEngine(execRepo *repository.ExecutionRepo, execLogRepo *repository.ExecutionLogRepo, configRepo *repository.NodeConfigRepo, configStoreRepo *repository.ConfigStoreRepo, workflowRepo *repository.WorkflowRepo) *Engine {
-	return &Engine{ExecRepo: execRepo, ExecLogRepo: execLogRepo, ConfigRepo: configRepo, ConfigStoreRepo: configStoreRepo, WorkflowRepo: workflowRepo}
+func NewEngine(execRepo *repository.ExecutionRepo, execLogRepo *repository.ExecutionLogRepo, configRepo *repository.NodeConfigRepo, configStoreRepo *repository.ConfigStoreRepo, workflowRepo *repository.WorkflowRepo, scriptLibRepo *repository.ScriptLibraryRepo) *Engine {
+	return &Engine{ExecRepo: execRepo, ExecLogRepo: execLogRepo, ConfigRepo: configRepo, ConfigStoreRepo: configStoreRepo, WorkflowRepo: workflowRepo, ScriptLibRepo: scriptLibRepo}
 }
 
 // RunWorkflow executes the workflow synchronously and returns the execution ID.
@@ -69,6 +77,10 @@ func (e *Engine) RunWorkflowWithInput(ctx context.Context, workflow *models.Work
 	if e.ConfigStoreRepo != nil {
 		ctx = context.WithValue(ctx, configStoreContextKey, e.ConfigStoreRepo)
 	}
+	// Inject script libraries for require() in function nodes
+	if e.ScriptLibRepo != nil {
+		ctx = context.WithValue(ctx, scriptLibContextKey, e.ScriptLibRepo)
+	}
 	// Load config store as key-value map for config variable (Function node, {{config.xxx}} in HTTP Request, etc.)
 	var configMap map[string]interface{}
 	if e.ConfigStoreRepo != nil {
@@ -111,6 +123,12 @@ func (e *Engine) RunWorkflowWithInput(ctx context.Context, workflow *models.Work
 	var execErr error
 
 	for len(queue) > 0 {
+		// Stop between nodes when the run is cancelled or times out
+		if err := ctx.Err(); err != nil {
+			execErr = fmt.Errorf("execution cancelled: %w", err)
+			break
+		}
+
 		currentID := queue[0]
 		queue = queue[1:]
 
@@ -183,7 +201,8 @@ func (e *Engine) RunWorkflowWithInput(ctx context.Context, workflow *models.Work
 		output, err := executor.Execute(ctx, node, input, resolveConfig)
 		if err != nil {
 			execErr = fmt.Errorf("node %s (%s) failed: %w", node.ID, node.Type, err)
-			e.logNode(execID, node, input, nil, err, debugSink)
+			// Executors may return partial output with an error (e.g. captured console output)
+			e.logNode(execID, node, input, output, err, debugSink)
 			break
 		}
 
@@ -200,8 +219,8 @@ func (e *Engine) RunWorkflowWithInput(ctx context.Context, workflow *models.Work
 			continue
 		}
 
-		// For condition/switch nodes, follow the appropriate branch
-		if node.Type == "condition" || node.Type == "switch" {
+		// For condition/switch/validate nodes, follow the appropriate branch
+		if node.Type == "condition" || node.Type == "switch" || node.Type == "validate" {
 			branchStr, _ := output["_branch"].(string)
 			for _, edge := range adjacency[currentID] {
 				if edge.SourceHandle == branchStr || edge.Label == branchStr {
@@ -0,0 +1,380 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"eflo/backend/models"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// TriggersLease is the lease whose holder runs the time- and poll-based triggers (Scheduler,
+// RedisSubscriber, EmailPoller). HTTP triggers run on every instance.
+const TriggersLease = "triggers"
+
+// LeaseStore is the backend of a LeaderElector (MySQL lease table or Redis).
+type LeaseStore interface {
+	TryAcquire(ctx context.Context, name, holder string, ttl time.Duration) (bool, error)
+	Release(ctx context.Context, name, holder string) error
+	Get(ctx context.Context, name string) (*models.LeaderLease, error)
+	BumpVersion(ctx context.Context, name string) error
+}
+
+// LeaderComponent is started when this instance becomes leader and stopped when it steps down.
+// Start's ctx is the parent of every run the component starts; it is cancelled when the lease is
+// lost. Reload re-reads its definitions after another instance changed them.
+type LeaderComponent interface {
+	Start(ctx context.Context) error
+	Stop()
+	Reload() error
+}
+
+// LeaderElector keeps a lease renewed and runs the leader-only components while it holds it.
+// A leader that cannot renew steps down one renew interval before its lease could expire, and a
+// follower takes over once the lease expires. Stepping down cancels the lease context, so runs
+// still in flight are cancelled before the next leader starts its own.
+//
+// A process paused for longer than the TTL (e.g. a stopped VM) can still outlive its lease, so
+// workflows started by leader-only triggers should tolerate the occasional duplicate.
+type LeaderElector struct {
+	store      LeaseStore
+	backend    string
+	name       string
+	instanceID string
+	ttl        time.Duration
+	components []LeaderComponent
+
+	mu          sync.Mutex
+	leader      bool
+	since       time.Time
+	lastRenew   time.Time
+	lastVersion int64
+	lastErr     string
+	cancelLease context.CancelFunc // cancels the components' runs; set while leader
+}
+
+// NewLeaderElector creates an elector for lease name. backend is reported in the status only.
+// A ttl <= 0 defaults to 15s; the lease is renewed every ttl/3.
+func NewLeaderElector(store LeaseStore, backend, name, instanceID string, ttl time.Duration, components ...LeaderComponent) *LeaderElector {
+	if ttl <= 0 {
+		ttl = 15 * time.Second
+	}
+	if instanceID == "" {
+		instanceID = InstanceID()
+	}
+	return &LeaderElector{
+		store:      store,
+		backend:    backend,
+		name:       name,
+		instanceID: instanceID,
+		ttl:        ttl,
+		components: components,
+	}
+}
+
+var instanceID string
+
+// SetInstanceID sets the name this process reports in leases, queue leases and trigger status.
+func SetInstanceID(id string) {
+	instanceID = id
+}
+
+// InstanceID returns the configured instance name, or DefaultInstanceID.
+func InstanceID() string {
+	if instanceID != "" {
+		return instanceID
+	}
+	return DefaultInstanceID()
+}
+
+// DefaultInstanceID identifies this process as hostname-pid.
+func DefaultInstanceID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "eflo"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
+
+// Run campaigns for leadership until ctx is cancelled, then steps down and releases the lease.
+// Besides the renew ticks it wakes at the renew deadline, so a leader whose renewals keep failing
+// steps down in time even if the last attempt failed just before it.
+func (le *LeaderElector) Run(ctx context.Context) {
+	ticker := time.NewTicker(le.ttl / 3)
+	defer ticker.Stop()
+	expiry := time.NewTimer(le.ttl)
+	defer expiry.Stop()
+	for {
+		le.tick(ctx)
+		if deadline, ok := le.renewDeadline(); ok {
+			expiry.Reset(time.Until(deadline))
+		} else {
+			expiry.Stop()
+		}
+		select {
+		case <-ctx.Done():
+			le.stepDown("shutting down", true)
+			releaseCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if err := le.store.Release(releaseCtx, le.name, le.instanceID); err != nil {
+				log.Printf("[Leader] Failed to release lease %q: %v", le.name, err)
+			}
+			cancel()
+			return
+		case <-ticker.C:
+		case <-expiry.C:
+		}
+	}
+}
+
+// renewDeadline returns when a leader without a confirmed renewal must have stepped down: one
+// renew interval before its lease could expire. ok is false when this instance is not leader.
+func (le *LeaderElector) renewDeadline() (deadline time.Time, ok bool) {
+	le.mu.Lock()
+	defer le.mu.Unlock()
+	return le.lastRenew.Add(le.ttl - le.ttl/3), le.leader
+}
+
+func (le *LeaderElector) tick(ctx context.Context) {
+	// The lease runs from when the renewal was sent, not from when it was confirmed
+	start := time.Now()
+	timeout := le.ttl / 3
+	if deadline, ok := le.renewDeadline(); ok {
+		if !start.Before(deadline) {
+			le.stepDown("lease renewal failed", false)
+		} else if d := deadline.Sub(start); d < timeout {
+			// A renewal still pending at the deadline is as good as failed
+			timeout = d
+		}
+	}
+	opCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ok, err := le.store.TryAcquire(opCtx, le.name, le.instanceID, le.ttl)
+	le.mu.Lock()
+	wasLeader := le.leader
+	if err != nil {
+		le.lastErr = err.Error()
+	} else {
+		le.lastErr = ""
+	}
+	if ok {
+		le.lastRenew = start
+	}
+	expiring := wasLeader && err != nil && !time.Now().Before(le.lastRenew.Add(le.ttl-le.ttl/3))
+	le.mu.Unlock()
+
+	switch {
+	case err != nil:
+		if ctx.Err() == nil {
+			log.Printf("[Leader] Lease %q renewal failed: %v", le.name, err)
+		}
+		if expiring {
+			le.stepDown("lease renewal failed", false)
+		}
+	case ok && !wasLeader:
+		le.becomeLeader(opCtx)
+	case !ok && wasLeader:
+		le.stepDown("lease taken by another instance", false)
+	case ok:
+		le.checkVersion(opCtx)
+	}
+}
+
+func (le *LeaderElector) becomeLeader(ctx context.Context) {
+	version := int64(0)
+	if l, err := le.store.Get(ctx, le.name); err == nil {
+		version = l.Version
+	}
+	leaseCtx, cancelLease := context.WithCancel(context.Background())
+	le.mu.Lock()
+	le.leader = true
+	le.since = time.Now()
+	le.lastVersion = version
+	le.cancelLease = cancelLease
+	le.mu.Unlock()
+
+	log.Printf("[Leader] Instance %s acquired lease %q; starting triggers", le.instanceID, le.name)
+	for _, c := range le.components {
+		if err := c.Start(leaseCtx); err != nil {
+			log.Printf("[Leader] Failed to start component: %v", err)
+		}
+	}
+}
+
+// stepDown stops the components. When the lease is lost their runs are cancelled first, since
+// another instance may already be leader; on shutdown (drain) the lease is still held, so runs
+// are allowed to finish.
+func (le *LeaderElector) stepDown(reason string, drain bool) {
+	le.mu.Lock()
+	if !le.leader {
+		le.mu.Unlock()
+		return
+	}
+	le.leader = false
+	cancelLease := le.cancelLease
+	le.cancelLease = nil
+	le.mu.Unlock()
+
+	log.Printf("[Leader] Instance %s lost lease %q (%s); stopping triggers", le.instanceID, le.name, reason)
+	if !drain {
+		cancelLease()
+	}
+	for i := len(le.components) - 1; i >= 0; i-- {
+		le.components[i].Stop()
+	}
+	cancelLease()
+}
+
+// checkVersion reloads the components when another instance changed trigger definitions.
+func (le *LeaderElector) checkVersion(ctx context.Context) {
+	l, err := le.store.Get(ctx, le.name)
+	if err != nil {
+		return
+	}
+	le.mu.Lock()
+	changed := l.Version != le.lastVersion
+	le.lastVersion = l.Version
+	le.mu.Unlock()
+	if !changed {
+		return
+	}
+	log.Printf("[Leader] Trigger definitions changed; reloading")
+	for _, c := range le.components {
+		if err := c.Reload(); err != nil {
+			log.Printf("[Leader] Failed to reload component: %v", err)
+		}
+	}
+}
+
+// IsLeader reports whether this instance currently runs the leader-only triggers.
+func (le *LeaderElector) IsLeader() bool {
+	le.mu.Lock()
+	defer le.mu.Unlock()
+	return le.leader
+}
+
+// NotifyChanged tells the leader that trigger definitions changed. Called after a CRUD change
+// made on an instance that is not the leader.
+func (le *LeaderElector) NotifyChanged() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := le.store.BumpVersion(ctx, le.name); err != nil {
+		log.Printf("[Leader] Failed to signal trigger change: %v", err)
+	}
+}
+
+// LeaderStatus describes this instance and the current lease holder.
+type LeaderStatus struct {
+	InstanceID  string              `json:"instanceId"`
+	IsLeader    bool                `json:"isLeader"`
+	LeaderSince *time.Time          `json:"leaderSince,omitempty"`
+	Backend     string              `json:"backend"`
+	TTLSeconds  float64             `json:"ttlSeconds"`
+	Lease       *models.LeaderLease `json:"lease,omitempty"`
+	LastError   string              `json:"lastError,omitempty"`
+}
+
+// Status returns this instance's view of leadership, including the stored lease.
+func (le *LeaderElector) Status(ctx context.Context) LeaderStatus {
+	le.mu.Lock()
+	st := LeaderStatus{
+		InstanceID: le.instanceID,
+		IsLeader:   le.leader,
+		Backend:    le.backend,
+		TTLSeconds: le.ttl.Seconds(),
+		LastError:  le.lastErr,
+	}
+	if le.leader {
+		since := le.since
+		st.LeaderSince = &since
+	}
+	le.mu.Unlock()
+
+	if l, err := le.store.Get(ctx, le.name); err == nil {
+		st.Lease = l
+	} else if st.LastError == "" {
+		st.LastError = err.Error()
+	}
+	return st
+}
+
+// RedisLeaseStore keeps leases in Redis: <prefix><name> holds the holder with a TTL,
+// <prefix><name>:since the acquisition time and <prefix><name>:version the change counter.
+type RedisLeaseStore struct {
+	Client *redis.Client
+	Prefix string
+}
+
+// NewRedisLeaseStore connects to addr; keys are prefixed with "eflo:leader:".
+func NewRedisLeaseStore(addr, password string, db int) *RedisLeaseStore {
+	return &RedisLeaseStore{
+		Client: redis.NewClient(&redis.Options{Addr: addr, Password: password, DB: db}),
+		Prefix: "eflo:leader:",
+	}
+}
+
+var redisAcquireScript = redis.NewScript(`
+local cur = redis.call('GET', KEYS[1])
+if cur == false then
+  redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+  redis.call('SET', KEYS[2], ARGV[3])
+  return 1
+elseif cur == ARGV[1] then
+  redis.call('PEXPIRE', KEYS[1], ARGV[2])
+  return 1
+end
+return 0`)
+
+var redisReleaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('DEL', KEYS[1])
+end
+return 0`)
+
+func (s *RedisLeaseStore) TryAcquire(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	key := s.Prefix + name
+	n, err := redisAcquireScript.Run(ctx, s.Client, []string{key, key + ":since"},
+		holder, ttl.Milliseconds(), time.Now().UTC().Format(time.RFC3339Nano)).Int()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+func (s *RedisLeaseStore) Release(ctx context.Context, name, holder string) error {
+	return redisReleaseScript.Run(ctx, s.Client, []string{s.Prefix + name}, holder).Err()
+}
+
+func (s *RedisLeaseStore) Get(ctx context.Context, name string) (*models.LeaderLease, error) {
+	key := s.Prefix + name
+	l := &models.LeaderLease{Name: name}
+	pipe := s.Client.Pipeline()
+	holder := pipe.Get(ctx, key)
+	ttl := pipe.PTTL(ctx, key)
+	since := pipe.Get(ctx, key+":since")
+	version := pipe.Get(ctx, key+":version")
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+	l.Version, _ = version.Int64()
+	if h, err := holder.Result(); err == nil {
+		l.Holder = h
+		l.Valid = true
+		if d := ttl.Val(); d > 0 {
+			l.ExpiresAt = time.Now().Add(d)
+		}
+		if t, err := time.Parse(time.RFC3339Nano, since.Val()); err == nil {
+			l.AcquiredAt = t
+		}
+	}
+	return l, nil
+}
+
+func (s *RedisLeaseStore) BumpVersion(ctx context.Context, name string) error {
+	return s.Client.Incr(ctx, s.Prefix+name+":version").Err()
+}
@@ -0,0 +1,253 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"eflo/backend/models"
+)
+
+// memLeaseStore is an in-memory LeaseStore shared by the electors of a test.
+type memLeaseStore struct {
+	mu      sync.Mutex
+	holder  string
+	expires time.Time
+	version int64
+}
+
+func (s *memLeaseStore) TryAcquire(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if s.holder != "" && s.holder != holder && now.Before(s.expires) {
+		return false, nil
+	}
+	s.holder, s.expires = holder, now.Add(ttl)
+	return true, nil
+}
+
+func (s *memLeaseStore) Release(ctx context.Context, name, holder string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.holder == holder {
+		s.holder = ""
+	}
+	return nil
+}
+
+func (s *memLeaseStore) Get(ctx context.Context, name string) (*models.LeaderLease, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l := &models.LeaderLease{Name: name, Version: s.version}
+	if s.holder != "" && time.Now().Before(s.expires) {
+		l.Holder, l.Valid, l.ExpiresAt = s.holder, true, s.expires
+	}
+	return l, nil
+}
+
+func (s *memLeaseStore) BumpVersion(ctx context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.version++
+	return nil
+}
+
+// flakyStore is one instance's connection to the shared store; while down, renewals fail.
+type flakyStore struct {
+	*memLeaseStore
+	down atomic.Bool
+}
+
+func (s *flakyStore) TryAcquire(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	if s.down.Load() {
+		return false, errors.New("connection refused")
+	}
+	return s.memLeaseStore.TryAcquire(ctx, name, holder, ttl)
+}
+
+// leaderLog records when each instance's component started and stopped and whether its runs
+// were cancelled, in order.
+type leaderLog struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (l *leaderLog) add(event string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, event)
+}
+
+func (l *leaderLog) snapshot() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.events...)
+}
+
+// runComponent stands in for a trigger: on Start it begins a run that lasts until the lease
+// context is cancelled or Stop lets it finish, and Stop waits for it like the real triggers do.
+type runComponent struct {
+	id       string
+	log      *leaderLog
+	stopping chan struct{}
+	wg       sync.WaitGroup
+}
+
+func (c *runComponent) Start(ctx context.Context) error {
+	c.log.add(c.id + " start")
+	c.stopping = make(chan struct{})
+	stopping := c.stopping
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		select {
+		case <-ctx.Done():
+		case <-stopping:
+		}
+		if ctx.Err() != nil {
+			c.log.add(c.id + " run cancelled")
+		} else {
+			c.log.add(c.id + " run finished")
+		}
+	}()
+	return nil
+}
+
+func (c *runComponent) Stop() {
+	close(c.stopping)
+	c.wg.Wait()
+	c.log.add(c.id + " stop")
+}
+
+func (c *runComponent) Reload() error { return nil }
+
+// startElector runs an elector for instance id until the test ends.
+func startElector(t *testing.T, store LeaseStore, id string, ttl time.Duration, log *leaderLog) (*LeaderElector, context.CancelFunc) {
+	t.Helper()
+	c := &runComponent{id: id, log: log}
+	le := NewLeaderElector(store, "memory", TriggersLease, id, ttl, c)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		le.Run(ctx)
+	}()
+	stop := func() {
+		cancel()
+		<-done
+	}
+	t.Cleanup(stop)
+	return le, stop
+}
+
+func waitFor(t *testing.T, timeout time.Duration, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestLeaderElectionSingleLeader(t *testing.T) {
+	shared := &memLeaseStore{}
+	log := &leaderLog{}
+	a, _ := startElector(t, shared, "a", 300*time.Millisecond, log)
+	waitFor(t, time.Second, "a to lead", a.IsLeader)
+	b, _ := startElector(t, shared, "b", 300*time.Millisecond, log)
+
+	time.Sleep(time.Second)
+	if !a.IsLeader() || b.IsLeader() {
+		t.Fatalf("leaders: a=%v b=%v, want only a", a.IsLeader(), b.IsLeader())
+	}
+	if got := log.snapshot(); len(got) != 1 || got[0] != "a start" {
+		t.Errorf("events = %q, want only a's start", got)
+	}
+}
+
+func TestLeaderFailoverOnShutdown(t *testing.T) {
+	shared := &memLeaseStore{}
+	log := &leaderLog{}
+	a, stopA := startElector(t, shared, "a", 300*time.Millisecond, log)
+	waitFor(t, time.Second, "a to lead", a.IsLeader)
+	b, _ := startElector(t, shared, "b", 300*time.Millisecond, log)
+
+	// a releases the lease on shutdown, so b does not wait for it to expire
+	start := time.Now()
+	stopA()
+	waitFor(t, time.Second, "b to lead", b.IsLeader)
+	if d := time.Since(start); d > 250*time.Millisecond {
+		t.Errorf("takeover after %v, want within one renew interval", d)
+	}
+	want := []string{"a start", "a run finished", "a stop", "b start"}
+	if got := log.snapshot(); !equalEvents(got, want) {
+		t.Errorf("events = %q, want %q (a lets its run finish)", got, want)
+	}
+}
+
+func TestLeaderStepsDownBeforeLeaseExpiresOnRenewalErrors(t *testing.T) {
+	const ttl = 300 * time.Millisecond
+	shared := &memLeaseStore{}
+	log := &leaderLog{}
+	storeA := &flakyStore{memLeaseStore: shared}
+	a, _ := startElector(t, storeA, "a", ttl, log)
+	waitFor(t, time.Second, "a to lead", a.IsLeader)
+	b, _ := startElector(t, shared, "b", ttl, log)
+
+	// a's last renewal was at most one renew interval ago; it gives up one interval before the
+	// lease could expire, i.e. within two intervals from now and well before the TTL
+	storeA.down.Store(true)
+	lost := time.Now()
+	waitFor(t, time.Second, "a to step down", func() bool { return !a.IsLeader() })
+	if d := time.Since(lost); d >= ttl-ttl/6 {
+		t.Errorf("a stepped down %v after its store failed, want before %v", d, ttl-ttl/6)
+	}
+	waitFor(t, 2*time.Second, "b to lead", b.IsLeader)
+
+	// a's run is cancelled before b starts, so the trigger never runs on both
+	want := []string{"a start", "a run cancelled", "a stop", "b start"}
+	if got := log.snapshot(); !equalEvents(got, want) {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+
+	// Once its store is back, a stays a follower
+	storeA.down.Store(false)
+	time.Sleep(ttl)
+	if a.IsLeader() || !b.IsLeader() {
+		t.Errorf("leaders: a=%v b=%v, want only b", a.IsLeader(), b.IsLeader())
+	}
+}
+
+func TestLeaderStepsDownWhenLeaseTaken(t *testing.T) {
+	shared := &memLeaseStore{}
+	log := &leaderLog{}
+	a, _ := startElector(t, shared, "a", 300*time.Millisecond, log)
+	waitFor(t, time.Second, "a to lead", a.IsLeader)
+
+	// The lease expired while a was paused and another instance took it
+	shared.mu.Lock()
+	shared.holder, shared.expires = "b", time.Now().Add(time.Minute)
+	shared.mu.Unlock()
+	waitFor(t, time.Second, "a to stop", func() bool { return len(log.snapshot()) == 3 })
+	if got, want := log.snapshot(), []string{"a start", "a run cancelled", "a stop"}; !equalEvents(got, want) {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+}
+
+func equalEvents(got, want []string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
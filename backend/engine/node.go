@@ -21,6 +21,7 @@ const (
 	httpRunContextKey     contextKey = 1
 	configStoreContextKey contextKey = 2
 	configMapContextKey   contextKey = 3
+	scriptLibContextKey   contextKey = 4
 )
 
 // ConfigStore provides get/set of key-value config (secrets, tokens) during workflow run.
@@ -50,6 +51,22 @@ func ConfigMapFromContext(ctx context.Context) map[string]interface{} {
 	return m
 }
 
+// ScriptLibraryResolver loads named JavaScript libraries for the function node's require().
+type ScriptLibraryResolver interface {
+	// ResolveScript returns the library at the given version (0 = latest).
+	ResolveScript(name string, version int) (*models.ScriptLibrary, error)
+}
+
+// ScriptLibrariesFromContext returns the script library resolver for this execution if set.
+func ScriptLibrariesFromContext(ctx context.Context) ScriptLibraryResolver {
+	v := ctx.Value(scriptLibContextKey)
+	if v == nil {
+		return nil
+	}
+	r, _ := v.(ScriptLibraryResolver)
+	return r
+}
+
 // ConfigResolver resolves a node config by its ID.
 type ConfigResolver func(configID int64) (*models.NodeConfig, error)
 
@@ -2,49 +2,78 @@ package nodes
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"time"
 
 	"eflo/backend/engine"
 	"eflo/backend/models"
-
-	"github.com/robfig/cron/v3"
 )
 
 type CronNode struct{}
 
+// Execute emits the trigger metadata. When the scheduler started the run, its input carries the
+// schedule (scheduleId, scheduledAt, firedAt, nextRun, timezone, payload) and that is used as is;
+// a manual run falls back to the node's own expression and timezone.
 func (n *CronNode) Execute(_ context.Context, node models.NodeDef, input map[string]interface{}, _ engine.ConfigResolver) (map[string]interface{}, error) {
-	expression, _ := node.Properties["expression"].(string)
-	if expression == "" {
-		expression = "* * * * *" // default: every minute
-	}
+	payload := cronNodePayload(node.Properties["payload"])
 
-	// Validate the cron expression
-	parser := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
-	schedule, err := parser.Parse(expression)
-	if err != nil {
-		return nil, fmt.Errorf("cron node: invalid expression %q: %w", expression, err)
-	}
+	var output map[string]interface{}
+	if _, scheduled := input["scheduleId"]; scheduled {
+		output = map[string]interface{}{
+			"triggered":   true,
+			"scheduleId":  input["scheduleId"],
+			"expression":  input["expression"],
+			"timezone":    input["timezone"],
+			"scheduledAt": input["scheduledAt"],
+			"firedAt":     input["firedAt"],
+			"triggeredAt": input["firedAt"],
+			"catchUp":     input["catchUp"],
+		}
+		if next, ok := input["nextRun"]; ok {
+			output["nextRun"] = next
+		}
+		// The schedule's payload is layered over the node's default payload
+		if sp, ok := input["payload"].(map[string]interface{}); ok {
+			if m, ok := payload.(map[string]interface{}); ok {
+				for k, v := range sp {
+					m[k] = v
+				}
+			} else if len(sp) > 0 || payload == nil {
+				payload = sp
+			}
+		}
+	} else {
+		expression, _ := node.Properties["expression"].(string)
+		if expression == "" {
+			expression = "* * * * *" // default: every minute
+		}
 
-	now := time.Now()
-	nextRun := schedule.Next(now)
+		timezone, _ := node.Properties["timezone"].(string)
+		if timezone == "" {
+			timezone = "UTC"
+		}
 
-	timezone, _ := node.Properties["timezone"].(string)
-	if timezone == "" {
-		timezone = "UTC"
-	}
+		// Validate the cron expression (optional seconds field, evaluated in timezone)
+		schedule, err := engine.ParseCronSchedule(expression, timezone)
+		if err != nil {
+			return nil, fmt.Errorf("cron node: invalid expression %q: %w", expression, err)
+		}
 
-	payload, _ := node.Properties["payload"].(string)
+		loc, _ := engine.LoadTimezone(timezone)
+		now := time.Now().In(loc)
+		nextRun := schedule.Next(now)
 
-	output := map[string]interface{}{
-		"triggered":   true,
-		"expression":  expression,
-		"timezone":    timezone,
-		"triggeredAt": now.Format(time.RFC3339),
-		"nextRun":     nextRun.Format(time.RFC3339),
+		output = map[string]interface{}{
+			"triggered":   true,
+			"expression":  expression,
+			"timezone":    timezone,
+			"triggeredAt": now.Format(time.RFC3339),
+			"nextRun":     nextRun.Format(time.RFC3339),
+		}
 	}
 
-	if payload != "" {
+	if payload != nil {
 		output["payload"] = payload
 	}
 
@@ -57,3 +86,25 @@ func (n *CronNode) Execute(_ context.Context, node models.NodeDef, input map[str
 
 	return output, nil
 }
+
+// cronNodePayload returns the node's payload property, decoded when it is a JSON object.
+func cronNodePayload(v interface{}) interface{} {
+	switch p := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(p))
+		for k, val := range p {
+			out[k] = val
+		}
+		return out
+	case string:
+		if p == "" {
+			return nil
+		}
+		var m map[string]interface{}
+		if err := json.Unmarshal([]byte(p), &m); err == nil {
+			return m
+		}
+		return p
+	}
+	return nil
+}
@@ -189,7 +189,7 @@ func buildDSN(driver string, cfg map[string]interface{}) (string, error) {
 }
 
 // resolvePlaceholders replaces {{path}} with ? and returns the query and args.
-// path can be "key" or "input.key" or "a.b.c" for nested input.
+// path can be "key" or "input.key" or any GetNested path (e.g. "a.b.c", "rows[0].id").
 func resolvePlaceholders(query string, input map[string]interface{}) (string, []interface{}, error) {
 	matches := placeholderRE.FindAllStringSubmatch(query, -1)
 	if len(matches) == 0 {
@@ -202,7 +202,7 @@ func resolvePlaceholders(query string, input map[string]interface{}) (string, []
 		if strings.HasPrefix(path, "input.") {
 			path = path[7:]
 		}
-		v, err := getNested(input, path)
+		v, err := GetNested(input, path)
 		if err != nil {
 			return "", nil, fmt.Errorf("placeholder {{%s}}: %w", path, err)
 		}
@@ -213,22 +213,6 @@ func resolvePlaceholders(query string, input map[string]interface{}) (string, []
 	return replaced, args, nil
 }
 
-func getNested(m map[string]interface{}, path string) (interface{}, error) {
-	parts := strings.Split(path, ".")
-	var current interface{} = m
-	for _, p := range parts {
-		if current == nil {
-			return nil, fmt.Errorf("missing path %q", path)
-		}
-		if mp, ok := current.(map[string]interface{}); ok {
-			current = mp[p]
-			continue
-		}
-		return nil, fmt.Errorf("path %q not a map at %q", path, p)
-	}
-	return current, nil
-}
-
 func wrapProcedure(driver, nameOrQuery string) string {
 	nameOrQuery = strings.TrimSpace(nameOrQuery)
 	if nameOrQuery == "" {
@@ -5,6 +5,7 @@ import (
 	"crypto/tls"
 	"fmt"
 	"net"
+	"net/mail"
 	"net/smtp"
 	"strconv"
 	"strings"
@@ -14,7 +15,8 @@ import (
 	"eflo/backend/models"
 )
 
-// EmailNode sends an email using SMTP via a shared email config.
+// EmailNode sends an email using SMTP via a shared email config. Messages can carry a plain
+// text and an HTML body (multipart/alternative), attachments, inline images and custom headers.
 type EmailNode struct{}
 
 func (n *EmailNode) Execute(ctx context.Context, node models.NodeDef, input map[string]interface{}, resolveConfig engine.ConfigResolver) (map[string]interface{}, error) {
@@ -66,43 +68,83 @@ func (n *EmailNode) Execute(ctx context.Context, node models.NodeDef, input map[
 		}
 	}
 
-	// Extract email fields from node properties (with fallback to input)
+	// Extract email fields from node properties (with fallback to input). Subject, bodies and
+	// custom headers are templates: {{path}} placeholders are filled from the input.
 	to := getStringProp(node.Properties, input, "to")
 	cc := getStringProp(node.Properties, input, "cc")
 	bcc := getStringProp(node.Properties, input, "bcc")
-	subject := getStringProp(node.Properties, input, "subject")
-	body := getStringProp(node.Properties, input, "body")
-	contentType := "text/plain"
-	if ct, _ := node.Properties["contentType"].(string); ct != "" {
-		contentType = ct
-	}
-
+	replyTo := getStringProp(node.Properties, input, "replyTo")
 	if to == "" {
 		return nil, fmt.Errorf("email node: 'to' address is required")
 	}
+
+	subject, err := ResolvePlaceholders(getStringProp(node.Properties, input, "subject"), input)
+	if err != nil {
+		return nil, fmt.Errorf("email node: subject: %w", err)
+	}
 	if subject == "" {
 		subject = "(no subject)"
 	}
+	body, err := ResolvePlaceholders(getStringProp(node.Properties, input, "body"), input)
+	if err != nil {
+		return nil, fmt.Errorf("email node: body: %w", err)
+	}
+	html, err := ResolvePlaceholders(getStringProp(node.Properties, input, "html"), input)
+	if err != nil {
+		return nil, fmt.Errorf("email node: html: %w", err)
+	}
+	// contentType text/html keeps the single-body form of older workflows working
+	if ct, _ := node.Properties["contentType"].(string); strings.HasPrefix(ct, "text/html") && html == "" {
+		html, body = body, ""
+	}
 
-	// Build email message
-	toAddrs := parseAddresses(to)
-	ccAddrs := parseAddresses(cc)
-	bccAddrs := parseAddresses(bcc)
+	msg := &outgoingEmail{Subject: subject, Text: body, HTML: html}
+	if msg.From, err = mail.ParseAddress(fromAddr); err != nil {
+		return nil, fmt.Errorf("email node: invalid from address %q: %w", fromAddr, err)
+	}
+	var bccAddrs []*mail.Address
+	for _, f := range []struct {
+		name, value string
+		dst         *[]*mail.Address
+	}{{"to", to, &msg.To}, {"cc", cc, &msg.Cc}, {"bcc", bcc, &bccAddrs}, {"replyTo", replyTo, &msg.ReplyTo}} {
+		if *f.dst, err = parseAddressList(f.value); err != nil {
+			return nil, fmt.Errorf("email node: invalid %s address: %w", f.name, err)
+		}
+	}
+	if msg.Headers, err = parseEmailHeaders(node.Properties["headers"], input); err != nil {
+		return nil, fmt.Errorf("email node: %w", err)
+	}
 
-	allRecipients := append(append(toAddrs, ccAddrs...), bccAddrs...)
+	// Attachments come from the property, else input.attachments; attachInput attaches the
+	// input itself (e.g. the output of a read_file node). Only the property may name files on
+	// disk: input comes from triggers and must not pick server files to send.
+	attachments, fromProperty := node.Properties["attachments"], true
+	if attachments == nil || attachments == "" {
+		attachments, fromProperty = input["attachments"], false
+	}
+	if msg.Attachments, err = parseEmailAttachments(attachments, fromProperty); err != nil {
+		return nil, fmt.Errorf("email node: %w", err)
+	}
+	if attachInput, _ := node.Properties["attachInput"].(bool); attachInput {
+		a, err := parseEmailAttachment(input, false)
+		if err != nil {
+			return nil, fmt.Errorf("email node: attach input: %w", err)
+		}
+		msg.Attachments = append(msg.Attachments, a)
+	}
 
-	headers := fmt.Sprintf("From: %s\r\n", fromAddr)
-	headers += fmt.Sprintf("To: %s\r\n", strings.Join(toAddrs, ", "))
-	if len(ccAddrs) > 0 {
-		headers += fmt.Sprintf("Cc: %s\r\n", strings.Join(ccAddrs, ", "))
+	msg.MessageID = newMessageID(msg.From)
+	raw, err := msg.build()
+	if err != nil {
+		return nil, fmt.Errorf("email node: failed to build message: %w", err)
 	}
-	headers += fmt.Sprintf("Subject: %s\r\n", subject)
-	headers += fmt.Sprintf("Date: %s\r\n", time.Now().Format(time.RFC1123Z))
-	headers += fmt.Sprintf("MIME-Version: 1.0\r\n")
-	headers += fmt.Sprintf("Content-Type: %s; charset=\"utf-8\"\r\n", contentType)
-	headers += "\r\n"
 
-	msg := headers + body
+	var allRecipients []string
+	for _, list := range [][]*mail.Address{msg.To, msg.Cc, bccAddrs} {
+		for _, a := range list {
+			allRecipients = append(allRecipients, a.Address)
+		}
+	}
 
 	// Send via SMTP
 	addr := net.JoinHostPort(smtpHost, smtpPort)
@@ -115,10 +157,10 @@ func (n *EmailNode) Execute(ctx context.Context, node models.NodeDef, input map[
 	var sendErr error
 	if useTLS && smtpPort == "465" {
 		// SSL/TLS on port 465 — direct TLS connection
-		sendErr = sendMailTLS(addr, smtpHost, auth, fromAddr, allRecipients, []byte(msg))
+		sendErr = sendMailTLS(addr, smtpHost, auth, msg.From.Address, allRecipients, raw)
 	} else {
 		// STARTTLS on port 587 or plain
-		sendErr = smtp.SendMail(addr, auth, fromAddr, allRecipients, []byte(msg))
+		sendErr = smtp.SendMail(addr, auth, msg.From.Address, allRecipients, raw)
 	}
 
 	if sendErr != nil {
@@ -126,15 +168,18 @@ func (n *EmailNode) Execute(ctx context.Context, node models.NodeDef, input map[
 	}
 
 	return map[string]interface{}{
-		"sent":       true,
-		"to":         to,
-		"cc":         cc,
-		"bcc":        bcc,
-		"subject":    subject,
-		"from":       fromAddr,
-		"smtpHost":   smtpHost,
-		"sentAt":     time.Now().Format(time.RFC3339),
-		"recipients": len(allRecipients),
+		"sent":        true,
+		"to":          to,
+		"cc":          cc,
+		"bcc":         bcc,
+		"replyTo":     replyTo,
+		"subject":     subject,
+		"from":        fromAddr,
+		"messageId":   msg.MessageID,
+		"attachments": len(msg.Attachments),
+		"smtpHost":    smtpHost,
+		"sentAt":      time.Now().Format(time.RFC3339),
+		"recipients":  len(allRecipients),
 	}, nil
 }
 
@@ -190,18 +235,3 @@ func getStringProp(props map[string]interface{}, input map[string]interface{}, k
 	}
 	return ""
 }
-
-func parseAddresses(s string) []string {
-	if s == "" {
-		return nil
-	}
-	parts := strings.Split(s, ",")
-	var addrs []string
-	for _, p := range parts {
-		p = strings.TrimSpace(p)
-		if p != "" {
-			addrs = append(addrs, p)
-		}
-	}
-	return addrs
-}
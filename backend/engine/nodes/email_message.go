@@ -0,0 +1,396 @@
+package nodes
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// emailAttachment is a file attached to an outgoing email. Inline attachments (images referenced
+// from the HTML body as cid:ContentID) are sent in a multipart/related part with the HTML.
+type emailAttachment struct {
+	Filename    string
+	ContentType string
+	ContentID   string
+	Inline      bool
+	Data        []byte
+}
+
+// outgoingEmail is a message built by the email node.
+type outgoingEmail struct {
+	From        *mail.Address
+	To, Cc      []*mail.Address
+	ReplyTo     []*mail.Address
+	Subject     string
+	Text, HTML  string
+	Headers     map[string]string
+	Attachments []emailAttachment
+	MessageID   string
+}
+
+// reservedEmailHeaders are set by the message builder and may not be overridden by custom headers.
+var reservedEmailHeaders = map[string]bool{
+	"From": true, "To": true, "Cc": true, "Bcc": true, "Reply-To": true, "Subject": true, "Date": true,
+	"Message-Id": true, "Mime-Version": true, "Content-Type": true, "Content-Transfer-Encoding": true,
+}
+
+// build renders the message: a single text part, multipart/alternative for text + HTML,
+// multipart/related around the HTML for inline images and multipart/mixed for attachments.
+func (m *outgoingEmail) build() ([]byte, error) {
+	var buf bytes.Buffer
+	h := textproto.MIMEHeader{}
+	h.Set("From", m.From.String())
+	h.Set("To", joinAddresses(m.To))
+	if len(m.Cc) > 0 {
+		h.Set("Cc", joinAddresses(m.Cc))
+	}
+	if len(m.ReplyTo) > 0 {
+		h.Set("Reply-To", joinAddresses(m.ReplyTo))
+	}
+	h.Set("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	h.Set("Date", time.Now().Format(time.RFC1123Z))
+	h.Set("Message-ID", m.MessageID)
+	h.Set("MIME-Version", "1.0")
+	for k, v := range m.Headers {
+		h.Set(k, mime.QEncoding.Encode("utf-8", v))
+	}
+
+	var inline, attached []emailAttachment
+	for _, a := range m.Attachments {
+		if a.Inline && m.HTML != "" {
+			inline = append(inline, a)
+		} else {
+			attached = append(attached, a)
+		}
+	}
+
+	// Each layer is written as a part of the enclosing multipart, or as the message body
+	// (headers merged into the top-level header) when it is outermost.
+	body := func(w *multipart.Writer, ph textproto.MIMEHeader, write func(*bytes.Buffer) error) error {
+		if w == nil {
+			for k, v := range ph {
+				h[k] = v
+			}
+			writeHeader(&buf, h)
+			return write(&buf)
+		}
+		var part bytes.Buffer
+		if err := write(&part); err != nil {
+			return err
+		}
+		pw, err := w.CreatePart(ph)
+		if err != nil {
+			return err
+		}
+		_, err = pw.Write(part.Bytes())
+		return err
+	}
+	multipartBody := func(parent *multipart.Writer, subtype string, parts func(*multipart.Writer) error) error {
+		var inner bytes.Buffer
+		mw := multipart.NewWriter(&inner)
+		if err := parts(mw); err != nil {
+			return err
+		}
+		if err := mw.Close(); err != nil {
+			return err
+		}
+		ph := textproto.MIMEHeader{"Content-Type": {"multipart/" + subtype + "; boundary=" + mw.Boundary()}}
+		return body(parent, ph, func(b *bytes.Buffer) error {
+			_, err := b.Write(inner.Bytes())
+			return err
+		})
+	}
+	textPart := func(parent *multipart.Writer, contentType, s string) error {
+		ph := textproto.MIMEHeader{
+			"Content-Type":              {contentType + "; charset=utf-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		}
+		return body(parent, ph, func(b *bytes.Buffer) error {
+			qp := quotedprintable.NewWriter(b)
+			if _, err := qp.Write([]byte(s)); err != nil {
+				return err
+			}
+			return qp.Close()
+		})
+	}
+	attachmentPart := func(parent *multipart.Writer, a emailAttachment) error {
+		disposition := "attachment"
+		if a.Inline {
+			disposition = "inline"
+		}
+		ph := textproto.MIMEHeader{
+			"Content-Type":              {mime.FormatMediaType(a.ContentType, map[string]string{"name": a.Filename})},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType(disposition, map[string]string{"filename": a.Filename})},
+		}
+		if a.ContentID != "" {
+			ph.Set("Content-ID", "<"+a.ContentID+">")
+		}
+		return body(parent, ph, func(b *bytes.Buffer) error {
+			writeBase64Lines(b, a.Data)
+			return nil
+		})
+	}
+
+	alternative := func(parent *multipart.Writer) error {
+		switch {
+		case m.HTML != "" && m.Text != "":
+			return multipartBody(parent, "alternative", func(w *multipart.Writer) error {
+				if err := textPart(w, "text/plain", m.Text); err != nil {
+					return err
+				}
+				return textPart(w, "text/html", m.HTML)
+			})
+		case m.HTML != "":
+			return textPart(parent, "text/html", m.HTML)
+		default:
+			return textPart(parent, "text/plain", m.Text)
+		}
+	}
+	related := func(parent *multipart.Writer) error {
+		if len(inline) == 0 {
+			return alternative(parent)
+		}
+		return multipartBody(parent, "related", func(w *multipart.Writer) error {
+			if err := alternative(w); err != nil {
+				return err
+			}
+			for _, a := range inline {
+				if err := attachmentPart(w, a); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+
+	var err error
+	if len(attached) == 0 {
+		err = related(nil)
+	} else {
+		err = multipartBody(nil, "mixed", func(w *multipart.Writer) error {
+			if err := related(w); err != nil {
+				return err
+			}
+			for _, a := range attached {
+				if err := attachmentPart(w, a); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeHeader(buf *bytes.Buffer, h textproto.MIMEHeader) {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range h[k] {
+			fmt.Fprintf(buf, "%s: %s\r\n", k, v)
+		}
+	}
+	buf.WriteString("\r\n")
+}
+
+func writeBase64Lines(buf *bytes.Buffer, data []byte) {
+	enc := base64.StdEncoding.EncodeToString(data)
+	for len(enc) > 76 {
+		buf.WriteString(enc[:76])
+		buf.WriteString("\r\n")
+		enc = enc[76:]
+	}
+	buf.WriteString(enc)
+	buf.WriteString("\r\n")
+}
+
+func joinAddresses(list []*mail.Address) string {
+	s := make([]string, len(list))
+	for i, a := range list {
+		s[i] = a.String()
+	}
+	return strings.Join(s, ", ")
+}
+
+// parseAddressList parses comma-separated addresses ("a@x.com, Jane <j@y.com>").
+func parseAddressList(s string) ([]*mail.Address, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	return mail.ParseAddressList(s)
+}
+
+// newMessageID returns a unique Message-ID in the sender's domain.
+func newMessageID(from *mail.Address) string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	domain := "eflo.local"
+	if i := strings.LastIndexByte(from.Address, '@'); i >= 0 && i < len(from.Address)-1 {
+		domain = from.Address[i+1:]
+	}
+	return fmt.Sprintf("<%d.%s@%s>", time.Now().UnixNano(), hex.EncodeToString(b), domain)
+}
+
+// parseEmailHeaders reads custom headers from an object or JSON string property.
+func parseEmailHeaders(v interface{}, input map[string]interface{}) (map[string]string, error) {
+	var raw map[string]interface{}
+	switch t := v.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		raw = t
+	case string:
+		if strings.TrimSpace(t) == "" {
+			return nil, nil
+		}
+		if err := json.Unmarshal([]byte(t), &raw); err != nil {
+			return nil, fmt.Errorf("headers must be a JSON object: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("headers must be an object")
+	}
+	headers := make(map[string]string, len(raw))
+	for k, v := range raw {
+		name := textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(k))
+		if name == "" || strings.ContainsAny(name, " :\r\n") {
+			return nil, fmt.Errorf("invalid header name %q", k)
+		}
+		if reservedEmailHeaders[name] {
+			return nil, fmt.Errorf("header %s is set by the node and cannot be overridden", name)
+		}
+		value, err := ResolvePlaceholders(placeholderString(v), input)
+		if err != nil {
+			return nil, fmt.Errorf("header %s: %w", name, err)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, fmt.Errorf("header %s contains a line break", name)
+		}
+		headers[name] = value
+	}
+	return headers, nil
+}
+
+// parseEmailAttachments converts attachment specs into files. A spec is an object with
+//
+//	content + encoding   base64 (the default, as in email_receive output) or any other encoding
+//	                     for raw text, as in read_file output
+//	path                 a file read from disk when there is no content; only when allowPath is
+//	                     set, i.e. for specs from the node's own properties, so workflow input
+//	                     cannot make the node mail out arbitrary server files
+//	filename, contentType, inline, contentId   optional; derived from path/filename when missing
+//
+// v may be a single spec, a list of specs or a JSON string of either.
+func parseEmailAttachments(v interface{}, allowPath bool) ([]emailAttachment, error) {
+	if s, ok := v.(string); ok {
+		if strings.TrimSpace(s) == "" {
+			return nil, nil
+		}
+		if err := json.Unmarshal([]byte(s), &v); err != nil {
+			return nil, fmt.Errorf("attachments must be JSON: %w", err)
+		}
+	}
+	var specs []interface{}
+	switch t := v.(type) {
+	case nil:
+		return nil, nil
+	case []interface{}:
+		specs = t
+	case []map[string]interface{}:
+		for _, m := range t {
+			specs = append(specs, m)
+		}
+	case map[string]interface{}:
+		specs = []interface{}{t}
+	default:
+		return nil, fmt.Errorf("attachments must be an object or a list")
+	}
+
+	var out []emailAttachment
+	for i, s := range specs {
+		spec, ok := s.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("attachment %d: must be an object", i+1)
+		}
+		a, err := parseEmailAttachment(spec, allowPath)
+		if err != nil {
+			return nil, fmt.Errorf("attachment %d: %w", i+1, err)
+		}
+		out = append(out, a)
+	}
+	return out, nil
+}
+
+func parseEmailAttachment(spec map[string]interface{}, allowPath bool) (emailAttachment, error) {
+	str := func(k string) string { s, _ := spec[k].(string); return s }
+	a := emailAttachment{
+		Filename:    str("filename"),
+		ContentType: str("contentType"),
+		ContentID:   strings.Trim(str("contentId"), "<>"),
+	}
+	a.Inline, _ = spec["inline"].(bool)
+	path := str("path")
+
+	if content, ok := spec["content"].(string); ok {
+		enc := strings.ToLower(str("encoding"))
+		if enc == "" || enc == "base64" {
+			data, err := base64.StdEncoding.DecodeString(content)
+			if err != nil {
+				return a, fmt.Errorf("invalid base64 content: %w", err)
+			}
+			a.Data = data
+		} else {
+			a.Data = []byte(content)
+		}
+	} else if path != "" {
+		if !allowPath {
+			return a, fmt.Errorf("path attachments are only allowed in the node's attachments property; pass content instead")
+		}
+		data, err := os.ReadFile(filepath.Clean(path))
+		if err != nil {
+			return a, fmt.Errorf("read %s: %w", path, err)
+		}
+		a.Data = data
+	} else {
+		return a, fmt.Errorf("content or path is required")
+	}
+
+	if a.Filename == "" {
+		a.Filename = filepath.Base(path)
+		if path == "" {
+			a.Filename = "attachment"
+		}
+	}
+	if a.ContentType == "" {
+		a.ContentType = mime.TypeByExtension(filepath.Ext(a.Filename))
+		if a.ContentType == "" {
+			a.ContentType = "application/octet-stream"
+		}
+	}
+	if i := strings.IndexByte(a.ContentType, ';'); i >= 0 {
+		a.ContentType = strings.TrimSpace(a.ContentType[:i])
+	}
+	if a.Inline && a.ContentID == "" {
+		a.ContentID = a.Filename
+	}
+	return a, nil
+}
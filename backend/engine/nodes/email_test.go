@@ -0,0 +1,218 @@
+package nodes
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"eflo/backend/models"
+	"eflo/backend/smtputil"
+)
+
+type receivedMail struct {
+	env  smtputil.Envelope
+	data []byte
+}
+
+// smtpStandIn accepts every recipient and hands received messages to a channel.
+type smtpStandIn chan receivedMail
+
+func (s smtpStandIn) AcceptRecipient(addr string) (bool, error) { return true, nil }
+
+func (s smtpStandIn) Deliver(ctx context.Context, env smtputil.Envelope, data []byte) error {
+	s <- receivedMail{env, append([]byte(nil), data...)}
+	return nil
+}
+
+// startSMTPStandIn runs an smtputil server on a local port and returns an email config for it.
+func startSMTPStandIn(t *testing.T) (smtpStandIn, *models.NodeConfig) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	received := make(smtpStandIn, 1)
+	srv := &smtputil.Server{Hostname: "mx.test", Backend: received}
+	go srv.Serve(ln)
+	t.Cleanup(func() { srv.Close() })
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	return received, &models.NodeConfig{ID: 1, Type: "email", Config: map[string]interface{}{
+		"host": host, "port": port, "tls": false, "from": "Sender <sender@example.com>",
+	}}
+}
+
+func resolveTo(cfg *models.NodeConfig) func(int64) (*models.NodeConfig, error) {
+	return func(int64) (*models.NodeConfig, error) { return cfg, nil }
+}
+
+// nextPart reads the next raw part (transfer encoding untouched) of r.
+func nextPart(t *testing.T, r *multipart.Reader, wantType string) (*multipart.Part, map[string]string, []byte) {
+	t.Helper()
+	p, err := r.NextRawPart()
+	if err != nil {
+		t.Fatalf("reading %s part: %v", wantType, err)
+	}
+	mediaType, params, err := mime.ParseMediaType(p.Header.Get("Content-Type"))
+	if err != nil || mediaType != wantType {
+		t.Fatalf("part Content-Type = %q, want %s", p.Header.Get("Content-Type"), wantType)
+	}
+	data, err := io.ReadAll(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p, params, data
+}
+
+func decodeBase64Part(t *testing.T, p *multipart.Part, data []byte) []byte {
+	t.Helper()
+	if cte := p.Header.Get("Content-Transfer-Encoding"); cte != "base64" {
+		t.Fatalf("Content-Transfer-Encoding = %q, want base64", cte)
+	}
+	for _, line := range strings.Fields(string(data)) {
+		if len(line) > 76 {
+			t.Fatalf("base64 line of %d characters exceeds 76", len(line))
+		}
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.NewReplacer("\r", "", "\n", "").Replace(string(data)))
+	if err != nil {
+		t.Fatalf("invalid base64 body: %v", err)
+	}
+	return decoded
+}
+
+func TestEmailNodeSendsMultipartMessage(t *testing.T) {
+	received, cfg := startSMTPStandIn(t)
+
+	report := filepath.Join(t.TempDir(), "report.txt")
+	reportData := bytes.Repeat([]byte("quarterly numbers\n"), 20)
+	if err := os.WriteFile(report, reportData, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	logo := []byte("\x89PNG\r\n\x1a\nnot really an image")
+
+	node := models.NodeDef{Type: "email", Properties: map[string]interface{}{
+		"configId": float64(1),
+		"to":       "Ada <ada@example.com>",
+		"bcc":      "audit@example.com",
+		"subject":  "Grüße, {{name}}",
+		"body":     "Hi {{name}}",
+		"html":     `<p>Hi {{name}}</p><img src="cid:logo">`,
+		"attachments": `[{"filename": "logo.png", "content": "` + base64.StdEncoding.EncodeToString(logo) + `", "inline": true, "contentId": "logo"},
+			{"path": "` + report + `"}]`,
+	}}
+	out, err := (&EmailNode{}).Execute(context.Background(), node, map[string]interface{}{"name": "Ada"}, resolveTo(cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out["attachments"] != 2 || out["recipients"] != 2 {
+		t.Errorf("output attachments = %v, recipients = %v; want 2, 2", out["attachments"], out["recipients"])
+	}
+
+	var got receivedMail
+	select {
+	case got = <-received:
+	case <-time.After(10 * time.Second):
+		t.Fatal("no message delivered")
+	}
+	if strings.Join(got.env.Recipients, ",") != "ada@example.com,audit@example.com" {
+		t.Errorf("envelope recipients = %v", got.env.Recipients)
+	}
+	msg, err := mail.ReadMessage(bytes.NewReader(got.data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Header.Get("Bcc") != "" {
+		t.Error("Bcc header leaked into the message")
+	}
+
+	// The non-ASCII subject is an RFC 2047 encoded word
+	rawSubject := msg.Header.Get("Subject")
+	if !strings.HasPrefix(rawSubject, "=?utf-8?q?") {
+		t.Errorf("Subject = %q, want an RFC 2047 Q-encoded word", rawSubject)
+	}
+	if subject, err := new(mime.WordDecoder).DecodeHeader(rawSubject); err != nil || subject != "Grüße, Ada" {
+		t.Errorf("decoded Subject = %q (%v), want %q", subject, err, "Grüße, Ada")
+	}
+
+	// mixed [ related [ alternative [ text, html ], inline logo ], report ]
+	mediaType, params, _ := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if mediaType != "multipart/mixed" {
+		t.Fatalf("Content-Type = %q, want multipart/mixed", msg.Header.Get("Content-Type"))
+	}
+	mixed := multipart.NewReader(msg.Body, params["boundary"])
+
+	_, params, relatedBody := nextPart(t, mixed, "multipart/related")
+	related := multipart.NewReader(bytes.NewReader(relatedBody), params["boundary"])
+	_, params, altBody := nextPart(t, related, "multipart/alternative")
+	alternative := multipart.NewReader(bytes.NewReader(altBody), params["boundary"])
+	for _, want := range []struct{ mediaType, body string }{
+		{"text/plain", "Hi Ada"},
+		{"text/html", `<p>Hi Ada</p><img src="cid:logo">`},
+	} {
+		p, err := alternative.NextPart() // decodes quoted-printable
+		if err != nil {
+			t.Fatal(err)
+		}
+		mt, ps, _ := mime.ParseMediaType(p.Header.Get("Content-Type"))
+		body, _ := io.ReadAll(p)
+		if mt != want.mediaType || ps["charset"] != "utf-8" || string(body) != want.body {
+			t.Errorf("alternative part = %s (charset %q) %q, want %s %q", mt, ps["charset"], body, want.mediaType, want.body)
+		}
+	}
+
+	p, _, data := nextPart(t, related, "image/png")
+	if p.Header.Get("Content-ID") != "<logo>" || !strings.HasPrefix(p.Header.Get("Content-Disposition"), "inline") {
+		t.Errorf("inline part Content-ID = %q, Content-Disposition = %q", p.Header.Get("Content-ID"), p.Header.Get("Content-Disposition"))
+	}
+	if !bytes.Equal(decodeBase64Part(t, p, data), logo) {
+		t.Error("inline image content differs")
+	}
+
+	p, _, data = nextPart(t, mixed, "text/plain")
+	if _, dp, _ := mime.ParseMediaType(p.Header.Get("Content-Disposition")); dp["filename"] != "report.txt" {
+		t.Errorf("attachment Content-Disposition = %q, want filename report.txt", p.Header.Get("Content-Disposition"))
+	}
+	if !bytes.Equal(decodeBase64Part(t, p, data), reportData) {
+		t.Error("attachment content differs")
+	}
+	if _, err := mixed.NextRawPart(); err != io.EOF {
+		t.Errorf("unexpected extra part (%v)", err)
+	}
+}
+
+func TestEmailNodeRejectsPathsFromInput(t *testing.T) {
+	received, cfg := startSMTPStandIn(t)
+	secret := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(secret, []byte("secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	node := models.NodeDef{Type: "email", Properties: map[string]interface{}{
+		"configId": float64(1), "to": "ada@example.com", "body": "see attached",
+	}}
+	input := map[string]interface{}{"attachments": []interface{}{map[string]interface{}{"path": secret}}}
+	if _, err := (&EmailNode{}).Execute(context.Background(), node, input, resolveTo(cfg)); err == nil {
+		t.Error("input.attachments: path attachment accepted")
+	}
+
+	node.Properties["attachInput"] = true
+	if _, err := (&EmailNode{}).Execute(context.Background(), node, map[string]interface{}{"path": secret}, resolveTo(cfg)); err == nil {
+		t.Error("attachInput: path attachment accepted")
+	}
+
+	select {
+	case <-received:
+		t.Error("message sent despite the rejected attachment")
+	default:
+	}
+}
@@ -15,8 +15,28 @@ import (
 
 // FunctionNode runs JavaScript code in a V8 isolate and returns the result to the flow.
 // Input is exposed as `input` in JS; the script should set `returnValue` to pass data downstream.
+// The code runs as the body of an async function, so top-level `await` works and the result is
+// read once the returned promise settles. `console.*` calls are captured into the output
+// (`_console`) and the server log, and `fetch(url, {method, headers, body})` performs HTTP
+// requests in Go, bounded by the node timeout (properties.fetchAllowedHosts / fetchMaxBytes).
+// Loopback, private and link-local addresses are refused unless FUNCTION_FETCH_ALLOW_PRIVATE
+// lists them (see ConfigureFunctionFetch).
+// `require('name')` / `require('name@3')` loads a script library (see ScriptLibraryRepo).
+// There is no per-script heap limit: v8go exposes neither V8's resource constraints nor a
+// near-heap-limit callback, so a script that allocates without bound runs until V8's own heap
+// limit aborts the process. Only the timeout (properties.timeoutMs) bounds a run.
 type FunctionNode struct{}
 
+// functionResultScript wraps user code so local `var/let/const returnValue` declarations are
+// visible when the result is read. An explicit `return x` is treated as returnValue = x.
+const functionResultScript = `(async function () {%s
+;return { __efloResult: true, has: typeof returnValue !== 'undefined', value: typeof returnValue !== 'undefined' ? returnValue : undefined };
+})().then(function (r) {
+	if (r && r.__efloResult) return { has: r.has, value: r.value };
+	if (r !== undefined) return { has: true, value: r };
+	return { has: typeof returnValue !== 'undefined', value: typeof returnValue !== 'undefined' ? returnValue : undefined };
+})`
+
 func (n *FunctionNode) Execute(ctx context.Context, node models.NodeDef, input map[string]interface{}, _ engine.ConfigResolver) (map[string]interface{}, error) {
 	code, _ := node.Properties["code"].(string)
 	if code == "" {
@@ -44,10 +64,23 @@ func (n *FunctionNode) Execute(ctx context.Context, node models.NodeDef, input m
 		configJSON = escapeForJS(string(cfgBytes))
 	}
 
-	iso := v8.NewIsolate()
-	defer iso.Dispose()
-	v8ctx := v8.NewContext(iso)
-	defer v8ctx.Close()
+	// The run context bounds both the script and any fetch calls it makes.
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// Isolates are pooled (see function_pool.go); a terminated isolate is not reused.
+	ji := functionIsolates.get()
+	iso := ji.iso
+	rt := newJSRuntime(runCtx, node.ID, ji, node.Properties)
+	v8ctx := rt.v8ctx
+	terminated := false
+	defer func() {
+		rt.close()
+		functionIsolates.put(ji, !terminated)
+	}()
+	if err := rt.install(); err != nil {
+		return nil, fmt.Errorf("function node: failed to install runtime: %w", err)
+	}
 
 	// Inject input and config as globals: input, config (use config['token'] or config.token in code)
 	bootstrap := fmt.Sprintf("var input = JSON.parse('%s'); var config = JSON.parse('%s');", escaped, configJSON)
@@ -55,66 +88,78 @@ func (n *FunctionNode) Execute(ctx context.Context, node models.NodeDef, input m
 		return nil, fmt.Errorf("function node: failed to inject input/config: %w", err)
 	}
 
-	// Run user script with timeout
+	// Run user script (and its pending promises) with timeout
 	done := make(chan struct{})
+	var result *v8.Value
 	var runErr error
 	go func() {
 		defer close(done)
-		_, runErr = v8ctx.RunScript(code, "function.js")
+		result, runErr = rt.run(fmt.Sprintf(functionResultScript, code), "function.js")
 	}()
 
 	select {
 	case <-done:
-		if runErr != nil {
-			if jsErr, ok := runErr.(*v8.JSError); ok {
-				return nil, fmt.Errorf("function node: %s (at %s)", jsErr.Message, jsErr.Location)
-			}
-			return nil, fmt.Errorf("function node: %w", runErr)
-		}
-	case <-time.After(timeout):
+	case <-runCtx.Done():
 		iso.TerminateExecution()
 		<-done
-		return nil, fmt.Errorf("function node: script timed out after %v", timeout)
-	case <-ctx.Done():
-		iso.TerminateExecution()
-		<-done
-		return nil, ctx.Err()
+		terminated = true
+		runErr = runCtx.Err()
 	}
 
-	// Check if returnValue is defined. If not, stop the flow at this node.
-	hasReturnVal, err := v8ctx.RunScript("typeof returnValue !== 'undefined'", "hasReturn.js")
-	if err != nil {
-		return nil, fmt.Errorf("function node: failed to inspect returnValue: %w", err)
+	// withConsole attaches captured console output so it lands in the execution log,
+	// including when the script fails.
+	withConsole := func(out map[string]interface{}) map[string]interface{} {
+		if entries := rt.consoleOutput(); entries != nil {
+			if out == nil {
+				out = map[string]interface{}{}
+			}
+			out["_console"] = entries
+		}
+		return out
 	}
-	if !hasReturnVal.Boolean() {
-		// No explicit returnValue: mark this node as terminal so the engine does not continue.
-		return map[string]interface{}{
-			"_stop": true,
-		}, nil
+
+	if runErr != nil {
+		if ctx.Err() != nil {
+			return withConsole(nil), ctx.Err()
+		}
+		if runCtx.Err() != nil {
+			functionStats.timeouts.Add(1)
+			return withConsole(nil), fmt.Errorf("function node: script timed out after %v", timeout)
+		}
+		if jsErr, ok := runErr.(*v8.JSError); ok {
+			return withConsole(nil), fmt.Errorf("function node: %s (at %s)", jsErr.Message, jsErr.Location)
+		}
+		return withConsole(nil), fmt.Errorf("function node: %w", runErr)
 	}
 
-	// Get returnValue only (no fallback to input)
-	resultScript := "JSON.stringify(returnValue)"
-	val, err := v8ctx.RunScript(resultScript, "result.js")
+	resultStr, err := v8.JSONStringify(v8ctx, result)
 	if err != nil {
-		return nil, fmt.Errorf("function node: failed to get result: %w", err)
+		return withConsole(nil), fmt.Errorf("function node: failed to get result: %w", err)
+	}
+	var settled struct {
+		Has   bool        `json:"has"`
+		Value interface{} `json:"value"`
+	}
+	if err := json.Unmarshal([]byte(resultStr), &settled); err != nil {
+		return withConsole(nil), fmt.Errorf("function node: result is not valid JSON: %w", err)
 	}
 
-	resultStr := val.String()
-	var resultData interface{}
-	if err := json.Unmarshal([]byte(resultStr), &resultData); err != nil {
-		return nil, fmt.Errorf("function node: result is not valid JSON: %w", err)
+	if !settled.Has {
+		// No explicit returnValue: mark this node as terminal so the engine does not continue.
+		return withConsole(map[string]interface{}{
+			"_stop": true,
+		}), nil
 	}
 
 	// When a value is returned:
 	// - If it's an object, it becomes the full output map (and thus the next node's input).
 	// - Otherwise, it is wrapped as { value: <primitive/array> }.
-	if m, ok := resultData.(map[string]interface{}); ok {
-		return m, nil
+	if m, ok := settled.Value.(map[string]interface{}); ok {
+		return withConsole(m), nil
 	}
-	return map[string]interface{}{
-		"value": resultData,
-	}, nil
+	return withConsole(map[string]interface{}{
+		"value": settled.Value,
+	}), nil
 }
 
 func escapeForJS(s string) string {
@@ -0,0 +1,137 @@
+package nodes
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// fetchPrivateAllow lists the internal destinations function nodes may fetch from (see
+// ConfigureFunctionFetch). Everything else that resolves to an internal address is refused.
+var fetchPrivateAllow struct {
+	sync.RWMutex
+	hosts map[string]bool
+	nets  []netip.Prefix
+}
+
+// ConfigureFunctionFetch sets the hosts and CIDRs function-node fetches may reach although they
+// are loopback, private, link-local or otherwise internal. By default none are.
+func ConfigureFunctionFetch(allow []string) error {
+	hosts := map[string]bool{}
+	var nets []netip.Prefix
+	for _, entry := range allow {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case entry == "":
+		case strings.Contains(entry, "/"):
+			p, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return fmt.Errorf("invalid CIDR %q in fetch allowlist: %w", entry, err)
+			}
+			nets = append(nets, p.Masked())
+		default:
+			if addr, err := netip.ParseAddr(entry); err == nil {
+				addr = addr.Unmap()
+				nets = append(nets, netip.PrefixFrom(addr, addr.BitLen()))
+			} else {
+				hosts[entry] = true
+			}
+		}
+	}
+	fetchPrivateAllow.Lock()
+	fetchPrivateAllow.hosts, fetchPrivateAllow.nets = hosts, nets
+	fetchPrivateAllow.Unlock()
+	return nil
+}
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), internal like RFC 1918.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// internalAddr reports whether addr is not a public unicast address: loopback, private,
+// link-local (including cloud metadata endpoints such as 169.254.169.254), unspecified,
+// multicast or carrier-grade NAT.
+func internalAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return !addr.IsValid() || addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() || sharedAddressSpace.Contains(addr) ||
+		(addr.Is4() && addr.As4()[0] == 0) // 0.0.0.0/8 reaches this host
+}
+
+// fetchAddrAllowed reports whether a fetch may connect to addr.
+func fetchAddrAllowed(addr netip.Addr) bool {
+	if !internalAddr(addr) {
+		return true
+	}
+	addr = addr.Unmap()
+	fetchPrivateAllow.RLock()
+	defer fetchPrivateAllow.RUnlock()
+	for _, p := range fetchPrivateAllow.nets {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchHostAllowedInternal reports whether host is allowlisted to resolve to internal addresses.
+func fetchHostAllowedInternal(host string) bool {
+	fetchPrivateAllow.RLock()
+	defer fetchPrivateAllow.RUnlock()
+	return fetchPrivateAllow.hosts[strings.ToLower(host)]
+}
+
+// fetchDialer refuses, after DNS resolution, connections to addresses fetchAddrAllowed rejects.
+// Checking the resolved address at connect time also covers redirects and DNS rebinding.
+var fetchDialer = &net.Dialer{
+	Timeout: 30 * time.Second,
+	Control: func(network, address string, _ syscall.RawConn) error {
+		ap, err := netip.ParseAddrPort(address)
+		if err != nil {
+			return fmt.Errorf("fetch: unexpected address %q", address)
+		}
+		if !fetchAddrAllowed(ap.Addr()) {
+			return fmt.Errorf("fetch: %s is an internal address", ap.Addr())
+		}
+		return nil
+	},
+}
+
+var fetchUnguardedDialer = &net.Dialer{Timeout: 30 * time.Second}
+
+// fetchTransport is shared by all function-node fetches. It ignores proxy settings, which would
+// hide the destination from the dialer.
+var fetchTransport = &http.Transport{
+	Proxy: nil,
+	DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if host, _, err := net.SplitHostPort(addr); err == nil && fetchHostAllowedInternal(host) {
+			return fetchUnguardedDialer.DialContext(ctx, network, addr)
+		}
+		return fetchDialer.DialContext(ctx, network, addr)
+	},
+	ForceAttemptHTTP2:     true,
+	MaxIdleConns:          100,
+	IdleConnTimeout:       90 * time.Second,
+	TLSHandshakeTimeout:   10 * time.Second,
+	ExpectContinueTimeout: time.Second,
+}
+
+// newFetchClient returns the client of one invocation; check vets every URL, redirects included.
+func newFetchClient(check func(*url.URL) error) *http.Client {
+	return &http.Client{
+		Transport: fetchTransport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return fmt.Errorf("stopped after 10 redirects")
+			}
+			return check(req.URL)
+		},
+	}
+}
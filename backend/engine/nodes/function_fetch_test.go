@@ -0,0 +1,152 @@
+package nodes
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestInternalAddr(t *testing.T) {
+	tests := []struct {
+		addr     string
+		internal bool
+	}{
+		{"127.0.0.1", true},
+		{"127.8.9.10", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"172.31.255.255", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"100.64.0.1", true},
+		{"0.0.0.0", true},
+		{"0.1.2.3", true},
+		{"224.0.0.1", true},
+		{"::1", true},
+		{"::", true},
+		{"fe80::1", true},
+		{"fc00::1", true},
+		{"fd12:3456::1", true},
+		{"ff02::1", true},
+		{"::ffff:127.0.0.1", true},
+		{"::ffff:169.254.169.254", true},
+		{"8.8.8.8", false},
+		{"172.32.0.1", false},
+		{"100.128.0.1", false},
+		{"2001:4860:4860::8888", false},
+		{"::ffff:8.8.8.8", false},
+	}
+	for _, tt := range tests {
+		if got := internalAddr(netip.MustParseAddr(tt.addr)); got != tt.internal {
+			t.Errorf("internalAddr(%s) = %v, want %v", tt.addr, got, tt.internal)
+		}
+	}
+}
+
+// allowPrivate configures the fetch allowlist for one test.
+func allowPrivate(t *testing.T, allow ...string) {
+	t.Helper()
+	if err := ConfigureFunctionFetch(allow); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ConfigureFunctionFetch(nil) })
+}
+
+// fetchFor runs doFetch without an isolate and decodes its JSON result.
+func fetchFor(t *testing.T, rawURL string, allowedHosts ...string) map[string]interface{} {
+	t.Helper()
+	rt := &jsRuntime{ctx: context.Background(), maxBytes: defaultFetchMaxBytes, allowedHosts: map[string]bool{}}
+	for _, h := range allowedHosts {
+		rt.allowedHosts[h] = true
+	}
+	rt.client = newFetchClient(rt.checkURL)
+	var out map[string]interface{}
+	if err := json.Unmarshal([]byte(rt.doFetch(rawURL, fetchRequest{})), &out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestFetchDeniesInternalAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("internal"))
+	}))
+	defer srv.Close()
+	port := srv.URL[strings.LastIndex(srv.URL, ":"):]
+
+	for _, rawURL := range []string{
+		srv.URL,
+		"http://localhost" + port, // resolved, then refused by the dialer
+		"http://[::ffff:127.0.0.1]" + port,
+		"http://169.254.169.254/latest/meta-data/",
+		"http://0.0.0.0" + port,
+	} {
+		out := fetchFor(t, rawURL)
+		if errMsg, _ := out["error"].(string); !strings.Contains(errMsg, "internal address") {
+			t.Errorf("fetch(%s) = %v, want an internal address error", rawURL, out)
+		}
+	}
+}
+
+func TestFetchAllowsAllowlistedInternalAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("internal"))
+	}))
+	defer srv.Close()
+	port := srv.URL[strings.LastIndex(srv.URL, ":"):]
+
+	tests := []struct {
+		name  string
+		allow []string
+		url   string
+	}{
+		{"CIDR", []string{"127.0.0.0/8"}, srv.URL},
+		{"address", []string{"127.0.0.1"}, srv.URL},
+		{"host", []string{"localhost"}, "http://localhost" + port},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowPrivate(t, tt.allow...)
+			if out := fetchFor(t, tt.url); out["body"] != "internal" {
+				t.Errorf("fetch(%s) = %v, want the response body", tt.url, out)
+			}
+		})
+	}
+}
+
+func TestFetchDeniesRedirectsToInternalAddresses(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("internal"))
+	}))
+	defer target.Close()
+	redirector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, r.URL.Query().Get("to"), http.StatusFound)
+	}))
+	defer redirector.Close()
+	port := func(s *httptest.Server) string { return s.URL[strings.LastIndex(s.URL, ":"):] }
+
+	// The redirector is reachable as an allowlisted host; where it sends fetch is not.
+	allowPrivate(t, "localhost")
+	entry := "http://localhost" + port(redirector) + "/?to="
+	for _, to := range []string{
+		target.URL,
+		"http://169.254.169.254/latest/meta-data/",
+		"http://[::1]" + port(target),
+	} {
+		out := fetchFor(t, entry+url.QueryEscape(to))
+		if errMsg, _ := out["error"].(string); !strings.Contains(errMsg, "internal address") {
+			t.Errorf("redirect to %s = %v, want an internal address error", to, out)
+		}
+	}
+
+	// fetchAllowedHosts is also applied to every hop.
+	out := fetchFor(t, entry+url.QueryEscape("http://example.com/"), "localhost")
+	if errMsg, _ := out["error"].(string); !strings.Contains(errMsg, "not in fetchAllowedHosts") {
+		t.Errorf("redirect off fetchAllowedHosts = %v, want a fetchAllowedHosts error", out)
+	}
+}
@@ -0,0 +1,219 @@
+package nodes
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	v8 "rogchap.com/v8go"
+)
+
+const (
+	// maxIsolateUses recycles an isolate after this many invocations so heap growth from
+	// long-lived compiled scripts stays bounded.
+	maxIsolateUses = 1000
+	// maxCompiledScripts caps the per-isolate compiled-script cache and the shared code cache;
+	// when full the cache is cleared rather than tracking recency.
+	maxCompiledScripts = 512
+)
+
+// jsIsolate is a pooled V8 isolate. Native bindings are registered once per isolate (v8go never
+// frees callbacks before Dispose) and dispatch to the runtime of the invocation currently using it.
+// Each invocation still gets a fresh context, so globals never leak between runs.
+type jsIsolate struct {
+	iso    *v8.Isolate
+	global *v8.ObjectTemplate
+	rt     *jsRuntime
+
+	// scripts caches compiled function code and library wrappers by scriptCacheKey.
+	scripts map[string]*v8.UnboundScript
+	uses    int
+}
+
+func newJSIsolate() *jsIsolate {
+	ji := &jsIsolate{iso: v8.NewIsolate(), scripts: map[string]*v8.UnboundScript{}}
+	ji.global = v8.NewObjectTemplate(ji.iso)
+	bind := func(name string, cb func(rt *jsRuntime, info *v8.FunctionCallbackInfo) *v8.Value) {
+		_ = ji.global.Set(name, v8.NewFunctionTemplate(ji.iso, func(info *v8.FunctionCallbackInfo) *v8.Value {
+			if ji.rt == nil {
+				return nil
+			}
+			return cb(ji.rt, info)
+		}), v8.ReadOnly)
+	}
+	bind("__console", (*jsRuntime).consoleCallback)
+	bind("__fetch", (*jsRuntime).fetchCallback)
+	bind("__require", (*jsRuntime).requireCallback)
+	functionStats.isolatesCreated.Add(1)
+	return ji
+}
+
+func (ji *jsIsolate) dispose() {
+	ji.iso.Dispose()
+	functionStats.isolatesDisposed.Add(1)
+}
+
+// isolatePool keeps up to size idle isolates for reuse across function node invocations.
+type isolatePool struct {
+	mu   sync.Mutex
+	idle []*jsIsolate
+	size int
+}
+
+var functionIsolates = &isolatePool{size: runtime.NumCPU()}
+
+// ConfigureFunctionRuntime sets the number of warm isolates kept for function nodes; a value
+// <= 0 keeps the default of one isolate per CPU. The pool is pre-warmed.
+func ConfigureFunctionRuntime(poolSize int) {
+	p := functionIsolates
+	p.mu.Lock()
+	if poolSize > 0 {
+		p.size = poolSize
+	}
+	var extra []*jsIsolate
+	for len(p.idle) > p.size {
+		extra = append(extra, p.idle[len(p.idle)-1])
+		p.idle = p.idle[:len(p.idle)-1]
+	}
+	warm := p.size - len(p.idle)
+	p.mu.Unlock()
+
+	for _, ji := range extra {
+		ji.dispose()
+	}
+	for i := 0; i < warm; i++ {
+		p.put(newJSIsolate(), true)
+	}
+}
+
+func (p *isolatePool) get() *jsIsolate {
+	functionStats.invocations.Add(1)
+	p.mu.Lock()
+	if n := len(p.idle); n > 0 {
+		ji := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.mu.Unlock()
+		functionStats.isolatesReused.Add(1)
+		ji.uses++
+		return ji
+	}
+	p.mu.Unlock()
+	ji := newJSIsolate()
+	ji.uses++
+	return ji
+}
+
+// put returns ji to the pool. Isolates that were terminated (timeout, cancel) or
+// have served maxIsolateUses invocations are disposed instead.
+func (p *isolatePool) put(ji *jsIsolate, reusable bool) {
+	ji.rt = nil
+	p.mu.Lock()
+	if reusable && ji.uses < maxIsolateUses && len(p.idle) < p.size {
+		p.idle = append(p.idle, ji)
+		p.mu.Unlock()
+		return
+	}
+	p.mu.Unlock()
+	ji.dispose()
+}
+
+// codeCache holds V8 code caches shared across isolates so a cold isolate can skip parsing.
+var codeCache = struct {
+	sync.Mutex
+	m map[string]*v8.CompilerCachedData
+}{m: map[string]*v8.CompilerCachedData{}}
+
+// scriptCacheKey identifies compiled code by a hash of its origin and source.
+func scriptCacheKey(origin, source string) string {
+	sum := sha256.Sum256([]byte(origin + "\x00" + source))
+	return hex.EncodeToString(sum[:16])
+}
+
+// compile returns the compiled script for source, using the isolate's own cache, then the
+// shared code cache, then a full compile.
+func (ji *jsIsolate) compile(origin, source string) (*v8.UnboundScript, error) {
+	key := scriptCacheKey(origin, source)
+	if s, ok := ji.scripts[key]; ok {
+		functionStats.scriptCacheHits.Add(1)
+		return s, nil
+	}
+
+	opts := v8.CompileOptions{}
+	codeCache.Lock()
+	if cached, ok := codeCache.m[key]; ok {
+		opts.CachedData = cached
+	}
+	codeCache.Unlock()
+
+	script, err := ji.iso.CompileUnboundScript(source, origin, opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.CachedData != nil && !opts.CachedData.Rejected {
+		functionStats.codeCacheHits.Add(1)
+	} else {
+		functionStats.compiles.Add(1)
+		data := script.CreateCodeCache()
+		codeCache.Lock()
+		if len(codeCache.m) >= maxCompiledScripts {
+			codeCache.m = map[string]*v8.CompilerCachedData{}
+		}
+		codeCache.m[key] = data
+		codeCache.Unlock()
+	}
+
+	if len(ji.scripts) >= maxCompiledScripts {
+		ji.scripts = map[string]*v8.UnboundScript{}
+	}
+	ji.scripts[key] = script
+	return script, nil
+}
+
+// functionStats counts function runtime activity for GET /api/stats/function-runtime.
+var functionStats struct {
+	invocations      atomic.Int64
+	isolatesCreated  atomic.Int64
+	isolatesReused   atomic.Int64
+	isolatesDisposed atomic.Int64
+	scriptCacheHits  atomic.Int64
+	codeCacheHits    atomic.Int64
+	compiles         atomic.Int64
+	timeouts         atomic.Int64
+}
+
+// FunctionRuntimeStats is a snapshot of the function node's isolate pool and script caches.
+type FunctionRuntimeStats struct {
+	PoolSize         int     `json:"poolSize"`
+	IdleIsolates     int     `json:"idleIsolates"`
+	Invocations      int64   `json:"invocations"`
+	IsolatesCreated  int64   `json:"isolatesCreated"`
+	IsolatesReused   int64   `json:"isolatesReused"`
+	IsolatesDisposed int64   `json:"isolatesDisposed"`
+	ReuseRatio       float64 `json:"reuseRatio"`
+	ScriptCacheHits  int64   `json:"scriptCacheHits"`
+	CodeCacheHits    int64   `json:"codeCacheHits"`
+	Compiles         int64   `json:"compiles"`
+	Timeouts         int64   `json:"timeouts"`
+}
+
+// GetFunctionRuntimeStats returns the current function runtime metrics.
+func GetFunctionRuntimeStats() FunctionRuntimeStats {
+	p := functionIsolates
+	p.mu.Lock()
+	s := FunctionRuntimeStats{PoolSize: p.size, IdleIsolates: len(p.idle)}
+	p.mu.Unlock()
+	s.Invocations = functionStats.invocations.Load()
+	s.IsolatesCreated = functionStats.isolatesCreated.Load()
+	s.IsolatesReused = functionStats.isolatesReused.Load()
+	s.IsolatesDisposed = functionStats.isolatesDisposed.Load()
+	s.ScriptCacheHits = functionStats.scriptCacheHits.Load()
+	s.CodeCacheHits = functionStats.codeCacheHits.Load()
+	s.Compiles = functionStats.compiles.Load()
+	s.Timeouts = functionStats.timeouts.Load()
+	if s.Invocations > 0 {
+		s.ReuseRatio = float64(s.IsolatesReused) / float64(s.Invocations)
+	}
+	return s
+}
@@ -0,0 +1,432 @@
+package nodes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"eflo/backend/engine"
+
+	v8 "rogchap.com/v8go"
+)
+
+const (
+	// maxConsoleEntries caps captured console output per invocation so a chatty loop
+	// cannot bloat the execution log.
+	maxConsoleEntries = 500
+	// defaultFetchMaxBytes caps a fetch response body unless properties.fetchMaxBytes is set.
+	defaultFetchMaxBytes = 5 << 20
+)
+
+// jsRuntimeBootstrap wires the Go bindings (__console, __fetch, __require) into the JS globals
+// console, fetch and require. fetch resolves to a minimal Response: status, statusText, ok, url,
+// headers.get/has, text() and json(). require('name') or require('name@version') loads a script
+// library CommonJS-style (module.exports / exports), once per invocation.
+const jsRuntimeBootstrap = `
+(function (g) {
+	var modules = {};
+	g.require = function (spec) {
+		spec = String(spec);
+		if (Object.prototype.hasOwnProperty.call(modules, spec)) return modules[spec].exports;
+		var factory = __require(spec);
+		if (typeof factory === 'string') throw new Error(factory);
+		var module = { exports: {} };
+		modules[spec] = module;
+		factory.call(module.exports, module, module.exports, g.require);
+		return module.exports;
+	};
+
+	var con = {};
+	['log', 'info', 'warn', 'error', 'debug'].forEach(function (level) {
+		con[level] = function () {
+			__console.apply(null, [level].concat(Array.prototype.slice.call(arguments)));
+		};
+	});
+	g.console = con;
+
+	g.fetch = function (resource, options) {
+		var opts = {};
+		if (options) {
+			for (var k in options) opts[k] = options[k];
+		}
+		opts.headers = Object.assign({}, opts.headers || {});
+		if (opts.body !== undefined && opts.body !== null && typeof opts.body !== 'string') {
+			opts.body = JSON.stringify(opts.body);
+			var hasType = Object.keys(opts.headers).some(function (h) { return h.toLowerCase() === 'content-type'; });
+			if (!hasType) opts.headers['Content-Type'] = 'application/json';
+		}
+		return __fetch(String(resource), JSON.stringify(opts)).then(function (raw) {
+			var res = JSON.parse(raw);
+			if (res.error) throw new TypeError('fetch failed: ' + res.error);
+			var headers = res.headers || {};
+			return {
+				status: res.status,
+				statusText: res.statusText,
+				ok: res.status >= 200 && res.status < 300,
+				url: res.url,
+				headers: {
+					get: function (name) {
+						var v = headers[String(name).toLowerCase()];
+						return v === undefined ? null : v;
+					},
+					has: function (name) { return headers[String(name).toLowerCase()] !== undefined; },
+					toJSON: function () { return headers; }
+				},
+				text: function () { return Promise.resolve(res.body); },
+				json: function () { return Promise.resolve().then(function () { return JSON.parse(res.body); }); }
+			};
+		});
+	};
+})(globalThis);
+`
+
+// jsRuntime holds the per-invocation state behind the console, fetch and require bindings and drives
+// the small event loop that settles the script's promise. All V8 calls happen on the goroutine
+// running run(); fetch I/O happens in separate goroutines that only report back over results.
+type jsRuntime struct {
+	ctx    context.Context // cancelled on node timeout or flow cancellation
+	nodeID string
+	ji     *jsIsolate
+	iso    *v8.Isolate
+	v8ctx  *v8.Context
+	libs   engine.ScriptLibraryResolver
+
+	allowedHosts map[string]bool
+	maxBytes     int64
+	client       *http.Client
+
+	console   []interface{}
+	truncated bool
+
+	pending int
+	results chan fetchResult
+}
+
+type fetchResult struct {
+	resolver *v8.PromiseResolver
+	payload  string
+}
+
+type fetchRequest struct {
+	Method  string            `json:"method"`
+	Headers map[string]string `json:"headers"`
+	Body    *string           `json:"body"`
+}
+
+// newJSRuntime binds a fresh context on the pooled isolate ji to this invocation.
+func newJSRuntime(ctx context.Context, nodeID string, ji *jsIsolate, props map[string]interface{}) *jsRuntime {
+	rt := &jsRuntime{
+		ctx:          ctx,
+		nodeID:       nodeID,
+		ji:           ji,
+		iso:          ji.iso,
+		v8ctx:        v8.NewContext(ji.iso, ji.global),
+		libs:         engine.ScriptLibrariesFromContext(ctx),
+		allowedHosts: parseAllowedHosts(props["fetchAllowedHosts"]),
+		maxBytes:     defaultFetchMaxBytes,
+		results:      make(chan fetchResult, 16),
+	}
+	if mb, ok := props["fetchMaxBytes"].(float64); ok && mb > 0 {
+		rt.maxBytes = int64(mb)
+	}
+	rt.client = newFetchClient(rt.checkURL)
+	ji.rt = rt
+	return rt
+}
+
+// install defines the console/fetch/require globals in the context.
+func (rt *jsRuntime) install() error {
+	script, err := rt.ji.compile("runtime.js", jsRuntimeBootstrap)
+	if err != nil {
+		return err
+	}
+	_, err = script.Run(rt.v8ctx)
+	return err
+}
+
+// close releases the context; the isolate goes back to the pool separately.
+func (rt *jsRuntime) close() {
+	rt.v8ctx.Close()
+}
+
+func (rt *jsRuntime) consoleCallback(info *v8.FunctionCallbackInfo) *v8.Value {
+	args := info.Args()
+	if len(args) == 0 {
+		return nil
+	}
+	level := args[0].String()
+	parts := make([]string, 0, len(args)-1)
+	for _, a := range args[1:] {
+		parts = append(parts, formatConsoleArg(info.Context(), a))
+	}
+	msg := strings.Join(parts, " ")
+	log.Printf("[FUNCTION NODE %s] console.%s: %s", rt.nodeID, level, msg)
+
+	if len(rt.console) >= maxConsoleEntries {
+		rt.truncated = true
+		return nil
+	}
+	rt.console = append(rt.console, map[string]interface{}{
+		"level":   level,
+		"message": msg,
+		"time":    time.Now().Format(time.RFC3339Nano),
+	})
+	return nil
+}
+
+func formatConsoleArg(ctx *v8.Context, v *v8.Value) string {
+	if v.IsString() || v.IsFunction() || v.IsNativeError() || !v.IsObject() {
+		return v.String()
+	}
+	s, err := v8.JSONStringify(ctx, v)
+	if err != nil {
+		return v.String()
+	}
+	return s
+}
+
+// fetchCallback starts the request in a goroutine and returns a promise that run() resolves
+// with a JSON payload ({status, statusText, url, headers, body} or {error}).
+func (rt *jsRuntime) fetchCallback(info *v8.FunctionCallbackInfo) *v8.Value {
+	resolver, err := v8.NewPromiseResolver(info.Context())
+	if err != nil {
+		return nil
+	}
+	args := info.Args()
+	rawURL := ""
+	if len(args) > 0 {
+		rawURL = args[0].String()
+	}
+	var req fetchRequest
+	if len(args) > 1 {
+		_ = json.Unmarshal([]byte(args[1].String()), &req)
+	}
+
+	rt.pending++
+	go func() {
+		payload := rt.doFetch(rawURL, req)
+		select {
+		case rt.results <- fetchResult{resolver: resolver, payload: payload}:
+		case <-rt.ctx.Done():
+		}
+	}()
+	return resolver.GetPromise().Value
+}
+
+// requireCallback resolves a script library and returns its compiled factory
+// function(module, exports, require), or an error message string.
+func (rt *jsRuntime) requireCallback(info *v8.FunctionCallbackInfo) *v8.Value {
+	fail := func(format string, args ...interface{}) *v8.Value {
+		v, _ := v8.NewValue(rt.iso, fmt.Sprintf(format, args...))
+		return v
+	}
+	args := info.Args()
+	if len(args) == 0 {
+		return fail("require: module name is required")
+	}
+	spec := args[0].String()
+	if rt.libs == nil {
+		return fail("require(%q): script libraries are not available", spec)
+	}
+	name, version := spec, 0
+	if i := strings.LastIndex(spec, "@"); i > 0 {
+		v, err := strconv.Atoi(spec[i+1:])
+		if err != nil || v <= 0 {
+			return fail("require(%q): invalid version", spec)
+		}
+		name, version = spec[:i], v
+	}
+	lib, err := rt.libs.ResolveScript(name, version)
+	if err != nil {
+		return fail("require(%q): %v", spec, err)
+	}
+
+	origin := fmt.Sprintf("%s@%d.js", lib.Name, lib.Version)
+	source := "(function (module, exports, require) {" + lib.Code + "\n})"
+	script, err := rt.ji.compile(origin, source)
+	if err != nil {
+		return fail("require(%q): %v", spec, err)
+	}
+	factory, err := script.Run(rt.v8ctx)
+	if err != nil {
+		return fail("require(%q): %v", spec, err)
+	}
+	return factory
+}
+
+func (rt *jsRuntime) doFetch(rawURL string, req fetchRequest) string {
+	fail := func(err error) string {
+		b, _ := json.Marshal(map[string]string{"error": err.Error()})
+		return string(b)
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fail(err)
+	}
+	if err := rt.checkURL(u); err != nil {
+		return fail(err)
+	}
+	method := strings.ToUpper(req.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	var body io.Reader
+	if req.Body != nil {
+		body = strings.NewReader(*req.Body)
+	}
+	httpReq, err := http.NewRequestWithContext(rt.ctx, method, u.String(), body)
+	if err != nil {
+		return fail(err)
+	}
+	for k, v := range req.Headers {
+		httpReq.Header.Set(k, v)
+	}
+
+	resp, err := rt.client.Do(httpReq)
+	if err != nil {
+		return fail(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, rt.maxBytes+1))
+	if err != nil {
+		return fail(err)
+	}
+	if int64(len(data)) > rt.maxBytes {
+		return fail(fmt.Errorf("response body exceeds %d bytes", rt.maxBytes))
+	}
+
+	headers := map[string]string{}
+	for k, vals := range resp.Header {
+		headers[strings.ToLower(k)] = strings.Join(vals, ", ")
+	}
+	b, _ := json.Marshal(map[string]interface{}{
+		"status":     resp.StatusCode,
+		"statusText": strings.TrimSpace(strings.TrimPrefix(resp.Status, fmt.Sprintf("%d", resp.StatusCode))),
+		"url":        resp.Request.URL.String(),
+		"headers":    headers,
+		"body":       string(data),
+	})
+	return string(b)
+}
+
+// checkURL enforces the fetch sandbox on the first request and every redirect: http(s) only,
+// when properties.fetchAllowedHosts is set only the listed hosts, and no literal internal
+// address. Hostnames resolving to internal addresses are refused at dial time (fetchDialer).
+func (rt *jsRuntime) checkURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported URL scheme %q", u.Scheme)
+	}
+	if len(rt.allowedHosts) > 0 && !rt.allowedHosts[strings.ToLower(u.Hostname())] {
+		return fmt.Errorf("host %q is not in fetchAllowedHosts", u.Hostname())
+	}
+	if addr, err := netip.ParseAddr(u.Hostname()); err == nil && !fetchAddrAllowed(addr) {
+		return fmt.Errorf("host %q is an internal address", u.Hostname())
+	}
+	return nil
+}
+
+// run executes the wrapped script (compiled once per isolate, see jsIsolate.compile) and pumps
+// microtasks and completed fetches until the returned promise settles. It returns the settled
+// promise's value.
+func (rt *jsRuntime) run(source, origin string) (*v8.Value, error) {
+	script, err := rt.ji.compile(origin, source)
+	if err != nil {
+		return nil, err
+	}
+	val, err := script.Run(rt.v8ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !val.IsPromise() {
+		return val, nil
+	}
+	promise, err := val.AsPromise()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		rt.v8ctx.PerformMicrotaskCheckpoint()
+		switch promise.State() {
+		case v8.Fulfilled:
+			return promise.Result(), nil
+		case v8.Rejected:
+			return nil, jsRejection(promise.Result())
+		}
+		if rt.pending == 0 {
+			return nil, fmt.Errorf("script finished with an unresolved promise")
+		}
+		select {
+		case r := <-rt.results:
+			rt.pending--
+			payload, err := v8.NewValue(rt.iso, r.payload)
+			if err != nil {
+				return nil, err
+			}
+			r.resolver.Resolve(payload)
+		case <-rt.ctx.Done():
+			return nil, rt.ctx.Err()
+		}
+	}
+}
+
+// consoleOutput returns the captured console entries, or nil if nothing was logged.
+func (rt *jsRuntime) consoleOutput() []interface{} {
+	if len(rt.console) == 0 {
+		return nil
+	}
+	if rt.truncated {
+		return append(rt.console, map[string]interface{}{
+			"level":   "warn",
+			"message": fmt.Sprintf("console output truncated after %d entries", maxConsoleEntries),
+			"time":    time.Now().Format(time.RFC3339Nano),
+		})
+	}
+	return rt.console
+}
+
+// jsRejection converts a rejected promise's reason into an error, keeping the first stack
+// location outside the runtime bootstrap when the reason is an Error.
+func jsRejection(reason *v8.Value) error {
+	msg := reason.DetailString()
+	if reason.IsObject() {
+		if stack, err := reason.Object().Get("stack"); err == nil && stack.IsString() {
+			for _, line := range strings.Split(stack.String(), "\n")[1:] {
+				line = strings.TrimSpace(line)
+				if strings.HasPrefix(line, "at ") && !strings.Contains(line, "runtime.js") {
+					return fmt.Errorf("%s (%s)", msg, line)
+				}
+			}
+		}
+	}
+	return fmt.Errorf("%s", msg)
+}
+
+func parseAllowedHosts(raw interface{}) map[string]bool {
+	var hosts []string
+	switch v := raw.(type) {
+	case string:
+		hosts = strings.Split(v, ",")
+	case []interface{}:
+		for _, h := range v {
+			if s, ok := h.(string); ok {
+				hosts = append(hosts, s)
+			}
+		}
+	}
+	set := map[string]bool{}
+	for _, h := range hosts {
+		if h = strings.ToLower(strings.TrimSpace(h)); h != "" {
+			set[h] = true
+		}
+	}
+	return set
+}
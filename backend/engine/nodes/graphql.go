@@ -128,7 +128,7 @@ func substituteVariables(variablesStr string, input map[string]interface{}) (str
 		if strings.HasPrefix(path, "input.") {
 			path = path[7:]
 		}
-		v, err := getNested(input, path)
+		v, err := GetNested(input, path)
 		if err != nil {
 			return "", fmt.Errorf("placeholder {{%s}}: %w", path, err)
 		}
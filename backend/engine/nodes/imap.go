@@ -0,0 +1,119 @@
+package nodes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"eflo/backend/engine"
+	"eflo/backend/imaputil"
+	"eflo/backend/models"
+)
+
+// ImapNode files a message by UID on the IMAP server of an email config: move or copy it,
+// add or remove flags, or delete it. The UID, mailbox and UIDVALIDITY default to the input,
+// so the node can follow an email_receive trigger directly.
+type ImapNode struct{}
+
+func (n *ImapNode) Execute(ctx context.Context, node models.NodeDef, input map[string]interface{}, resolveConfig engine.ConfigResolver) (map[string]interface{}, error) {
+	props := node.Properties
+	if props == nil {
+		props = make(map[string]interface{})
+	}
+
+	configIDRaw, ok := props["configId"]
+	if !ok {
+		return nil, fmt.Errorf("imap node: configId is required")
+	}
+	configID, err := toInt64(configIDRaw)
+	if err != nil {
+		return nil, fmt.Errorf("imap node: invalid configId: %w", err)
+	}
+	cfg, err := resolveConfig(configID)
+	if err != nil {
+		return nil, fmt.Errorf("imap node: failed to resolve config %d: %w", configID, err)
+	}
+	if cfg.Type != "email" {
+		return nil, fmt.Errorf("imap node: config %d is not an email config (got %s)", configID, cfg.Type)
+	}
+
+	uid, err := uidValue(props["uid"], input["uid"])
+	if err != nil || uid == 0 {
+		return nil, fmt.Errorf("imap node: a message uid is required (property or input.uid)")
+	}
+	// A UIDVALIDITY from the trigger guards against acting on a recreated mailbox
+	uidValidity, _ := uidValue(props["uidValidity"], input["uidValidity"])
+	mailbox := getStringProp(props, input, "mailbox")
+	if mailbox == "" {
+		mailbox = "INBOX"
+	}
+
+	actionType, _ := props["action"].(string)
+	action := models.EmailAction{Type: actionType, Mailbox: getStringProp(props, input, "targetMailbox")}
+	switch f := props["flags"].(type) {
+	case string:
+		action.Flags = strings.FieldsFunc(f, func(r rune) bool { return r == ',' || r == ' ' })
+	case []interface{}:
+		for _, v := range f {
+			if s, ok := v.(string); ok && s != "" {
+				action.Flags = append(action.Flags, s)
+			}
+		}
+	}
+	if err := imaputil.ValidateActions([]models.EmailAction{action}); err != nil {
+		return nil, fmt.Errorf("imap node: %w", err)
+	}
+
+	var secrets imaputil.SecretStore
+	if store := engine.ConfigStoreFromContext(ctx); store != nil {
+		secrets = store
+	}
+	c, err := imaputil.Dial(cfg, secrets)
+	if err != nil {
+		return nil, fmt.Errorf("imap node: %w", err)
+	}
+	defer c.Close()
+	if err := c.SelectUIDs(mailbox, uidValidity); err != nil {
+		return nil, fmt.Errorf("imap node: %w", err)
+	}
+	if err := c.Apply(uid, action); err != nil {
+		return nil, fmt.Errorf("imap node: %w", err)
+	}
+
+	output := map[string]interface{}{
+		"action":  action.Type,
+		"uid":     uid,
+		"mailbox": mailbox,
+		"done":    true,
+	}
+	if action.Mailbox != "" {
+		output["targetMailbox"] = action.Mailbox
+	}
+	if len(action.Flags) > 0 {
+		output["flags"] = action.Flags
+	}
+	return output, nil
+}
+
+// uidValue reads a UID from a property, falling back to the input value. Trigger input holds
+// uint32 values; JSON-decoded ones are float64 or strings.
+func uidValue(prop, fallback interface{}) (uint32, error) {
+	v := prop
+	if v == nil || v == "" {
+		v = fallback
+	}
+	if v == nil {
+		return 0, nil
+	}
+	if u, ok := v.(uint32); ok {
+		return u, nil
+	}
+	n, err := toInt64(v)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 || n > 1<<32-1 {
+		return 0, fmt.Errorf("uid %d out of range", n)
+	}
+	return uint32(n), nil
+}
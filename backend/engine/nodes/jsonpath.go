@@ -0,0 +1,117 @@
+package nodes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"eflo/backend/engine"
+	"eflo/backend/models"
+)
+
+// JSONPathNode extracts values from the upstream input using path expressions
+// (array indices, wildcards, filters, quoted keys — see path.go).
+//
+// Single extraction: properties.path (e.g. "json.items[?(@.qty > 0)].id") writes the value to
+// properties.outputKey (default "result").
+// Multiple extractions: properties.paths is an object of outputKey -> path, or its JSON text.
+// properties.default is used when a path yields nothing; properties.required fails the node instead.
+type JSONPathNode struct{}
+
+func (n *JSONPathNode) Execute(_ context.Context, node models.NodeDef, input map[string]interface{}, _ engine.ConfigResolver) (map[string]interface{}, error) {
+	path, _ := node.Properties["path"].(string)
+	paths, err := jsonPathList(node.Properties["paths"])
+	if err != nil {
+		return nil, fmt.Errorf("jsonpath node: %w", err)
+	}
+	if path == "" && len(paths) == 0 {
+		return nil, fmt.Errorf("jsonpath node: 'path' or 'paths' is required")
+	}
+
+	outputKey, _ := node.Properties["outputKey"].(string)
+	if outputKey == "" {
+		outputKey = "result"
+	}
+	required, _ := node.Properties["required"].(bool)
+	defaultVal, hasDefault := node.Properties["default"]
+
+	extract := func(p string) (interface{}, bool, error) {
+		v, err := GetNested(input, p)
+		if err != nil {
+			if required {
+				return nil, false, err
+			}
+			v = nil
+		}
+		found := !isEmptyPathResult(v)
+		if !found {
+			if required {
+				return nil, false, fmt.Errorf("path %q matched nothing", p)
+			}
+			if hasDefault {
+				v = defaultVal
+			}
+		}
+		return v, found, nil
+	}
+
+	output := map[string]interface{}{}
+	if path != "" {
+		v, found, err := extract(path)
+		if err != nil {
+			return nil, fmt.Errorf("jsonpath node: %w", err)
+		}
+		output[outputKey] = v
+		output["found"] = found
+		output["path"] = path
+	}
+	for key, raw := range paths {
+		p, ok := raw.(string)
+		if !ok || p == "" {
+			return nil, fmt.Errorf("jsonpath node: paths.%s must be a non-empty string", key)
+		}
+		v, _, err := extract(p)
+		if err != nil {
+			return nil, fmt.Errorf("jsonpath node: paths.%s: %w", key, err)
+		}
+		output[key] = v
+	}
+
+	for k, v := range input {
+		if _, exists := output[k]; !exists {
+			output[k] = v
+		}
+	}
+	return output, nil
+}
+
+// jsonPathList reads properties.paths, given as an object or as JSON text (the editor's form).
+func jsonPathList(raw interface{}) (map[string]interface{}, error) {
+	switch v := raw.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		return v, nil
+	case string:
+		if strings.TrimSpace(v) == "" {
+			return nil, nil
+		}
+		var paths map[string]interface{}
+		if err := json.Unmarshal([]byte(v), &paths); err != nil {
+			return nil, fmt.Errorf("paths is not a valid JSON object: %w", err)
+		}
+		return paths, nil
+	}
+	return nil, fmt.Errorf("paths must be an object of outputKey -> path")
+}
+
+func isEmptyPathResult(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	if list, ok := v.([]interface{}); ok {
+		return len(list) == 0
+	}
+	return false
+}
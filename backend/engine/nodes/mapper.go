@@ -0,0 +1,340 @@
+package nodes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+
+	"eflo/backend/engine"
+	"eflo/backend/models"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+)
+
+// MapperNode builds a new object from declarative target-path → source rules, so simple
+// field renames between APIs don't need a function node. properties.mappings is a list of:
+//
+//	{ "target": "customer.name", "source": "user.fullName" }           // source is a path (see path.go)
+//	{ "target": "total", "expression": "price * qty", "type": "number" } // expr-lang over the input
+//	{ "target": "status", "value": "new" }                             // constant
+//	{ "target": "tags", "source": "labels[*].name", "default": [] }
+//
+// An object { "customer.name": "user.fullName", ... } or a JSON string of either form is also accepted.
+// Optional per rule: "default" (used when the source is missing/null), "type" (string, number,
+// integer, boolean, array, object, json) and "required" (fail instead of leaving the field out).
+//
+// properties.unmapped is "drop" (default: output is exactly the mapped object) or "keep"
+// (input fields are copied first, except those consumed by a top-level source rename and the
+// injected config map).
+type MapperNode struct{}
+
+type mapRule struct {
+	Target     string
+	Source     string
+	Expression string
+	Value      interface{}
+	HasValue   bool
+	Default    interface{}
+	HasDefault bool
+	Type       string
+	Required   bool
+}
+
+func (n *MapperNode) Execute(_ context.Context, node models.NodeDef, input map[string]interface{}, _ engine.ConfigResolver) (map[string]interface{}, error) {
+	rules, err := parseMapRules(node.Properties["mappings"])
+	if err != nil {
+		return nil, fmt.Errorf("mapper node: %w", err)
+	}
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("mapper node: 'mappings' is required")
+	}
+
+	unmapped, _ := node.Properties["unmapped"].(string)
+	if unmapped == "" {
+		unmapped = "drop"
+	}
+	if unmapped != "drop" && unmapped != "keep" {
+		return nil, fmt.Errorf("mapper node: unmapped must be 'drop' or 'keep' (got %q)", unmapped)
+	}
+
+	output := map[string]interface{}{}
+	if unmapped == "keep" {
+		consumed := map[string]bool{"config": true}
+		for _, r := range rules {
+			if r.Source != "" && !strings.ContainsAny(r.Source, ".[*$\\") {
+				consumed[r.Source] = true
+			}
+		}
+		for k, v := range input {
+			if !consumed[k] {
+				output[k] = v
+			}
+		}
+	}
+
+	for _, r := range rules {
+		v, ok, err := r.resolve(input)
+		if err != nil {
+			return nil, fmt.Errorf("mapper node: %s: %w", r.Target, err)
+		}
+		if !ok {
+			if r.Required {
+				return nil, fmt.Errorf("mapper node: %s: source %q is missing", r.Target, r.Source)
+			}
+			continue
+		}
+		if r.Type != "" {
+			v, err = coerceValue(v, r.Type)
+			if err != nil {
+				return nil, fmt.Errorf("mapper node: %s: %w", r.Target, err)
+			}
+		}
+		if err := SetNested(output, r.Target, v); err != nil {
+			return nil, fmt.Errorf("mapper node: %w", err)
+		}
+	}
+	return output, nil
+}
+
+// resolve returns the rule's value and whether it should be written.
+func (r mapRule) resolve(input map[string]interface{}) (interface{}, bool, error) {
+	var v interface{}
+	switch {
+	case r.HasValue:
+		return r.Value, true, nil
+	case r.Expression != "":
+		program, err := compileMapExpr(r.Expression)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to compile expression: %w", err)
+		}
+		v, err = expr.Run(program, input)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to evaluate expression: %w", err)
+		}
+	case r.Source != "":
+		// A missing intermediate value is treated like a missing field so defaults apply.
+		v, _ = GetNested(input, r.Source)
+		if list, ok := v.([]interface{}); ok && len(list) == 0 && !r.HasDefault {
+			return list, true, nil
+		}
+	}
+	if isEmptyPathResult(v) {
+		if r.HasDefault {
+			return r.Default, true, nil
+		}
+		return nil, false, nil
+	}
+	return v, true, nil
+}
+
+var mapExprCache sync.Map // expression source -> *vm.Program
+
+// compileMapExpr compiles a rule expression once per source text. Programs are compiled without
+// the input's types, so one program serves every execution; unknown variables evaluate to nil and
+// fall back to the rule's default.
+func compileMapExpr(src string) (*vm.Program, error) {
+	if p, ok := mapExprCache.Load(src); ok {
+		return p.(*vm.Program), nil
+	}
+	program, err := expr.Compile(src, expr.AllowUndefinedVariables())
+	if err != nil {
+		return nil, err
+	}
+	mapExprCache.Store(src, program)
+	return program, nil
+}
+
+func parseMapRules(raw interface{}) ([]mapRule, error) {
+	if s, ok := raw.(string); ok {
+		if strings.TrimSpace(s) == "" {
+			return nil, nil
+		}
+		if err := json.Unmarshal([]byte(s), &raw); err != nil {
+			return nil, fmt.Errorf("mappings is not valid JSON: %w", err)
+		}
+	}
+
+	var rules []mapRule
+	switch v := raw.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		// Shorthand: { "target": "source.path" }
+		for _, target := range sortedKeys(v) {
+			src, ok := v[target].(string)
+			if !ok {
+				return nil, fmt.Errorf("mapping %q: source must be a string", target)
+			}
+			rules = append(rules, mapRule{Target: target, Source: src})
+		}
+	case []interface{}:
+		for i, item := range v {
+			m, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("mapping %d: must be an object", i)
+			}
+			r := mapRule{}
+			r.Target, _ = m["target"].(string)
+			r.Source, _ = m["source"].(string)
+			r.Expression, _ = m["expression"].(string)
+			r.Value, r.HasValue = m["value"]
+			r.Default, r.HasDefault = m["default"]
+			r.Type, _ = m["type"].(string)
+			r.Required, _ = m["required"].(bool)
+			if r.Target == "" {
+				return nil, fmt.Errorf("mapping %d: target is required", i)
+			}
+			if r.Source == "" && r.Expression == "" && !r.HasValue && !r.HasDefault {
+				return nil, fmt.Errorf("mapping %d (%s): one of source, expression or value is required", i, r.Target)
+			}
+			rules = append(rules, r)
+		}
+	default:
+		return nil, fmt.Errorf("mappings must be a list or an object (got %T)", raw)
+	}
+	return rules, nil
+}
+
+// SetNested writes value at path inside m, creating intermediate objects (and growing
+// arrays for bracket indices, e.g. "items[0].id"). Quoted keys and \. escapes work as in GetNested.
+// Objects and arrays below m are copied before they are written to, so values m shares with
+// other maps (e.g. copied from a node's input) are never modified.
+func SetNested(m map[string]interface{}, path string, value interface{}) error {
+	cp, err := compilePath(path)
+	if err != nil {
+		return err
+	}
+	if len(cp.segments) == 0 {
+		return fmt.Errorf("target path %q is empty", path)
+	}
+	for _, seg := range cp.segments {
+		if seg.recursive || (seg.kind != segKey && seg.kind != segIndex) {
+			return fmt.Errorf("target path %q may only contain keys and indices", path)
+		}
+		if seg.kind == segIndex && seg.index < 0 {
+			return fmt.Errorf("target path %q: negative index", path)
+		}
+	}
+	seg := cp.segments[0]
+	if seg.kind != segKey {
+		return fmt.Errorf("target path %q: %s is not an array", path, seg.key)
+	}
+	child, err := setSegment(m[seg.key], cp.segments[1:], value, path)
+	if err != nil {
+		return err
+	}
+	m[seg.key] = child
+	return nil
+}
+
+// setSegment returns a copy of container with value written at segs.
+func setSegment(container interface{}, segs []pathSegment, value interface{}, path string) (interface{}, error) {
+	if len(segs) == 0 {
+		return value, nil
+	}
+	seg := segs[0]
+	if seg.kind == segIndex {
+		orig, _ := container.([]interface{})
+		if container != nil && orig == nil {
+			return nil, fmt.Errorf("target path %q: %s is not an array", path, seg.key)
+		}
+		arr := make([]interface{}, max(len(orig), seg.index+1))
+		copy(arr, orig)
+		child, err := setSegment(arr[seg.index], segs[1:], value, path)
+		if err != nil {
+			return nil, err
+		}
+		arr[seg.index] = child
+		return arr, nil
+	}
+	orig, _ := container.(map[string]interface{})
+	if container != nil && orig == nil {
+		return nil, fmt.Errorf("target path %q: %s is not an object", path, seg.key)
+	}
+	m := make(map[string]interface{}, len(orig)+1)
+	for k, v := range orig {
+		m[k] = v
+	}
+	child, err := setSegment(m[seg.key], segs[1:], value, path)
+	if err != nil {
+		return nil, err
+	}
+	m[seg.key] = child
+	return m, nil
+}
+
+// coerceValue converts v to the named type (string, number, integer, boolean, array, object, json).
+func coerceValue(v interface{}, typ string) (interface{}, error) {
+	switch strings.ToLower(typ) {
+	case "string":
+		return placeholderString(v), nil
+	case "number", "float":
+		switch t := v.(type) {
+		case float64:
+			return t, nil
+		case int:
+			return float64(t), nil
+		case int64:
+			return float64(t), nil
+		case bool:
+			if t {
+				return float64(1), nil
+			}
+			return float64(0), nil
+		}
+		f, err := strconv.ParseFloat(strings.TrimSpace(placeholderString(v)), 64)
+		if err != nil {
+			return nil, fmt.Errorf("cannot convert %v to number", v)
+		}
+		return f, nil
+	case "integer", "int":
+		f, err := coerceValue(v, "number")
+		if err != nil {
+			return nil, fmt.Errorf("cannot convert %v to integer", v)
+		}
+		return int64(math.Trunc(f.(float64))), nil
+	case "boolean", "bool":
+		switch t := v.(type) {
+		case bool:
+			return t, nil
+		case float64:
+			return t != 0, nil
+		case int:
+			return t != 0, nil
+		case int64:
+			return t != 0, nil
+		}
+		b, err := strconv.ParseBool(strings.TrimSpace(placeholderString(v)))
+		if err != nil {
+			return nil, fmt.Errorf("cannot convert %v to boolean", v)
+		}
+		return b, nil
+	case "array":
+		if arr, ok := asSlice(v); ok {
+			return arr, nil
+		}
+		if s, ok := v.(string); ok {
+			var arr []interface{}
+			if err := json.Unmarshal([]byte(s), &arr); err == nil {
+				return arr, nil
+			}
+		}
+		return []interface{}{v}, nil
+	case "object", "json":
+		s, ok := v.(string)
+		if !ok {
+			return v, nil
+		}
+		var parsed interface{}
+		if err := json.Unmarshal([]byte(s), &parsed); err != nil {
+			return nil, fmt.Errorf("value is not valid JSON: %w", err)
+		}
+		return parsed, nil
+	}
+	return nil, fmt.Errorf("unknown type %q", typ)
+}
@@ -0,0 +1,93 @@
+package nodes
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"eflo/backend/models"
+)
+
+func TestMapperLeavesInputUnchanged(t *testing.T) {
+	newInput := func() map[string]interface{} {
+		var m map[string]interface{}
+		_ = json.Unmarshal([]byte(`{
+			"customer": {"name": "Ada", "address": {"city": "London"}},
+			"items": [{"id": 1}, {"id": 2}]
+		}`), &m)
+		return m
+	}
+
+	tests := []struct {
+		name     string
+		unmapped string
+		mappings string
+		want     string
+	}{
+		{
+			name:     "keep mode writes below an input object",
+			unmapped: "keep",
+			mappings: `[{"target": "customer.address.zip", "value": "N1"}, {"target": "items[1].sku", "value": "B"}]`,
+			want: `{
+				"customer": {"name": "Ada", "address": {"city": "London", "zip": "N1"}},
+				"items": [{"id": 1}, {"id": 2, "sku": "B"}]
+			}`,
+		},
+		{
+			name:     "keep mode appends to an input array",
+			unmapped: "keep",
+			mappings: `[{"target": "items[2].id", "value": 3}]`,
+			want: `{
+				"customer": {"name": "Ada", "address": {"city": "London"}},
+				"items": [{"id": 1}, {"id": 2}, {"id": 3}]
+			}`,
+		},
+		{
+			name:     "drop mode writes below a copied object",
+			unmapped: "drop",
+			mappings: `[{"target": "buyer", "source": "customer"}, {"target": "buyer.address.country", "value": "UK"},
+				{"target": "first", "source": "items[0]"}, {"target": "first.qty", "value": 5}]`,
+			want: `{
+				"buyer": {"name": "Ada", "address": {"city": "London", "country": "UK"}},
+				"first": {"id": 1, "qty": 5}
+			}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := newInput()
+			node := models.NodeDef{Type: "mapper", Properties: map[string]interface{}{
+				"unmapped": tt.unmapped, "mappings": tt.mappings,
+			}}
+			out, err := (&MapperNode{}).Execute(context.Background(), node, input, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var want map[string]interface{}
+			if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			if got := roundTrip(t, out); !reflect.DeepEqual(got, want) {
+				t.Errorf("output = %v, want %v", got, want)
+			}
+			if !reflect.DeepEqual(input, newInput()) {
+				t.Errorf("input was modified: %v", input)
+			}
+		})
+	}
+}
+
+// roundTrip normalizes v to what JSON decoding produces (float64 numbers, generic maps).
+func roundTrip(t *testing.T, v interface{}) map[string]interface{} {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
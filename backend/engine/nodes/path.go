@@ -0,0 +1,641 @@
+package nodes
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+)
+
+// Path syntax (JSONPath-style) used by placeholders and the jsonpath node:
+//
+//	user.name              dot segments walk maps
+//	items.0.id, items[0]   numeric segments / brackets index arrays; negative counts from the end
+//	items[*], user.*       wildcard over array elements or map values
+//	items[0:2], items[::2] slices (start:end:step)
+//	items[0,2], ['a','b']  unions of indices or keys
+//	['key.with.dots']      quoted keys; key\.with\.dots also escapes a dot in a bare segment
+//	..email                recursive descent
+//	items[?(@.price > 10)] filter; the expression is expr-lang with @ bound to the current element
+//
+// A filter does not match an element it cannot look into: one missing the field (nil) or one that
+// is not an object. Other evaluation errors (e.g. comparing a string with a number) fail the path.
+//
+// A leading "$" or "$." is optional. Paths that only contain plain keys and indices yield a
+// single value; paths with a wildcard, slice, union, filter or recursive descent yield a list.
+
+type segmentKind int
+
+const (
+	segKey segmentKind = iota
+	segIndex
+	segWildcard
+	segSlice
+	segUnion
+	segFilter
+)
+
+type pathSegment struct {
+	kind      segmentKind
+	key       string // segKey; also the raw text for error messages
+	index     int    // segIndex
+	numeric   bool   // segKey that looks like an integer: indexes arrays, keys maps
+	keys      []string
+	indices   []int
+	slice     [3]*int
+	filter    *vm.Program
+	recursive bool // preceded by ".."
+}
+
+func (s pathSegment) multi() bool {
+	return s.recursive || (s.kind != segKey && s.kind != segIndex)
+}
+
+type compiledPath struct {
+	raw      string
+	segments []pathSegment
+}
+
+// pathCacheSize bounds the compiled path cache. Paths usually come from workflow definitions,
+// but placeholders can be built from data, so the cache must not grow without limit.
+const pathCacheSize = 1024
+
+// pathLRU caches compiled paths by their source text, evicting the least recently used.
+type pathLRU struct {
+	mu    sync.Mutex
+	max   int
+	order *list.List               // front = most recently used; values are *compiledPath
+	items map[string]*list.Element // raw path -> element in order
+}
+
+func newPathLRU(max int) *pathLRU {
+	return &pathLRU{max: max, order: list.New(), items: make(map[string]*list.Element)}
+}
+
+func (c *pathLRU) get(path string) (*compiledPath, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[path]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*compiledPath), true
+}
+
+func (c *pathLRU) put(cp *compiledPath) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[cp.raw]; ok {
+		c.order.MoveToFront(el)
+		return
+	}
+	c.items[cp.raw] = c.order.PushFront(cp)
+	for c.order.Len() > c.max {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*compiledPath).raw)
+	}
+}
+
+func (c *pathLRU) size() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+var pathCache = newPathLRU(pathCacheSize)
+
+// compilePath parses a path expression; compiled paths are cached by their source text.
+func compilePath(path string) (*compiledPath, error) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return nil, fmt.Errorf("empty path")
+	}
+	if cp, ok := pathCache.get(path); ok {
+		return cp, nil
+	}
+	segs, err := parsePath(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path %q: %w", path, err)
+	}
+	cp := &compiledPath{raw: path, segments: segs}
+	pathCache.put(cp)
+	return cp, nil
+}
+
+// filterError is a filter expression that failed on an element; unlike a missing value in a
+// multi-valued path it is reported rather than skipped.
+type filterError struct {
+	filter string
+	err    error
+}
+
+func (e *filterError) Error() string {
+	return fmt.Sprintf("filter %s: %v", e.filter, e.err)
+}
+
+func (e *filterError) Unwrap() error { return e.err }
+
+// EvalPath evaluates path against root (maps, slices and scalars as produced by encoding/json).
+// A missing key or out-of-range index at the last segment yields nil; walking through a missing
+// value or a scalar is an error. Multi-valued paths always return a []interface{} (possibly empty).
+func EvalPath(root interface{}, path string) (interface{}, error) {
+	cp, err := compilePath(path)
+	if err != nil {
+		return nil, err
+	}
+	return cp.Eval(root)
+}
+
+// Eval evaluates the compiled path against root. See EvalPath.
+func (cp *compiledPath) Eval(root interface{}) (interface{}, error) {
+	current := []interface{}{root}
+	multi := false
+	for _, seg := range cp.segments {
+		if seg.multi() {
+			multi = true
+		}
+		var next []interface{}
+		for _, v := range current {
+			candidates := []interface{}{v}
+			if seg.recursive {
+				candidates = descendants(v, nil)
+			}
+			for _, c := range candidates {
+				vals, err := seg.apply(c, multi)
+				if err != nil {
+					var fe *filterError
+					if multi && !errors.As(err, &fe) {
+						continue
+					}
+					return nil, fmt.Errorf("path %q %s", cp.raw, err.Error())
+				}
+				next = append(next, vals...)
+			}
+		}
+		current = next
+	}
+	if multi {
+		if current == nil {
+			return []interface{}{}, nil
+		}
+		return current, nil
+	}
+	if len(current) == 0 {
+		return nil, nil
+	}
+	return current[0], nil
+}
+
+// apply selects children of v. In single-value mode a missing child yields a nil result
+// (so the caller can keep reporting "missing path" on the next step); in multi mode it is skipped.
+func (s pathSegment) apply(v interface{}, multi bool) ([]interface{}, error) {
+	if v == nil {
+		return nil, fmt.Errorf("missing at %q", s.key)
+	}
+	switch s.kind {
+	case segKey:
+		switch t := v.(type) {
+		case map[string]interface{}:
+			val, ok := t[s.key]
+			if !ok && multi {
+				return nil, nil
+			}
+			return []interface{}{val}, nil
+		case []interface{}:
+			if s.numeric {
+				n, _ := strconv.Atoi(s.key)
+				return indexSlice(t, n, multi), nil
+			}
+		case []map[string]interface{}:
+			if s.numeric {
+				n, _ := strconv.Atoi(s.key)
+				return indexSlice(toInterfaceSlice(t), n, multi), nil
+			}
+		}
+		return nil, fmt.Errorf("not a map at %q", s.key)
+	case segIndex:
+		arr, ok := asSlice(v)
+		if !ok {
+			return nil, fmt.Errorf("not an array at %q", s.key)
+		}
+		return indexSlice(arr, s.index, multi), nil
+	case segWildcard:
+		if m, ok := v.(map[string]interface{}); ok {
+			out := make([]interface{}, 0, len(m))
+			for _, k := range sortedKeys(m) {
+				out = append(out, m[k])
+			}
+			return out, nil
+		}
+		if arr, ok := asSlice(v); ok {
+			return append([]interface{}{}, arr...), nil
+		}
+		return nil, nil
+	case segSlice:
+		arr, ok := asSlice(v)
+		if !ok {
+			return nil, nil
+		}
+		return sliceOf(arr, s.slice), nil
+	case segUnion:
+		var out []interface{}
+		if m, ok := v.(map[string]interface{}); ok {
+			for _, k := range s.keys {
+				if val, ok := m[k]; ok {
+					out = append(out, val)
+				}
+			}
+			return out, nil
+		}
+		if arr, ok := asSlice(v); ok {
+			for _, i := range s.indices {
+				out = append(out, indexSlice(arr, i, true)...)
+			}
+		}
+		return out, nil
+	case segFilter:
+		var out []interface{}
+		var items []interface{}
+		if arr, ok := asSlice(v); ok {
+			items = arr
+		} else if m, ok := v.(map[string]interface{}); ok {
+			for _, k := range sortedKeys(m) {
+				items = append(items, m[k])
+			}
+		}
+		for _, item := range items {
+			res, err := expr.Run(s.filter, map[string]interface{}{filterItemVar: item})
+			if err != nil {
+				if filterMissing(item, err) {
+					continue
+				}
+				return nil, &filterError{filter: s.key, err: err}
+			}
+			if b, ok := res.(bool); ok && b {
+				out = append(out, item)
+			}
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("unsupported segment %q", s.key)
+}
+
+// filterMissing reports whether a filter error means the element lacks what the filter looks at:
+// the expression reached a nil value (a missing field), or the element is not an object, so it
+// has no fields (e.g. scalars met by recursive descent). expr-lang reports nil as "<nil>".
+func filterMissing(item interface{}, err error) bool {
+	if _, ok := item.(map[string]interface{}); ok {
+		return strings.Contains(err.Error(), "<nil>")
+	}
+	return true
+}
+
+func indexSlice(arr []interface{}, i int, multi bool) []interface{} {
+	if i < 0 {
+		i += len(arr)
+	}
+	if i < 0 || i >= len(arr) {
+		if multi {
+			return nil
+		}
+		return []interface{}{nil}
+	}
+	return []interface{}{arr[i]}
+}
+
+func sliceOf(arr []interface{}, bounds [3]*int) []interface{} {
+	n := len(arr)
+	step := 1
+	if bounds[2] != nil {
+		step = *bounds[2]
+	}
+	if step == 0 {
+		return nil
+	}
+	norm := func(p *int, def int) int {
+		if p == nil {
+			return def
+		}
+		i := *p
+		if i < 0 {
+			i += n
+		}
+		if i < 0 {
+			i = -1
+			if step > 0 {
+				i = 0
+			}
+		}
+		if i > n {
+			i = n
+		}
+		return i
+	}
+	var out []interface{}
+	if step > 0 {
+		start, end := norm(bounds[0], 0), norm(bounds[1], n)
+		for i := start; i < end; i += step {
+			out = append(out, arr[i])
+		}
+		return out
+	}
+	start, end := norm(bounds[0], n-1), norm(bounds[1], -1)
+	if start >= n {
+		start = n - 1
+	}
+	for i := start; i > end; i += step {
+		out = append(out, arr[i])
+	}
+	return out
+}
+
+func descendants(v interface{}, acc []interface{}) []interface{} {
+	acc = append(acc, v)
+	if m, ok := v.(map[string]interface{}); ok {
+		for _, k := range sortedKeys(m) {
+			acc = descendants(m[k], acc)
+		}
+	} else if arr, ok := asSlice(v); ok {
+		for _, item := range arr {
+			acc = descendants(item, acc)
+		}
+	}
+	return acc
+}
+
+func asSlice(v interface{}) ([]interface{}, bool) {
+	switch t := v.(type) {
+	case []interface{}:
+		return t, true
+	case []map[string]interface{}:
+		return toInterfaceSlice(t), true
+	case []string:
+		out := make([]interface{}, len(t))
+		for i, s := range t {
+			out[i] = s
+		}
+		return out, true
+	}
+	return nil, false
+}
+
+func toInterfaceSlice(rows []map[string]interface{}) []interface{} {
+	out := make([]interface{}, len(rows))
+	for i, r := range rows {
+		out[i] = r
+	}
+	return out
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// --- parser ---
+
+// filterItemVar is the expr-lang variable that "@" is rewritten to inside filters.
+const filterItemVar = "_at"
+
+func parsePath(p string) ([]pathSegment, error) {
+	var segs []pathSegment
+	i := 0
+	if strings.HasPrefix(p, "$") {
+		i = 1
+	}
+	first := true
+	for i < len(p) {
+		recursive := false
+		switch {
+		case strings.HasPrefix(p[i:], ".."):
+			recursive = true
+			i += 2
+		case p[i] == '.':
+			i++
+		case p[i] == '[':
+			// handled below
+		default:
+			if !first {
+				return nil, fmt.Errorf("unexpected %q at offset %d", p[i], i)
+			}
+		}
+		first = false
+		if i >= len(p) {
+			return nil, fmt.Errorf("path ends with a separator")
+		}
+
+		if p[i] == '[' {
+			end, err := matchBracket(p, i)
+			if err != nil {
+				return nil, err
+			}
+			seg, err := parseBracket(p[i+1 : end])
+			if err != nil {
+				return nil, err
+			}
+			seg.recursive = recursive
+			segs = append(segs, seg)
+			i = end + 1
+			continue
+		}
+
+		// Bare name: read until an unescaped '.' or '['
+		var name strings.Builder
+		for i < len(p) && p[i] != '.' && p[i] != '[' {
+			if p[i] == '\\' && i+1 < len(p) {
+				i++
+			}
+			name.WriteByte(p[i])
+			i++
+		}
+		key := name.String()
+		if key == "" {
+			return nil, fmt.Errorf("empty segment at offset %d", i)
+		}
+		if key == "*" {
+			segs = append(segs, pathSegment{kind: segWildcard, key: key, recursive: recursive})
+			continue
+		}
+		_, err := strconv.Atoi(key)
+		segs = append(segs, pathSegment{kind: segKey, key: key, numeric: err == nil, recursive: recursive})
+	}
+	return segs, nil
+}
+
+// matchBracket returns the index of the ']' closing the '[' at start, skipping quoted strings
+// and nested brackets/parentheses inside filters.
+func matchBracket(p string, start int) (int, error) {
+	depth := 0
+	var quote byte
+	for i := start; i < len(p); i++ {
+		c := p[i]
+		if quote != 0 {
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch c {
+		case '\'', '"':
+			quote = c
+		case '[', '(':
+			depth++
+		case ']', ')':
+			depth--
+			if depth == 0 {
+				if c != ']' {
+					return 0, fmt.Errorf("unbalanced brackets at offset %d", i)
+				}
+				return i, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("unterminated '[' at offset %d", start)
+}
+
+func parseBracket(body string) (pathSegment, error) {
+	raw := "[" + body + "]"
+	body = strings.TrimSpace(body)
+	switch {
+	case body == "*":
+		return pathSegment{kind: segWildcard, key: raw}, nil
+	case strings.HasPrefix(body, "?"):
+		return parseFilter(raw, strings.TrimSpace(body[1:]))
+	case strings.HasPrefix(body, "'") || strings.HasPrefix(body, `"`):
+		keys, err := parseQuotedList(body)
+		if err != nil {
+			return pathSegment{}, err
+		}
+		if len(keys) == 1 {
+			return pathSegment{kind: segKey, key: keys[0]}, nil
+		}
+		return pathSegment{kind: segUnion, key: raw, keys: keys}, nil
+	case strings.Contains(body, ":"):
+		parts := strings.Split(body, ":")
+		if len(parts) > 3 {
+			return pathSegment{}, fmt.Errorf("invalid slice %s", raw)
+		}
+		seg := pathSegment{kind: segSlice, key: raw}
+		for j, part := range parts {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return pathSegment{}, fmt.Errorf("invalid slice %s", raw)
+			}
+			seg.slice[j] = &n
+		}
+		return seg, nil
+	case strings.Contains(body, ","):
+		seg := pathSegment{kind: segUnion, key: raw}
+		for _, part := range strings.Split(body, ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil {
+				return pathSegment{}, fmt.Errorf("invalid index list %s", raw)
+			}
+			seg.indices = append(seg.indices, n)
+		}
+		return seg, nil
+	}
+	n, err := strconv.Atoi(body)
+	if err != nil {
+		return pathSegment{}, fmt.Errorf("invalid index %s", raw)
+	}
+	return pathSegment{kind: segIndex, key: raw, index: n}, nil
+}
+
+func parseQuotedList(body string) ([]string, error) {
+	var keys []string
+	i := 0
+	for i < len(body) {
+		for i < len(body) && (body[i] == ' ' || body[i] == ',') {
+			i++
+		}
+		if i >= len(body) {
+			break
+		}
+		quote := body[i]
+		if quote != '\'' && quote != '"' {
+			return nil, fmt.Errorf("expected quoted key in [%s]", body)
+		}
+		i++
+		var key strings.Builder
+		closed := false
+		for i < len(body) {
+			c := body[i]
+			if c == '\\' && i+1 < len(body) {
+				key.WriteByte(body[i+1])
+				i += 2
+				continue
+			}
+			i++
+			if c == quote {
+				closed = true
+				break
+			}
+			key.WriteByte(c)
+		}
+		if !closed {
+			return nil, fmt.Errorf("unterminated quoted key in [%s]", body)
+		}
+		keys = append(keys, key.String())
+	}
+	return keys, nil
+}
+
+func parseFilter(raw, body string) (pathSegment, error) {
+	if !strings.HasPrefix(body, "(") || !strings.HasSuffix(body, ")") {
+		return pathSegment{}, fmt.Errorf("filter must look like [?(...)]")
+	}
+	src := rewriteFilterItem(body[1 : len(body)-1])
+	program, err := expr.Compile(src, expr.AllowUndefinedVariables())
+	if err != nil {
+		return pathSegment{}, fmt.Errorf("filter %s: %w", raw, err)
+	}
+	return pathSegment{kind: segFilter, key: raw, filter: program}, nil
+}
+
+// rewriteFilterItem replaces @ (outside string literals) with the filter item variable.
+func rewriteFilterItem(s string) string {
+	var b strings.Builder
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if quote != 0 {
+			b.WriteByte(c)
+			if c == '\\' && i+1 < len(s) {
+				i++
+				b.WriteByte(s[i])
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch c {
+		case '\'', '"', '`':
+			quote = c
+			b.WriteByte(c)
+		case '@':
+			b.WriteString(filterItemVar)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
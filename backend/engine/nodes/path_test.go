@@ -0,0 +1,176 @@
+package nodes
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const pathTestDoc = `{
+	"user": {"name": "Ada", "email": "ada@example.com", "tags": ["admin", "ops"]},
+	"items": [
+		{"id": 1, "price": 5, "name": "pen"},
+		{"id": 2, "price": 20, "name": "book", "meta": {"email": "shop@example.com"}},
+		{"id": 3, "price": 12, "name": "lamp", "sku": "L-1"}
+	],
+	"key.with.dots": "dotted",
+	"it's": "quoted",
+	"rows": [[1, 2], [3, 4]]
+}`
+
+func pathTestInput(t *testing.T) map[string]interface{} {
+	t.Helper()
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(pathTestDoc), &m); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestGetNested(t *testing.T) {
+	tests := []struct {
+		path string
+		want string // JSON of the result
+	}{
+		// keys and indices
+		{"user.name", `"Ada"`},
+		{"$.user.name", `"Ada"`},
+		{"$['user']['name']", `"Ada"`},
+		{"items.1.name", `"book"`},
+		{"items[1].name", `"book"`},
+		{"items[-1].id", `3`},
+		{"rows[1][0]", `3`},
+		{"user.missing", `null`},
+		{"items[7]", `null`},
+
+		// wildcards
+		{"items[*].id", `[1, 2, 3]`},
+		{"user.*", `["ada@example.com", "Ada", ["admin", "ops"]]`},
+		{"user.tags[*]", `["admin", "ops"]`},
+
+		// slices and unions
+		{"items[0:2].id", `[1, 2]`},
+		{"items[1:].id", `[2, 3]`},
+		{"items[::2].id", `[1, 3]`},
+		{"items[::-1].id", `[3, 2, 1]`},
+		{"items[-2:].id", `[2, 3]`},
+		{"items[0,2].name", `["pen", "lamp"]`},
+		{"user['name','email']", `["Ada", "ada@example.com"]`},
+
+		// recursive descent
+		{"..email", `["shop@example.com", "ada@example.com"]`},
+		{"$..sku", `["L-1"]`},
+		{"..nothing", `[]`},
+
+		// filters; elements without the field do not match
+		{"items[?(@.price > 10)].name", `["book", "lamp"]`},
+		{"items[?(@.name == 'pen' || @.id == 3)].id", `[1, 3]`},
+		{"items[?(@.sku != nil)].id", `[3]`},
+		{"items[?(@.sku startsWith 'L')].id", `[3]`},
+		{"items[?(@.meta.email endsWith 'example.com')].id", `[2]`},
+		{"$..[?(@.price >= 12)].id", `[2, 3]`},
+		{"items[?(@.price > 100)]", `[]`},
+
+		// escaping
+		{`key\.with\.dots`, `"dotted"`},
+		{"['key.with.dots']", `"dotted"`},
+		{`["it's"]`, `"quoted"`},
+		{`['it\'s']`, `"quoted"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := GetNested(pathTestInput(t), tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var want interface{}
+			if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("GetNested(%q) = %#v, want %s", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetNestedErrors(t *testing.T) {
+	tests := []struct {
+		path    string
+		wantErr string
+	}{
+		{"", "empty path"},
+		{"user.", "ends with a separator"},
+		{"items[0", "unterminated '['"},
+		{"items[a:b]", "invalid slice"},
+		{"items[x]", "invalid index"},
+		{"items[?@.id]", "filter must look like"},
+		{"items[?(@.id >)]", "filter"},
+		{"user.missing.deeper", "missing at"},
+		{"user.name.first", "not a map"},
+		// Comparing a string with a number is a mistake in the filter, not a non-match
+		{"items[?(@.name > 3)]", "filter [?(@.name > 3)]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			_, err := GetNested(pathTestInput(t), tt.path)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("GetNested(%q) error = %v, want it to mention %q", tt.path, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestResolvePlaceholders(t *testing.T) {
+	tests := []struct {
+		tmpl    string
+		want    string
+		wantErr bool
+	}{
+		{"Hello {{user.name}}", "Hello Ada", false},
+		{"{{ items[-1].name }} costs {{items[2].price}}", "lamp costs 12", false},
+		{"ids={{items[*].id}}", "ids=[1,2,3]", false},
+		{"{{user.tags}}", `["admin","ops"]`, false},
+		{"cheap: {{items[?(@.price < 10)].name}}", `cheap: ["pen"]`, false},
+		{"{{['key.with.dots']}}", "dotted", false},
+		{"[{{user.missing}}]", "[]", false},
+		{"no placeholders", "no placeholders", false},
+		{"{{user.missing.deeper}}", "{{user.missing.deeper}}", true},
+		{"{{items[?(@.name > 3)]}}", "{{items[?(@.name > 3)]}}", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tmpl, func(t *testing.T) {
+			got, err := ResolvePlaceholders(tt.tmpl, pathTestInput(t))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolvePlaceholders(%q) error = %v, wantErr %v", tt.tmpl, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ResolvePlaceholders(%q) = %q, want %q", tt.tmpl, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPathCacheIsBounded(t *testing.T) {
+	input := map[string]interface{}{"a": map[string]interface{}{}}
+	for i := 0; i < pathCacheSize+100; i++ {
+		if _, err := GetNested(input, fmt.Sprintf("a.k%d", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := pathCache.size(); n != pathCacheSize {
+		t.Errorf("cache holds %d paths, want %d", n, pathCacheSize)
+	}
+
+	// A path in use stays cached while newer ones evict the others
+	hot, _ := compilePath("a.hot")
+	for i := 0; i < pathCacheSize; i++ {
+		_, _ = compilePath("a.hot")
+		_, _ = compilePath(fmt.Sprintf("a.cold%d", i))
+	}
+	if cp, _ := compilePath("a.hot"); cp != hot {
+		t.Error("recently used path was evicted")
+	}
+}
@@ -1,6 +1,7 @@
 package nodes
 
 import (
+	"encoding/json"
 	"fmt"
 	"regexp"
 	"strings"
@@ -8,25 +9,10 @@ import (
 
 var resolvePlaceholderRE = regexp.MustCompile(`\{\{([^}]+)\}\}`)
 
-// GetNested returns a value from a nested map using dot path (e.g. "config.token", "input.userId").
+// GetNested returns a value from a nested map using a path (e.g. "config.token", "input.userId",
+// "json.items[0].id", "rows.2.email"). See path.go for the full syntax.
 func GetNested(m map[string]interface{}, path string) (interface{}, error) {
-	path = strings.TrimSpace(path)
-	if path == "" {
-		return nil, fmt.Errorf("empty path")
-	}
-	parts := strings.Split(path, ".")
-	var current interface{} = m
-	for _, p := range parts {
-		if current == nil {
-			return nil, fmt.Errorf("missing path %q", path)
-		}
-		if mp, ok := current.(map[string]interface{}); ok {
-			current = mp[p]
-			continue
-		}
-		return nil, fmt.Errorf("path %q not a map at %q", path, p)
-	}
-	return current, nil
+	return EvalPath(m, path)
 }
 
 // ResolvePlaceholders replaces {{path}} in s with values from input (e.g. {{config.token}}, {{input.userId}}).
@@ -46,10 +32,24 @@ func ResolvePlaceholders(s string, input map[string]interface{}) (string, error)
 			errOut = err
 			return match
 		}
-		if v == nil {
-			return ""
-		}
-		return fmt.Sprintf("%v", v)
+		return placeholderString(v)
 	})
 	return out, errOut
 }
+
+// placeholderString formats a resolved value for substitution into text. Maps and lists are
+// JSON-encoded (e.g. a wildcard path result) rather than printed in Go syntax.
+func placeholderString(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case map[string]interface{}, []interface{}, []map[string]interface{}:
+		b, err := json.Marshal(t)
+		if err == nil {
+			return string(b)
+		}
+	}
+	return fmt.Sprintf("%v", v)
+}
@@ -10,8 +10,8 @@ import (
 )
 
 // RedisSubscribeNode acts as a trigger entry point. When the flow runs
-// (triggered by the RedisSubscriber service), it passes along the message
-// received from the Redis channel.
+// (triggered by the RedisSubscriber or RedisStreamConsumer service), it passes
+// along the message received from the Redis channel or stream.
 type RedisSubscribeNode struct{}
 
 func (n *RedisSubscribeNode) Execute(_ context.Context, node models.NodeDef, input map[string]interface{}, _ engine.ConfigResolver) (map[string]interface{}, error) {
@@ -11,11 +11,15 @@ func RegisterAll() {
 	engine.Register("condition", &ConditionNode{})
 	engine.Register("log", &LogNode{})
 	engine.Register("transform", &TransformNode{})
+	engine.Register("jsonpath", &JSONPathNode{})
+	engine.Register("mapper", &MapperNode{})
+	engine.Register("validate", &ValidateNode{})
 	engine.Register("redis", &RedisNode{})
 	engine.Register("cron", &CronNode{})
 	engine.Register("redis_subscribe", &RedisSubscribeNode{})
 	engine.Register("email", &EmailNode{})
 	engine.Register("email_receive", &EmailReceiveNode{})
+	engine.Register("imap", &ImapNode{})
 	engine.Register("read_file", &ReadFileNode{})
 	engine.Register("write_file", &WriteFileNode{})
 	engine.Register("exec", &ExecNode{})
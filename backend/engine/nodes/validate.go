@@ -0,0 +1,85 @@
+package nodes
+
+import (
+	"context"
+	"fmt"
+
+	"eflo/backend/engine"
+	"eflo/backend/models"
+	"eflo/backend/schemautil"
+)
+
+// ValidateNode checks its input (or a sub-path of it) against a JSON Schema and routes to the
+// "valid" or "invalid" branch. The schema is either inline (properties.schema, object or JSON
+// string) or stored in the config store under properties.schemaKey.
+// Violations are returned as errors: [{ "path": "$.items[0].id", "message": "is required" }].
+type ValidateNode struct{}
+
+func (n *ValidateNode) Execute(ctx context.Context, node models.NodeDef, input map[string]interface{}, _ engine.ConfigResolver) (map[string]interface{}, error) {
+	rawSchema := node.Properties["schema"]
+	if s, ok := rawSchema.(string); ok && s == "" {
+		rawSchema = nil
+	}
+	schemaKey, _ := node.Properties["schemaKey"].(string)
+	if rawSchema == nil && schemaKey != "" {
+		store := engine.ConfigStoreFromContext(ctx)
+		if store == nil {
+			return nil, fmt.Errorf("validate node: config store not available")
+		}
+		value, ok, err := store.Get(schemaKey)
+		if err != nil {
+			return nil, fmt.Errorf("validate node: %w", err)
+		}
+		if !ok {
+			return nil, fmt.Errorf("validate node: schema key %q not found in config store", schemaKey)
+		}
+		rawSchema = value
+	}
+	if rawSchema == nil {
+		return nil, fmt.Errorf("validate node: 'schema' or 'schemaKey' is required")
+	}
+	schema, err := schemautil.Parse(rawSchema)
+	if err != nil {
+		return nil, fmt.Errorf("validate node: %w", err)
+	}
+
+	// Validate the whole input (minus the injected config map) or the value at properties.path
+	var target interface{}
+	path, _ := node.Properties["path"].(string)
+	if path != "" {
+		target, err = GetNested(input, path)
+		if err != nil {
+			target = nil
+		}
+	} else {
+		doc := make(map[string]interface{}, len(input))
+		for k, v := range input {
+			if k != "config" {
+				doc[k] = v
+			}
+		}
+		target = doc
+	}
+
+	violations := schemautil.Validate(schema, target)
+	branch := "valid"
+	if len(violations) > 0 {
+		branch = "invalid"
+	}
+	errs := make([]interface{}, 0, len(violations))
+	for _, v := range violations {
+		errs = append(errs, map[string]interface{}{"path": v.Path, "message": v.Message})
+	}
+
+	output := map[string]interface{}{
+		"_branch": branch,
+		"valid":   len(violations) == 0,
+		"errors":  errs,
+	}
+	for k, v := range input {
+		if _, exists := output[k]; !exists {
+			output[k] = v
+		}
+	}
+	return output, nil
+}
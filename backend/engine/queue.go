@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"eflo/backend/models"
+)
+
+// ExecutionQueue is a durable queue of workflow executions consumed by workers. Claimed jobs are
+// leased for a visibility timeout that the worker extends with heartbeats; jobs whose lease
+// expires (crashed worker) are claimed again until MaxAttempts is reached.
+type ExecutionQueue interface {
+	Enqueue(ctx context.Context, job *models.QueuedJob) (int64, error)
+	Claim(ctx context.Context, workerID string, visibility time.Duration) (*models.QueuedJob, error)
+	Heartbeat(ctx context.Context, id int64, workerID string, visibility time.Duration) (bool, error)
+	Complete(ctx context.Context, id int64, workerID string, execID int64, errMsg string) error
+	Cancel(ctx context.Context, id int64) error
+	Get(ctx context.Context, id int64) (*models.QueuedJob, error)
+	Stats(ctx context.Context) (map[string]int64, error)
+}
+
+const (
+	// defaultJobAttempts is how often a job abandoned by crashed workers is retried.
+	defaultJobAttempts = 3
+	// jobResultPollMin and jobResultPollMax bound how often a queued job is checked for its
+	// result: the interval doubles while the job waits or runs and after failed checks.
+	jobResultPollMin = 250 * time.Millisecond
+	jobResultPollMax = 5 * time.Second
+)
+
+// Execute runs a trigger-started workflow and returns its execution ID. Without a queue it runs
+// in-process; with one it enqueues the run for a worker and waits for the result, so callers see
+// the same outcome either way. With a queue, ctx's deadline becomes the job's timeout, which
+// starts when a worker claims the job; cancelling ctx cancels the job.
+func (e *Engine) Execute(ctx context.Context, wf *models.Workflow, input map[string]interface{}, source string) (int64, error) {
+	if e.Queue == nil {
+		return e.RunWorkflowWithInput(ctx, wf, input, nil, nil)
+	}
+
+	var timeout time.Duration
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+	jobID, err := e.enqueue(ctx, wf, input, source, timeout)
+	if err != nil {
+		return 0, err
+	}
+	return e.awaitJob(ctx, jobID)
+}
+
+// enqueue hands a run to the execution queue; timeout <= 0 leaves it to the worker's default.
+func (e *Engine) enqueue(ctx context.Context, wf *models.Workflow, input map[string]interface{}, source string, timeout time.Duration) (int64, error) {
+	job := &models.QueuedJob{WorkflowID: wf.ID, Input: input, Source: source, MaxAttempts: e.QueueMaxAttempts}
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = defaultJobAttempts
+	}
+	if timeout > 0 {
+		job.TimeoutMs = timeout.Milliseconds()
+	}
+	jobID, err := e.Queue.Enqueue(ctx, job)
+	if err != nil {
+		return 0, fmt.Errorf("enqueue execution: %w", err)
+	}
+	return jobID, nil
+}
+
+// awaitJob waits for a queued job's result. The job's own timeout runs from when a worker claims
+// it, so ctx's deadline passing while the job waits in the queue does not end the wait; only
+// cancelling ctx (shutdown, a client going away) cancels the job.
+func (e *Engine) awaitJob(ctx context.Context, jobID int64) (int64, error) {
+	done := ctx.Done()
+	wait := jobResultPollMin
+	for {
+		select {
+		case <-done:
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				done = nil
+				continue
+			}
+			cancelCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+			_ = e.Queue.Cancel(cancelCtx, jobID)
+			cancel()
+			return 0, fmt.Errorf("execution cancelled: %w", ctx.Err())
+		case <-time.After(wait):
+		}
+		if wait *= 2; wait > jobResultPollMax {
+			wait = jobResultPollMax
+		}
+
+		getCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		j, err := e.Queue.Get(getCtx, jobID)
+		cancel()
+		if err != nil {
+			log.Printf("[Queue] Failed to check job %d: %v; retrying in %s", jobID, err, wait)
+			continue
+		}
+		var execID int64
+		if j.ExecutionID != nil {
+			execID = *j.ExecutionID
+		}
+		switch j.Status {
+		case models.JobCompleted:
+			return execID, nil
+		case models.JobFailed:
+			return execID, errors.New(j.LastError)
+		case models.JobCancelled:
+			return execID, fmt.Errorf("execution cancelled")
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"eflo/backend/models"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisExecutionQueue keeps the execution queue in Redis: job hashes at <prefix>job:<id>, a FIFO
+// list of pending ids and a sorted set of running ids scored by lease expiry (unix ms).
+// Finished jobs are kept for a day so waiting callers can read the result.
+type RedisExecutionQueue struct {
+	Client *redis.Client
+	Prefix string
+}
+
+// NewRedisExecutionQueue connects to addr; keys are prefixed with "eflo:queue:".
+func NewRedisExecutionQueue(addr, password string, db int) *RedisExecutionQueue {
+	return &RedisExecutionQueue{
+		Client: redis.NewClient(&redis.Options{Addr: addr, Password: password, DB: db}),
+		Prefix: "eflo:queue:",
+	}
+}
+
+const redisFinishedJobTTL = 24 * time.Hour
+
+// KEYS: pending, running. ARGV: now ms, lease expiry ms, worker id, job key prefix.
+// Expired leases are requeued at the front, then the next pending job is leased.
+var redisClaimScript = redis.NewScript(`
+local expired = redis.call('ZRANGEBYSCORE', KEYS[2], '-inf', ARGV[1])
+for _, id in ipairs(expired) do
+  redis.call('ZREM', KEYS[2], id)
+  local key = ARGV[4] .. id
+  redis.call('HSET', key, 'status', 'pending', 'lastError', 'abandoned by worker ' .. (redis.call('HGET', key, 'workerId') or ''))
+  redis.call('RPUSH', KEYS[1], id)
+end
+local id = redis.call('RPOP', KEYS[1])
+if not id then return false end
+local key = ARGV[4] .. id
+redis.call('HSET', key, 'status', 'running', 'workerId', ARGV[3], 'visibleAt', ARGV[2])
+redis.call('HINCRBY', key, 'attempts', 1)
+redis.call('ZADD', KEYS[2], ARGV[2], id)
+return id`)
+
+// KEYS: running, job key. ARGV: id, worker id, lease expiry ms.
+var redisHeartbeatScript = redis.NewScript(`
+if redis.call('HGET', KEYS[2], 'workerId') ~= ARGV[2] or redis.call('HGET', KEYS[2], 'status') ~= 'running' then
+  return 0
+end
+redis.call('ZADD', KEYS[1], 'XX', ARGV[3], ARGV[1])
+redis.call('HSET', KEYS[2], 'visibleAt', ARGV[3])
+return 1`)
+
+// KEYS: running, job key. ARGV: id, worker id, status, execution id, error, ttl seconds.
+var redisCompleteScript = redis.NewScript(`
+if redis.call('HGET', KEYS[2], 'workerId') ~= ARGV[2] or redis.call('HGET', KEYS[2], 'status') ~= 'running' then
+  return 0
+end
+redis.call('ZREM', KEYS[1], ARGV[1])
+redis.call('HSET', KEYS[2], 'status', ARGV[3], 'executionId', ARGV[4], 'lastError', ARGV[5])
+redis.call('EXPIRE', KEYS[2], ARGV[6])
+return 1`)
+
+// KEYS: pending, running, job key. ARGV: id, ttl seconds.
+var redisCancelScript = redis.NewScript(`
+local status = redis.call('HGET', KEYS[3], 'status')
+if status ~= 'pending' and status ~= 'running' then return 0 end
+redis.call('LREM', KEYS[1], 0, ARGV[1])
+redis.call('ZREM', KEYS[2], ARGV[1])
+redis.call('HSET', KEYS[3], 'status', 'cancelled')
+redis.call('EXPIRE', KEYS[3], ARGV[2])
+return 1`)
+
+func (q *RedisExecutionQueue) jobKey(id int64) string {
+	return q.Prefix + "job:" + strconv.FormatInt(id, 10)
+}
+
+func (q *RedisExecutionQueue) Enqueue(ctx context.Context, j *models.QueuedJob) (int64, error) {
+	id, err := q.Client.Incr(ctx, q.Prefix+"seq").Result()
+	if err != nil {
+		return 0, err
+	}
+	input, _ := json.Marshal(j.Input)
+	now := time.Now()
+	pipe := q.Client.TxPipeline()
+	pipe.HSet(ctx, q.jobKey(id), map[string]interface{}{
+		"workflowId":  j.WorkflowID,
+		"input":       string(input),
+		"source":      j.Source,
+		"status":      models.JobPending,
+		"attempts":    0,
+		"maxAttempts": j.MaxAttempts,
+		"timeoutMs":   j.TimeoutMs,
+		"visibleAt":   now.UnixMilli(),
+		"createdAt":   now.UnixMilli(),
+	})
+	pipe.LPush(ctx, q.Prefix+"pending", id)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (q *RedisExecutionQueue) Claim(ctx context.Context, workerID string, visibility time.Duration) (*models.QueuedJob, error) {
+	now := time.Now()
+	res, err := redisClaimScript.Run(ctx, q.Client, []string{q.Prefix + "pending", q.Prefix + "running"},
+		now.UnixMilli(), now.Add(visibility).UnixMilli(), workerID, q.Prefix+"job:").Text()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	id, err := strconv.ParseInt(res, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid job id %q", res)
+	}
+	return q.Get(ctx, id)
+}
+
+func (q *RedisExecutionQueue) Heartbeat(ctx context.Context, id int64, workerID string, visibility time.Duration) (bool, error) {
+	n, err := redisHeartbeatScript.Run(ctx, q.Client, []string{q.Prefix + "running", q.jobKey(id)},
+		id, workerID, time.Now().Add(visibility).UnixMilli()).Int()
+	return n == 1, err
+}
+
+func (q *RedisExecutionQueue) Complete(ctx context.Context, id int64, workerID string, execID int64, errMsg string) error {
+	status := models.JobCompleted
+	if errMsg != "" {
+		status = models.JobFailed
+	}
+	return redisCompleteScript.Run(ctx, q.Client, []string{q.Prefix + "running", q.jobKey(id)},
+		id, workerID, status, execID, errMsg, int(redisFinishedJobTTL.Seconds())).Err()
+}
+
+func (q *RedisExecutionQueue) Cancel(ctx context.Context, id int64) error {
+	return redisCancelScript.Run(ctx, q.Client, []string{q.Prefix + "pending", q.Prefix + "running", q.jobKey(id)},
+		id, int(redisFinishedJobTTL.Seconds())).Err()
+}
+
+func (q *RedisExecutionQueue) Get(ctx context.Context, id int64) (*models.QueuedJob, error) {
+	h, err := q.Client.HGetAll(ctx, q.jobKey(id)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(h) == 0 {
+		return nil, fmt.Errorf("job %d not found", id)
+	}
+	j := &models.QueuedJob{
+		ID:        id,
+		Source:    h["source"],
+		Status:    h["status"],
+		WorkerID:  h["workerId"],
+		LastError: h["lastError"],
+	}
+	j.WorkflowID, _ = strconv.ParseInt(h["workflowId"], 10, 64)
+	j.Attempts, _ = strconv.Atoi(h["attempts"])
+	j.MaxAttempts, _ = strconv.Atoi(h["maxAttempts"])
+	j.TimeoutMs, _ = strconv.ParseInt(h["timeoutMs"], 10, 64)
+	if ms, err := strconv.ParseInt(h["visibleAt"], 10, 64); err == nil {
+		j.VisibleAt = time.UnixMilli(ms)
+	}
+	if ms, err := strconv.ParseInt(h["createdAt"], 10, 64); err == nil {
+		j.CreatedAt = time.UnixMilli(ms)
+	}
+	if execID, err := strconv.ParseInt(h["executionId"], 10, 64); err == nil && execID != 0 {
+		j.ExecutionID = &execID
+	}
+	if in := h["input"]; in != "" {
+		_ = json.Unmarshal([]byte(in), &j.Input)
+	}
+	return j, nil
+}
+
+// Stats reports pending and running counts; finished jobs expire and are not counted.
+func (q *RedisExecutionQueue) Stats(ctx context.Context) (map[string]int64, error) {
+	pipe := q.Client.Pipeline()
+	pending := pipe.LLen(ctx, q.Prefix+"pending")
+	running := pipe.ZCard(ctx, q.Prefix+"running")
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	return map[string]int64{models.JobPending: pending.Val(), models.JobRunning: running.Val()}, nil
+}
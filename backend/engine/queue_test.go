@@ -0,0 +1,185 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"eflo/backend/models"
+)
+
+// memQueue is an in-memory ExecutionQueue; getErrors makes the next Get calls fail.
+type memQueue struct {
+	mu        sync.Mutex
+	jobs      map[int64]*models.QueuedJob
+	nextID    int64
+	getErrors int
+	gets      int
+}
+
+func newMemQueue() *memQueue {
+	return &memQueue{jobs: map[int64]*models.QueuedJob{}}
+}
+
+func (q *memQueue) Enqueue(ctx context.Context, j *models.QueuedJob) (int64, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.nextID++
+	job := *j
+	job.ID, job.Status = q.nextID, models.JobPending
+	q.jobs[job.ID] = &job
+	return job.ID, nil
+}
+
+func (q *memQueue) Claim(ctx context.Context, workerID string, visibility time.Duration) (*models.QueuedJob, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for id := int64(1); id <= q.nextID; id++ {
+		if j := q.jobs[id]; j != nil && j.Status == models.JobPending {
+			j.Status, j.WorkerID = models.JobRunning, workerID
+			j.Attempts++
+			job := *j
+			return &job, nil
+		}
+	}
+	return nil, nil
+}
+
+func (q *memQueue) Heartbeat(ctx context.Context, id int64, workerID string, visibility time.Duration) (bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	j := q.jobs[id]
+	return j != nil && j.WorkerID == workerID && j.Status == models.JobRunning, nil
+}
+
+func (q *memQueue) Complete(ctx context.Context, id int64, workerID string, execID int64, errMsg string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	j := q.jobs[id]
+	if j == nil || j.WorkerID != workerID || j.Status != models.JobRunning {
+		return nil
+	}
+	j.Status, j.LastError = models.JobCompleted, errMsg
+	if errMsg != "" {
+		j.Status = models.JobFailed
+	}
+	if execID != 0 {
+		j.ExecutionID = &execID
+	}
+	return nil
+}
+
+func (q *memQueue) Cancel(ctx context.Context, id int64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if j := q.jobs[id]; j != nil && (j.Status == models.JobPending || j.Status == models.JobRunning) {
+		j.Status = models.JobCancelled
+	}
+	return nil
+}
+
+func (q *memQueue) Get(ctx context.Context, id int64) (*models.QueuedJob, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.gets++
+	if q.getErrors > 0 {
+		q.getErrors--
+		return nil, errors.New("connection reset")
+	}
+	j := q.jobs[id]
+	if j == nil {
+		return nil, errors.New("not found")
+	}
+	job := *j
+	return &job, nil
+}
+
+func (q *memQueue) Stats(ctx context.Context) (map[string]int64, error) {
+	return nil, nil
+}
+
+func (q *memQueue) status(id int64) string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.jobs[id].Status
+}
+
+// finishLater claims the queued job as a worker would after delay and completes it.
+func (q *memQueue) finishLater(t *testing.T, delay time.Duration, execID int64) {
+	time.AfterFunc(delay, func() {
+		job, _ := q.Claim(context.Background(), "w1", time.Minute)
+		if job == nil {
+			t.Error("no job to claim")
+			return
+		}
+		_ = q.Complete(context.Background(), job.ID, "w1", execID, "")
+	})
+}
+
+func TestExecuteWaitsPastCallerDeadlineForQueuedJob(t *testing.T) {
+	q := newMemQueue()
+	e := &Engine{Queue: q}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// No worker claims the job before the caller's deadline
+	q.finishLater(t, 400*time.Millisecond, 42)
+	execID, err := e.Execute(ctx, &models.Workflow{ID: 7}, nil, "cron")
+	if err != nil || execID != 42 {
+		t.Fatalf("Execute = %d, %v; want 42, nil", execID, err)
+	}
+	if q.jobs[1].TimeoutMs <= 0 || q.jobs[1].TimeoutMs > 100 {
+		t.Errorf("job timeout = %dms, want the caller's remaining 100ms", q.jobs[1].TimeoutMs)
+	}
+}
+
+func TestExecuteCancelsQueuedJobOnStop(t *testing.T) {
+	q := newMemQueue()
+	e := &Engine{Queue: q}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	_, err := e.Execute(ctx, &models.Workflow{ID: 7}, nil, "cron")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Execute error = %v, want context.Canceled", err)
+	}
+	if got := q.status(1); got != models.JobCancelled {
+		t.Errorf("job status = %s, want cancelled", got)
+	}
+}
+
+func TestAwaitJobRetriesFailedChecks(t *testing.T) {
+	q := newMemQueue()
+	q.getErrors = 2
+	e := &Engine{Queue: q}
+	jobID, _ := q.Enqueue(context.Background(), &models.QueuedJob{WorkflowID: 7})
+	q.finishLater(t, 0, 9)
+
+	execID, err := e.awaitJob(context.Background(), jobID)
+	if err != nil || execID != 9 {
+		t.Fatalf("awaitJob = %d, %v; want 9, nil", execID, err)
+	}
+	if q.gets != 3 {
+		t.Errorf("Get called %d times, want 3 (two failures, then the result)", q.gets)
+	}
+}
+
+func TestWorkerGivesUpAfterMaxAttempts(t *testing.T) {
+	q := newMemQueue()
+	jobID, _ := q.Enqueue(context.Background(), &models.QueuedJob{WorkflowID: 7, MaxAttempts: 2})
+	w := NewWorker(&Engine{Queue: q}, nil, q, "w1", 1, time.Minute)
+
+	// Two workers crashed with the job; the third claim is over the limit
+	q.jobs[jobID].Attempts = 2
+	q.jobs[jobID].LastError = "abandoned by worker w0"
+	job, _ := q.Claim(context.Background(), "w1", time.Minute)
+	w.process(job)
+
+	j, _ := q.Get(context.Background(), jobID)
+	if j.Status != models.JobFailed || !strings.Contains(j.LastError, "gave up after abandoned by worker w0") {
+		t.Errorf("job = %s %q, want failed after giving up", j.Status, j.LastError)
+	}
+}
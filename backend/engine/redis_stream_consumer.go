@@ -0,0 +1,560 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"eflo/backend/models"
+	"eflo/backend/repository"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStreamConsumer reads Redis streams through consumer groups and triggers workflow
+// executions. An entry is acknowledged (XACK) only after its execution completes, so entries
+// of a crashed consumer stay pending and are reclaimed with XAUTOCLAIM (at-least-once). An entry
+// delivered MaxDeliveries times without being run is acknowledged and kept as a dead letter.
+type RedisStreamConsumer struct {
+	engine       *Engine
+	workflowRepo *repository.WorkflowRepo
+	configRepo   *repository.NodeConfigRepo
+	triggerRepo  *repository.RedisStreamTriggerRepo
+	mu           sync.Mutex
+	readers      map[int64]*streamRun // triggerID -> running reader
+	wg           sync.WaitGroup
+	active       bool            // started (this instance is the trigger leader)
+	notify       func()          // signals the leader about changes made while inactive
+	runCtx       context.Context // parent of the runs; cancelled when the lease is lost
+}
+
+const (
+	// redisStreamBlock is how long XREADGROUP waits for new entries; it bounds stop latency.
+	redisStreamBlock = 5 * time.Second
+	// RedisStreamMaxConcurrency is the upper bound of a stream trigger's concurrency setting.
+	RedisStreamMaxConcurrency = 100
+	// redisStreamDefaultClaimIdle is the default idle time before a pending entry is reclaimed.
+	redisStreamDefaultClaimIdle = 300
+	// RedisStreamDefaultMaxDeliveries is the default of a stream trigger's MaxDeliveries.
+	RedisStreamDefaultMaxDeliveries = 5
+)
+
+// NewRedisStreamConsumer creates a new Redis stream consumer manager.
+func NewRedisStreamConsumer(
+	eng *Engine,
+	workflowRepo *repository.WorkflowRepo,
+	configRepo *repository.NodeConfigRepo,
+	triggerRepo *repository.RedisStreamTriggerRepo,
+) *RedisStreamConsumer {
+	return &RedisStreamConsumer{
+		engine:       eng,
+		workflowRepo: workflowRepo,
+		configRepo:   configRepo,
+		triggerRepo:  triggerRepo,
+		readers:      make(map[int64]*streamRun),
+	}
+}
+
+// streamRun is a trigger's supervised reader; done is closed once the reader and the executions
+// it started have finished.
+type streamRun struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// SetChangeNotifier sets the callback used when triggers change while this consumer is not
+// running (another instance is the leader).
+func (sc *RedisStreamConsumer) SetChangeNotifier(fn func()) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.notify = fn
+}
+
+// Start loads all enabled stream triggers and begins consuming. Runs are cancelled when ctx is
+// done; their entries stay pending for the next leader.
+func (sc *RedisStreamConsumer) Start(ctx context.Context) error {
+	triggers, err := sc.triggerRepo.ListEnabled()
+	if err != nil {
+		return fmt.Errorf("redis stream consumer: failed to list triggers: %w", err)
+	}
+	sc.mu.Lock()
+	sc.active = true
+	sc.runCtx = ctx
+	sc.mu.Unlock()
+
+	for _, t := range triggers {
+		if err := sc.startTrigger(t); err != nil {
+			log.Printf("[RedisStreamConsumer] Failed to start trigger %d: %v", t.ID, err)
+		}
+	}
+
+	log.Printf("[RedisStreamConsumer] Started with %d active stream triggers", len(triggers))
+	return nil
+}
+
+// Stop cancels all consumers and waits for in-flight executions to be acknowledged.
+func (sc *RedisStreamConsumer) Stop() {
+	sc.stopAll()
+	sc.mu.Lock()
+	sc.active = false
+	sc.mu.Unlock()
+	log.Println("[RedisStreamConsumer] Stopped")
+}
+
+// Reload restarts all consumers from the DB (after another instance changed triggers).
+func (sc *RedisStreamConsumer) Reload() error {
+	if !sc.isActive() {
+		return nil
+	}
+	sc.stopAll()
+	return sc.Start(sc.runContext())
+}
+
+func (sc *RedisStreamConsumer) stopAll() {
+	sc.mu.Lock()
+	for id, run := range sc.readers {
+		run.cancel()
+		delete(sc.readers, id)
+	}
+	sc.mu.Unlock()
+	sc.wg.Wait()
+}
+
+// runContext returns the parent of the runs, set by Start.
+func (sc *RedisStreamConsumer) runContext() context.Context {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if sc.runCtx == nil {
+		return context.Background()
+	}
+	return sc.runCtx
+}
+
+// isActive reports whether the consumer is running; if not, the leader is signalled instead.
+func (sc *RedisStreamConsumer) isActive() bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if !sc.active && sc.notify != nil {
+		go sc.notify()
+	}
+	return sc.active
+}
+
+// AddTrigger starts (or restarts) consuming for a stream trigger.
+func (sc *RedisStreamConsumer) AddTrigger(t *models.RedisStreamTrigger) error {
+	if !sc.isActive() {
+		return nil
+	}
+	return sc.startTrigger(t)
+}
+
+// RemoveTrigger stops a running stream consumer and waits until its in-flight executions have
+// finished, so a restarted reader recovering this consumer's pending entries doesn't run them
+// a second time. Entries left unacknowledged are reclaimed once the trigger runs again.
+func (sc *RedisStreamConsumer) RemoveTrigger(triggerID int64) {
+	sc.mu.Lock()
+	run := sc.readers[triggerID]
+	delete(sc.readers, triggerID)
+	if !sc.active && sc.notify != nil {
+		go sc.notify()
+	}
+	sc.mu.Unlock()
+	if run != nil {
+		run.cancel()
+		<-run.done
+	}
+}
+
+func (sc *RedisStreamConsumer) startTrigger(t *models.RedisStreamTrigger) error {
+	opts, err := redisConfigOptions(sc.configRepo, t.ConfigID)
+	if err != nil {
+		return err
+	}
+
+	sc.RemoveTrigger(t.ID)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	run := &streamRun{cancel: cancel, done: make(chan struct{})}
+	sc.mu.Lock()
+	sc.readers[t.ID] = run
+	sc.mu.Unlock()
+
+	trigger := *t
+	sc.wg.Add(1)
+	go func() {
+		defer sc.wg.Done()
+		defer close(run.done)
+		sc.supervise(ctx, &trigger, opts)
+	}()
+	return nil
+}
+
+// supervise keeps a trigger consuming until ctx is cancelled, reconnecting with exponential
+// backoff (reset after each successful connect).
+func (sc *RedisStreamConsumer) supervise(ctx context.Context, t *models.RedisStreamTrigger, opts *redis.Options) {
+	backoff := redisReconnectMin
+	for {
+		connected, err := sc.consume(ctx, t, opts)
+		if ctx.Err() != nil {
+			log.Printf("[RedisStreamConsumer] Stopped trigger %d", t.ID)
+			return
+		}
+		if connected {
+			backoff = redisReconnectMin
+		}
+
+		wait := backoff + time.Duration(rand.Int63n(int64(backoff)/5+1))
+		log.Printf("[RedisStreamConsumer] Trigger %d disconnected: %v; reconnecting in %s", t.ID, err, wait.Round(time.Millisecond))
+		select {
+		case <-ctx.Done():
+			log.Printf("[RedisStreamConsumer] Stopped trigger %d", t.ID)
+			return
+		case <-time.After(wait):
+		}
+		if backoff *= 2; backoff > redisReconnectMax {
+			backoff = redisReconnectMax
+		}
+	}
+}
+
+// streamReader is one connected consumer: it hands entries to at most cap(slots) concurrent
+// executions and remembers which entries are in flight so reclaiming skips them.
+type streamReader struct {
+	sc       *RedisStreamConsumer
+	t        *models.RedisStreamTrigger
+	rdb      *redis.Client
+	consumer string
+	slots    chan struct{}
+	running  sync.WaitGroup
+
+	mu       sync.Mutex
+	inFlight map[string]bool
+}
+
+// consume connects, ensures the consumer group exists, recovers this consumer's own pending
+// entries and then reads new entries until the connection fails or ctx is cancelled.
+func (sc *RedisStreamConsumer) consume(ctx context.Context, t *models.RedisStreamTrigger, opts *redis.Options) (connected bool, err error) {
+	rdb := redis.NewClient(opts)
+	defer rdb.Close()
+
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		return false, fmt.Errorf("redis connection failed: %w", err)
+	}
+	startID := t.StartID
+	if startID == "" {
+		startID = "$"
+	}
+	if err := rdb.XGroupCreateMkStream(ctx, t.Stream, t.Group, startID).Err(); err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return false, fmt.Errorf("create consumer group: %w", err)
+	}
+
+	r := &streamReader{
+		sc:       sc,
+		t:        t,
+		rdb:      rdb,
+		consumer: t.Consumer,
+		slots:    make(chan struct{}, clampConcurrency(t.Concurrency)),
+		inFlight: make(map[string]bool),
+	}
+	if r.consumer == "" {
+		r.consumer = InstanceID()
+	}
+	// Acknowledgements need the client, so in-flight executions finish before it is closed
+	defer r.running.Wait()
+
+	log.Printf("[RedisStreamConsumer] XREADGROUP %q group %q as %q for workflow %d (trigger %d)",
+		t.Stream, t.Group, r.consumer, t.WorkflowID, t.ID)
+
+	// Entries delivered to this consumer before a restart and never acknowledged
+	if err := r.recoverPending(ctx); err != nil {
+		return true, err
+	}
+
+	claimIdle := time.Duration(t.ClaimIdleSec) * time.Second
+	if claimIdle <= 0 {
+		claimIdle = redisStreamDefaultClaimIdle * time.Second
+	}
+	claimEvery := claimIdle / 2
+	if claimEvery < redisStreamBlock {
+		claimEvery = redisStreamBlock
+	}
+	claimCursor := "0-0"
+	lastClaim := time.Now()
+
+	for {
+		n, err := r.acquire(ctx)
+		if err != nil {
+			return true, err
+		}
+
+		if time.Since(lastClaim) >= claimEvery {
+			lastClaim = time.Now()
+			msgs, next, err := rdb.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+				Stream:   t.Stream,
+				Group:    t.Group,
+				Consumer: r.consumer,
+				MinIdle:  claimIdle,
+				Start:    claimCursor,
+				Count:    int64(n),
+			}).Result()
+			if err != nil {
+				r.release(n)
+				return true, fmt.Errorf("XAUTOCLAIM: %w", err)
+			}
+			claimCursor = next
+			if len(msgs) > 0 {
+				log.Printf("[RedisStreamConsumer] Trigger %d reclaimed %d idle entries", t.ID, len(msgs))
+				r.dispatch(msgs, n, true)
+				continue
+			}
+		}
+
+		streams, err := rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    t.Group,
+			Consumer: r.consumer,
+			Streams:  []string{t.Stream, ">"},
+			Count:    int64(n),
+			Block:    redisStreamBlock,
+		}).Result()
+		if err != nil {
+			r.release(n)
+			if errors.Is(err, redis.Nil) {
+				continue
+			}
+			if ctx.Err() != nil {
+				return true, ctx.Err()
+			}
+			return true, fmt.Errorf("XREADGROUP: %w", err)
+		}
+		var msgs []redis.XMessage
+		for _, s := range streams {
+			msgs = append(msgs, s.Messages...)
+		}
+		r.dispatch(msgs, n, false)
+	}
+}
+
+// recoverPending replays the consumer's own pending entries list from the beginning.
+func (r *streamReader) recoverPending(ctx context.Context) error {
+	cursor := "0"
+	for {
+		n, err := r.acquire(ctx)
+		if err != nil {
+			return err
+		}
+		streams, err := r.rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    r.t.Group,
+			Consumer: r.consumer,
+			Streams:  []string{r.t.Stream, cursor},
+			Count:    int64(n),
+		}).Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			r.release(n)
+			return fmt.Errorf("read pending entries: %w", err)
+		}
+		var msgs []redis.XMessage
+		for _, s := range streams {
+			msgs = append(msgs, s.Messages...)
+		}
+		if len(msgs) == 0 {
+			r.release(n)
+			return nil
+		}
+		cursor = msgs[len(msgs)-1].ID
+		log.Printf("[RedisStreamConsumer] Trigger %d redelivering %d pending entries", r.t.ID, len(msgs))
+		r.dispatch(msgs, n, true)
+	}
+}
+
+// acquire waits for at least one free execution slot and takes as many as are free.
+func (r *streamReader) acquire(ctx context.Context) (int, error) {
+	select {
+	case r.slots <- struct{}{}:
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+	n := 1
+	for n < cap(r.slots) {
+		select {
+		case r.slots <- struct{}{}:
+			n++
+		default:
+			return n, nil
+		}
+	}
+	return n, nil
+}
+
+func (r *streamReader) release(n int) {
+	for i := 0; i < n; i++ {
+		<-r.slots
+	}
+}
+
+// dispatch starts an execution per entry, each holding one of the n acquired slots; unused
+// slots are released. redelivered entries (pending or reclaimed) have their delivery count
+// looked up; new ones were delivered once.
+func (r *streamReader) dispatch(msgs []redis.XMessage, n int, redelivered bool) {
+	for _, msg := range msgs {
+		// Entries deleted from the stream come back from XAUTOCLAIM without values
+		if msg.Values == nil {
+			r.ack(msg.ID)
+			continue
+		}
+		// Entries still executing here may be reclaimed by ourselves after a long run
+		if n == 0 || !r.begin(msg.ID) {
+			continue
+		}
+		n--
+		r.running.Add(1)
+		go func(msg redis.XMessage) {
+			defer r.running.Done()
+			defer r.release(1)
+			defer r.done(msg.ID)
+			deliveries := int64(1)
+			if redelivered {
+				deliveries = r.deliveries(msg.ID)
+			}
+			if r.sc.handleEntry(r.t, r.consumer, msg, deliveries) {
+				r.ack(msg.ID)
+			}
+		}(msg)
+	}
+	r.release(n)
+}
+
+func (r *streamReader) begin(id string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.inFlight[id] {
+		return false
+	}
+	r.inFlight[id] = true
+	return true
+}
+
+func (r *streamReader) done(id string) {
+	r.mu.Lock()
+	delete(r.inFlight, id)
+	r.mu.Unlock()
+}
+
+// deliveries returns how often the pending entry id was delivered (XPENDING), or 0 if unknown.
+func (r *streamReader) deliveries(id string) int64 {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	pending, err := r.rdb.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: r.t.Stream,
+		Group:  r.t.Group,
+		Start:  id,
+		End:    id,
+		Count:  1,
+	}).Result()
+	if err != nil {
+		log.Printf("[RedisStreamConsumer] Failed to read delivery count of %s on %q (trigger %d): %v", id, r.t.Stream, r.t.ID, err)
+		return 0
+	}
+	if len(pending) == 0 {
+		return 0
+	}
+	return pending[0].RetryCount
+}
+
+func (r *streamReader) ack(id string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := r.rdb.XAck(ctx, r.t.Stream, r.t.Group, id).Err(); err != nil {
+		log.Printf("[RedisStreamConsumer] Failed to ack %s on %q (trigger %d): %v", id, r.t.Stream, r.t.ID, err)
+	}
+}
+
+// handleEntry runs the workflow for one stream entry, delivered deliveries times (0 if unknown).
+// It reports whether the entry should be acknowledged: true once an execution ran (whatever its
+// outcome), false when the workflow could not be run so the entry stays pending for redelivery,
+// until it was delivered MaxDeliveries times and becomes a dead letter instead.
+func (sc *RedisStreamConsumer) handleEntry(t *models.RedisStreamTrigger, consumer string, msg redis.XMessage, deliveries int64) bool {
+	log.Printf("[RedisStreamConsumer] Entry %s on %q (trigger %d, delivery %d)", msg.ID, t.Stream, t.ID, deliveries)
+
+	// Redeliveries were counted the first time
+	if deliveries == 1 {
+		_ = sc.triggerRepo.IncrementMsgCount(t.ID)
+	}
+
+	input := map[string]interface{}{
+		"message":         msg.Values,
+		"id":              msg.ID,
+		"stream":          t.Stream,
+		"group":           t.Group,
+		"consumer":        consumer,
+		"streamTriggerId": t.ID,
+		"receivedAt":      time.Now().Format(time.RFC3339),
+	}
+	maxDeliveries := int64(t.MaxDeliveries)
+	if maxDeliveries <= 0 {
+		maxDeliveries = RedisStreamDefaultMaxDeliveries
+	}
+	// giveUp reports whether an entry that could not be run is acknowledged as a dead letter.
+	giveUp := func(err error) bool {
+		if deliveries < maxDeliveries {
+			return false
+		}
+		log.Printf("[RedisStreamConsumer] Giving up on entry %s on %q after %d deliveries (trigger %d)", msg.ID, t.Stream, deliveries, t.ID)
+		sc.engine.RecordDeadLetter(models.TriggerRedisStream, t.ID, t.WorkflowID, 0, input,
+			fmt.Errorf("not run after %d deliveries: %w", deliveries, err))
+		return true
+	}
+
+	// Delivered more often than allowed without being acknowledged: earlier runs never finished
+	// (e.g. the instance crashed during them), so don't start another.
+	if deliveries > maxDeliveries {
+		return giveUp(errors.New("earlier deliveries were not acknowledged"))
+	}
+
+	wf, err := sc.workflowRepo.GetByID(t.WorkflowID)
+	if err != nil {
+		log.Printf("[RedisStreamConsumer] Failed to load workflow %d: %v", t.WorkflowID, err)
+		return giveUp(fmt.Errorf("load workflow %d: %w", t.WorkflowID, err))
+	}
+
+	timeout := t.ExecTimeoutSec
+	if timeout <= 0 {
+		timeout = models.DefaultTriggerExecTimeoutSec
+	}
+	parent := sc.runContext()
+	ctx, cancel := context.WithTimeout(parent, time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	execID, err := sc.engine.Execute(ctx, wf, input, "redis_stream")
+
+	if err != nil {
+		log.Printf("[RedisStreamConsumer] Workflow %d execution failed (exec %d): %v", t.WorkflowID, execID, err)
+		if parent.Err() != nil {
+			// Cancelled because the lease was lost: the next leader reclaims the entry
+			return false
+		}
+		if execID == 0 {
+			return giveUp(err)
+		}
+		// The entry is acknowledged, so the failed event lives on as a dead letter
+		sc.engine.RecordDeadLetter(models.TriggerRedisStream, t.ID, t.WorkflowID, execID, input, err)
+		return true
+	}
+	log.Printf("[RedisStreamConsumer] Workflow %d execution completed (exec %d)", t.WorkflowID, execID)
+	return true
+}
+
+// clampConcurrency applies the default (1) and upper bound to a trigger's concurrency.
+func clampConcurrency(n int) int {
+	if n <= 0 {
+		return 1
+	}
+	if n > RedisStreamMaxConcurrency {
+		return RedisStreamMaxConcurrency
+	}
+	return n
+}
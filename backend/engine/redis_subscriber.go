@@ -2,12 +2,16 @@ package engine
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"math/rand"
+	"net"
 	"strconv"
 	"sync"
 	"time"
 
+	"eflo/backend/models"
 	"eflo/backend/repository"
 
 	"github.com/redis/go-redis/v9"
@@ -22,8 +26,21 @@ type RedisSubscriber struct {
 	mu           sync.Mutex
 	cancels      map[int64]context.CancelFunc // subscriptionID -> cancel func
 	wg           sync.WaitGroup
+	active       bool            // started (this instance is the trigger leader)
+	notify       func()          // signals the leader about changes made while inactive
+	runCtx       context.Context // parent of the runs; cancelled when the lease is lost
+
+	statusMu sync.Mutex
+	statuses map[int64]*models.RedisSubscriptionStatus // subscriptionID -> listener status
 }
 
+const (
+	redisReconnectMin = time.Second
+	redisReconnectMax = time.Minute
+	// redisHealthCheckInterval is how long a subscription may be silent before it is pinged.
+	redisHealthCheckInterval = 30 * time.Second
+)
+
 // NewRedisSubscriber creates a new Redis subscriber manager.
 func NewRedisSubscriber(
 	eng *Engine,
@@ -37,18 +54,32 @@ func NewRedisSubscriber(
 		configRepo:   configRepo,
 		subRepo:      subRepo,
 		cancels:      make(map[int64]context.CancelFunc),
+		statuses:     make(map[int64]*models.RedisSubscriptionStatus),
 	}
 }
 
-// Start loads all enabled subscriptions and begins listening.
-func (rs *RedisSubscriber) Start() error {
+// SetChangeNotifier sets the callback used when subscriptions change while this subscriber is
+// not running (another instance is the leader).
+func (rs *RedisSubscriber) SetChangeNotifier(fn func()) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.notify = fn
+}
+
+// Start loads all enabled subscriptions and begins listening. Runs are cancelled when ctx is
+// done.
+func (rs *RedisSubscriber) Start(ctx context.Context) error {
 	subs, err := rs.subRepo.ListEnabled()
 	if err != nil {
 		return fmt.Errorf("redis subscriber: failed to list subs: %w", err)
 	}
+	rs.mu.Lock()
+	rs.active = true
+	rs.runCtx = ctx
+	rs.mu.Unlock()
 
 	for _, sub := range subs {
-		if err := rs.startSubscription(sub.ID, sub.WorkflowID, sub.ConfigID, sub.Channel, sub.IsPattern); err != nil {
+		if err := rs.startSubscription(sub.ID, sub.WorkflowID, sub.ConfigID, sub.Channel, sub.IsPattern, sub.TriggerLimits); err != nil {
 			log.Printf("[RedisSubscriber] Failed to start sub %d: %v", sub.ID, err)
 		}
 	}
@@ -59,6 +90,23 @@ func (rs *RedisSubscriber) Start() error {
 
 // Stop cancels all active subscriptions and waits for goroutines to finish.
 func (rs *RedisSubscriber) Stop() {
+	rs.stopAll()
+	rs.mu.Lock()
+	rs.active = false
+	rs.mu.Unlock()
+	log.Println("[RedisSubscriber] Stopped")
+}
+
+// Reload restarts all listeners from the DB (after another instance changed subscriptions).
+func (rs *RedisSubscriber) Reload() error {
+	if !rs.isActive() {
+		return nil
+	}
+	rs.stopAll()
+	return rs.Start(rs.runContext())
+}
+
+func (rs *RedisSubscriber) stopAll() {
 	rs.mu.Lock()
 	for id, cancel := range rs.cancels {
 		cancel()
@@ -66,12 +114,34 @@ func (rs *RedisSubscriber) Stop() {
 	}
 	rs.mu.Unlock()
 	rs.wg.Wait()
-	log.Println("[RedisSubscriber] Stopped")
+}
+
+// runContext returns the parent of the runs, set by Start.
+func (rs *RedisSubscriber) runContext() context.Context {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if rs.runCtx == nil {
+		return context.Background()
+	}
+	return rs.runCtx
+}
+
+// isActive reports whether the subscriber is running; if not, the leader is signalled instead.
+func (rs *RedisSubscriber) isActive() bool {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if !rs.active && rs.notify != nil {
+		go rs.notify()
+	}
+	return rs.active
 }
 
 // AddSubscription starts listening on a new subscription.
-func (rs *RedisSubscriber) AddSubscription(subID, workflowID, configID int64, channel string, isPattern bool) error {
-	return rs.startSubscription(subID, workflowID, configID, channel, isPattern)
+func (rs *RedisSubscriber) AddSubscription(subID, workflowID, configID int64, channel string, isPattern bool, limits models.TriggerLimits) error {
+	if !rs.isActive() {
+		return nil
+	}
+	return rs.startSubscription(subID, workflowID, configID, channel, isPattern, limits)
 }
 
 // RemoveSubscription stops a running subscription listener.
@@ -82,16 +152,54 @@ func (rs *RedisSubscriber) RemoveSubscription(subID int64) {
 		cancel()
 		delete(rs.cancels, subID)
 	}
+	if !rs.active && rs.notify != nil {
+		go rs.notify()
+	}
 }
 
-func (rs *RedisSubscriber) startSubscription(subID, workflowID, configID int64, channel string, isPattern bool) error {
-	// Resolve Redis config
-	cfg, err := rs.configRepo.GetByID(configID)
+// startSubscription validates the Redis config and starts a supervised listener. Connection
+// problems do not fail the call: the listener keeps retrying with exponential backoff.
+// Messages are executed by a dispatcher bounded by limits, so slow runs do not stall reading.
+func (rs *RedisSubscriber) startSubscription(subID, workflowID, configID int64, channel string, isPattern bool, limits models.TriggerLimits) error {
+	opts, err := redisConfigOptions(rs.configRepo, configID)
 	if err != nil {
-		return fmt.Errorf("failed to resolve config %d: %w", configID, err)
+		return err
+	}
+
+	// Remove existing sub if running
+	rs.RemoveSubscription(subID)
+
+	subCtx, cancel := context.WithCancel(context.Background())
+
+	rs.mu.Lock()
+	rs.cancels[subID] = cancel
+	rs.mu.Unlock()
+
+	rs.setStatus(subID, func(st *models.RedisSubscriptionStatus) {
+		st.State = models.RedisSubConnecting
+		st.ReconnectCount = 0
+		st.NextRetryAt = nil
+	})
+
+	d := newTriggerDispatcher(rs.runContext(), fmt.Sprintf("[RedisSubscriber] Sub %d", subID), limits)
+	rs.wg.Add(1)
+	go func() {
+		defer rs.wg.Done()
+		defer d.Close()
+		rs.supervise(subCtx, subID, workflowID, channel, isPattern, opts, d)
+	}()
+
+	return nil
+}
+
+// redisConfigOptions resolves a Redis connection from a node_configs entry of type redis.
+func redisConfigOptions(configRepo *repository.NodeConfigRepo, configID int64) (*redis.Options, error) {
+	cfg, err := configRepo.GetByID(configID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve config %d: %w", configID, err)
 	}
 	if cfg.Type != "redis" {
-		return fmt.Errorf("config %d is not redis type (got %s)", configID, cfg.Type)
+		return nil, fmt.Errorf("config %d is not redis type (got %s)", configID, cfg.Type)
 	}
 
 	host, _ := cfg.Config["host"].(string)
@@ -114,97 +222,195 @@ func (rs *RedisSubscriber) startSubscription(subID, workflowID, configID int64,
 		}
 	}
 
-	rdb := redis.NewClient(&redis.Options{
+	return &redis.Options{
 		Addr:     host + ":" + port,
 		Password: password,
 		DB:       dbNum,
-	})
+	}, nil
+}
 
-	// Test connection
-	ctx := context.Background()
-	if err := rdb.Ping(ctx).Err(); err != nil {
-		rdb.Close()
-		return fmt.Errorf("redis connection failed: %w", err)
+// supervise keeps a subscription listening until ctx is cancelled, reconnecting with
+// exponential backoff (reset after each successful subscribe).
+func (rs *RedisSubscriber) supervise(ctx context.Context, subID, workflowID int64, channel string, isPattern bool, opts *redis.Options, d *triggerDispatcher) {
+	backoff := redisReconnectMin
+	for {
+		connected, err := rs.listen(ctx, subID, workflowID, channel, isPattern, opts, d)
+		if ctx.Err() != nil {
+			rs.stopped(subID)
+			return
+		}
+		if connected {
+			backoff = redisReconnectMin
+		}
+
+		// Up to 20% jitter so many subscriptions do not reconnect in lockstep
+		wait := backoff + time.Duration(rand.Int63n(int64(backoff)/5+1))
+		now := time.Now()
+		retryAt := now.Add(wait)
+		rs.setStatus(subID, func(st *models.RedisSubscriptionStatus) {
+			st.State = models.RedisSubReconnecting
+			st.LastError = err.Error()
+			st.LastErrorAt = &now
+			st.ReconnectCount++
+			st.NextRetryAt = &retryAt
+		})
+		log.Printf("[RedisSubscriber] Sub %d disconnected: %v; reconnecting in %s", subID, err, wait.Round(time.Millisecond))
+
+		select {
+		case <-ctx.Done():
+			rs.stopped(subID)
+			return
+		case <-time.After(wait):
+		}
+		if backoff *= 2; backoff > redisReconnectMax {
+			backoff = redisReconnectMax
+		}
+		rs.setStatus(subID, func(st *models.RedisSubscriptionStatus) {
+			st.State = models.RedisSubConnecting
+			st.NextRetryAt = nil
+		})
 	}
+}
 
-	// Remove existing sub if running
-	rs.RemoveSubscription(subID)
+func (rs *RedisSubscriber) stopped(subID int64) {
+	rs.setStatus(subID, func(st *models.RedisSubscriptionStatus) {
+		st.State = models.RedisSubStopped
+		st.NextRetryAt = nil
+	})
+	log.Printf("[RedisSubscriber] Stopped sub %d", subID)
+}
 
-	subCtx, cancel := context.WithCancel(context.Background())
+// listen connects, subscribes and handles messages until the connection fails or ctx is
+// cancelled. connected reports whether the subscription was established.
+func (rs *RedisSubscriber) listen(ctx context.Context, subID, workflowID int64, channel string, isPattern bool, opts *redis.Options, d *triggerDispatcher) (connected bool, err error) {
+	rdb := redis.NewClient(opts)
+	defer rdb.Close()
 
-	rs.mu.Lock()
-	rs.cancels[subID] = cancel
-	rs.mu.Unlock()
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		return false, fmt.Errorf("redis connection failed: %w", err)
+	}
 
-	rs.wg.Add(1)
+	var pubsub *redis.PubSub
+	if isPattern {
+		pubsub = rdb.PSubscribe(ctx, channel)
+	} else {
+		pubsub = rdb.Subscribe(ctx, channel)
+	}
+	defer pubsub.Close()
+	// Unblock a pending receive as soon as the subscription is stopped
+	stop := context.AfterFunc(ctx, func() { _ = pubsub.Close() })
+	defer stop()
 
-	go func() {
-		defer rs.wg.Done()
-		defer rdb.Close()
+	if _, err := pubsub.Receive(ctx); err != nil {
+		return false, fmt.Errorf("subscribe failed: %w", err)
+	}
 
-		var pubsub *redis.PubSub
-		if isPattern {
-			pubsub = rdb.PSubscribe(subCtx, channel)
-		} else {
-			pubsub = rdb.Subscribe(subCtx, channel)
-		}
-		defer pubsub.Close()
+	mode := "SUBSCRIBE"
+	if isPattern {
+		mode = "PSUBSCRIBE"
+	}
+	log.Printf("[RedisSubscriber] %s %q for workflow %d (sub %d)", mode, channel, workflowID, subID)
+	now := time.Now()
+	rs.setStatus(subID, func(st *models.RedisSubscriptionStatus) {
+		st.State = models.RedisSubConnected
+		st.ConnectedAt = &now
+	})
 
-		mode := "SUBSCRIBE"
-		if isPattern {
-			mode = "PSUBSCRIBE"
-		}
-		log.Printf("[RedisSubscriber] %s %q for workflow %d (sub %d)", mode, channel, workflowID, subID)
-
-		ch := pubsub.Channel()
-		for {
-			select {
-			case <-subCtx.Done():
-				log.Printf("[RedisSubscriber] Stopped sub %d", subID)
-				return
-			case msg, ok := <-ch:
-				if !ok {
-					log.Printf("[RedisSubscriber] Channel closed for sub %d, reconnecting...", subID)
-					return
+	// A quiet connection is probed with PING; no reply within another interval means it is dead
+	awaitingPong := false
+	for {
+		msg, err := pubsub.ReceiveTimeout(ctx, redisHealthCheckInterval)
+		if err != nil {
+			if ctx.Err() != nil {
+				return true, ctx.Err()
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				if awaitingPong {
+					return true, fmt.Errorf("no reply to health check within %s", redisHealthCheckInterval)
 				}
-				rs.handleMessage(subID, workflowID, channel, msg)
+				if err := pubsub.Ping(ctx); err != nil {
+					return true, fmt.Errorf("health check failed: %w", err)
+				}
+				awaitingPong = true
+				continue
 			}
+			return true, err
 		}
-	}()
+		awaitingPong = false
+		if m, ok := msg.(*redis.Message); ok {
+			err := d.Submit(ctx, func(execCtx context.Context) {
+				rs.handleMessage(execCtx, subID, workflowID, m)
+			})
+			if errors.Is(err, ErrTriggerOverflow) {
+				log.Printf("[RedisSubscriber] Sub %d buffer full, rejected message on %q", subID, m.Channel)
+			}
+		}
+	}
+}
 
-	return nil
+// setStatus updates a subscription's status in memory and in the DB.
+func (rs *RedisSubscriber) setStatus(subID int64, update func(st *models.RedisSubscriptionStatus)) {
+	rs.statusMu.Lock()
+	st, ok := rs.statuses[subID]
+	if !ok {
+		st = &models.RedisSubscriptionStatus{SubscriptionID: subID, Instance: InstanceID()}
+		rs.statuses[subID] = st
+	}
+	update(st)
+	st.UpdatedAt = time.Now()
+	snapshot := *st
+	rs.statusMu.Unlock()
+
+	if err := rs.subRepo.SaveStatus(&snapshot); err != nil {
+		log.Printf("[RedisSubscriber] Failed to store status for sub %d: %v", subID, err)
+	}
 }
 
-func (rs *RedisSubscriber) handleMessage(subID, workflowID int64, channel string, msg *redis.Message) {
+// Status returns the listener status of a subscription running on this instance.
+func (rs *RedisSubscriber) Status(subID int64) (*models.RedisSubscriptionStatus, bool) {
+	rs.statusMu.Lock()
+	defer rs.statusMu.Unlock()
+	st, ok := rs.statuses[subID]
+	if !ok {
+		return nil, false
+	}
+	snapshot := *st
+	return &snapshot, true
+}
+
+// handleMessage runs the workflow for one message; ctx carries the execution timeout.
+func (rs *RedisSubscriber) handleMessage(ctx context.Context, subID, workflowID int64, msg *redis.Message) {
 	log.Printf("[RedisSubscriber] Message on %q (sub %d): %s", msg.Channel, subID, truncate(msg.Payload, 100))
 
 	// Update stats
 	_ = rs.subRepo.IncrementMsgCount(subID)
 
-	// Load workflow
-	wf, err := rs.workflowRepo.GetByID(workflowID)
-	if err != nil {
-		log.Printf("[RedisSubscriber] Failed to load workflow %d: %v", workflowID, err)
-		return
-	}
-
 	// Inject the message as input to the start node
 	// We'll do this by modifying the engine context — the redis_subscribe node
 	// will receive this data through its input parameter
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-	defer cancel()
-
-	// Run the workflow with message data injected
-	execID, err := rs.engine.RunWorkflowWithInput(ctx, wf, map[string]interface{}{
+	input := map[string]interface{}{
 		"message":        msg.Payload,
 		"channel":        msg.Channel,
 		"pattern":        msg.Pattern,
 		"subscriptionId": subID,
 		"receivedAt":     time.Now().Format(time.RFC3339),
-	}, nil, nil)
+	}
+
+	// Load workflow
+	wf, err := rs.workflowRepo.GetByID(workflowID)
+	if err != nil {
+		log.Printf("[RedisSubscriber] Failed to load workflow %d: %v", workflowID, err)
+		rs.engine.RecordDeadLetter(models.TriggerRedis, subID, workflowID, 0, input, err)
+		return
+	}
+
+	// Run the workflow with message data injected
+	execID, err := rs.engine.Execute(ctx, wf, input, "redis")
 
 	if err != nil {
 		log.Printf("[RedisSubscriber] Workflow %d execution failed (exec %d): %v", workflowID, execID, err)
+		rs.engine.RecordDeadLetter(models.TriggerRedis, subID, workflowID, execID, input, err)
 	} else {
 		log.Printf("[RedisSubscriber] Workflow %d execution completed (exec %d)", workflowID, execID)
 	}
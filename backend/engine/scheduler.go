@@ -2,23 +2,71 @@ package engine
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"math/rand"
 	"sync"
 	"time"
 
+	"eflo/backend/models"
 	"eflo/backend/repository"
 
 	"github.com/robfig/cron/v3"
 )
 
-// Scheduler manages cron-based workflow executions.
+// Schedule events recorded in cron_schedule_events. Regular runs are not recorded; only
+// decisions that deviate from "one tick, one run".
+const (
+	scheduleEventSkipped     = "skipped_overlap"    // tick dropped: previous run still going (skip, or queue already full)
+	scheduleEventQueued      = "queued"             // tick deferred until the previous run finishes
+	scheduleEventCancelled   = "cancelled_previous" // running execution cancelled for a new tick
+	scheduleEventMisfireRun  = "misfire_run"        // catch-up run for a tick missed while down
+	scheduleEventMisfireSkip = "misfire_skipped"    // ticks missed while down that were not run
+)
+
+// maxMisfireScan bounds how many missed ticks are counted on startup (e.g. a per-second
+// schedule after a long outage).
+const maxMisfireScan = 100000
+
+// The parts of the repositories the scheduler uses (see repository.CronScheduleRepo and
+// ScheduleCalendarRepo).
+type (
+	cronScheduleStore interface {
+		ListEnabled() ([]*models.CronSchedule, error)
+		UpdateNextRun(id int64, nextRunAt interface{}) error
+		UpdateLastRun(id int64, lastRunAt, nextRunAt interface{}) error
+		RecordEvent(e *models.CronScheduleEvent) error
+	}
+	scheduleCalendarGetter interface {
+		GetByIDs(ids []int64) ([]*models.ScheduleCalendar, error)
+	}
+)
+
+// Scheduler manages cron-based workflow executions. Each schedule is evaluated in its own
+// timezone (see ParseCronSchedule), skips its exclusion calendars and applies its overlap,
+// misfire and jitter settings.
 type Scheduler struct {
 	engine       *Engine
-	workflowRepo *repository.WorkflowRepo
-	cronRepo     *repository.CronScheduleRepo
+	workflowRepo workflowGetter
+	cronRepo     cronScheduleStore
+	calendarRepo scheduleCalendarGetter // nil: calendars are not applied
+	now          func() time.Time
 	cron         *cron.Cron
 	mu           sync.Mutex
 	entryMap     map[int64]cron.EntryID // scheduleID -> cron entryID
+	active       bool                   // started (this instance is the trigger leader)
+	notify       func()                 // signals the leader about changes made while inactive
+	runCtx       context.Context        // parent of the runs; cancelled when the lease is lost
+
+	stateMu sync.Mutex
+	states  map[int64]*scheduleState // scheduleID -> in-flight runs
+}
+
+// scheduleState tracks the in-flight runs of one schedule for the overlap policy.
+type scheduleState struct {
+	seq    int64
+	runs   map[int64]context.CancelFunc // run sequence -> cancel
+	queued *time.Time                   // tick waiting for the current run (overlap "queue")
 }
 
 // NewScheduler creates a new cron scheduler.
@@ -26,25 +74,48 @@ func NewScheduler(
 	eng *Engine,
 	workflowRepo *repository.WorkflowRepo,
 	cronRepo *repository.CronScheduleRepo,
+	calendarRepo *repository.ScheduleCalendarRepo,
 ) *Scheduler {
-	return &Scheduler{
+	s := &Scheduler{
 		engine:       eng,
 		workflowRepo: workflowRepo,
 		cronRepo:     cronRepo,
-		cron:         cron.New(cron.WithParser(cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor))),
+		now:          time.Now,
+		cron:         cron.New(cron.WithParser(cronParser), cron.WithLocation(time.UTC)),
 		entryMap:     make(map[int64]cron.EntryID),
+		states:       make(map[int64]*scheduleState),
 	}
+	if calendarRepo != nil {
+		s.calendarRepo = calendarRepo
+	}
+	return s
+}
+
+// SetChangeNotifier sets the callback used when schedules change while this scheduler is not
+// running (another instance is the leader), so the leader can reload them.
+func (s *Scheduler) SetChangeNotifier(fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notify = fn
 }
 
-// Start loads all enabled schedules from DB and starts the cron runner.
-func (s *Scheduler) Start() error {
+// Start loads all enabled schedules from DB, handles ticks missed while the server was down
+// (per misfire policy) and starts the cron runner. Runs are cancelled when ctx is done.
+func (s *Scheduler) Start(ctx context.Context) error {
 	schedules, err := s.cronRepo.ListEnabled()
 	if err != nil {
 		return err
 	}
+	s.mu.Lock()
+	s.active = true
+	s.runCtx = ctx
+	s.mu.Unlock()
 
+	now := s.now()
 	for _, sched := range schedules {
-		if err := s.addSchedule(sched.ID, sched.WorkflowID, sched.Expression); err != nil {
+		// Misfires are computed from the stored next_run_at before it is refreshed below
+		s.handleMisfires(*sched, now)
+		if err := s.addSchedule(sched); err != nil {
 			log.Printf("[Scheduler] Failed to add schedule %d: %v", sched.ID, err)
 		}
 	}
@@ -54,17 +125,48 @@ func (s *Scheduler) Start() error {
 	return nil
 }
 
-// Stop gracefully stops the cron scheduler.
+// Stop gracefully stops the cron scheduler and removes its entries; it can be started again.
 func (s *Scheduler) Stop() {
 	ctx := s.cron.Stop()
 	<-ctx.Done()
+	s.mu.Lock()
+	s.active = false
+	for schedID, entryID := range s.entryMap {
+		s.cron.Remove(entryID)
+		delete(s.entryMap, schedID)
+	}
+	s.mu.Unlock()
 	log.Println("[Scheduler] Stopped")
 }
 
+// inactiveNoLock reports whether the scheduler is not running and, if so, signals the leader.
+func (s *Scheduler) inactiveNoLock() bool {
+	if s.active {
+		return false
+	}
+	if s.notify != nil {
+		go s.notify()
+	}
+	return true
+}
+
+// runContext returns the parent of the runs, set by Start.
+func (s *Scheduler) runContext() context.Context {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.runCtx == nil {
+		return context.Background()
+	}
+	return s.runCtx
+}
+
 // Reload removes all existing entries and reloads from DB.
 func (s *Scheduler) Reload() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if s.inactiveNoLock() {
+		return nil
+	}
 
 	// Remove all existing entries
 	for schedID, entryID := range s.entryMap {
@@ -78,7 +180,7 @@ func (s *Scheduler) Reload() error {
 	}
 
 	for _, sched := range schedules {
-		if err := s.addScheduleNoLock(sched.ID, sched.WorkflowID, sched.Expression); err != nil {
+		if err := s.addScheduleNoLock(sched); err != nil {
 			log.Printf("[Scheduler] Failed to reload schedule %d: %v", sched.ID, err)
 		}
 	}
@@ -87,11 +189,15 @@ func (s *Scheduler) Reload() error {
 	return nil
 }
 
-// AddJob adds a single cron job for a schedule.
-func (s *Scheduler) AddJob(scheduleID, workflowID int64, expression string) error {
+// AddJob adds (or replaces) the cron job for a schedule.
+// While another instance is the leader the change is only signalled to it.
+func (s *Scheduler) AddJob(sched *models.CronSchedule) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	return s.addScheduleNoLock(scheduleID, workflowID, expression)
+	if s.inactiveNoLock() {
+		return nil
+	}
+	return s.addScheduleNoLock(sched)
 }
 
 // RemoveJob removes a cron job by schedule ID.
@@ -102,33 +208,241 @@ func (s *Scheduler) RemoveJob(scheduleID int64) {
 		s.cron.Remove(entryID)
 		delete(s.entryMap, scheduleID)
 	}
+	s.inactiveNoLock()
 }
 
-func (s *Scheduler) addSchedule(scheduleID, workflowID int64, expression string) error {
+func (s *Scheduler) addSchedule(sched *models.CronSchedule) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	return s.addScheduleNoLock(scheduleID, workflowID, expression)
+	return s.addScheduleNoLock(sched)
 }
 
-func (s *Scheduler) addScheduleNoLock(scheduleID, workflowID int64, expression string) error {
+func (s *Scheduler) addScheduleNoLock(sched *models.CronSchedule) error {
 	// Remove old entry if exists
-	if oldID, ok := s.entryMap[scheduleID]; ok {
+	if oldID, ok := s.entryMap[sched.ID]; ok {
 		s.cron.Remove(oldID)
-		delete(s.entryMap, scheduleID)
+		delete(s.entryMap, sched.ID)
 	}
 
-	entryID, err := s.cron.AddFunc(expression, func() {
-		s.runWorkflow(scheduleID, workflowID)
-	})
+	schedule, err := s.scheduleFor(sched)
 	if err != nil {
 		return err
 	}
+	settings := *sched
+	var entryID cron.EntryID
+	entryID = s.cron.Schedule(schedule, cron.FuncJob(func() {
+		// Entry.Prev is the tick being fired; fall back to now if the entry is gone
+		scheduledAt := s.now().Truncate(time.Second)
+		if prev := s.cron.Entry(entryID).Prev; !prev.IsZero() {
+			scheduledAt = prev
+		}
+		s.onTick(settings, scheduledAt)
+	}))
+	s.entryMap[sched.ID] = entryID
 
-	s.entryMap[scheduleID] = entryID
+	// Persist the upcoming fire time so it is visible before the first run
+	// A zero time means the schedule never fires again (e.g. fully excluded by its calendars)
+	var nextRun *time.Time
+	if next := schedule.Next(s.now()); !next.IsZero() {
+		nextRun = &next
+	}
+	sched.NextRunAt = nextRun
+	if err := s.cronRepo.UpdateNextRun(sched.ID, nextRun); err != nil {
+		log.Printf("[Scheduler] Failed to store next run for schedule %d: %v", sched.ID, err)
+	}
 	return nil
 }
 
-func (s *Scheduler) runWorkflow(scheduleID, workflowID int64) {
+// scheduleFor parses the schedule's expression and applies its exclusion calendars.
+func (s *Scheduler) scheduleFor(sched *models.CronSchedule) (cron.Schedule, error) {
+	schedule, err := ParseCronSchedule(sched.Expression, sched.Timezone)
+	if err != nil || len(sched.CalendarIDs) == 0 || s.calendarRepo == nil {
+		return schedule, err
+	}
+	calendars, err := s.calendarRepo.GetByIDs(sched.CalendarIDs)
+	if err != nil {
+		return nil, fmt.Errorf("load calendars: %w", err)
+	}
+	return ApplyCalendars(schedule, sched.Timezone, calendars), nil
+}
+
+// onTick applies the schedule's jitter and fires it.
+func (s *Scheduler) onTick(sched models.CronSchedule, scheduledAt time.Time) {
+	if sched.JitterSec > 0 {
+		time.Sleep(time.Duration(rand.Int63n(int64(sched.JitterSec) * int64(time.Second))))
+	}
+	s.fire(sched, scheduledAt, "")
+}
+
+// fire runs the schedule's workflow for the tick at scheduledAt, honoring the overlap policy
+// when a previous run is still in flight. event is recorded with the run ("" = regular tick).
+func (s *Scheduler) fire(sched models.CronSchedule, scheduledAt time.Time, event string) {
+	parent := s.runContext()
+	s.stateMu.Lock()
+	st := s.states[sched.ID]
+	if st == nil {
+		st = &scheduleState{runs: map[int64]context.CancelFunc{}}
+		s.states[sched.ID] = st
+	}
+	if len(st.runs) > 0 {
+		switch sched.OverlapPolicy {
+		case models.OverlapSkip:
+			s.stateMu.Unlock()
+			s.recordEvent(sched.ID, scheduleEventSkipped, &scheduledAt, nil, "previous run still in progress")
+			return
+		case models.OverlapQueue:
+			if st.queued != nil {
+				s.stateMu.Unlock()
+				s.recordEvent(sched.ID, scheduleEventSkipped, &scheduledAt, nil, "a run is already queued")
+				return
+			}
+			st.queued = &scheduledAt
+			s.stateMu.Unlock()
+			s.recordEvent(sched.ID, scheduleEventQueued, &scheduledAt, nil, "previous run still in progress")
+			return
+		case models.OverlapCancelPrevious:
+			n := len(st.runs)
+			for _, cancel := range st.runs {
+				cancel()
+			}
+			defer s.recordEvent(sched.ID, scheduleEventCancelled, &scheduledAt, nil, fmt.Sprintf("cancelled %d running execution(s)", n))
+		}
+	}
+	st.seq++
+	seq := st.seq
+	ctx, cancel := context.WithCancel(parent)
+	st.runs[seq] = cancel
+	s.stateMu.Unlock()
+
+	s.runWorkflow(ctx, sched, scheduledAt, event)
+	cancel()
+
+	s.stateMu.Lock()
+	delete(st.runs, seq)
+	var queued *time.Time
+	if len(st.runs) == 0 && st.queued != nil {
+		queued, st.queued = st.queued, nil
+	}
+	s.stateMu.Unlock()
+
+	if queued != nil {
+		s.fire(sched, *queued, "")
+	}
+}
+
+// handleMisfires applies the misfire policy to ticks between the stored next_run_at (or
+// last_run_at) and now, i.e. ticks missed while no scheduler was running.
+func (s *Scheduler) handleMisfires(sched models.CronSchedule, now time.Time) {
+	schedule, err := s.scheduleFor(&sched)
+	if err != nil {
+		return
+	}
+	var first time.Time
+	switch {
+	case sched.NextRunAt != nil:
+		first = *sched.NextRunAt
+	case sched.LastRunAt != nil:
+		first = schedule.Next(*sched.LastRunAt)
+	default:
+		return
+	}
+	if sched.LastRunAt != nil && !first.After(*sched.LastRunAt) {
+		first = schedule.Next(*sched.LastRunAt)
+	}
+
+	keep := 0
+	switch sched.MisfirePolicy {
+	case models.MisfireRunOnce:
+		keep = 1
+	case models.MisfireRunAll:
+		keep = sched.MisfireMax
+		if keep <= 0 {
+			keep = 10
+		}
+	}
+
+	// Keep the most recent `keep` missed ticks, oldest first
+	var missed []time.Time
+	count := 0
+	for t := first; !t.IsZero() && t.Before(now) && count < maxMisfireScan; t = schedule.Next(t) {
+		count++
+		if keep > 0 {
+			missed = append(missed, t)
+			if len(missed) > keep {
+				missed = missed[1:]
+			}
+		}
+	}
+	if count == 0 {
+		return
+	}
+
+	if skipped := count - len(missed); skipped > 0 {
+		detail := fmt.Sprintf("%d missed run(s) not executed (policy %s)", skipped, misfirePolicyName(sched.MisfirePolicy))
+		if count >= maxMisfireScan {
+			detail = fmt.Sprintf("at least %d missed run(s) not executed (policy %s)", skipped, misfirePolicyName(sched.MisfirePolicy))
+		}
+		s.recordEvent(sched.ID, scheduleEventMisfireSkip, &first, nil, detail)
+		log.Printf("[Scheduler] Schedule %d: %s", sched.ID, detail)
+	}
+	if len(missed) == 0 {
+		return
+	}
+	log.Printf("[Scheduler] Schedule %d: catching up %d missed run(s)", sched.ID, len(missed))
+	go func() {
+		for _, t := range missed {
+			s.fire(sched, t, scheduleEventMisfireRun)
+		}
+	}()
+}
+
+func misfirePolicyName(p string) string {
+	if p == "" {
+		return models.MisfireSkip
+	}
+	return p
+}
+
+func (s *Scheduler) recordEvent(scheduleID int64, event string, scheduledAt *time.Time, execID *int64, detail string) {
+	err := s.cronRepo.RecordEvent(&models.CronScheduleEvent{
+		ScheduleID:  scheduleID,
+		Event:       event,
+		ScheduledAt: scheduledAt,
+		ExecutionID: execID,
+		Detail:      detail,
+	})
+	if err != nil {
+		log.Printf("[Scheduler] Failed to record %s for schedule %d: %v", event, scheduleID, err)
+	}
+}
+
+// scheduleInput is the initial input of a scheduled run, read by the cron entry node.
+func scheduleInput(sched models.CronSchedule, scheduledAt, firedAt time.Time, nextRun *time.Time, event string) map[string]interface{} {
+	loc, err := LoadTimezone(sched.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	input := map[string]interface{}{
+		"scheduleId":  sched.ID,
+		"expression":  sched.Expression,
+		"timezone":    sched.Timezone,
+		"scheduledAt": scheduledAt.In(loc).Format(time.RFC3339),
+		"firedAt":     firedAt.In(loc).Format(time.RFC3339),
+		"catchUp":     event == scheduleEventMisfireRun,
+	}
+	if nextRun != nil {
+		input["nextRun"] = nextRun.In(loc).Format(time.RFC3339)
+	}
+	payload := map[string]interface{}{}
+	for k, v := range sched.Payload {
+		payload[k] = v
+	}
+	input["payload"] = payload
+	return input
+}
+
+func (s *Scheduler) runWorkflow(ctx context.Context, sched models.CronSchedule, scheduledAt time.Time, event string) {
+	scheduleID, workflowID := sched.ID, sched.WorkflowID
 	log.Printf("[Scheduler] Triggering workflow %d (schedule %d)", workflowID, scheduleID)
 
 	wf, err := s.workflowRepo.GetByID(workflowID)
@@ -137,11 +451,24 @@ func (s *Scheduler) runWorkflow(scheduleID, workflowID int64) {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	// last_run_at is the tick being run; it is the baseline for misfire detection on restart
+	firedAt := s.now()
+	var nextRun *time.Time
+	if schedule, err := s.scheduleFor(&sched); err == nil {
+		if next := schedule.Next(firedAt); !next.IsZero() {
+			nextRun = &next
+		}
+	}
+	_ = s.cronRepo.UpdateLastRun(scheduleID, &scheduledAt, nextRun)
+
+	timeout := sched.ExecTimeoutSec
+	if timeout <= 0 {
+		timeout = models.DefaultTriggerExecTimeoutSec
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
 	defer cancel()
 
-	execID, err := s.engine.RunWorkflow(ctx, wf)
-	now := time.Now()
+	execID, err := s.engine.Execute(ctx, wf, scheduleInput(sched, scheduledAt, firedAt, nextRun, event), "cron")
 
 	if err != nil {
 		log.Printf("[Scheduler] Workflow %d execution failed (exec %d): %v", workflowID, execID, err)
@@ -149,14 +476,15 @@ func (s *Scheduler) runWorkflow(scheduleID, workflowID int64) {
 		log.Printf("[Scheduler] Workflow %d execution completed (exec %d)", workflowID, execID)
 	}
 
-	// Calculate next run from the cron entry
-	var nextRun *time.Time
-	if entryID, ok := s.entryMap[scheduleID]; ok {
-		entry := s.cron.Entry(entryID)
-		if !entry.Next.IsZero() {
-			nextRun = &entry.Next
+	if event != "" {
+		detail := ""
+		if err != nil {
+			detail = err.Error()
+		}
+		var execRef *int64
+		if execID != 0 {
+			execRef = &execID
 		}
+		s.recordEvent(scheduleID, event, &scheduledAt, execRef, detail)
 	}
-
-	_ = s.cronRepo.UpdateLastRun(scheduleID, &now, nextRun)
 }
@@ -0,0 +1,421 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"eflo/backend/models"
+
+	"github.com/robfig/cron/v3"
+)
+
+// schedTestNow is the fake clock of the scheduler tests.
+var schedTestNow = time.Date(2026, 3, 2, 15, 30, 0, 0, time.UTC)
+
+// fakeCronStore records the scheduler's events and last-run updates.
+type fakeCronStore struct {
+	mu       sync.Mutex
+	events   []models.CronScheduleEvent
+	lastRuns []time.Time
+}
+
+func (s *fakeCronStore) ListEnabled() ([]*models.CronSchedule, error)        { return nil, nil }
+func (s *fakeCronStore) UpdateNextRun(id int64, nextRunAt interface{}) error { return nil }
+
+func (s *fakeCronStore) UpdateLastRun(id int64, lastRunAt, nextRunAt interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastRuns = append(s.lastRuns, *lastRunAt.(*time.Time))
+	return nil
+}
+
+func (s *fakeCronStore) RecordEvent(e *models.CronScheduleEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, *e)
+	return nil
+}
+
+// lastRunLog returns the stored last_run_at values as "15:04".
+func (s *fakeCronStore) lastRunLog() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []string
+	for _, t := range s.lastRuns {
+		out = append(out, t.UTC().Format("15:04"))
+	}
+	return out
+}
+
+// eventLog returns the recorded events as "event@15:04 detail".
+func (s *fakeCronStore) eventLog() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []string
+	for _, e := range s.events {
+		entry := e.Event
+		if e.ScheduledAt != nil {
+			entry += "@" + e.ScheduledAt.UTC().Format("15:04")
+		}
+		if e.Detail != "" {
+			entry += " " + e.Detail
+		}
+		out = append(out, entry)
+	}
+	return out
+}
+
+// schedQueue stands in for the execution queue. With a gate, each run lasts until the test sends
+// on it or the run's context is cancelled; runs are logged by their scheduledAt.
+type schedQueue struct {
+	gate chan struct{}
+
+	mu        sync.Mutex
+	started   []string
+	cancelled []string
+	jobs      []*models.QueuedJob
+}
+
+func (q *schedQueue) Enqueue(ctx context.Context, job *models.QueuedJob) (int64, error) {
+	at := scheduledClock(job.Input)
+	q.mu.Lock()
+	q.started = append(q.started, at)
+	q.jobs = append(q.jobs, job)
+	id := int64(len(q.jobs))
+	q.mu.Unlock()
+	if q.gate != nil {
+		select {
+		case <-q.gate:
+		case <-ctx.Done():
+			q.mu.Lock()
+			q.cancelled = append(q.cancelled, at)
+			q.mu.Unlock()
+			return 0, ctx.Err()
+		}
+	}
+	return id, nil
+}
+
+func (q *schedQueue) Get(ctx context.Context, id int64) (*models.QueuedJob, error) {
+	return &models.QueuedJob{ID: id, Status: models.JobCompleted}, nil
+}
+
+func (q *schedQueue) Claim(context.Context, string, time.Duration) (*models.QueuedJob, error) {
+	return nil, nil
+}
+
+func (q *schedQueue) Heartbeat(context.Context, int64, string, time.Duration) (bool, error) {
+	return true, nil
+}
+
+func (q *schedQueue) Complete(context.Context, int64, string, int64, string) error { return nil }
+func (q *schedQueue) Cancel(context.Context, int64) error                          { return nil }
+func (q *schedQueue) Stats(context.Context) (map[string]int64, error)              { return nil, nil }
+
+func (q *schedQueue) runs() (started, cancelled []string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]string(nil), q.started...), append([]string(nil), q.cancelled...)
+}
+
+func (q *schedQueue) job(i int) *models.QueuedJob {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.jobs[i]
+}
+
+// scheduledClock returns a run input's scheduledAt as "15:04" (the tests use UTC schedules).
+func scheduledClock(input map[string]interface{}) string {
+	at, err := time.Parse(time.RFC3339, fmt.Sprint(input["scheduledAt"]))
+	if err != nil {
+		return fmt.Sprint(input["scheduledAt"])
+	}
+	return at.UTC().Format("15:04")
+}
+
+func newTestScheduler(q *schedQueue) (*Scheduler, *fakeCronStore) {
+	store := &fakeCronStore{}
+	s := &Scheduler{
+		engine:       &Engine{Queue: q},
+		workflowRepo: fakeWorkflows{},
+		cronRepo:     store,
+		now:          func() time.Time { return schedTestNow },
+		entryMap:     make(map[int64]cron.EntryID),
+		states:       make(map[int64]*scheduleState),
+	}
+	return s, store
+}
+
+// tick returns hour:minute on the fake clock's day.
+func tick(hour, minute int) time.Time {
+	return time.Date(2026, 3, 2, hour, minute, 0, 0, time.UTC)
+}
+
+// fireAsync fires sched for at in the background; the returned channel closes when fire returns.
+func fireAsync(s *Scheduler, sched models.CronSchedule, at time.Time) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.fire(sched, at, "")
+	}()
+	return done
+}
+
+func waitClosed(t *testing.T, ch <-chan struct{}, what string) {
+	t.Helper()
+	select {
+	case <-ch:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %s", what)
+	}
+}
+
+func waitStarted(t *testing.T, q *schedQueue, n int) {
+	t.Helper()
+	waitFor(t, 5*time.Second, fmt.Sprintf("%d run(s) to start", n), func() bool {
+		started, _ := q.runs()
+		return len(started) >= n
+	})
+}
+
+func TestSchedulerOverlapSkip(t *testing.T) {
+	q := &schedQueue{gate: make(chan struct{})}
+	s, store := newTestScheduler(q)
+	sched := models.CronSchedule{ID: 1, WorkflowID: 2, Expression: "* * * * *", OverlapPolicy: models.OverlapSkip}
+
+	first := fireAsync(s, sched, tick(10, 0))
+	waitStarted(t, q, 1)
+	s.fire(sched, tick(10, 1), "") // returns at once: the previous run is still going
+	q.gate <- struct{}{}
+	waitClosed(t, first, "the first run")
+
+	if started, _ := q.runs(); !equalEvents(started, []string{"10:00"}) {
+		t.Errorf("runs = %q, want only 10:00", started)
+	}
+	want := []string{"skipped_overlap@10:01 previous run still in progress"}
+	if got := store.eventLog(); !equalEvents(got, want) {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+
+	// Once the run is done the next tick runs again
+	next := fireAsync(s, sched, tick(10, 2))
+	waitStarted(t, q, 2)
+	q.gate <- struct{}{}
+	waitClosed(t, next, "the next run")
+}
+
+func TestSchedulerOverlapQueue(t *testing.T) {
+	q := &schedQueue{gate: make(chan struct{})}
+	s, store := newTestScheduler(q)
+	sched := models.CronSchedule{ID: 1, WorkflowID: 2, Expression: "* * * * *", OverlapPolicy: models.OverlapQueue}
+
+	first := fireAsync(s, sched, tick(10, 0))
+	waitStarted(t, q, 1)
+	s.fire(sched, tick(10, 1), "") // queued behind 10:00
+	s.fire(sched, tick(10, 2), "") // dropped: only one tick waits
+
+	// The queued tick runs on the goroutine of the run it waited for
+	q.gate <- struct{}{}
+	waitStarted(t, q, 2)
+	q.gate <- struct{}{}
+	waitClosed(t, first, "the first and queued runs")
+
+	if started, _ := q.runs(); !equalEvents(started, []string{"10:00", "10:01"}) {
+		t.Errorf("runs = %q, want 10:00 then the queued 10:01", started)
+	}
+	want := []string{
+		"queued@10:01 previous run still in progress",
+		"skipped_overlap@10:02 a run is already queued",
+	}
+	if got := store.eventLog(); !equalEvents(got, want) {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+}
+
+func TestSchedulerOverlapCancelPrevious(t *testing.T) {
+	q := &schedQueue{gate: make(chan struct{})}
+	s, store := newTestScheduler(q)
+	sched := models.CronSchedule{ID: 1, WorkflowID: 2, Expression: "* * * * *", OverlapPolicy: models.OverlapCancelPrevious}
+
+	first := fireAsync(s, sched, tick(10, 0))
+	waitStarted(t, q, 1)
+	second := fireAsync(s, sched, tick(10, 1))
+	waitClosed(t, first, "the cancelled run")
+	waitStarted(t, q, 2)
+	q.gate <- struct{}{}
+	waitClosed(t, second, "the second run")
+
+	started, cancelled := q.runs()
+	if !equalEvents(started, []string{"10:00", "10:01"}) || !equalEvents(cancelled, []string{"10:00"}) {
+		t.Errorf("runs = %q, cancelled = %q; want 10:00 cancelled by 10:01", started, cancelled)
+	}
+	want := []string{"cancelled_previous@10:01 cancelled 1 running execution(s)"}
+	if got := store.eventLog(); !equalEvents(got, want) {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+}
+
+func TestSchedulerOverlapAllow(t *testing.T) {
+	q := &schedQueue{gate: make(chan struct{})}
+	s, store := newTestScheduler(q)
+	sched := models.CronSchedule{ID: 1, WorkflowID: 2, Expression: "* * * * *", OverlapPolicy: models.OverlapAllow}
+
+	first := fireAsync(s, sched, tick(10, 0))
+	second := fireAsync(s, sched, tick(10, 1))
+	waitStarted(t, q, 2)
+	q.gate <- struct{}{}
+	q.gate <- struct{}{}
+	waitClosed(t, first, "the first run")
+	waitClosed(t, second, "the second run")
+
+	if got := store.eventLog(); len(got) != 0 {
+		t.Errorf("events = %q, want none", got)
+	}
+}
+
+func TestSchedulerRunUsesScheduleTimeout(t *testing.T) {
+	tests := []struct {
+		timeoutSec int
+		want       time.Duration
+	}{
+		{timeoutSec: 42, want: 42 * time.Second},
+		{timeoutSec: 0, want: models.DefaultTriggerExecTimeoutSec * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.timeoutSec), func(t *testing.T) {
+			q := &schedQueue{}
+			s, _ := newTestScheduler(q)
+			sched := models.CronSchedule{ID: 1, WorkflowID: 2, Expression: "* * * * *", ExecTimeoutSec: tt.timeoutSec}
+			s.fire(sched, tick(10, 0), "")
+
+			// The job's timeout is what is left of the run's deadline when it is enqueued
+			got := time.Duration(q.job(0).TimeoutMs) * time.Millisecond
+			if got > tt.want || got < tt.want-time.Second {
+				t.Errorf("job timeout = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchedulerHandleMisfires(t *testing.T) {
+	at := func(hour, minute int) *time.Time {
+		v := tick(hour, minute)
+		return &v
+	}
+
+	// The server was down from before 10:00 until 15:30 (the fake clock): an hourly schedule
+	// missed 10:00 through 15:00
+	tests := []struct {
+		name       string
+		policy     string
+		max        int
+		nextRun    *time.Time
+		lastRun    *time.Time
+		wantRuns   []string
+		wantEvents []string
+	}{
+		{
+			name:       "skip",
+			policy:     models.MisfireSkip,
+			nextRun:    at(10, 0),
+			wantEvents: []string{"misfire_skipped@10:00 6 missed run(s) not executed (policy skip)"},
+		},
+		{
+			name:       "empty policy skips",
+			nextRun:    at(10, 0),
+			wantEvents: []string{"misfire_skipped@10:00 6 missed run(s) not executed (policy skip)"},
+		},
+		{
+			name:     "run once runs the latest tick",
+			policy:   models.MisfireRunOnce,
+			nextRun:  at(10, 0),
+			wantRuns: []string{"15:00"},
+			wantEvents: []string{
+				"misfire_skipped@10:00 5 missed run(s) not executed (policy run_once)",
+				"misfire_run@15:00",
+			},
+		},
+		{
+			name:     "run all is capped to the latest ticks",
+			policy:   models.MisfireRunAll,
+			max:      3,
+			nextRun:  at(10, 0),
+			wantRuns: []string{"13:00", "14:00", "15:00"},
+			wantEvents: []string{
+				"misfire_skipped@10:00 3 missed run(s) not executed (policy run_all)",
+				"misfire_run@13:00", "misfire_run@14:00", "misfire_run@15:00",
+			},
+		},
+		{
+			name:       "run all under the cap",
+			policy:     models.MisfireRunAll,
+			max:        10,
+			nextRun:    at(13, 0),
+			wantRuns:   []string{"13:00", "14:00", "15:00"},
+			wantEvents: []string{"misfire_run@13:00", "misfire_run@14:00", "misfire_run@15:00"},
+		},
+		{
+			name:       "without a next run the tick after the last run is the first missed",
+			policy:     models.MisfireRunAll,
+			max:        10,
+			lastRun:    at(13, 0),
+			wantRuns:   []string{"14:00", "15:00"},
+			wantEvents: []string{"misfire_run@14:00", "misfire_run@15:00"},
+		},
+		{
+			name:       "a stale next run before the last run is ignored",
+			policy:     models.MisfireRunAll,
+			max:        10,
+			nextRun:    at(10, 0),
+			lastRun:    at(14, 0),
+			wantRuns:   []string{"15:00"},
+			wantEvents: []string{"misfire_run@15:00"},
+		},
+		{
+			name:    "nothing missed",
+			policy:  models.MisfireRunAll,
+			nextRun: at(16, 0),
+			lastRun: at(15, 0),
+		},
+		{
+			name:   "never scheduled",
+			policy: models.MisfireRunAll,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := &schedQueue{}
+			s, store := newTestScheduler(q)
+			sched := models.CronSchedule{
+				ID: 1, WorkflowID: 2, Expression: "0 * * * *", Timezone: "UTC",
+				MisfirePolicy: tt.policy, MisfireMax: tt.max, NextRunAt: tt.nextRun, LastRunAt: tt.lastRun,
+			}
+			s.handleMisfires(sched, s.now())
+
+			// Catch-up runs happen in the background, one after the other
+			waitFor(t, 10*time.Second, "the catch-up runs", func() bool {
+				return len(store.eventLog()) >= len(tt.wantEvents)
+			})
+			if got := store.eventLog(); !equalEvents(got, tt.wantEvents) {
+				t.Errorf("events = %q, want %q", got, tt.wantEvents)
+			}
+			if started, _ := q.runs(); !equalEvents(started, tt.wantRuns) {
+				t.Errorf("runs = %q, want %q", started, tt.wantRuns)
+			}
+			// Each catch-up run moves last_run_at to its tick, the baseline of the next restart
+			if got := store.lastRunLog(); !equalEvents(got, tt.wantRuns) {
+				t.Errorf("last runs = %q, want %q", got, tt.wantRuns)
+			}
+			for i := range tt.wantRuns {
+				input := q.job(i).Input
+				if input["catchUp"] != true || input["firedAt"] != schedTestNow.Format(time.RFC3339) {
+					t.Errorf("run %d: catchUp = %v, firedAt = %v; want a catch-up fired at %s",
+						i, input["catchUp"], input["firedAt"], schedTestNow.Format(time.RFC3339))
+				}
+			}
+		})
+	}
+}
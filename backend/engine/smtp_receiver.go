@@ -0,0 +1,454 @@
+package engine
+
+import (
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"eflo/backend/imaputil"
+	"eflo/backend/models"
+	"eflo/backend/repository"
+	"eflo/backend/smtputil"
+)
+
+// SmtpReceiver runs the embedded SMTP server and triggers the workflow of the SMTP trigger
+// matching each recipient. Triggers are looked up per message, so like HTTP-in it runs on every
+// API instance and trigger changes apply without a reload.
+//
+// A message is acknowledged only once it is stored: enqueued when the engine has an execution
+// queue, else kept in the spool (Spool) until its run ends. Spooled messages are leased to the
+// instance running them; when an instance stops, the messages it had not finished are taken over
+// and run by the receiver of the next instance that starts or is running. Without either store a
+// message only lives in memory until its run.
+type SmtpReceiver struct {
+	engine       *Engine
+	workflowRepo *repository.WorkflowRepo
+	triggerRepo  *repository.SmtpTriggerRepo
+	server       *smtputil.Server
+	mu           sync.Mutex
+	dispatchers  map[int64]*smtpDispatcher // triggerID -> dispatcher of its runs
+	held         map[int64]bool            // spooled messages this instance runs, leases renewed
+	stopping     bool
+
+	// Spool keeps accepted mail until its run ends when the engine has no execution queue.
+	Spool     *repository.SmtpSpoolRepo
+	spoolStop chan struct{}
+	spoolDone chan struct{}
+
+	// MaxBufferedBytes caps the raw size of the messages accepted but not yet run; further
+	// mail is refused with a temporary error (default 256 MB).
+	MaxBufferedBytes int64
+	buffered         atomic.Int64
+}
+
+const (
+	// defaultSmtpBufferedBytes is the default of SmtpReceiver.MaxBufferedBytes.
+	defaultSmtpBufferedBytes = 256 << 20
+	// smtpSpoolLease is how long a spooled message stays with an instance that stopped renewing
+	// it; smtpSpoolRenewInterval is how often leases are renewed and lapsed ones taken over.
+	smtpSpoolLease         = 2 * time.Minute
+	smtpSpoolRenewInterval = 30 * time.Second
+	smtpSpoolReclaimBatch  = 100
+)
+
+// errSmtpDropped is the dead letter error of a spooled message the drop_oldest policy discarded.
+var errSmtpDropped = errors.New("dropped from the full buffer of the SMTP trigger before it ran")
+
+// smtpDispatcher is a trigger's dispatcher with the limits it was created with.
+type smtpDispatcher struct {
+	limits models.TriggerLimits
+	d      *triggerDispatcher
+}
+
+func NewSmtpReceiver(eng *Engine, workflowRepo *repository.WorkflowRepo, triggerRepo *repository.SmtpTriggerRepo) *SmtpReceiver {
+	return &SmtpReceiver{
+		engine:       eng,
+		workflowRepo: workflowRepo,
+		triggerRepo:  triggerRepo,
+		dispatchers:  make(map[int64]*smtpDispatcher),
+		held:         make(map[int64]bool),
+	}
+}
+
+// Start listens on addr. hostname is announced to clients, larger messages than maxMessageBytes
+// are refused and a non-nil tlsConfig offers STARTTLS.
+func (sr *SmtpReceiver) Start(addr, hostname string, maxMessageBytes int, tlsConfig *tls.Config) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	sr.server = &smtputil.Server{
+		Addr:            addr,
+		Hostname:        hostname,
+		MaxMessageBytes: maxMessageBytes,
+		TLSConfig:       tlsConfig,
+		Backend:         sr,
+	}
+	go func() {
+		if err := sr.server.Serve(ln); err != nil && !errors.Is(err, smtputil.ErrServerClosed) {
+			log.Printf("[SmtpReceiver] Server stopped: %v", err)
+		}
+	}()
+	log.Printf("[SmtpReceiver] Listening on %s (STARTTLS: %v)", addr, tlsConfig != nil)
+	if sr.spooling() {
+		sr.spoolStop = make(chan struct{})
+		sr.spoolDone = make(chan struct{})
+		go sr.keepSpool()
+	}
+	return nil
+}
+
+// Stop closes the server and waits for accepted messages to finish running.
+func (sr *SmtpReceiver) Stop() {
+	if sr.server != nil {
+		_ = sr.server.Close()
+	}
+	sr.mu.Lock()
+	sr.stopping = true
+	dispatchers := sr.dispatchers
+	sr.dispatchers = make(map[int64]*smtpDispatcher)
+	sr.mu.Unlock()
+	for _, e := range dispatchers {
+		e.d.Close()
+	}
+	if sr.spoolStop != nil {
+		close(sr.spoolStop)
+		<-sr.spoolDone
+	}
+	log.Println("[SmtpReceiver] Stopped")
+}
+
+// AcceptRecipient accepts addresses routed to an enabled SMTP trigger.
+func (sr *SmtpReceiver) AcceptRecipient(addr string) (bool, error) {
+	triggers, err := sr.triggerRepo.ListEnabled()
+	if err != nil {
+		return false, err
+	}
+	return MatchSmtpTrigger(triggers, addr) != nil, nil
+}
+
+// Deliver runs each matching trigger's workflow once per message, however many of its
+// recipients the message is addressed to. The message is refused (and retried by the sender)
+// only if no trigger could take it.
+func (sr *SmtpReceiver) Deliver(ctx context.Context, env smtputil.Envelope, data []byte) error {
+	triggers, err := sr.triggerRepo.ListEnabled()
+	if err != nil {
+		return fmt.Errorf("load triggers: %w", err)
+	}
+	sr.prune(triggers)
+
+	var matched []*models.SmtpTrigger
+	recipients := make(map[int64][]string)
+	for _, rcpt := range env.Recipients {
+		t := MatchSmtpTrigger(triggers, rcpt)
+		if t == nil {
+			continue // disabled since RCPT
+		}
+		if _, ok := recipients[t.ID]; !ok {
+			matched = append(matched, t)
+		}
+		recipients[t.ID] = append(recipients[t.ID], rcpt)
+	}
+	if len(matched) == 0 {
+		return errors.New("no trigger for the recipients")
+	}
+
+	var lastErr error
+	failed := 0
+	for _, t := range matched {
+		if err := sr.submit(ctx, t, env, recipients[t.ID], data); err != nil {
+			log.Printf("[SmtpReceiver] Trigger %d rejected message from %s: %v", t.ID, env.From, err)
+			lastErr = err
+			failed++
+		}
+	}
+	if failed == len(matched) {
+		return lastErr
+	}
+	return nil
+}
+
+// submit queues the message for a run of the trigger's workflow. The run waits for the message
+// to be stored (see SmtpReceiver), which happens once the dispatcher took it, so a full buffer
+// refuses it before anything is written.
+func (sr *SmtpReceiver) submit(ctx context.Context, t *models.SmtpTrigger, env smtputil.Envelope, rcpts []string, data []byte) error {
+	size := int64(len(data))
+	if !sr.reserve(size) {
+		return errors.New("too many messages waiting, try again later")
+	}
+	release := func() { sr.buffered.Add(-size) }
+
+	triggerID, workflowID := t.ID, t.WorkflowID
+	wf, err := sr.workflowRepo.GetByID(workflowID)
+	if err != nil {
+		release()
+		return fmt.Errorf("failed to load workflow %d: %w", workflowID, err)
+	}
+	emailData, err := imaputil.ParseMessage(data, t.MaxAttachmentBytes)
+	if err != nil {
+		release()
+		return err
+	}
+	emailData["envelopeFrom"] = env.From
+	emailData["recipients"] = rcpts
+	emailData["remoteAddr"] = env.RemoteAddr
+	emailData["helo"] = env.Helo
+	emailData["tls"] = env.TLS
+	emailData["triggerId"] = triggerID
+	emailData["receivedAt"] = time.Now().Format(time.RFC3339)
+
+	stored := make(chan smtpStored, 1)
+	err = sr.dispatcher(t).SubmitDroppable(ctx, func(execCtx context.Context) {
+		defer release()
+		if st := <-stored; st.err == nil {
+			sr.run(execCtx, wf, triggerID, emailData, st)
+		}
+	}, func() {
+		release()
+		go func() {
+			if st := <-stored; st.spoolID != 0 {
+				sr.engine.RecordDeadLetter(models.TriggerSMTP, triggerID, workflowID, 0, emailData, errSmtpDropped)
+				sr.unspool(st.spoolID)
+			}
+		}()
+	})
+	if err != nil {
+		release()
+		if errors.Is(err, ErrTriggerOverflow) {
+			return fmt.Errorf("trigger %d is busy, try again later", triggerID)
+		}
+		return err
+	}
+
+	st := sr.store(ctx, t, wf, emailData)
+	stored <- st
+	return st.err
+}
+
+// run runs the workflow for a stored message and removes it from the spool afterwards.
+func (sr *SmtpReceiver) run(ctx context.Context, wf *models.Workflow, triggerID int64, emailData map[string]interface{}, st smtpStored) {
+	var execID int64
+	var err error
+	if st.jobID != 0 {
+		execID, err = sr.engine.awaitJob(ctx, st.jobID)
+	} else {
+		execID, err = sr.engine.Execute(ctx, wf, emailData, "smtp")
+	}
+
+	_ = sr.triggerRepo.IncrementMsgCount(triggerID)
+	if err != nil {
+		log.Printf("[SmtpReceiver] Workflow %d exec failed (exec %d): %v", wf.ID, execID, err)
+		sr.engine.RecordDeadLetter(models.TriggerSMTP, triggerID, wf.ID, execID, emailData, err)
+	} else {
+		log.Printf("[SmtpReceiver] Workflow %d exec completed (exec %d) for email: %s", wf.ID, execID, emailData["subject"])
+	}
+	if st.spoolID != 0 {
+		sr.unspool(st.spoolID)
+	}
+}
+
+// smtpStored is where an accepted message was stored before it was acknowledged.
+type smtpStored struct {
+	jobID   int64 // queued run, with an execution queue
+	spoolID int64 // spooled message removed when the run ends, without one
+	err     error
+}
+
+// store enqueues the message's run, or without an execution queue spools the message.
+func (sr *SmtpReceiver) store(ctx context.Context, t *models.SmtpTrigger, wf *models.Workflow, emailData map[string]interface{}) smtpStored {
+	if sr.engine.Queue != nil {
+		timeout := time.Duration(normalizeTriggerLimits(t.TriggerLimits).ExecTimeoutSec) * time.Second
+		jobID, err := sr.engine.enqueue(ctx, wf, emailData, "smtp", timeout)
+		return smtpStored{jobID: jobID, err: err}
+	}
+	if !sr.spooling() {
+		return smtpStored{}
+	}
+	id, err := sr.Spool.Create(&models.SpooledMessage{
+		TriggerID:  t.ID,
+		WorkflowID: t.WorkflowID,
+		Payload:    emailData,
+	}, InstanceID(), smtpSpoolLease)
+	if err != nil {
+		return smtpStored{err: fmt.Errorf("store message: %w", err)}
+	}
+	sr.mu.Lock()
+	sr.held[id] = true
+	sr.mu.Unlock()
+	return smtpStored{spoolID: id}
+}
+
+// spooling reports whether accepted mail is kept in the spool.
+func (sr *SmtpReceiver) spooling() bool {
+	return sr.Spool != nil && sr.engine.Queue == nil
+}
+
+// unspool removes a message whose run ended from the spool.
+func (sr *SmtpReceiver) unspool(id int64) {
+	if err := sr.Spool.Delete(id); err != nil {
+		log.Printf("[SmtpReceiver] Failed to remove spooled message %d of a finished run: %v", id, err)
+	}
+	sr.mu.Lock()
+	delete(sr.held, id)
+	sr.mu.Unlock()
+}
+
+// keepSpool renews the leases of the spooled messages this instance runs and takes over those
+// whose lease lapsed, right away and then every smtpSpoolRenewInterval until Stop.
+func (sr *SmtpReceiver) keepSpool() {
+	defer close(sr.spoolDone)
+	ticker := time.NewTicker(smtpSpoolRenewInterval)
+	defer ticker.Stop()
+	for {
+		sr.mu.Lock()
+		ids := make([]int64, 0, len(sr.held))
+		for id := range sr.held {
+			ids = append(ids, id)
+		}
+		stopping := sr.stopping
+		sr.mu.Unlock()
+
+		if err := sr.Spool.Renew(InstanceID(), ids, smtpSpoolLease); err != nil {
+			log.Printf("[SmtpReceiver] Failed to renew spooled messages: %v", err)
+		}
+		if !stopping {
+			msgs, err := sr.Spool.Reclaim(InstanceID(), smtpSpoolLease, smtpSpoolReclaimBatch)
+			if err != nil {
+				log.Printf("[SmtpReceiver] Failed to take over spooled messages: %v", err)
+			}
+			for _, m := range msgs {
+				go sr.resume(m)
+			}
+		}
+
+		select {
+		case <-sr.spoolStop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// resume runs a spooled message taken over from an instance that stopped before its run ended.
+// On a transient error the message is left to its lease and taken over again.
+func (sr *SmtpReceiver) resume(m *models.SpooledMessage) {
+	log.Printf("[SmtpReceiver] Taking over spooled message %d of trigger %d", m.ID, m.TriggerID)
+	t, err := sr.triggerRepo.GetByID(m.TriggerID)
+	if errors.Is(err, sql.ErrNoRows) {
+		sr.engine.RecordDeadLetter(models.TriggerSMTP, m.TriggerID, m.WorkflowID, 0, m.Payload,
+			fmt.Errorf("SMTP trigger %d was deleted before the message ran", m.TriggerID))
+		sr.unspool(m.ID)
+		return
+	}
+	if err != nil {
+		log.Printf("[SmtpReceiver] Failed to load trigger %d of spooled message %d: %v", m.TriggerID, m.ID, err)
+		return
+	}
+	wf, err := sr.workflowRepo.GetByID(m.WorkflowID)
+	if err != nil {
+		log.Printf("[SmtpReceiver] Failed to load workflow %d of spooled message %d: %v", m.WorkflowID, m.ID, err)
+		return
+	}
+
+	sr.mu.Lock()
+	if sr.stopping {
+		sr.mu.Unlock()
+		return
+	}
+	sr.held[m.ID] = true
+	sr.mu.Unlock()
+	st := smtpStored{spoolID: m.ID}
+	err = sr.dispatcher(t).SubmitDroppable(context.Background(), func(execCtx context.Context) {
+		sr.run(execCtx, wf, t.ID, m.Payload, st)
+	}, func() {
+		go func() {
+			sr.engine.RecordDeadLetter(models.TriggerSMTP, t.ID, wf.ID, 0, m.Payload, errSmtpDropped)
+			sr.unspool(m.ID)
+		}()
+	})
+	if err != nil {
+		// Left to lapse and be taken over again
+		log.Printf("[SmtpReceiver] Trigger %d could not take spooled message %d: %v", t.ID, m.ID, err)
+		sr.mu.Lock()
+		delete(sr.held, m.ID)
+		sr.mu.Unlock()
+	}
+}
+
+// reserve counts a message of size bytes against MaxBufferedBytes. A message is always accepted
+// when nothing else is buffered, so a cap below the message size limit cannot block all mail.
+func (sr *SmtpReceiver) reserve(size int64) bool {
+	limit := sr.MaxBufferedBytes
+	if limit <= 0 {
+		limit = defaultSmtpBufferedBytes
+	}
+	if total := sr.buffered.Add(size); total > limit && total != size {
+		sr.buffered.Add(-size)
+		return false
+	}
+	return true
+}
+
+// dispatcher returns the trigger's dispatcher, replacing it when the trigger's limits changed.
+func (sr *SmtpReceiver) dispatcher(t *models.SmtpTrigger) *triggerDispatcher {
+	limits := normalizeTriggerLimits(t.TriggerLimits)
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	if e, ok := sr.dispatchers[t.ID]; ok {
+		if e.limits == limits {
+			return e.d
+		}
+		go e.d.Close() // drains the runs already accepted
+	}
+	d := newTriggerDispatcher(context.Background(), fmt.Sprintf("[SmtpReceiver] Trigger %d", t.ID), limits)
+	sr.dispatchers[t.ID] = &smtpDispatcher{limits: limits, d: d}
+	return d
+}
+
+// prune closes the dispatchers of triggers that were deleted or disabled.
+func (sr *SmtpReceiver) prune(enabled []*models.SmtpTrigger) {
+	keep := make(map[int64]bool, len(enabled))
+	for _, t := range enabled {
+		keep[t.ID] = true
+	}
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	for id, e := range sr.dispatchers {
+		if !keep[id] {
+			go e.d.Close()
+			delete(sr.dispatchers, id)
+		}
+	}
+}
+
+// MatchSmtpTrigger returns the trigger for a recipient address: one listing the address itself,
+// else one listing its domain (@example.com). Addresses and patterns compare case-insensitively.
+func MatchSmtpTrigger(triggers []*models.SmtpTrigger, addr string) *models.SmtpTrigger {
+	addr = strings.ToLower(addr)
+	at := strings.LastIndexByte(addr, '@')
+	if at < 0 {
+		return nil
+	}
+	domain := addr[at:]
+	var byDomain *models.SmtpTrigger
+	for _, t := range triggers {
+		for _, r := range t.Recipients {
+			r = strings.ToLower(r)
+			if r == addr {
+				return t
+			}
+			if r == domain && byDomain == nil {
+				byDomain = t
+			}
+		}
+	}
+	return byDomain
+}
@@ -0,0 +1,171 @@
+package engine
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"eflo/backend/models"
+	"eflo/backend/repository"
+)
+
+const (
+	// workerIdleInterval is how long a worker slot waits after finding the queue empty.
+	workerIdleInterval = time.Second
+	// defaultJobTimeout applies to jobs enqueued without a deadline.
+	defaultJobTimeout = 5 * time.Minute
+	// finishedJobRetention is how long finished jobs stay in a queue that supports purging.
+	finishedJobRetention = 7 * 24 * time.Hour
+)
+
+// Worker executes workflows claimed from an ExecutionQueue with a fixed number of slots.
+type Worker struct {
+	engine       *Engine
+	workflowRepo *repository.WorkflowRepo
+	queue        ExecutionQueue
+	id           string
+	concurrency  int
+	visibility   time.Duration
+}
+
+// NewWorker creates a worker. concurrency <= 0 means one slot; visibility <= 0 means 30s.
+func NewWorker(eng *Engine, workflowRepo *repository.WorkflowRepo, queue ExecutionQueue, id string, concurrency int, visibility time.Duration) *Worker {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	if visibility <= 0 {
+		visibility = 30 * time.Second
+	}
+	if id == "" {
+		id = InstanceID()
+	}
+	return &Worker{
+		engine:       eng,
+		workflowRepo: workflowRepo,
+		queue:        queue,
+		id:           id,
+		concurrency:  concurrency,
+		visibility:   visibility,
+	}
+}
+
+// Run claims and executes jobs until ctx is cancelled, then waits for in-flight runs to finish.
+func (w *Worker) Run(ctx context.Context) {
+	log.Printf("[Worker] %s started with %d slot(s), visibility %s", w.id, w.concurrency, w.visibility)
+	var wg sync.WaitGroup
+	for i := 0; i < w.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.loop(ctx)
+		}()
+	}
+	if p, ok := w.queue.(interface {
+		PurgeFinished(ctx context.Context, before time.Time) (int64, error)
+	}); ok {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ticker := time.NewTicker(time.Hour)
+			defer ticker.Stop()
+			for {
+				if n, err := p.PurgeFinished(ctx, time.Now().Add(-finishedJobRetention)); err == nil && n > 0 {
+					log.Printf("[Worker] Purged %d finished job(s)", n)
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	log.Printf("[Worker] %s stopped", w.id)
+}
+
+func (w *Worker) loop(ctx context.Context) {
+	for ctx.Err() == nil {
+		job, err := w.queue.Claim(ctx, w.id, w.visibility)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("[Worker] Claim failed: %v", err)
+		}
+		if job == nil {
+			select {
+			case <-ctx.Done():
+			case <-time.After(workerIdleInterval):
+			}
+			continue
+		}
+		w.process(job)
+	}
+}
+
+// process runs one job. The run is not tied to the worker's context: on shutdown in-flight runs
+// complete rather than being abandoned.
+func (w *Worker) process(job *models.QueuedJob) {
+	if job.Attempts > job.MaxAttempts {
+		log.Printf("[Worker] Job %d exceeded %d attempts; giving up", job.ID, job.MaxAttempts)
+		w.complete(job, 0, "gave up after "+job.LastError)
+		return
+	}
+	if job.Attempts > 1 {
+		log.Printf("[Worker] Retrying job %d (attempt %d/%d): %s", job.ID, job.Attempts, job.MaxAttempts, job.LastError)
+	}
+
+	wf, err := w.workflowRepo.GetByID(job.WorkflowID)
+	if err != nil {
+		w.complete(job, 0, "workflow not found")
+		return
+	}
+
+	timeout := time.Duration(job.TimeoutMs) * time.Millisecond
+	if timeout <= 0 {
+		timeout = defaultJobTimeout
+	}
+	runCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// Heartbeat keeps the lease; losing it (cancelled or reclaimed) stops the run
+	stopBeat := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(w.visibility / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopBeat:
+				return
+			case <-ticker.C:
+				ok, err := w.queue.Heartbeat(runCtx, job.ID, w.id, w.visibility)
+				if err != nil {
+					log.Printf("[Worker] Heartbeat for job %d failed: %v", job.ID, err)
+					continue
+				}
+				if !ok {
+					log.Printf("[Worker] Job %d no longer leased to %s; stopping", job.ID, w.id)
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	log.Printf("[Worker] Running job %d (workflow %d, source %s)", job.ID, job.WorkflowID, job.Source)
+	execID, err := w.engine.RunWorkflowWithInput(runCtx, wf, job.Input, nil, nil)
+	close(stopBeat)
+
+	errMsg := ""
+	if err != nil {
+		errMsg = err.Error()
+	}
+	w.complete(job, execID, errMsg)
+}
+
+func (w *Worker) complete(job *models.QueuedJob, execID int64, errMsg string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := w.queue.Complete(ctx, job.ID, w.id, execID, errMsg); err != nil {
+		log.Printf("[Worker] Failed to complete job %d: %v", job.ID, err)
+	}
+}
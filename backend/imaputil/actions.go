@@ -0,0 +1,150 @@
+package imaputil
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"eflo/backend/models"
+)
+
+// ValidateActions checks a list of post-processing actions: known types, a target mailbox for
+// move/copy, valid flags for flag/unflag, and move/delete only in last position.
+func ValidateActions(actions []models.EmailAction) error {
+	for i, a := range actions {
+		switch a.Type {
+		case models.EmailActionMove, models.EmailActionCopy:
+			if strings.TrimSpace(a.Mailbox) == "" {
+				return fmt.Errorf("action %d (%s): mailbox is required", i+1, a.Type)
+			}
+		case models.EmailActionFlag, models.EmailActionUnflag:
+			if len(a.Flags) == 0 {
+				return fmt.Errorf("action %d (%s): flags are required", i+1, a.Type)
+			}
+			for _, f := range a.Flags {
+				if !validFlag(f) {
+					return fmt.Errorf("action %d (%s): invalid flag %q", i+1, a.Type, f)
+				}
+			}
+		case models.EmailActionDelete:
+		default:
+			return fmt.Errorf("action %d: unknown type %q (use move, copy, flag, unflag or delete)", i+1, a.Type)
+		}
+		if (a.Type == models.EmailActionMove || a.Type == models.EmailActionDelete) && i != len(actions)-1 {
+			return fmt.Errorf("action %d (%s) removes the message and must be the last action", i+1, a.Type)
+		}
+	}
+	return nil
+}
+
+// validFlag accepts a system flag (\Seen, \Flagged, ...) or a keyword atom.
+func validFlag(f string) bool {
+	name := strings.TrimPrefix(f, `\`)
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		b := name[i]
+		if b <= ' ' || b >= 0x7f || strings.IndexByte(`(){%*"\]`, b) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// ErrUIDValidityChanged is returned when a mailbox was recreated since a UID was obtained, so
+// the UID may now name a different message.
+var ErrUIDValidityChanged = errors.New("mailbox UIDVALIDITY changed; UID is no longer valid")
+
+// SelectUIDs selects a mailbox for acting on UIDs obtained earlier; a non-zero uidValidity must
+// still match the mailbox's.
+func (c *Client) SelectUIDs(mailbox string, uidValidity uint32) error {
+	st, err := c.Select(mailbox)
+	if err != nil {
+		return err
+	}
+	if uidValidity != 0 && st.UIDValidity != uidValidity {
+		return ErrUIDValidityChanged
+	}
+	return nil
+}
+
+// Apply performs a post-processing action on a message of the selected mailbox.
+func (c *Client) Apply(uid uint32, a models.EmailAction) error {
+	switch a.Type {
+	case models.EmailActionMove:
+		return c.Move(uid, a.Mailbox)
+	case models.EmailActionCopy:
+		return c.Copy(uid, a.Mailbox)
+	case models.EmailActionFlag:
+		return c.StoreFlags(uid, true, a.Flags)
+	case models.EmailActionUnflag:
+		return c.StoreFlags(uid, false, a.Flags)
+	case models.EmailActionDelete:
+		return c.Delete(uid)
+	}
+	return fmt.Errorf("unknown email action %q", a.Type)
+}
+
+// Copy copies a message to another mailbox.
+func (c *Client) Copy(uid uint32, mailbox string) error {
+	if err := c.cmd("UID COPY", uidArg(uid), mailboxArg(mailbox)); err != nil {
+		return fmt.Errorf("IMAP COPY failed: %w", err)
+	}
+	return nil
+}
+
+// Move moves a message to another mailbox, with MOVE when the server supports it and COPY
+// followed by Delete otherwise.
+func (c *Client) Move(uid uint32, mailbox string) error {
+	if c.HasCapability("MOVE") {
+		if err := c.cmd("UID MOVE", uidArg(uid), mailboxArg(mailbox)); err != nil {
+			return fmt.Errorf("IMAP MOVE failed: %w", err)
+		}
+		return nil
+	}
+	if err := c.Copy(uid, mailbox); err != nil {
+		return err
+	}
+	return c.Delete(uid)
+}
+
+// StoreFlags adds (or removes) flags and keywords on a message.
+func (c *Client) StoreFlags(uid uint32, add bool, flags []string) error {
+	for _, f := range flags {
+		if !validFlag(f) {
+			return fmt.Errorf("invalid flag %q", f)
+		}
+	}
+	op := "+FLAGS.SILENT"
+	if !add {
+		op = "-FLAGS.SILENT"
+	}
+	if err := c.cmd("UID STORE", uidArg(uid), op, "("+strings.Join(flags, " ")+")"); err != nil {
+		return fmt.Errorf("IMAP STORE failed: %w", err)
+	}
+	return nil
+}
+
+// Delete flags a message \Deleted and expunges it. Without UIDPLUS only a plain EXPUNGE is
+// available, which also removes any other message already flagged \Deleted in the mailbox.
+func (c *Client) Delete(uid uint32) error {
+	if err := c.StoreFlags(uid, true, []string{`\Deleted`}); err != nil {
+		return err
+	}
+	var err error
+	if c.HasCapability("UIDPLUS") {
+		err = c.cmd("UID EXPUNGE", uidArg(uid))
+	} else {
+		err = c.cmd("EXPUNGE")
+	}
+	if err != nil {
+		return fmt.Errorf("IMAP EXPUNGE failed: %w", err)
+	}
+	return nil
+}
+
+func uidArg(uid uint32) string {
+	return strconv.FormatUint(uint64(uid), 10)
+}
@@ -2,11 +2,15 @@ package imaputil
 
 import (
 	"bufio"
+	"context"
 	"crypto/tls"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -14,8 +18,52 @@ import (
 	"eflo/backend/models"
 )
 
-// FetchNewEmails connects to an IMAP server and fetches unseen email headers.
-func FetchNewEmails(cfg *models.NodeConfig, mailbox string, markSeen bool, maxFetch uint32) ([]map[string]interface{}, error) {
+// ErrIdleUnsupported is returned by Client.Idle when the server does not advertise IDLE.
+var ErrIdleUnsupported = errors.New("IMAP server does not support IDLE")
+
+// SecretStore looks up secrets by key; the config store implements it. Used for OAuth2 tokens.
+type SecretStore interface {
+	Get(key string) (string, bool, error)
+}
+
+// FetchOptions controls how much of a message FetchMessage reads.
+type FetchOptions struct {
+	// MaxMessageBytes: larger messages are fetched header-only and flagged truncated (0 = no limit).
+	MaxMessageBytes int
+	// MaxAttachmentBytes: larger attachments are listed without content (0 = no limit).
+	MaxAttachmentBytes int
+}
+
+// MailboxStatus is what SELECT reports about a mailbox.
+type MailboxStatus struct {
+	Exists      uint32
+	UIDValidity uint32
+	UIDNext     uint32
+}
+
+// Client is a logged-in IMAP connection. It is not safe for concurrent use.
+type Client struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	tagNum int
+	caps   map[string]bool
+}
+
+// response is one complete server response. Literals ({n} followed by n raw bytes) are collected
+// in order; text is the response with the literal data left out and the {n} markers kept.
+type response struct {
+	text     string
+	literals [][]byte
+}
+
+// Dial connects to the IMAP server of an email node config and authenticates. Config keys:
+//
+//	imapHost, imapPort   server (host derived from the SMTP host, port 993 by default)
+//	imapSecurity         "tls" (implicit TLS), "starttls" or "none"; default starttls on port 143, tls otherwise
+//	imapAuth             "password" (LOGIN, default) or "xoauth2"
+//	imapTokenKey         config store key holding the OAuth2 access token for xoauth2
+//	username, password   credentials (password is not used with xoauth2)
+func Dial(cfg *models.NodeConfig, secrets SecretStore) (*Client, error) {
 	if cfg.Type != "email" {
 		return nil, fmt.Errorf("config is not email type (got %s)", cfg.Type)
 	}
@@ -33,189 +81,550 @@ func FetchNewEmails(cfg *models.NodeConfig, mailbox string, markSeen bool, maxFe
 	if p, ok := cfg.Config["imapPort"]; ok {
 		switch v := p.(type) {
 		case string:
-			imapPort = v
+			if v != "" {
+				imapPort = v
+			}
 		case float64:
 			imapPort = strconv.Itoa(int(v))
 		}
 	}
-	username, _ := cfg.Config["username"].(string)
-	password, _ := cfg.Config["password"].(string)
-
-	if mailbox == "" {
-		mailbox = "INBOX"
-	}
-	if maxFetch == 0 {
-		maxFetch = 10
+	security, _ := cfg.Config["imapSecurity"].(string)
+	if security == "" {
+		security = "tls"
+		if imapPort == "143" {
+			security = "starttls"
+		}
 	}
+	username, _ := cfg.Config["username"].(string)
 
 	addr := net.JoinHostPort(imapHost, imapPort)
 	tlsCfg := &tls.Config{ServerName: imapHost}
-	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: 15 * time.Second}, "tcp", addr, tlsCfg)
+	dialer := &net.Dialer{Timeout: 15 * time.Second}
+	var conn net.Conn
+	var err error
+	switch security {
+	case "tls":
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsCfg)
+	case "starttls", "none":
+		conn, err = dialer.Dial("tcp", addr)
+	default:
+		return nil, fmt.Errorf("unknown imapSecurity %q (use tls, starttls or none)", security)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("IMAP connection failed (%s): %w", addr, err)
 	}
-	defer conn.Close()
 
-	reader := bufio.NewReader(conn)
+	c := &Client{conn: conn, reader: bufio.NewReader(conn)}
+	fail := func(format string, err error) (*Client, error) {
+		c.conn.Close()
+		return nil, fmt.Errorf(format, err)
+	}
+	greeting, err := c.readResponse()
+	if err != nil {
+		return fail("IMAP greeting failed: %w", err)
+	}
+	if strings.HasPrefix(greeting.text, "* BYE") {
+		return fail("IMAP server refused connection: %w", errors.New(greeting.text))
+	}
+	if err := c.loadCapabilities(); err != nil {
+		return fail("IMAP CAPABILITY failed: %w", err)
+	}
+
+	if security == "starttls" {
+		if !c.HasCapability("STARTTLS") {
+			return fail("IMAP STARTTLS failed: %w", errors.New("server does not advertise STARTTLS"))
+		}
+		if err := c.cmd("STARTTLS"); err != nil {
+			return fail("IMAP STARTTLS failed: %w", err)
+		}
+		tlsConn := tls.Client(conn, tlsCfg)
+		c.conn = tlsConn
+		_ = tlsConn.SetDeadline(time.Now().Add(15 * time.Second))
+		if err := tlsConn.Handshake(); err != nil {
+			return fail("IMAP STARTTLS handshake failed: %w", err)
+		}
+		_ = tlsConn.SetDeadline(time.Time{})
+		c.reader = bufio.NewReader(tlsConn)
+		// Capabilities sent before TLS must not be trusted
+		if err := c.loadCapabilities(); err != nil {
+			return fail("IMAP CAPABILITY failed: %w", err)
+		}
+	}
+
+	auth, _ := cfg.Config["imapAuth"].(string)
+	switch auth {
+	case "", "password":
+		if c.HasCapability("LOGINDISABLED") {
+			return fail("IMAP LOGIN failed: %w", errors.New("server disables LOGIN on this connection (use TLS or STARTTLS)"))
+		}
+		password, _ := cfg.Config["password"].(string)
+		if err := c.cmd("LOGIN", astring(username), astring(password)); err != nil {
+			return fail("IMAP LOGIN failed: %w", err)
+		}
+	case "xoauth2":
+		key, _ := cfg.Config["imapTokenKey"].(string)
+		token, err := lookupToken(secrets, key)
+		if err != nil {
+			return fail("IMAP XOAUTH2 failed: %w", err)
+		}
+		if err := c.authenticateXOAuth2(username, token); err != nil {
+			return fail("IMAP XOAUTH2 failed: %w", err)
+		}
+	default:
+		return fail("IMAP login failed: %w", fmt.Errorf("unknown imapAuth %q (use password or xoauth2)", auth))
+	}
+
+	// Servers may advertise more (e.g. IDLE) once authenticated
+	if err := c.loadCapabilities(); err != nil {
+		return fail("IMAP CAPABILITY failed: %w", err)
+	}
+	return c, nil
+}
+
+func lookupToken(secrets SecretStore, key string) (string, error) {
+	if key == "" {
+		return "", errors.New("imapTokenKey is not set in the email config")
+	}
+	if secrets == nil {
+		return "", errors.New("config store unavailable")
+	}
+	token, ok, err := secrets.Get(key)
+	if err != nil {
+		return "", fmt.Errorf("read config store key %q: %w", key, err)
+	}
+	if !ok || token == "" {
+		return "", fmt.Errorf("config store key %q is empty", key)
+	}
+	return token, nil
+}
 
-	if _, err := readLine(reader); err != nil {
-		return nil, fmt.Errorf("IMAP greeting failed: %w", err)
+// authenticateXOAuth2 runs SASL XOAUTH2, sending the initial response inline when the server
+// supports SASL-IR and after the continuation request otherwise.
+func (c *Client) authenticateXOAuth2(username, token string) error {
+	ir := base64.StdEncoding.EncodeToString([]byte("user=" + username + "\x01auth=Bearer " + token + "\x01\x01"))
+	sentIR := c.HasCapability("SASL-IR")
+	tag := c.nextTag()
+	line := tag + " AUTHENTICATE XOAUTH2"
+	if sentIR {
+		line += " " + ir
+	}
+	if _, err := fmt.Fprintf(c.conn, "%s\r\n", line); err != nil {
+		return err
+	}
+	for {
+		resp, err := c.readResponse()
+		if err != nil {
+			return err
+		}
+		switch {
+		case strings.HasPrefix(resp.text, "+"):
+			// Either the request for the initial response or a base64 error challenge, which
+			// must be answered with an empty line before the server sends NO
+			reply := ""
+			if !sentIR {
+				reply, sentIR = ir, true
+			}
+			if _, err := fmt.Fprintf(c.conn, "%s\r\n", reply); err != nil {
+				return err
+			}
+		case strings.HasPrefix(resp.text, tag+" "):
+			return taggedResult(tag, resp.text)
+		}
 	}
+}
 
-	if err := cmd(conn, reader, "A001", fmt.Sprintf(`LOGIN "%s" "%s"`, escape(username), escape(password))); err != nil {
-		return nil, fmt.Errorf("IMAP LOGIN failed: %w", err)
+// Close logs out and closes the connection.
+func (c *Client) Close() error {
+	_ = c.conn.SetDeadline(time.Now().Add(5 * time.Second))
+	_ = c.cmd("LOGOUT")
+	return c.conn.Close()
+}
+
+// HasCapability reports whether the server advertised a capability (e.g. "IDLE").
+func (c *Client) HasCapability(name string) bool {
+	return c.caps[strings.ToUpper(name)]
+}
+
+func (c *Client) loadCapabilities() error {
+	resps, err := c.execute("CAPABILITY")
+	if err != nil {
+		return err
 	}
+	c.caps = map[string]bool{}
+	for _, resp := range resps {
+		if strings.HasPrefix(strings.ToUpper(resp.text), "* CAPABILITY ") {
+			for _, cp := range strings.Fields(resp.text)[2:] {
+				c.caps[strings.ToUpper(cp)] = true
+			}
+		}
+	}
+	return nil
+}
 
-	if err := cmd(conn, reader, "A002", fmt.Sprintf("SELECT %s", mailbox)); err != nil {
+// Select opens a mailbox read-write and returns its UIDVALIDITY and UIDNEXT. The name may
+// contain spaces and non-ASCII characters; it is sent quoted in modified UTF-7.
+func (c *Client) Select(mailbox string) (*MailboxStatus, error) {
+	if mailbox == "" {
+		mailbox = "INBOX"
+	}
+	resps, err := c.execute("SELECT", mailboxArg(mailbox))
+	if err != nil {
 		return nil, fmt.Errorf("IMAP SELECT failed: %w", err)
 	}
+	st := &MailboxStatus{}
+	for _, resp := range resps {
+		if m := existsPattern.FindStringSubmatch(resp.text); m != nil {
+			st.Exists = parseUint32(m[1])
+		}
+		if m := uidValidityPattern.FindStringSubmatch(resp.text); m != nil {
+			st.UIDValidity = parseUint32(m[1])
+		}
+		if m := uidNextPattern.FindStringSubmatch(resp.text); m != nil {
+			st.UIDNext = parseUint32(m[1])
+		}
+	}
+	return st, nil
+}
 
-	searchResp, err := cmdUntagged(conn, reader, "A003", "SEARCH UNSEEN")
+// UIDsAfter returns the UIDs greater than lastUID in ascending order, at most max of them
+// (0 = all). The oldest are returned first so none are skipped.
+func (c *Client) UIDsAfter(lastUID uint32, max int) ([]uint32, error) {
+	resps, err := c.execute("UID SEARCH UID", fmt.Sprintf("%d:*", lastUID+1))
 	if err != nil {
 		return nil, fmt.Errorf("IMAP SEARCH failed: %w", err)
 	}
+	var uids []uint32
+	for _, uid := range parseSearch(resps) {
+		// "n:*" also matches the highest UID when it is below n
+		if uid > lastUID {
+			uids = append(uids, uid)
+		}
+	}
+	sort.Slice(uids, func(i, j int) bool { return uids[i] < uids[j] })
+	if max > 0 && len(uids) > max {
+		uids = uids[:max]
+	}
+	return uids, nil
+}
+
+// OldestUnseen returns the lowest UID of the messages without the \Seen flag; ok is false when
+// all messages are read.
+func (c *Client) OldestUnseen() (uid uint32, ok bool, err error) {
+	resps, err := c.execute("UID SEARCH UNSEEN")
+	if err != nil {
+		return 0, false, fmt.Errorf("IMAP SEARCH failed: %w", err)
+	}
+	for _, u := range parseSearch(resps) {
+		if !ok || u < uid {
+			uid, ok = u, true
+		}
+	}
+	return uid, ok, nil
+}
 
-	uids := parseSearch(searchResp)
-	if len(uids) == 0 {
-		_ = cmd(conn, reader, "A999", "LOGOUT")
-		return nil, nil
+// FetchMessage reads one message by UID: its size and flags first, then the whole message, or
+// only the header when it exceeds opts.MaxMessageBytes. The message is parsed with ParseMessage.
+func (c *Client) FetchMessage(uid uint32, opts FetchOptions) (map[string]interface{}, error) {
+	resps, err := c.execute("UID FETCH", uidArg(uid), "(FLAGS RFC822.SIZE)")
+	if err != nil {
+		return nil, err
+	}
+	meta, ok := fetchResponse(resps, uid)
+	if !ok {
+		return nil, fmt.Errorf("message UID %d not found", uid)
+	}
+	size := 0
+	if m := sizePattern.FindStringSubmatch(meta.text); m != nil {
+		size, _ = strconv.Atoi(m[1])
+	}
+	flags := []string{}
+	if m := flagsPattern.FindStringSubmatch(meta.text); m != nil {
+		flags = strings.Fields(m[1])
 	}
 
-	if len(uids) > int(maxFetch) {
-		uids = uids[len(uids)-int(maxFetch):]
+	truncated := opts.MaxMessageBytes > 0 && size > opts.MaxMessageBytes
+	section := "BODY.PEEK[]"
+	if truncated {
+		section = "BODY.PEEK[HEADER]"
+	}
+	resps, err = c.execute("UID FETCH", uidArg(uid), "("+section+")")
+	if err != nil {
+		return nil, err
+	}
+	body, ok := fetchResponse(resps, uid)
+	if !ok || len(body.literals) == 0 {
+		return nil, fmt.Errorf("no message data returned")
 	}
 
-	var results []map[string]interface{}
+	var data map[string]interface{}
+	if truncated {
+		data, err = ParseHeader(body.literals[0])
+	} else {
+		data, err = ParseMessage(body.literals[0], opts.MaxAttachmentBytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+	data["uid"] = uid
+	data["size"] = size
+	data["flags"] = flags
+	if truncated {
+		data["truncated"] = true
+	}
+	return data, nil
+}
 
-	for i, uid := range uids {
-		tag := fmt.Sprintf("F%d", i)
-		fetchCmd := fmt.Sprintf("FETCH %d (FLAGS BODY.PEEK[HEADER.FIELDS (FROM TO CC SUBJECT DATE MESSAGE-ID)])", uid)
-		lines, err := cmdUntagged(conn, reader, tag, fetchCmd)
-		if err != nil {
-			log.Printf("[EmailReceive] FETCH %d failed: %v", uid, err)
+// fetchResponse picks the FETCH response for uid: servers may interleave unsolicited FETCH
+// responses for other messages (e.g. flag changes), but UID FETCH replies always carry the UID.
+func fetchResponse(resps []response, uid uint32) (response, bool) {
+	want := uidArg(uid)
+	for _, resp := range resps {
+		if !fetchPattern.MatchString(resp.text) {
 			continue
 		}
+		if m := uidPattern.FindStringSubmatch(resp.text); m != nil && m[1] == want {
+			return resp, true
+		}
+	}
+	return response{}, false
+}
 
-		emailData := parseHeaders(lines, uid)
-		emailData["fetchedAt"] = time.Now().Format(time.RFC3339)
-		results = append(results, emailData)
+// MarkSeen sets the \Seen flag on a message.
+func (c *Client) MarkSeen(uid uint32) error {
+	return c.cmd("UID STORE", uidArg(uid), `+FLAGS.SILENT (\Seen)`)
+}
 
-		if markSeen {
-			stag := fmt.Sprintf("S%d", i)
-			_ = cmd(conn, reader, stag, fmt.Sprintf("STORE %d +FLAGS (\\Seen)", uid))
+// Idle waits in IMAP IDLE until the server reports new messages (true), refresh elapses (false;
+// servers drop idle clients after 30 minutes) or ctx is done.
+func (c *Client) Idle(ctx context.Context, refresh time.Duration) (bool, error) {
+	if !c.HasCapability("IDLE") {
+		return false, ErrIdleUnsupported
+	}
+	tag := c.nextTag()
+	if _, err := fmt.Fprintf(c.conn, "%s IDLE\r\n", tag); err != nil {
+		return false, err
+	}
+	if err := c.waitContinuation(tag); err != nil {
+		return false, fmt.Errorf("IDLE rejected: %w", err)
+	}
+
+	// A read deadline ends the wait on refresh; cancelling ctx moves the deadline to now
+	_ = c.conn.SetReadDeadline(time.Now().Add(refresh))
+	stop := context.AfterFunc(ctx, func() { _ = c.conn.SetReadDeadline(time.Now()) })
+	newMail := false
+	for !newMail {
+		resp, err := c.readResponse()
+		if err != nil {
+			var netErr net.Error
+			if !errors.As(err, &netErr) || !netErr.Timeout() {
+				stop()
+				return false, err
+			}
+			break
 		}
+		newMail = existsPattern.MatchString(resp.text)
+	}
+	stop()
+	_ = c.conn.SetReadDeadline(time.Time{})
+	if err := ctx.Err(); err != nil {
+		return false, err
 	}
 
-	_ = cmd(conn, reader, "A999", "LOGOUT")
-	return results, nil
+	if _, err := fmt.Fprint(c.conn, "DONE\r\n"); err != nil {
+		return false, err
+	}
+	if err := c.waitTagged(tag); err != nil {
+		return false, err
+	}
+	return newMail, nil
 }
 
-func cmd(conn net.Conn, reader *bufio.Reader, tag, c string) error {
-	_, err := fmt.Fprintf(conn, "%s %s\r\n", tag, c)
-	if err != nil {
-		return err
+func (c *Client) nextTag() string {
+	c.tagNum++
+	return fmt.Sprintf("A%03d", c.tagNum)
+}
+
+var (
+	literalPattern     = regexp.MustCompile(`\{(\d+)\+?\}$`)
+	sizePattern        = regexp.MustCompile(`RFC822\.SIZE (\d+)`)
+	flagsPattern       = regexp.MustCompile(`FLAGS \(([^)]*)\)`)
+	fetchPattern       = regexp.MustCompile(`^\* \d+ FETCH `)
+	uidPattern         = regexp.MustCompile(`[( ]UID (\d+)`)
+	existsPattern      = regexp.MustCompile(`^\* (\d+) EXISTS`)
+	uidValidityPattern = regexp.MustCompile(`\[UIDVALIDITY (\d+)\]`)
+	uidNextPattern     = regexp.MustCompile(`\[UIDNEXT (\d+)\]`)
+)
+
+// literal is a command argument sent as a synchronizing literal: {n}, then the bytes once the
+// server asks for them.
+type literal []byte
+
+// astring renders s as an IMAP string argument: quoted, or a literal when it contains bytes a
+// quoted string cannot carry (CR, LF, NUL or 8-bit characters).
+func astring(s string) interface{} {
+	for i := 0; i < len(s); i++ {
+		if b := s[i]; b == '\r' || b == '\n' || b == 0 || b >= 0x80 {
+			return literal(s)
+		}
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// mailboxArg renders a mailbox name: INBOX is case-insensitive and sent as-is, anything else is
+// encoded to modified UTF-7 and quoted.
+func mailboxArg(name string) interface{} {
+	if strings.EqualFold(name, "INBOX") {
+		return "INBOX"
+	}
+	return astring(EncodeMailbox(name))
+}
+
+// send writes a tagged command; string arguments are written verbatim and literal arguments are
+// sent after the server's continuation request.
+func (c *Client) send(tag string, args ...interface{}) error {
+	var b strings.Builder
+	b.WriteString(tag)
+	for _, arg := range args {
+		b.WriteByte(' ')
+		switch v := arg.(type) {
+		case string:
+			b.WriteString(v)
+		case literal:
+			fmt.Fprintf(&b, "{%d}\r\n", len(v))
+			if _, err := io.WriteString(c.conn, b.String()); err != nil {
+				return err
+			}
+			b.Reset()
+			if err := c.waitContinuation(tag); err != nil {
+				return err
+			}
+			if _, err := c.conn.Write(v); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unsupported IMAP argument %T", arg)
+		}
 	}
+	b.WriteString("\r\n")
+	_, err := io.WriteString(c.conn, b.String())
+	return err
+}
+
+// waitContinuation reads until the server's "+" continuation request, failing if the command
+// is completed (rejected) instead.
+func (c *Client) waitContinuation(tag string) error {
 	for {
-		resp, err := readLine(reader)
+		resp, err := c.readResponse()
 		if err != nil {
 			return err
 		}
-		if strings.HasPrefix(resp, tag+" OK") {
+		if strings.HasPrefix(resp.text, "+") {
 			return nil
 		}
-		if strings.HasPrefix(resp, tag+" NO") || strings.HasPrefix(resp, tag+" BAD") {
-			return fmt.Errorf("%s", resp)
+		if strings.HasPrefix(resp.text, tag+" ") {
+			if err := taggedResult(tag, resp.text); err != nil {
+				return err
+			}
+			return fmt.Errorf("unexpected completion: %s", resp.text)
 		}
 	}
 }
 
-func cmdUntagged(conn net.Conn, reader *bufio.Reader, tag, c string) ([]string, error) {
-	_, err := fmt.Fprintf(conn, "%s %s\r\n", tag, c)
-	if err != nil {
+// execute runs a command and returns its untagged responses, or an error for NO/BAD.
+func (c *Client) execute(args ...interface{}) ([]response, error) {
+	tag := c.nextTag()
+	if err := c.send(tag, args...); err != nil {
 		return nil, err
 	}
-	var lines []string
+	var resps []response
 	for {
-		resp, err := readLine(reader)
+		resp, err := c.readResponse()
 		if err != nil {
-			return lines, err
+			return resps, err
 		}
-		if strings.HasPrefix(resp, tag+" OK") {
-			return lines, nil
+		if strings.HasPrefix(resp.text, tag+" ") {
+			return resps, taggedResult(tag, resp.text)
 		}
-		if strings.HasPrefix(resp, tag+" NO") || strings.HasPrefix(resp, tag+" BAD") {
-			return lines, fmt.Errorf("%s", resp)
-		}
-		lines = append(lines, resp)
+		resps = append(resps, resp)
 	}
 }
 
-func readLine(reader *bufio.Reader) (string, error) {
-	line, err := reader.ReadString('\n')
-	if err != nil && err != io.EOF {
-		return "", err
-	}
-	return strings.TrimRight(line, "\r\n"), nil
+func (c *Client) cmd(args ...interface{}) error {
+	_, err := c.execute(args...)
+	return err
 }
 
-func parseSearch(lines []string) []int {
-	var uids []int
-	for _, line := range lines {
-		if strings.Contains(line, "SEARCH") {
-			parts := strings.Fields(line)
-			for _, p := range parts {
-				if uid, err := strconv.Atoi(p); err == nil {
-					uids = append(uids, uid)
-				}
-			}
+func (c *Client) waitTagged(tag string) error {
+	for {
+		resp, err := c.readResponse()
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(resp.text, tag+" ") {
+			return taggedResult(tag, resp.text)
 		}
 	}
-	return uids
 }
 
-func parseHeaders(lines []string, seqNum int) map[string]interface{} {
-	data := map[string]interface{}{"seqNum": seqNum}
-	full := strings.Join(lines, "\n")
+func taggedResult(tag, line string) error {
+	if strings.HasPrefix(line, tag+" OK") {
+		return nil
+	}
+	return errors.New(line)
+}
 
-	extract := func(name string) string {
-		lower := strings.ToLower(full)
-		key := strings.ToLower(name) + ":"
-		idx := strings.Index(lower, key)
-		if idx < 0 {
-			return ""
+// readResponse reads one response, following literals: a line ending in {n} is followed by n
+// raw bytes and then the rest of the response on the next line.
+func (c *Client) readResponse() (response, error) {
+	var resp response
+	var text strings.Builder
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return resp, err
+		}
+		text.WriteString(line)
+		m := literalPattern.FindStringSubmatch(line)
+		if m == nil {
+			resp.text = text.String()
+			return resp, nil
+		}
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			return resp, fmt.Errorf("invalid literal size in %q", line)
 		}
-		start := idx + len(key)
-		end := strings.IndexAny(full[start:], "\r\n)")
-		if end < 0 {
-			end = len(full) - start
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(c.reader, buf); err != nil {
+			return resp, err
 		}
-		return strings.TrimSpace(full[start : start+end])
+		resp.literals = append(resp.literals, buf)
 	}
+}
 
-	if v := extract("From"); v != "" {
-		data["from"] = v
-	}
-	if v := extract("To"); v != "" {
-		data["to"] = v
-	}
-	if v := extract("Cc"); v != "" {
-		data["cc"] = v
-	}
-	if v := extract("Subject"); v != "" {
-		data["subject"] = v
-	}
-	if v := extract("Date"); v != "" {
-		data["date"] = v
-	}
-	if v := extract("Message-ID"); v != "" {
-		data["messageId"] = v
+func (c *Client) readLine() (string, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", err
 	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
 
-	return data
+func parseSearch(resps []response) []uint32 {
+	var uids []uint32
+	for _, resp := range resps {
+		if !strings.HasPrefix(strings.ToUpper(resp.text), "* SEARCH") {
+			continue
+		}
+		for _, p := range strings.Fields(resp.text)[2:] {
+			if uid, err := strconv.ParseUint(p, 10, 32); err == nil {
+				uids = append(uids, uint32(uid))
+			}
+		}
+	}
+	return uids
 }
 
-func escape(s string) string {
-	s = strings.ReplaceAll(s, `\`, `\\`)
-	s = strings.ReplaceAll(s, `"`, `\"`)
-	return s
+func parseUint32(s string) uint32 {
+	n, _ := strconv.ParseUint(s, 10, 32)
+	return uint32(n)
 }
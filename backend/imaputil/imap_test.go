@@ -0,0 +1,112 @@
+package imaputil
+
+import (
+	"bufio"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       string
+		text     string
+		literals []string
+	}{
+		{
+			name: "plain line",
+			in:   "* OK [UIDVALIDITY 7] UIDs valid\r\n",
+			text: "* OK [UIDVALIDITY 7] UIDs valid",
+		},
+		{
+			name:     "literal",
+			in:       "* 1 FETCH (UID 5 BODY[] {11}\r\nHello\r\nBye!)\r\n",
+			text:     "* 1 FETCH (UID 5 BODY[] {11})",
+			literals: []string{"Hello\r\nBye!"},
+		},
+		{
+			name:     "non-synchronizing literal",
+			in:       "* 1 FETCH (BODY[] {3+}\r\nabc)\r\n",
+			text:     "* 1 FETCH (BODY[] {3+})",
+			literals: []string{"abc"},
+		},
+		{
+			name:     "several literals",
+			in:       "* 1 FETCH (BODY[HEADER] {2}\r\nab BODY[TEXT] {0}\r\n)\r\n",
+			text:     "* 1 FETCH (BODY[HEADER] {2} BODY[TEXT] {0})",
+			literals: []string{"ab", ""},
+		},
+		{
+			name:     "literal containing a literal marker",
+			in:       "* 2 FETCH (BODY[] {6}\r\n{99}\r\n)\r\n",
+			text:     "* 2 FETCH (BODY[] {6})",
+			literals: []string{"{99}\r\n"},
+		},
+		{
+			name: "braces not at the end of the line",
+			in:   "* OK {5} is not a literal here\r\n",
+			text: "* OK {5} is not a literal here",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Client{reader: bufio.NewReader(strings.NewReader(tt.in))}
+			resp, err := c.readResponse()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.text != tt.text {
+				t.Errorf("text = %q, want %q", resp.text, tt.text)
+			}
+			var literals []string
+			for _, l := range resp.literals {
+				literals = append(literals, string(l))
+			}
+			if !reflect.DeepEqual(literals, tt.literals) {
+				t.Errorf("literals = %q, want %q", literals, tt.literals)
+			}
+		})
+	}
+}
+
+func TestReadResponseTruncatedLiteral(t *testing.T) {
+	c := &Client{reader: bufio.NewReader(strings.NewReader("* 1 FETCH (BODY[] {10}\r\nshort"))}
+	if _, err := c.readResponse(); err == nil {
+		t.Fatal("expected an error for a literal cut short")
+	}
+}
+
+func TestMailboxUTF7(t *testing.T) {
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{"INBOX", "INBOX"},
+		{"Sent Items", "Sent Items"},
+		{"A&B", "A&-B"},
+		{"Entwürfe", "Entw&APw-rfe"},
+		{"~peter/mail/台北/日本語", "~peter/mail/&U,BTFw-/&ZeVnLIqe-"},
+		{"Inbox 📧", "Inbox &2D3c5w-"},
+		{"日本語&Co", "&ZeVnLIqe-&-Co"},
+	}
+	for _, tt := range tests {
+		if got := EncodeMailbox(tt.name); got != tt.encoded {
+			t.Errorf("EncodeMailbox(%q) = %q, want %q", tt.name, got, tt.encoded)
+		}
+		got, err := DecodeMailbox(tt.encoded)
+		if err != nil {
+			t.Errorf("DecodeMailbox(%q): %v", tt.encoded, err)
+		} else if got != tt.name {
+			t.Errorf("DecodeMailbox(%q) = %q, want %q", tt.encoded, got, tt.name)
+		}
+	}
+}
+
+func TestDecodeMailboxInvalid(t *testing.T) {
+	for _, in := range []string{"&ZeVn", "&Z-", "&!!!-"} {
+		if got, err := DecodeMailbox(in); err == nil {
+			t.Errorf("DecodeMailbox(%q) = %q, want an error", in, got)
+		}
+	}
+}
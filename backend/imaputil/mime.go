@@ -0,0 +1,199 @@
+package imaputil
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// maxMIMEDepth bounds multipart nesting so a malicious message cannot recurse forever.
+const maxMIMEDepth = 10
+
+// wordDecoder decodes RFC 2047 encoded-words in any charset known to the WHATWG index.
+var wordDecoder = &mime.WordDecoder{CharsetReader: charsetReader}
+
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "", "utf-8", "utf8", "us-ascii", "ascii":
+		return input, nil
+	}
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported charset %q", charset)
+	}
+	return enc.NewDecoder().Reader(input), nil
+}
+
+// decodeHeader decodes encoded-words in a header value, falling back to the raw value.
+func decodeHeader(v string) string {
+	if d, err := wordDecoder.DecodeHeader(v); err == nil {
+		return d
+	}
+	return v
+}
+
+// ParseMessage parses a full RFC 5322 message into the email_receive input: decoded headers,
+// the text and HTML bodies and an attachments list. Attachment content is base64; attachments
+// larger than maxAttachmentBytes (0 = no limit) are listed without content.
+func ParseMessage(raw []byte, maxAttachmentBytes int) (map[string]interface{}, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("parse message: %w", err)
+	}
+	data := parseHeaderFields(msg.Header)
+	data["size"] = len(raw)
+
+	p := &mimeParser{maxAttachment: maxAttachmentBytes, attachments: []map[string]interface{}{}}
+	if err := p.walk(textproto.MIMEHeader(msg.Header), msg.Body, 0); err != nil {
+		data["parseError"] = err.Error()
+	}
+	data["text"] = strings.Join(p.text, "\n")
+	data["html"] = strings.Join(p.html, "\n")
+	data["attachments"] = p.attachments
+	return data, nil
+}
+
+// ParseHeader parses just a message header block (used when the message exceeds the size
+// limit and only its header was fetched).
+func ParseHeader(raw []byte) (map[string]interface{}, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("parse header: %w", err)
+	}
+	return parseHeaderFields(msg.Header), nil
+}
+
+func parseHeaderFields(h mail.Header) map[string]interface{} {
+	data := map[string]interface{}{}
+	for key, name := range map[string]string{
+		"from": "From", "to": "To", "cc": "Cc", "replyTo": "Reply-To", "subject": "Subject",
+		"date": "Date", "messageId": "Message-ID", "inReplyTo": "In-Reply-To", "references": "References",
+	} {
+		if v := h.Get(name); v != "" {
+			data[key] = decodeHeader(v)
+		}
+	}
+	if addr, err := mail.ParseAddress(h.Get("From")); err == nil {
+		data["fromAddress"] = addr.Address
+		data["fromName"] = addr.Name
+	}
+	return data
+}
+
+type mimeParser struct {
+	maxAttachment int
+	text, html    []string
+	attachments   []map[string]interface{}
+}
+
+// walk visits a MIME entity: multiparts are descended into, text parts without a filename become
+// the body and everything else is an attachment.
+func (p *mimeParser) walk(h textproto.MIMEHeader, body io.Reader, depth int) error {
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		if depth >= maxMIMEDepth {
+			return fmt.Errorf("MIME nesting deeper than %d", maxMIMEDepth)
+		}
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := p.walk(part.Header, part, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	disposition, dparams, _ := mime.ParseMediaType(h.Get("Content-Disposition"))
+	filename := dparams["filename"]
+	if filename == "" {
+		filename = params["name"]
+	}
+	filename = decodeHeader(filename)
+	content := transferDecoder(h.Get("Content-Transfer-Encoding"), body)
+
+	isText := mediaType == "text/plain" || mediaType == "text/html"
+	if isText && disposition != "attachment" && filename == "" {
+		b, err := io.ReadAll(content)
+		if err != nil {
+			return err
+		}
+		s := decodeCharset(params["charset"], b)
+		if mediaType == "text/html" {
+			p.html = append(p.html, s)
+		} else {
+			p.text = append(p.text, s)
+		}
+		return nil
+	}
+
+	att := map[string]interface{}{
+		"filename":    filename,
+		"contentType": mediaType,
+		"inline":      disposition == "inline",
+	}
+	if cid := strings.Trim(h.Get("Content-Id"), "<> "); cid != "" {
+		att["contentId"] = cid
+	}
+	var b []byte
+	if p.maxAttachment > 0 {
+		b, err = io.ReadAll(io.LimitReader(content, int64(p.maxAttachment)+1))
+	} else {
+		b, err = io.ReadAll(content)
+	}
+	if err != nil {
+		return err
+	}
+	if p.maxAttachment > 0 && len(b) > p.maxAttachment {
+		rest, _ := io.Copy(io.Discard, content)
+		att["size"] = int64(len(b)) + rest
+		att["omitted"] = true
+	} else {
+		att["size"] = len(b)
+		att["content"] = base64.StdEncoding.EncodeToString(b)
+	}
+	p.attachments = append(p.attachments, att)
+	return nil
+}
+
+func transferDecoder(encoding string, r io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, r)
+	case "quoted-printable":
+		return quotedprintable.NewReader(r)
+	}
+	return r
+}
+
+// decodeCharset converts text in the given charset to UTF-8, keeping the bytes as-is when the
+// charset is unknown.
+func decodeCharset(charset string, b []byte) string {
+	r, err := charsetReader(charset, bytes.NewReader(b))
+	if err != nil {
+		return string(b)
+	}
+	out, err := io.ReadAll(r)
+	if err != nil {
+		return string(b)
+	}
+	return string(out)
+}
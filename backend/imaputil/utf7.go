@@ -0,0 +1,78 @@
+package imaputil
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+// utf7Encoding is the base64 variant of modified UTF-7 (RFC 3501 5.1.3): "," replaces "/" and
+// there is no padding.
+var utf7Encoding = base64.NewEncoding("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+,").WithPadding(base64.NoPadding)
+
+// EncodeMailbox encodes a mailbox name to IMAP modified UTF-7: printable ASCII stays as-is, "&"
+// becomes "&-" and other characters are UTF-16 base64 runs between "&" and "-".
+func EncodeMailbox(name string) string {
+	var b strings.Builder
+	var run []rune
+	flush := func() {
+		if len(run) == 0 {
+			return
+		}
+		u := utf16.Encode(run)
+		buf := make([]byte, 0, len(u)*2)
+		for _, r := range u {
+			buf = append(buf, byte(r>>8), byte(r))
+		}
+		b.WriteByte('&')
+		b.WriteString(utf7Encoding.EncodeToString(buf))
+		b.WriteByte('-')
+		run = run[:0]
+	}
+	for _, r := range name {
+		switch {
+		case r == '&':
+			flush()
+			b.WriteString("&-")
+		case r >= 0x20 && r <= 0x7e:
+			flush()
+			b.WriteRune(r)
+		default:
+			run = append(run, r)
+		}
+	}
+	flush()
+	return b.String()
+}
+
+// DecodeMailbox decodes a modified UTF-7 mailbox name as returned by LIST.
+func DecodeMailbox(name string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] != '&' {
+			b.WriteByte(name[i])
+			continue
+		}
+		end := strings.IndexByte(name[i:], '-')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated shift in mailbox name %q", name)
+		}
+		enc := name[i+1 : i+end]
+		i += end
+		if enc == "" {
+			b.WriteByte('&')
+			continue
+		}
+		buf, err := utf7Encoding.DecodeString(enc)
+		if err != nil || len(buf)%2 != 0 {
+			return "", fmt.Errorf("invalid mailbox name %q", name)
+		}
+		u := make([]uint16, len(buf)/2)
+		for j := range u {
+			u[j] = uint16(buf[2*j])<<8 | uint16(buf[2*j+1])
+		}
+		b.WriteString(string(utf16.Decode(u)))
+	}
+	return b.String(), nil
+}
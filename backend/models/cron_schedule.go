@@ -6,11 +6,48 @@ import "time"
 type CronSchedule struct {
 	ID         int64      `json:"id"`
 	WorkflowID int64      `json:"workflowId"`
-	Expression string     `json:"expression"` // cron expression e.g. "*/5 * * * *"
-	Timezone   string     `json:"timezone"`
+	Expression string     `json:"expression"` // cron expression e.g. "*/5 * * * *" (optional leading seconds field)
+	Timezone   string     `json:"timezone"`   // IANA name the expression is evaluated in
 	Enabled    bool       `json:"enabled"`
 	LastRunAt  *time.Time `json:"lastRunAt,omitempty"`
 	NextRunAt  *time.Time `json:"nextRunAt,omitempty"`
-	CreatedAt  time.Time  `json:"createdAt"`
-	UpdatedAt  time.Time  `json:"updatedAt"`
+
+	OverlapPolicy string `json:"overlapPolicy"` // allow (default), skip, queue, cancel_previous
+	MisfirePolicy string `json:"misfirePolicy"` // on startup: skip (default), run_once, run_all
+	MisfireMax    int    `json:"misfireMax"`    // max catch-up runs for run_all
+	JitterSec     int    `json:"jitterSec"`     // random start delay of 0..JitterSec seconds
+
+	ExecTimeoutSec int `json:"execTimeoutSec"` // per-execution timeout (default 300)
+
+	CalendarIDs []int64 `json:"calendarIds"` // exclusion calendars (see ScheduleCalendar)
+
+	// Payload is passed to the workflow as input.payload on every run of this schedule.
+	Payload map[string]interface{} `json:"payload,omitempty"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Overlap and misfire policies for CronSchedule.
+const (
+	OverlapAllow          = "allow"
+	OverlapSkip           = "skip"
+	OverlapQueue          = "queue"
+	OverlapCancelPrevious = "cancel_previous"
+
+	MisfireSkip    = "skip"
+	MisfireRunOnce = "run_once"
+	MisfireRunAll  = "run_all"
+)
+
+// CronScheduleEvent records a scheduler decision for a schedule: a run, a tick skipped or
+// queued because of the overlap policy, a cancelled run, or a misfire catch-up/skip.
+type CronScheduleEvent struct {
+	ID          int64      `json:"id"`
+	ScheduleID  int64      `json:"scheduleId"`
+	Event       string     `json:"event"`
+	ScheduledAt *time.Time `json:"scheduledAt,omitempty"`
+	ExecutionID *int64     `json:"executionId,omitempty"`
+	Detail      string     `json:"detail,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
 }
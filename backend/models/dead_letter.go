@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// DeadLetter is a trigger event whose workflow run failed, kept with its payload so it can be
+// inspected and replayed.
+type DeadLetter struct {
+	ID          int64                  `json:"id"`
+	TriggerType string                 `json:"triggerType"` // redis, redis_stream, email, smtp, http
+	TriggerID   int64                  `json:"triggerId"`   // subscription / trigger row of that type
+	WorkflowID  int64                  `json:"workflowId"`
+	Payload     map[string]interface{} `json:"payload,omitempty"` // workflow input of the failed run
+	ExecutionID *int64                 `json:"executionId,omitempty"`
+	Error       string                 `json:"error"`
+	Status      string                 `json:"status"` // pending, replayed
+	ReplayCount int                    `json:"replayCount"`
+	// ReplayExecutionID is the execution of the latest replay.
+	ReplayExecutionID *int64     `json:"replayExecutionId,omitempty"`
+	ReplayedAt        *time.Time `json:"replayedAt,omitempty"`
+	CreatedAt         time.Time  `json:"createdAt"`
+	UpdatedAt         time.Time  `json:"updatedAt"`
+}
+
+// Trigger types recorded on dead letters.
+const (
+	TriggerRedis       = "redis"
+	TriggerRedisStream = "redis_stream"
+	TriggerEmail       = "email"
+	TriggerSMTP        = "smtp"
+	TriggerHTTP        = "http"
+)
+
+// DeadLetter statuses: pending until a replay succeeds.
+const (
+	DeadLetterPending  = "pending"
+	DeadLetterReplayed = "replayed"
+)
+
+// DeadLetterFilter selects dead letters for listing, bulk replay and purge. Zero fields match all.
+type DeadLetterFilter struct {
+	TriggerType string     `json:"triggerType,omitempty"`
+	TriggerID   int64      `json:"triggerId,omitempty"`
+	WorkflowID  int64      `json:"workflowId,omitempty"`
+	Status      string     `json:"status,omitempty"`
+	Since       *time.Time `json:"since,omitempty"`  // created at or after
+	Before      *time.Time `json:"before,omitempty"` // created before
+}
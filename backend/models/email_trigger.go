@@ -2,18 +2,65 @@ package models
 
 import "time"
 
-// EmailTrigger represents an IMAP email polling trigger that runs a workflow on new emails.
+// EmailTrigger represents an IMAP email trigger that runs a workflow on new emails. New
+// messages are found by UID, so each is processed once whatever its flags; with UseIdle the
+// server pushes them via IMAP IDLE, falling back to polling when IDLE is unsupported.
 type EmailTrigger struct {
-	ID              int64      `json:"id"`
-	WorkflowID      int64      `json:"workflowId"`
-	ConfigID        int64      `json:"configId"`
-	Mailbox         string     `json:"mailbox"`
-	PollIntervalSec int        `json:"pollIntervalSec"`
-	MarkSeen        bool       `json:"markSeen"`
-	MaxFetch        int        `json:"maxFetch"`
-	Enabled         bool       `json:"enabled"`
-	LastPollAt      *time.Time `json:"lastPollAt,omitempty"`
-	MsgCount        int64      `json:"msgCount"`
-	CreatedAt       time.Time  `json:"createdAt"`
-	UpdatedAt       time.Time  `json:"updatedAt"`
+	ID              int64  `json:"id"`
+	WorkflowID      int64  `json:"workflowId"`
+	ConfigID        int64  `json:"configId"`
+	Mailbox         string `json:"mailbox"`
+	PollIntervalSec int    `json:"pollIntervalSec"`
+	MarkSeen        bool   `json:"markSeen"`
+	MaxFetch        int    `json:"maxFetch"`
+	UseIdle         bool   `json:"useIdle"`
+	// Messages larger than MaxMessageBytes are delivered header-only (truncated: true);
+	// attachments larger than MaxAttachmentBytes are listed without content.
+	MaxMessageBytes    int        `json:"maxMessageBytes"`
+	MaxAttachmentBytes int        `json:"maxAttachmentBytes"`
+	Enabled            bool       `json:"enabled"`
+	LastPollAt         *time.Time `json:"lastPollAt,omitempty"`
+	MsgCount           int64      `json:"msgCount"`
+	CreatedAt          time.Time  `json:"createdAt"`
+	UpdatedAt          time.Time  `json:"updatedAt"`
+	// UID cursor: messages above LastUID in a mailbox with this UIDVALIDITY are new. Maintained
+	// by the poller; ignored on create and update.
+	UIDValidity int64 `json:"uidValidity"`
+	LastUID     int64 `json:"lastUid"`
+	// Where the cursor starts on the first poll: after the existing mail (new) or before the
+	// oldest unread message (unseen).
+	StartFrom string `json:"startFrom"`
+	// Post-processing of the triggering message depending on the run's outcome
+	OnSuccess []EmailAction `json:"onSuccess"`
+	OnFailure []EmailAction `json:"onFailure"`
+	TriggerLimits
+}
+
+// Default email size limits.
+const (
+	DefaultEmailMaxMessageBytes    = 25 << 20
+	DefaultEmailMaxAttachmentBytes = 10 << 20
+)
+
+// Email trigger StartFrom values.
+const (
+	EmailStartNew    = "new"
+	EmailStartUnseen = "unseen"
+)
+
+// Email post-processing action types.
+const (
+	EmailActionMove   = "move"   // move to Mailbox (MOVE, or COPY + delete)
+	EmailActionCopy   = "copy"   // copy to Mailbox
+	EmailActionFlag   = "flag"   // add Flags
+	EmailActionUnflag = "unflag" // remove Flags
+	EmailActionDelete = "delete" // flag \Deleted and expunge
+)
+
+// EmailAction is a step applied by UID to the message that triggered a run, after the run
+// finishes. Move and delete remove the message, so they must come last.
+type EmailAction struct {
+	Type    string   `json:"type"`
+	Mailbox string   `json:"mailbox,omitempty"`
+	Flags   []string `json:"flags,omitempty"` // system flags (\Flagged) or keywords (processed)
 }
@@ -5,11 +5,102 @@ import "time"
 // HttpTrigger represents an HTTP endpoint that triggers a workflow (like Node-RED HTTP-in).
 // When a request matches the path and method, the workflow runs with request data as input.
 type HttpTrigger struct {
-	ID         int64     `json:"id"`
-	WorkflowID int64     `json:"workflowId"`
-	Path       string    `json:"path"`   // e.g. "webhook" or "api/v1/events"
-	Method     string    `json:"method"` // GET, POST, PUT, DELETE, etc.
-	Enabled    bool      `json:"enabled"`
-	CreatedAt  time.Time `json:"createdAt"`
-	UpdatedAt  time.Time `json:"updatedAt"`
+	ID            int64                  `json:"id"`
+	WorkflowID    int64                  `json:"workflowId"`
+	Path          string                 `json:"path"`   // e.g. "webhook", "orders/{id}/items" or "files/*"
+	Method        string                 `json:"method"` // GET, POST, PUT, DELETE, etc.
+	Enabled       bool                   `json:"enabled"`
+	RequestSchema map[string]interface{} `json:"requestSchema,omitempty"` // optional JSON Schema for the request body
+	Auth          *HttpTriggerAuth       `json:"auth,omitempty"`          // nil: anyone reaching /api/in may call it
+	Limits        HttpTriggerLimits      `json:"limits"`
+	CreatedAt     time.Time              `json:"createdAt"`
+	UpdatedAt     time.Time              `json:"updatedAt"`
 }
+
+// HTTP trigger authentication types.
+const (
+	HttpAuthNone   = "none"
+	HttpAuthAPIKey = "api_key" // static key in a header or query parameter
+	HttpAuthHMAC   = "hmac"    // signature of the raw body in a header (GitHub, Stripe, Slack style)
+	HttpAuthBasic  = "basic"   // HTTP Basic auth
+	HttpAuthJWT    = "jwt"     // bearer JWT verified with a shared secret or a JWKS
+)
+
+// HMAC signature header formats.
+const (
+	HmacFormatPlain  = "plain"  // the header holds the signature, after an optional Prefix
+	HmacFormatStripe = "stripe" // the header holds "t=<timestamp>,v1=<signature>[,v1=...]"
+)
+
+// HttpTriggerAuth is the authentication policy of an HTTP trigger. Secrets are never stored on
+// the trigger: SecretKey names the config store entry holding the API key, the HMAC secret, the
+// Basic auth password or the JWT shared secret.
+type HttpTriggerAuth struct {
+	Type      string `json:"type"`
+	SecretKey string `json:"secretKey,omitempty"`
+	// api_key: the header carrying the key (default X-API-Key), or QueryParam when set.
+	// hmac: the header carrying the signature.
+	Header     string `json:"header,omitempty"`
+	QueryParam string `json:"queryParam,omitempty"`
+	// hmac: the signature is Algorithm (sha256, sha1, sha512) over the raw body, Encoding (hex,
+	// base64), after Prefix (e.g. "sha256=") in a plain header. With a timestamp - the stripe
+	// format's t=, or TimestampHeader - the signed payload is SignedPayload with {timestamp} and
+	// {body} replaced (default "{timestamp}.{body}", Slack uses "v0:{timestamp}:{body}").
+	Algorithm       string `json:"algorithm,omitempty"`
+	Encoding        string `json:"encoding,omitempty"`
+	Prefix          string `json:"prefix,omitempty"`
+	Format          string `json:"format,omitempty"`
+	TimestampHeader string `json:"timestampHeader,omitempty"`
+	SignedPayload   string `json:"signedPayload,omitempty"`
+	// hmac: maximum age of the signature timestamp; jwt: clock skew allowed on exp and nbf.
+	ToleranceSec int `json:"toleranceSec,omitempty"`
+	// basic
+	Username string `json:"username,omitempty"`
+	// jwt: tokens are verified with the HS* secret at SecretKey or the keys published at JWKSURL;
+	// iss and aud must match Issuer and Audience when set.
+	JWKSURL  string `json:"jwksUrl,omitempty"`
+	Issuer   string `json:"issuer,omitempty"`
+	Audience string `json:"audience,omitempty"`
+}
+
+// Defaults applied to unset HttpTriggerAuth fields.
+const (
+	DefaultHttpAPIKeyHeader      = "X-API-Key"
+	DefaultHmacToleranceSec      = 300
+	DefaultJWTLeewaySec          = 60
+	DefaultHmacSignedPayload     = "{timestamp}.{body}"
+	DefaultHmacAlgorithm         = "sha256"
+	DefaultHmacSignatureEncoding = "hex"
+)
+
+// HttpTriggerLimits protects an HTTP trigger from oversized, excessive or unwanted requests,
+// which are answered with 413, 429 (with Retry-After) or 403 before any execution is created.
+// Rate and concurrency are counted per API instance.
+type HttpTriggerLimits struct {
+	MaxBodyBytes int64 `json:"maxBodyBytes"` // default DefaultHttpMaxBodyBytes
+	// Token bucket: RatePerSec requests per second on average, up to Burst at once (0 = no limit).
+	// RateKey shares a bucket between all callers (global), or gives one to each client IP (ip)
+	// or each value of RateKeyHeader (header), e.g. an API key. Beyond 10000 active keys per
+	// instance, further keys share one bucket.
+	RatePerSec    float64 `json:"ratePerSec"`
+	Burst         int     `json:"burst"`
+	RateKey       string  `json:"rateKey"`
+	RateKeyHeader string  `json:"rateKeyHeader,omitempty"`
+	MaxConcurrent int     `json:"maxConcurrent"` // executions running at once (0 = no limit)
+	// CIDR lists matched against the client IP: denied addresses are refused, and with an allow
+	// list only listed addresses are accepted. Behind a proxy, ClientIPHeader (e.g.
+	// X-Forwarded-For, last entry) gives the client IP instead of the connection's address.
+	AllowCIDRs     []string `json:"allowCidrs,omitempty"`
+	DenyCIDRs      []string `json:"denyCidrs,omitempty"`
+	ClientIPHeader string   `json:"clientIpHeader,omitempty"`
+}
+
+// Rate limit keys for HttpTriggerLimits.
+const (
+	RateKeyGlobal = "global"
+	RateKeyIP     = "ip"
+	RateKeyHeader = "header"
+)
+
+// DefaultHttpMaxBodyBytes bounds request bodies of triggers without an explicit limit.
+const DefaultHttpMaxBodyBytes = 10 << 20
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// LeaderLease is the current holder of a named leadership lease (e.g. "triggers").
+type LeaderLease struct {
+	Name       string    `json:"name"`
+	Holder     string    `json:"holder"`
+	AcquiredAt time.Time `json:"acquiredAt"`
+	RenewedAt  time.Time `json:"renewedAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	// Valid is false when the lease has expired and nobody holds leadership.
+	Valid bool `json:"valid"`
+	// Version is bumped whenever trigger definitions change so the leader can reload them.
+	Version int64 `json:"version"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// QueuedJob is a workflow execution waiting in (or taken from) the durable execution queue.
+type QueuedJob struct {
+	ID          int64                  `json:"id"`
+	WorkflowID  int64                  `json:"workflowId"`
+	Input       map[string]interface{} `json:"input,omitempty"`
+	Source      string                 `json:"source"` // cron, redis, email, api
+	Status      string                 `json:"status"`
+	Attempts    int                    `json:"attempts"`
+	MaxAttempts int                    `json:"maxAttempts"`
+	WorkerID    string                 `json:"workerId,omitempty"`
+	// VisibleAt is when a pending job may be claimed, or when a running job's lease expires
+	// and it is considered abandoned.
+	VisibleAt   time.Time `json:"visibleAt"`
+	TimeoutMs   int64     `json:"timeoutMs"`
+	ExecutionID *int64    `json:"executionId,omitempty"`
+	LastError   string    `json:"lastError,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// QueuedJob statuses.
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobCompleted = "completed"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
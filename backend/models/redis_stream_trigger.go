@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// RedisStreamTrigger consumes a Redis stream through a consumer group and triggers a workflow
+// per entry. Entries are acknowledged only after their execution completes (at-least-once).
+type RedisStreamTrigger struct {
+	ID             int64      `json:"id"`
+	WorkflowID     int64      `json:"workflowId"`
+	ConfigID       int64      `json:"configId"`       // references node_configs (type=redis)
+	Stream         string     `json:"stream"`         // stream key
+	Group          string     `json:"group"`          // consumer group, created on first use
+	Consumer       string     `json:"consumer"`       // consumer name; empty = instance ID
+	StartID        string     `json:"startId"`        // where a new group starts: "$" (new entries) or "0" (whole stream)
+	Concurrency    int        `json:"concurrency"`    // max entries processed at once
+	ClaimIdleSec   int        `json:"claimIdleSec"`   // pending entries idle this long are reclaimed (XAUTOCLAIM)
+	ExecTimeoutSec int        `json:"execTimeoutSec"` // per-execution timeout (default 300)
+	MaxDeliveries  int        `json:"maxDeliveries"`  // deliveries of an entry that could not be run before it is dead-lettered (default 5)
+	Enabled        bool       `json:"enabled"`
+	LastMsgAt      *time.Time `json:"lastMsgAt,omitempty"`
+	MsgCount       int64      `json:"msgCount"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+}
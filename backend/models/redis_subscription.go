@@ -14,4 +14,27 @@ type RedisSubscription struct {
 	MsgCount   int64      `json:"msgCount"`
 	CreatedAt  time.Time  `json:"createdAt"`
 	UpdatedAt  time.Time  `json:"updatedAt"`
+	TriggerLimits
 }
+
+// RedisSubscriptionStatus is the connection health of a running subscription listener.
+type RedisSubscriptionStatus struct {
+	SubscriptionID int64      `json:"subscriptionId"`
+	State          string     `json:"state"` // connecting, connected, reconnecting, stopped, disabled
+	Instance       string     `json:"instance,omitempty"`
+	ConnectedAt    *time.Time `json:"connectedAt,omitempty"`
+	LastError      string     `json:"lastError,omitempty"`
+	LastErrorAt    *time.Time `json:"lastErrorAt,omitempty"`
+	ReconnectCount int64      `json:"reconnectCount"`
+	NextRetryAt    *time.Time `json:"nextRetryAt,omitempty"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+}
+
+// Redis subscription listener states.
+const (
+	RedisSubConnecting   = "connecting"
+	RedisSubConnected    = "connected"
+	RedisSubReconnecting = "reconnecting"
+	RedisSubStopped      = "stopped"
+	RedisSubDisabled     = "disabled"
+)
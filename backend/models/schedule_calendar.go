@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// ScheduleCalendar is a named set of exclusions (holidays, maintenance windows) that cron
+// schedules can reference so they do not fire during those periods.
+type ScheduleCalendar struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Timezone the dates are interpreted in; empty means the referencing schedule's timezone.
+	Timezone string `json:"timezone"`
+	// Dates are whole days: "2026-12-25" for a single date or "12-25" for every year.
+	Dates []string `json:"dates"`
+	// Windows are absolute time ranges, e.g. a maintenance window.
+	Windows   []CalendarWindow `json:"windows"`
+	CreatedAt time.Time        `json:"createdAt"`
+	UpdatedAt time.Time        `json:"updatedAt"`
+}
+
+// CalendarWindow is an excluded time range [Start, End).
+type CalendarWindow struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Label string    `json:"label,omitempty"`
+}
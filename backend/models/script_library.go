@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// ScriptLibrary is a named JavaScript module that function nodes can load with require(name).
+// Every change to Code bumps Version; previous versions are kept in ScriptLibraryVersion.
+type ScriptLibrary struct {
+	ID          int64     `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Code        string    `json:"code"`
+	Version     int       `json:"version"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// ScriptLibraryVersion is an immutable snapshot of a library's code.
+type ScriptLibraryVersion struct {
+	ID        int64     `json:"id"`
+	LibraryID int64     `json:"libraryId"`
+	Version   int       `json:"version"`
+	Code      string    `json:"code"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// SmtpTrigger runs a workflow for mail delivered to the embedded SMTP receiver. Recipients are
+// full addresses (alerts@eflo.example.com) or whole domains (@eflo.example.com); an address
+// match wins over a domain match. The message is parsed like an email_receive trigger's.
+type SmtpTrigger struct {
+	ID         int64    `json:"id"`
+	WorkflowID int64    `json:"workflowId"`
+	Recipients []string `json:"recipients"`
+	Enabled    bool     `json:"enabled"`
+	// Attachments larger than MaxAttachmentBytes are listed without content.
+	MaxAttachmentBytes int        `json:"maxAttachmentBytes"`
+	LastReceivedAt     *time.Time `json:"lastReceivedAt,omitempty"`
+	MsgCount           int64      `json:"msgCount"`
+	CreatedAt          time.Time  `json:"createdAt"`
+	UpdatedAt          time.Time  `json:"updatedAt"`
+	TriggerLimits
+}
+
+// SpooledMessage is mail accepted by the SMTP receiver and kept until its run ends. The instance
+// running it holds a lease until LeasedUntil; a message whose lease lapsed (the instance
+// stopped) is taken over and run by another.
+type SpooledMessage struct {
+	ID          int64                  `json:"id"`
+	TriggerID   int64                  `json:"triggerId"`
+	WorkflowID  int64                  `json:"workflowId"`
+	Payload     map[string]interface{} `json:"payload,omitempty"`
+	Owner       string                 `json:"owner"`
+	LeasedUntil time.Time              `json:"leasedUntil"`
+	CreatedAt   time.Time              `json:"createdAt"`
+}
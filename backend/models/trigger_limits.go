@@ -0,0 +1,25 @@
+package models
+
+// TriggerLimits bounds how an event-driven trigger (Redis subscription, email) runs its
+// workflow: up to MaxInFlight executions at once, with up to BufferSize further events waiting
+// for a free slot. OverflowPolicy decides what happens to an event when the buffer is full.
+type TriggerLimits struct {
+	MaxInFlight    int    `json:"maxInFlight"`    // concurrent executions (default 1)
+	BufferSize     int    `json:"bufferSize"`     // events waiting for a slot (default 100)
+	OverflowPolicy string `json:"overflowPolicy"` // block (default), drop_oldest, reject
+	ExecTimeoutSec int    `json:"execTimeoutSec"` // per-execution timeout (default 300)
+}
+
+// Overflow policies for TriggerLimits.
+const (
+	OverflowBlock      = "block"       // wait for space, pausing the source
+	OverflowDropOldest = "drop_oldest" // discard the longest-waiting event
+	OverflowReject     = "reject"      // discard the new event
+)
+
+// Defaults applied to unset TriggerLimits fields.
+const (
+	DefaultTriggerMaxInFlight    = 1
+	DefaultTriggerBufferSize     = 100
+	DefaultTriggerExecTimeoutSec = 300
+)
@@ -3,6 +3,7 @@ package repository
 import (
 	"database/sql"
 	"eflo/backend/models"
+	"encoding/json"
 )
 
 type CronScheduleRepo struct {
@@ -13,11 +14,18 @@ func NewCronScheduleRepo(db *sql.DB) *CronScheduleRepo {
 	return &CronScheduleRepo{DB: db}
 }
 
+const cronScheduleColumns = `id, workflow_id, expression, timezone, enabled, last_run_at, next_run_at,
+	overlap_policy, misfire_policy, misfire_max, jitter_sec, calendar_ids, payload, exec_timeout_sec,
+	created_at, updated_at`
+
 func (r *CronScheduleRepo) Create(s *models.CronSchedule) (int64, error) {
 	res, err := r.DB.Exec(
-		`INSERT INTO cron_schedules (workflow_id, expression, timezone, enabled, next_run_at) 
-		 VALUES (?, ?, ?, ?, ?)`,
+		`INSERT INTO cron_schedules (workflow_id, expression, timezone, enabled, next_run_at,
+		 overlap_policy, misfire_policy, misfire_max, jitter_sec, calendar_ids, payload, exec_timeout_sec)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		s.WorkflowID, s.Expression, s.Timezone, s.Enabled, s.NextRunAt,
+		s.OverlapPolicy, s.MisfirePolicy, s.MisfireMax, s.JitterSec, calendarIDsJSON(s.CalendarIDs),
+		nullableJSON(s.Payload), s.ExecTimeoutSec,
 	)
 	if err != nil {
 		return 0, err
@@ -27,7 +35,7 @@ func (r *CronScheduleRepo) Create(s *models.CronSchedule) (int64, error) {
 
 func (r *CronScheduleRepo) GetByID(id int64) (*models.CronSchedule, error) {
 	row := r.DB.QueryRow(
-		`SELECT id, workflow_id, expression, timezone, enabled, last_run_at, next_run_at, created_at, updated_at 
+		`SELECT `+cronScheduleColumns+`
 		 FROM cron_schedules WHERE id = ?`, id,
 	)
 	return r.scanRow(row)
@@ -35,7 +43,7 @@ func (r *CronScheduleRepo) GetByID(id int64) (*models.CronSchedule, error) {
 
 func (r *CronScheduleRepo) GetByWorkflowID(workflowID int64) ([]*models.CronSchedule, error) {
 	rows, err := r.DB.Query(
-		`SELECT id, workflow_id, expression, timezone, enabled, last_run_at, next_run_at, created_at, updated_at 
+		`SELECT `+cronScheduleColumns+`
 		 FROM cron_schedules WHERE workflow_id = ? ORDER BY id ASC`, workflowID,
 	)
 	if err != nil {
@@ -47,7 +55,7 @@ func (r *CronScheduleRepo) GetByWorkflowID(workflowID int64) ([]*models.CronSche
 
 func (r *CronScheduleRepo) ListEnabled() ([]*models.CronSchedule, error) {
 	rows, err := r.DB.Query(
-		`SELECT id, workflow_id, expression, timezone, enabled, last_run_at, next_run_at, created_at, updated_at 
+		`SELECT ` + cronScheduleColumns + `
 		 FROM cron_schedules WHERE enabled = 1 ORDER BY id ASC`,
 	)
 	if err != nil {
@@ -59,7 +67,7 @@ func (r *CronScheduleRepo) ListEnabled() ([]*models.CronSchedule, error) {
 
 func (r *CronScheduleRepo) List() ([]*models.CronSchedule, error) {
 	rows, err := r.DB.Query(
-		`SELECT id, workflow_id, expression, timezone, enabled, last_run_at, next_run_at, created_at, updated_at 
+		`SELECT ` + cronScheduleColumns + `
 		 FROM cron_schedules ORDER BY id ASC`,
 	)
 	if err != nil {
@@ -71,8 +79,12 @@ func (r *CronScheduleRepo) List() ([]*models.CronSchedule, error) {
 
 func (r *CronScheduleRepo) Update(s *models.CronSchedule) error {
 	_, err := r.DB.Exec(
-		`UPDATE cron_schedules SET expression = ?, timezone = ?, enabled = ?, next_run_at = ? WHERE id = ?`,
-		s.Expression, s.Timezone, s.Enabled, s.NextRunAt, s.ID,
+		`UPDATE cron_schedules SET expression = ?, timezone = ?, enabled = ?, next_run_at = ?,
+		 overlap_policy = ?, misfire_policy = ?, misfire_max = ?, jitter_sec = ?, calendar_ids = ?,
+		 payload = ?, exec_timeout_sec = ? WHERE id = ?`,
+		s.Expression, s.Timezone, s.Enabled, s.NextRunAt,
+		s.OverlapPolicy, s.MisfirePolicy, s.MisfireMax, s.JitterSec, calendarIDsJSON(s.CalendarIDs),
+		nullableJSON(s.Payload), s.ExecTimeoutSec, s.ID,
 	)
 	return err
 }
@@ -85,19 +97,94 @@ func (r *CronScheduleRepo) UpdateLastRun(id int64, lastRunAt, nextRunAt interfac
 	return err
 }
 
+func (r *CronScheduleRepo) UpdateNextRun(id int64, nextRunAt interface{}) error {
+	_, err := r.DB.Exec(`UPDATE cron_schedules SET next_run_at = ? WHERE id = ?`, nextRunAt, id)
+	return err
+}
+
 func (r *CronScheduleRepo) Delete(id int64) error {
 	_, err := r.DB.Exec("DELETE FROM cron_schedules WHERE id = ?", id)
 	return err
 }
 
+// RecordEvent stores a scheduler decision (run, skipped, queued, misfire, ...) for a schedule.
+func (r *CronScheduleRepo) RecordEvent(e *models.CronScheduleEvent) error {
+	_, err := r.DB.Exec(
+		`INSERT INTO cron_schedule_events (schedule_id, event, scheduled_at, execution_id, detail)
+		 VALUES (?, ?, ?, ?, ?)`,
+		e.ScheduleID, e.Event, e.ScheduledAt, e.ExecutionID, e.Detail,
+	)
+	return err
+}
+
+// ListEvents returns the most recent events for a schedule, newest first.
+func (r *CronScheduleRepo) ListEvents(scheduleID int64, limit int) ([]*models.CronScheduleEvent, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	rows, err := r.DB.Query(
+		`SELECT id, schedule_id, event, scheduled_at, execution_id, detail, created_at
+		 FROM cron_schedule_events WHERE schedule_id = ? ORDER BY id DESC LIMIT ?`,
+		scheduleID, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []*models.CronScheduleEvent
+	for rows.Next() {
+		e := &models.CronScheduleEvent{}
+		var scheduledAt sql.NullTime
+		var execID sql.NullInt64
+		var detail sql.NullString
+		if err := rows.Scan(&e.ID, &e.ScheduleID, &e.Event, &scheduledAt, &execID, &detail, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		if scheduledAt.Valid {
+			e.ScheduledAt = &scheduledAt.Time
+		}
+		if execID.Valid {
+			e.ExecutionID = &execID.Int64
+		}
+		e.Detail = detail.String
+		list = append(list, e)
+	}
+	return list, nil
+}
+
 func (r *CronScheduleRepo) scanRow(row *sql.Row) (*models.CronSchedule, error) {
+	return scanCronSchedule(row)
+}
+
+func (r *CronScheduleRepo) scanRows(rows *sql.Rows) ([]*models.CronSchedule, error) {
+	var schedules []*models.CronSchedule
+	for rows.Next() {
+		s, err := scanCronSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, s)
+	}
+	return schedules, nil
+}
+
+func scanCronSchedule(sc interface{ Scan(...interface{}) error }) (*models.CronSchedule, error) {
 	s := &models.CronSchedule{}
 	var lastRun, nextRun sql.NullTime
-	err := row.Scan(&s.ID, &s.WorkflowID, &s.Expression, &s.Timezone, &s.Enabled,
-		&lastRun, &nextRun, &s.CreatedAt, &s.UpdatedAt)
+	var calendarIDs, payload []byte
+	err := sc.Scan(&s.ID, &s.WorkflowID, &s.Expression, &s.Timezone, &s.Enabled,
+		&lastRun, &nextRun, &s.OverlapPolicy, &s.MisfirePolicy, &s.MisfireMax, &s.JitterSec,
+		&calendarIDs, &payload, &s.ExecTimeoutSec, &s.CreatedAt, &s.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
+	if len(calendarIDs) > 0 {
+		_ = json.Unmarshal(calendarIDs, &s.CalendarIDs)
+	}
+	if len(payload) > 0 {
+		_ = json.Unmarshal(payload, &s.Payload)
+	}
 	if lastRun.Valid {
 		s.LastRunAt = &lastRun.Time
 	}
@@ -107,23 +194,10 @@ func (r *CronScheduleRepo) scanRow(row *sql.Row) (*models.CronSchedule, error) {
 	return s, nil
 }
 
-func (r *CronScheduleRepo) scanRows(rows *sql.Rows) ([]*models.CronSchedule, error) {
-	var schedules []*models.CronSchedule
-	for rows.Next() {
-		s := &models.CronSchedule{}
-		var lastRun, nextRun sql.NullTime
-		err := rows.Scan(&s.ID, &s.WorkflowID, &s.Expression, &s.Timezone, &s.Enabled,
-			&lastRun, &nextRun, &s.CreatedAt, &s.UpdatedAt)
-		if err != nil {
-			return nil, err
-		}
-		if lastRun.Valid {
-			s.LastRunAt = &lastRun.Time
-		}
-		if nextRun.Valid {
-			s.NextRunAt = &nextRun.Time
-		}
-		schedules = append(schedules, s)
+func calendarIDsJSON(ids []int64) interface{} {
+	if len(ids) == 0 {
+		return nil
 	}
-	return schedules, nil
+	b, _ := json.Marshal(ids)
+	return string(b)
 }
@@ -0,0 +1,181 @@
+package repository
+
+import (
+	"database/sql"
+	"eflo/backend/models"
+	"encoding/json"
+	"strings"
+)
+
+// DeadLetterRepo stores trigger events whose workflow run failed.
+type DeadLetterRepo struct {
+	DB *sql.DB
+}
+
+func NewDeadLetterRepo(db *sql.DB) *DeadLetterRepo {
+	return &DeadLetterRepo{DB: db}
+}
+
+const deadLetterColumns = `id, trigger_type, trigger_id, workflow_id, payload, execution_id, error, status,
+	replay_count, replay_execution_id, replayed_at, created_at, updated_at`
+
+func (r *DeadLetterRepo) Create(d *models.DeadLetter) (int64, error) {
+	res, err := r.DB.Exec(
+		`INSERT INTO dead_letters (trigger_type, trigger_id, workflow_id, payload, execution_id, error, status)
+		 VALUES (?, ?, ?, ?, ?, ?, 'pending')`,
+		d.TriggerType, d.TriggerID, d.WorkflowID, nullableJSON(d.Payload), d.ExecutionID, d.Error,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (r *DeadLetterRepo) GetByID(id int64) (*models.DeadLetter, error) {
+	row := r.DB.QueryRow(`SELECT `+deadLetterColumns+` FROM dead_letters WHERE id = ?`, id)
+	return scanDeadLetter(row)
+}
+
+// List returns dead letters matching f, newest first.
+func (r *DeadLetterRepo) List(f models.DeadLetterFilter, limit, offset int) ([]*models.DeadLetter, error) {
+	where, args := deadLetterWhere(f)
+	args = append(args, limit, offset)
+	rows, err := r.DB.Query(
+		`SELECT `+deadLetterColumns+` FROM dead_letters`+where+` ORDER BY id DESC LIMIT ? OFFSET ?`, args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []*models.DeadLetter
+	for rows.Next() {
+		d, err := scanDeadLetter(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, d)
+	}
+	return list, rows.Err()
+}
+
+// Count returns how many dead letters match f.
+func (r *DeadLetterRepo) Count(f models.DeadLetterFilter) (int64, error) {
+	where, args := deadLetterWhere(f)
+	var n int64
+	err := r.DB.QueryRow(`SELECT COUNT(*) FROM dead_letters`+where, args...).Scan(&n)
+	return n, err
+}
+
+// IDs returns the IDs of up to limit dead letters matching f, oldest first (replay order).
+func (r *DeadLetterRepo) IDs(f models.DeadLetterFilter, limit int) ([]int64, error) {
+	where, args := deadLetterWhere(f)
+	args = append(args, limit)
+	rows, err := r.DB.Query(`SELECT id FROM dead_letters`+where+` ORDER BY id ASC LIMIT ?`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// RecordReplay stores the outcome of a replay: a successful one marks the dead letter replayed,
+// a failed one keeps it pending with the new error.
+func (r *DeadLetterRepo) RecordReplay(id int64, execID *int64, errMsg string) error {
+	if errMsg == "" {
+		_, err := r.DB.Exec(
+			`UPDATE dead_letters SET status = 'replayed', replay_count = replay_count + 1, replay_execution_id = ?,
+			 replayed_at = NOW() WHERE id = ?`, execID, id,
+		)
+		return err
+	}
+	_, err := r.DB.Exec(
+		`UPDATE dead_letters SET replay_count = replay_count + 1, replay_execution_id = ?, replayed_at = NOW(),
+		 error = ? WHERE id = ?`, execID, errMsg, id,
+	)
+	return err
+}
+
+func (r *DeadLetterRepo) Delete(id int64) error {
+	_, err := r.DB.Exec("DELETE FROM dead_letters WHERE id = ?", id)
+	return err
+}
+
+// Purge deletes all dead letters matching f and returns how many were removed.
+func (r *DeadLetterRepo) Purge(f models.DeadLetterFilter) (int64, error) {
+	where, args := deadLetterWhere(f)
+	res, err := r.DB.Exec(`DELETE FROM dead_letters`+where, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func deadLetterWhere(f models.DeadLetterFilter) (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	if f.TriggerType != "" {
+		conds = append(conds, "trigger_type = ?")
+		args = append(args, f.TriggerType)
+	}
+	if f.TriggerID > 0 {
+		conds = append(conds, "trigger_id = ?")
+		args = append(args, f.TriggerID)
+	}
+	if f.WorkflowID > 0 {
+		conds = append(conds, "workflow_id = ?")
+		args = append(args, f.WorkflowID)
+	}
+	if f.Status != "" {
+		conds = append(conds, "status = ?")
+		args = append(args, f.Status)
+	}
+	if f.Since != nil {
+		conds = append(conds, "created_at >= ?")
+		args = append(args, *f.Since)
+	}
+	if f.Before != nil {
+		conds = append(conds, "created_at < ?")
+		args = append(args, *f.Before)
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+func scanDeadLetter(sc interface{ Scan(...interface{}) error }) (*models.DeadLetter, error) {
+	d := &models.DeadLetter{}
+	var payload []byte
+	var execID, replayExecID sql.NullInt64
+	var errMsg sql.NullString
+	var replayedAt sql.NullTime
+	err := sc.Scan(&d.ID, &d.TriggerType, &d.TriggerID, &d.WorkflowID, &payload, &execID, &errMsg, &d.Status,
+		&d.ReplayCount, &replayExecID, &replayedAt, &d.CreatedAt, &d.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if len(payload) > 0 {
+		_ = json.Unmarshal(payload, &d.Payload)
+	}
+	if execID.Valid {
+		d.ExecutionID = &execID.Int64
+	}
+	d.Error = errMsg.String
+	if replayExecID.Valid {
+		d.ReplayExecutionID = &replayExecID.Int64
+	}
+	if replayedAt.Valid {
+		d.ReplayedAt = &replayedAt.Time
+	}
+	return d, nil
+}
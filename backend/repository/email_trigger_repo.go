@@ -3,6 +3,7 @@ package repository
 import (
 	"database/sql"
 	"eflo/backend/models"
+	"encoding/json"
 )
 
 type EmailTriggerRepo struct {
@@ -15,9 +16,13 @@ func NewEmailTriggerRepo(db *sql.DB) *EmailTriggerRepo {
 
 func (r *EmailTriggerRepo) Create(t *models.EmailTrigger) (int64, error) {
 	res, err := r.DB.Exec(
-		`INSERT INTO email_triggers (workflow_id, config_id, mailbox, poll_interval_sec, mark_seen, max_fetch, enabled)
-		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		`INSERT INTO email_triggers (workflow_id, config_id, mailbox, poll_interval_sec, mark_seen, max_fetch, enabled,
+		 max_in_flight, buffer_size, overflow_policy, exec_timeout_sec, max_message_bytes, max_attachment_bytes,
+		 use_idle, start_from, on_success, on_failure)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		t.WorkflowID, t.ConfigID, t.Mailbox, t.PollIntervalSec, t.MarkSeen, t.MaxFetch, t.Enabled,
+		t.MaxInFlight, t.BufferSize, t.OverflowPolicy, t.ExecTimeoutSec, t.MaxMessageBytes, t.MaxAttachmentBytes,
+		t.UseIdle, t.StartFrom, emailActionsJSON(t.OnSuccess), emailActionsJSON(t.OnFailure),
 	)
 	if err != nil {
 		return 0, err
@@ -27,7 +32,8 @@ func (r *EmailTriggerRepo) Create(t *models.EmailTrigger) (int64, error) {
 
 func (r *EmailTriggerRepo) GetByID(id int64) (*models.EmailTrigger, error) {
 	row := r.DB.QueryRow(
-		`SELECT id, workflow_id, config_id, mailbox, poll_interval_sec, mark_seen, max_fetch, enabled, last_poll_at, msg_count, created_at, updated_at
+		`SELECT id, workflow_id, config_id, mailbox, poll_interval_sec, mark_seen, max_fetch, enabled, last_poll_at, msg_count, created_at, updated_at, max_in_flight, buffer_size, overflow_policy, exec_timeout_sec,
+		 max_message_bytes, max_attachment_bytes, use_idle, uid_validity, last_uid, start_from, on_success, on_failure
 		 FROM email_triggers WHERE id = ?`, id,
 	)
 	return r.scanRow(row)
@@ -35,7 +41,8 @@ func (r *EmailTriggerRepo) GetByID(id int64) (*models.EmailTrigger, error) {
 
 func (r *EmailTriggerRepo) List() ([]*models.EmailTrigger, error) {
 	rows, err := r.DB.Query(
-		`SELECT id, workflow_id, config_id, mailbox, poll_interval_sec, mark_seen, max_fetch, enabled, last_poll_at, msg_count, created_at, updated_at
+		`SELECT id, workflow_id, config_id, mailbox, poll_interval_sec, mark_seen, max_fetch, enabled, last_poll_at, msg_count, created_at, updated_at, max_in_flight, buffer_size, overflow_policy, exec_timeout_sec,
+		 max_message_bytes, max_attachment_bytes, use_idle, uid_validity, last_uid, start_from, on_success, on_failure
 		 FROM email_triggers ORDER BY id ASC`,
 	)
 	if err != nil {
@@ -47,7 +54,8 @@ func (r *EmailTriggerRepo) List() ([]*models.EmailTrigger, error) {
 
 func (r *EmailTriggerRepo) ListEnabled() ([]*models.EmailTrigger, error) {
 	rows, err := r.DB.Query(
-		`SELECT id, workflow_id, config_id, mailbox, poll_interval_sec, mark_seen, max_fetch, enabled, last_poll_at, msg_count, created_at, updated_at
+		`SELECT id, workflow_id, config_id, mailbox, poll_interval_sec, mark_seen, max_fetch, enabled, last_poll_at, msg_count, created_at, updated_at, max_in_flight, buffer_size, overflow_policy, exec_timeout_sec,
+		 max_message_bytes, max_attachment_bytes, use_idle, uid_validity, last_uid, start_from, on_success, on_failure
 		 FROM email_triggers WHERE enabled = 1 ORDER BY id ASC`,
 	)
 	if err != nil {
@@ -59,8 +67,14 @@ func (r *EmailTriggerRepo) ListEnabled() ([]*models.EmailTrigger, error) {
 
 func (r *EmailTriggerRepo) Update(t *models.EmailTrigger) error {
 	_, err := r.DB.Exec(
-		`UPDATE email_triggers SET mailbox = ?, poll_interval_sec = ?, mark_seen = ?, max_fetch = ?, enabled = ?, config_id = ? WHERE id = ?`,
-		t.Mailbox, t.PollIntervalSec, t.MarkSeen, t.MaxFetch, t.Enabled, t.ConfigID, t.ID,
+		`UPDATE email_triggers SET mailbox = ?, poll_interval_sec = ?, mark_seen = ?, max_fetch = ?, enabled = ?, config_id = ?,
+		 max_in_flight = ?, buffer_size = ?, overflow_policy = ?, exec_timeout_sec = ?,
+		 max_message_bytes = ?, max_attachment_bytes = ?, use_idle = ?, start_from = ?,
+		 on_success = ?, on_failure = ? WHERE id = ?`,
+		t.Mailbox, t.PollIntervalSec, t.MarkSeen, t.MaxFetch, t.Enabled, t.ConfigID,
+		t.MaxInFlight, t.BufferSize, t.OverflowPolicy, t.ExecTimeoutSec,
+		t.MaxMessageBytes, t.MaxAttachmentBytes, t.UseIdle, t.StartFrom,
+		emailActionsJSON(t.OnSuccess), emailActionsJSON(t.OnFailure), t.ID,
 	)
 	return err
 }
@@ -72,39 +86,61 @@ func (r *EmailTriggerRepo) IncrementMsgCount(id int64) error {
 	return err
 }
 
+// SaveCursor stores the IMAP UID cursor of a trigger.
+func (r *EmailTriggerRepo) SaveCursor(id, uidValidity, lastUID int64) error {
+	_, err := r.DB.Exec(`UPDATE email_triggers SET uid_validity = ?, last_uid = ? WHERE id = ?`, uidValidity, lastUID, id)
+	return err
+}
+
 func (r *EmailTriggerRepo) Delete(id int64) error {
 	_, err := r.DB.Exec("DELETE FROM email_triggers WHERE id = ?", id)
 	return err
 }
 
 func (r *EmailTriggerRepo) scanRow(row *sql.Row) (*models.EmailTrigger, error) {
+	return scanEmailTrigger(row)
+}
+
+func (r *EmailTriggerRepo) scanRows(rows *sql.Rows) ([]*models.EmailTrigger, error) {
+	var triggers []*models.EmailTrigger
+	for rows.Next() {
+		t, err := scanEmailTrigger(rows)
+		if err != nil {
+			return nil, err
+		}
+		triggers = append(triggers, t)
+	}
+	return triggers, nil
+}
+
+func scanEmailTrigger(sc interface{ Scan(...interface{}) error }) (*models.EmailTrigger, error) {
 	t := &models.EmailTrigger{}
 	var lastPoll sql.NullTime
-	err := row.Scan(&t.ID, &t.WorkflowID, &t.ConfigID, &t.Mailbox, &t.PollIntervalSec,
-		&t.MarkSeen, &t.MaxFetch, &t.Enabled, &lastPoll, &t.MsgCount, &t.CreatedAt, &t.UpdatedAt)
+	var onSuccess, onFailure []byte
+	err := sc.Scan(&t.ID, &t.WorkflowID, &t.ConfigID, &t.Mailbox, &t.PollIntervalSec,
+		&t.MarkSeen, &t.MaxFetch, &t.Enabled, &lastPoll, &t.MsgCount, &t.CreatedAt, &t.UpdatedAt,
+		&t.MaxInFlight, &t.BufferSize, &t.OverflowPolicy, &t.ExecTimeoutSec,
+		&t.MaxMessageBytes, &t.MaxAttachmentBytes, &t.UseIdle, &t.UIDValidity, &t.LastUID,
+		&t.StartFrom, &onSuccess, &onFailure)
 	if err != nil {
 		return nil, err
 	}
 	if lastPoll.Valid {
 		t.LastPollAt = &lastPoll.Time
 	}
+	if len(onSuccess) > 0 {
+		_ = json.Unmarshal(onSuccess, &t.OnSuccess)
+	}
+	if len(onFailure) > 0 {
+		_ = json.Unmarshal(onFailure, &t.OnFailure)
+	}
 	return t, nil
 }
 
-func (r *EmailTriggerRepo) scanRows(rows *sql.Rows) ([]*models.EmailTrigger, error) {
-	var triggers []*models.EmailTrigger
-	for rows.Next() {
-		t := &models.EmailTrigger{}
-		var lastPoll sql.NullTime
-		err := rows.Scan(&t.ID, &t.WorkflowID, &t.ConfigID, &t.Mailbox, &t.PollIntervalSec,
-			&t.MarkSeen, &t.MaxFetch, &t.Enabled, &lastPoll, &t.MsgCount, &t.CreatedAt, &t.UpdatedAt)
-		if err != nil {
-			return nil, err
-		}
-		if lastPoll.Valid {
-			t.LastPollAt = &lastPoll.Time
-		}
-		triggers = append(triggers, t)
+func emailActionsJSON(actions []models.EmailAction) interface{} {
+	if len(actions) == 0 {
+		return nil
 	}
-	return triggers, nil
+	b, _ := json.Marshal(actions)
+	return string(b)
 }
@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"eflo/backend/models"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ExecutionQueueRepo is the MySQL execution queue. A claimed job is leased to a worker until
+// visible_at; a running job whose lease expired is treated as abandoned and claimed again.
+type ExecutionQueueRepo struct {
+	DB *sql.DB
+}
+
+func NewExecutionQueueRepo(db *sql.DB) *ExecutionQueueRepo {
+	return &ExecutionQueueRepo{DB: db}
+}
+
+const executionQueueColumns = `id, workflow_id, input, source, status, attempts, max_attempts, worker_id,
+	visible_at, timeout_ms, execution_id, last_error, created_at, updated_at`
+
+func (r *ExecutionQueueRepo) Enqueue(ctx context.Context, j *models.QueuedJob) (int64, error) {
+	res, err := r.DB.ExecContext(ctx,
+		`INSERT INTO execution_queue (workflow_id, input, source, status, max_attempts, visible_at, timeout_ms)
+		 VALUES (?, ?, ?, 'pending', ?, NOW(6), ?)`,
+		j.WorkflowID, nullableJSON(j.Input), j.Source, j.MaxAttempts, j.TimeoutMs,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// Claim leases the oldest available job (pending, or running with an expired lease) to workerID
+// for visibility. It returns nil when the queue is empty. The returned job's Attempts includes
+// this claim; callers give up on jobs whose Attempts exceed MaxAttempts.
+func (r *ExecutionQueueRepo) Claim(ctx context.Context, workerID string, visibility time.Duration) (*models.QueuedJob, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	row := tx.QueryRowContext(ctx,
+		`SELECT `+executionQueueColumns+` FROM execution_queue
+		 WHERE status IN ('pending', 'running') AND visible_at <= NOW(6)
+		 ORDER BY id ASC LIMIT 1 FOR UPDATE SKIP LOCKED`,
+	)
+	j, err := scanQueuedJob(row)
+	if err == sql.ErrNoRows {
+		return nil, tx.Commit()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if j.Status == models.JobRunning {
+		j.LastError = fmt.Sprintf("abandoned by worker %s", j.WorkerID)
+	}
+	j.Status = models.JobRunning
+	j.WorkerID = workerID
+	j.Attempts++
+	_, err = tx.ExecContext(ctx,
+		`UPDATE execution_queue SET status = 'running', worker_id = ?, attempts = ?, last_error = ?,
+		 visible_at = DATE_ADD(NOW(6), INTERVAL ? MICROSECOND) WHERE id = ?`,
+		workerID, j.Attempts, j.LastError, visibility.Microseconds(), j.ID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return j, tx.Commit()
+}
+
+// Heartbeat extends workerID's lease on a running job. It returns false when the job is no
+// longer leased to workerID (cancelled, or reclaimed after the lease expired).
+func (r *ExecutionQueueRepo) Heartbeat(ctx context.Context, id int64, workerID string, visibility time.Duration) (bool, error) {
+	res, err := r.DB.ExecContext(ctx,
+		`UPDATE execution_queue SET visible_at = DATE_ADD(NOW(6), INTERVAL ? MICROSECOND)
+		 WHERE id = ? AND worker_id = ? AND status = 'running'`,
+		visibility.Microseconds(), id, workerID,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// Complete records the result of a job run by workerID; an empty errMsg means success.
+func (r *ExecutionQueueRepo) Complete(ctx context.Context, id int64, workerID string, execID int64, errMsg string) error {
+	status := models.JobCompleted
+	if errMsg != "" {
+		status = models.JobFailed
+	}
+	var execRef interface{}
+	if execID != 0 {
+		execRef = execID
+	}
+	_, err := r.DB.ExecContext(ctx,
+		`UPDATE execution_queue SET status = ?, execution_id = ?, last_error = ?
+		 WHERE id = ? AND worker_id = ? AND status = 'running'`,
+		status, execRef, errMsg, id, workerID,
+	)
+	return err
+}
+
+// Cancel marks a pending or running job as cancelled; a running job's worker stops it at its
+// next heartbeat.
+func (r *ExecutionQueueRepo) Cancel(ctx context.Context, id int64) error {
+	_, err := r.DB.ExecContext(ctx,
+		`UPDATE execution_queue SET status = 'cancelled' WHERE id = ? AND status IN ('pending', 'running')`, id,
+	)
+	return err
+}
+
+func (r *ExecutionQueueRepo) Get(ctx context.Context, id int64) (*models.QueuedJob, error) {
+	row := r.DB.QueryRowContext(ctx, `SELECT `+executionQueueColumns+` FROM execution_queue WHERE id = ?`, id)
+	return scanQueuedJob(row)
+}
+
+// Stats counts jobs by status.
+func (r *ExecutionQueueRepo) Stats(ctx context.Context) (map[string]int64, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT status, COUNT(*) FROM execution_queue GROUP BY status`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	stats := map[string]int64{}
+	for rows.Next() {
+		var status string
+		var n int64
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, err
+		}
+		stats[status] = n
+	}
+	return stats, nil
+}
+
+// PurgeFinished deletes completed, failed and cancelled jobs last updated before the cutoff.
+func (r *ExecutionQueueRepo) PurgeFinished(ctx context.Context, before time.Time) (int64, error) {
+	res, err := r.DB.ExecContext(ctx,
+		`DELETE FROM execution_queue WHERE status IN ('completed', 'failed', 'cancelled') AND updated_at < ?`, before,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func scanQueuedJob(sc interface{ Scan(...interface{}) error }) (*models.QueuedJob, error) {
+	j := &models.QueuedJob{}
+	var input []byte
+	var workerID, lastError sql.NullString
+	var execID sql.NullInt64
+	err := sc.Scan(&j.ID, &j.WorkflowID, &input, &j.Source, &j.Status, &j.Attempts, &j.MaxAttempts,
+		&workerID, &j.VisibleAt, &j.TimeoutMs, &execID, &lastError, &j.CreatedAt, &j.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if len(input) > 0 {
+		_ = json.Unmarshal(input, &j.Input)
+	}
+	j.WorkerID = workerID.String
+	j.LastError = lastError.String
+	if execID.Valid {
+		j.ExecutionID = &execID.Int64
+	}
+	return j, nil
+}
@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"eflo/backend/db"
+	"eflo/backend/models"
+)
+
+// testQueue connects to the MySQL database in EFLO_TEST_MYSQL_DSN (e.g.
+// "root:rootpass@tcp(127.0.0.1:3306)/eflo_test?parseTime=true"), migrates it and empties the
+// execution queue. The test is skipped without it.
+func testQueue(t *testing.T) *ExecutionQueueRepo {
+	t.Helper()
+	dsn := os.Getenv("EFLO_TEST_MYSQL_DSN")
+	if dsn == "" {
+		t.Skip("EFLO_TEST_MYSQL_DSN not set")
+	}
+	database, err := db.Connect(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+	if err := db.RunMigrations(database); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := database.Exec("DELETE FROM execution_queue"); err != nil {
+		t.Fatal(err)
+	}
+	return NewExecutionQueueRepo(database)
+}
+
+// enqueueJobs adds n jobs; the queue has no foreign key to workflows, so any ID does.
+func enqueueJobs(t *testing.T, q *ExecutionQueueRepo, n, maxAttempts int) []int64 {
+	t.Helper()
+	var ids []int64
+	for i := 0; i < n; i++ {
+		id, err := q.Enqueue(context.Background(), &models.QueuedJob{WorkflowID: 1, Source: "test", MaxAttempts: maxAttempts})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func TestQueueClaimSkipsLockedJobs(t *testing.T) {
+	q := testQueue(t)
+	ctx := context.Background()
+	ids := enqueueJobs(t, q, 2, 3)
+
+	// Another claimer holds the oldest job's row lock
+	tx, err := q.DB.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("SELECT id FROM execution_queue WHERE id = ? FOR UPDATE", ids[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	claimCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	job, err := q.Claim(claimCtx, "w1", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job == nil || job.ID != ids[1] {
+		t.Fatalf("Claim = %+v, want job %d (the unlocked one)", job, ids[1])
+	}
+}
+
+func TestQueueConcurrentClaimsTakeEachJobOnce(t *testing.T) {
+	q := testQueue(t)
+	const jobs, workers = 20, 5
+	enqueueJobs(t, q, jobs, 3)
+
+	var mu sync.Mutex
+	claimed := map[int64]string{}
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(worker string) {
+			defer wg.Done()
+			for {
+				job, err := q.Claim(context.Background(), worker, time.Minute)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if job == nil {
+					return
+				}
+				mu.Lock()
+				if prev, ok := claimed[job.ID]; ok {
+					t.Errorf("job %d claimed by %s and %s", job.ID, prev, worker)
+				}
+				claimed[job.ID] = worker
+				mu.Unlock()
+			}
+		}(string(rune('a' + w)))
+	}
+	wg.Wait()
+	if len(claimed) != jobs {
+		t.Errorf("%d jobs claimed, want %d", len(claimed), jobs)
+	}
+}
+
+func TestQueueReclaimsExpiredLease(t *testing.T) {
+	q := testQueue(t)
+	ctx := context.Background()
+	id := enqueueJobs(t, q, 1, 3)[0]
+
+	if job, _ := q.Claim(ctx, "w1", 200*time.Millisecond); job == nil || job.Attempts != 1 {
+		t.Fatalf("first claim = %+v", job)
+	}
+	if job, _ := q.Claim(ctx, "w2", time.Minute); job != nil {
+		t.Fatalf("job %d claimed while leased", job.ID)
+	}
+
+	time.Sleep(400 * time.Millisecond)
+	job, err := q.Claim(ctx, "w2", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job == nil || job.ID != id || job.Attempts != 2 || !strings.Contains(job.LastError, "abandoned by worker w1") {
+		t.Fatalf("reclaim = %+v, want job %d on attempt 2 abandoned by w1", job, id)
+	}
+
+	// The first worker lost the lease: its heartbeat fails and its result is ignored
+	if ok, err := q.Heartbeat(ctx, id, "w1", time.Minute); err != nil || ok {
+		t.Errorf("stale Heartbeat = %v, %v; want false", ok, err)
+	}
+	if err := q.Complete(ctx, id, "w1", 0, "late"); err != nil {
+		t.Fatal(err)
+	}
+	if j, _ := q.Get(ctx, id); j.Status != models.JobRunning || j.WorkerID != "w2" {
+		t.Errorf("after stale Complete: %s by %s, want running by w2", j.Status, j.WorkerID)
+	}
+	if err := q.Complete(ctx, id, "w2", 0, ""); err != nil {
+		t.Fatal(err)
+	}
+	if j, _ := q.Get(ctx, id); j.Status != models.JobCompleted {
+		t.Errorf("status = %s, want completed", j.Status)
+	}
+}
+
+func TestQueueHeartbeatKeepsLease(t *testing.T) {
+	q := testQueue(t)
+	ctx := context.Background()
+	id := enqueueJobs(t, q, 1, 3)[0]
+
+	if job, _ := q.Claim(ctx, "w1", 300*time.Millisecond); job == nil {
+		t.Fatal("nothing claimed")
+	}
+	for i := 0; i < 5; i++ {
+		time.Sleep(150 * time.Millisecond)
+		if ok, err := q.Heartbeat(ctx, id, "w1", 300*time.Millisecond); err != nil || !ok {
+			t.Fatalf("Heartbeat = %v, %v; want true", ok, err)
+		}
+		if job, _ := q.Claim(ctx, "w2", time.Minute); job != nil {
+			t.Fatalf("job claimed by w2 despite heartbeats")
+		}
+	}
+}
+
+func TestQueueRetriesUntilMaxAttempts(t *testing.T) {
+	q := testQueue(t)
+	ctx := context.Background()
+	id := enqueueJobs(t, q, 1, 2)[0]
+
+	// Each claim's worker crashes; the claim after the last allowed attempt reports it
+	for attempt := 1; attempt <= 3; attempt++ {
+		job, err := q.Claim(ctx, "w", 50*time.Millisecond)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job == nil || job.ID != id || job.Attempts != attempt {
+			t.Fatalf("claim %d = %+v, want job %d on attempt %d", attempt, job, id, attempt)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
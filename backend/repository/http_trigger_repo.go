@@ -3,47 +3,151 @@ package repository
 import (
 	"database/sql"
 	"eflo/backend/models"
+	"eflo/backend/routeutil"
+	"encoding/json"
+	"sync"
+	"time"
 )
 
+// httpRouteCacheTTL bounds how long the parsed routes are reused. Changes made through this repo
+// invalidate them at once; the TTL picks up changes made by other instances.
+const httpRouteCacheTTL = 5 * time.Second
+
 type HttpTriggerRepo struct {
 	DB *sql.DB
+
+	routesMu  sync.Mutex
+	routes    map[string]*httpRouteSet // method -> enabled triggers with their parsed routes
+	routesGen int64                    // bumped on every invalidation
+}
+
+// httpRouteSet is the cached routing table of one method.
+type httpRouteSet struct {
+	loadedAt time.Time
+	entries  []httpRoute
+}
+
+type httpRoute struct {
+	trigger *models.HttpTrigger
+	route   *routeutil.Route // nil: the path does not parse and only matches exactly
 }
 
 func NewHttpTriggerRepo(db *sql.DB) *HttpTriggerRepo {
 	return &HttpTriggerRepo{DB: db}
 }
 
+const httpTriggerColumns = `id, workflow_id, path, method, enabled, request_schema, auth, limits, created_at, updated_at`
+
 func (r *HttpTriggerRepo) Create(t *models.HttpTrigger) (int64, error) {
 	res, err := r.DB.Exec(
-		`INSERT INTO http_triggers (workflow_id, path, method, enabled)
-		 VALUES (?, ?, ?, ?)`,
-		t.WorkflowID, t.Path, t.Method, t.Enabled,
+		`INSERT INTO http_triggers (workflow_id, path, method, enabled, request_schema, auth, limits)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		t.WorkflowID, t.Path, t.Method, t.Enabled, nullableJSON(t.RequestSchema), httpAuthJSON(t.Auth), httpLimitsJSON(t.Limits),
 	)
 	if err != nil {
 		return 0, err
 	}
+	r.invalidateRoutes()
 	return res.LastInsertId()
 }
 
 func (r *HttpTriggerRepo) GetByID(id int64) (*models.HttpTrigger, error) {
 	row := r.DB.QueryRow(
-		`SELECT id, workflow_id, path, method, enabled, created_at, updated_at
+		`SELECT `+httpTriggerColumns+`
 		 FROM http_triggers WHERE id = ?`, id,
 	)
 	return r.scanRow(row)
 }
 
-func (r *HttpTriggerRepo) FindByPathAndMethod(path, method string) (*models.HttpTrigger, error) {
-	row := r.DB.QueryRow(
-		`SELECT id, workflow_id, path, method, enabled, created_at, updated_at
-		 FROM http_triggers WHERE path = ? AND method = ? AND enabled = 1`, path, method,
+// FindByPathAndMethod returns the enabled trigger whose route matches path, with the values of the
+// route's parameters. When several routes match, the most specific wins (see routeutil.Compare);
+// sql.ErrNoRows is returned when none does. The parsed routes of each method are cached (see
+// httpRouteCacheTTL); the returned trigger is a copy.
+func (r *HttpTriggerRepo) FindByPathAndMethod(path, method string) (*models.HttpTrigger, map[string]string, error) {
+	entries, err := r.routesFor(method)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var best *models.HttpTrigger
+	var bestRoute *routeutil.Route
+	var bestParams map[string]string
+	for _, e := range entries {
+		if e.route == nil {
+			// Paths saved before patterns were validated only match exactly
+			if e.trigger.Path == path {
+				t := *e.trigger
+				return &t, map[string]string{}, nil
+			}
+			continue
+		}
+		params, ok := e.route.Match(path)
+		if !ok {
+			continue
+		}
+		if best == nil || routeutil.Compare(e.route, bestRoute) > 0 {
+			best, bestRoute, bestParams = e.trigger, e.route, params
+		}
+	}
+	if best == nil {
+		return nil, nil, sql.ErrNoRows
+	}
+	t := *best
+	return &t, bestParams, nil
+}
+
+// routesFor returns the enabled triggers of method with their parsed routes, loading them when
+// the cached set is missing or older than httpRouteCacheTTL.
+func (r *HttpTriggerRepo) routesFor(method string) ([]httpRoute, error) {
+	r.routesMu.Lock()
+	if set := r.routes[method]; set != nil && time.Since(set.loadedAt) < httpRouteCacheTTL {
+		r.routesMu.Unlock()
+		return set.entries, nil
+	}
+	gen := r.routesGen
+	r.routesMu.Unlock()
+
+	rows, err := r.DB.Query(
+		`SELECT `+httpTriggerColumns+`
+		 FROM http_triggers WHERE method = ? AND enabled = 1 ORDER BY id ASC`, method,
 	)
-	return r.scanRow(row)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	triggers, err := r.scanRows(rows)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]httpRoute, 0, len(triggers))
+	for _, t := range triggers {
+		route, _ := routeutil.Parse(t.Path) // nil when the path does not parse
+		entries = append(entries, httpRoute{trigger: t, route: route})
+	}
+
+	// A load that raced with a change is used for this lookup only
+	r.routesMu.Lock()
+	if r.routesGen == gen {
+		if r.routes == nil {
+			r.routes = make(map[string]*httpRouteSet)
+		}
+		r.routes[method] = &httpRouteSet{loadedAt: time.Now(), entries: entries}
+	}
+	r.routesMu.Unlock()
+	return entries, nil
+}
+
+// invalidateRoutes drops the cached routes after a trigger changed.
+func (r *HttpTriggerRepo) invalidateRoutes() {
+	r.routesMu.Lock()
+	r.routes = nil
+	r.routesGen++
+	r.routesMu.Unlock()
 }
 
 func (r *HttpTriggerRepo) List() ([]*models.HttpTrigger, error) {
 	rows, err := r.DB.Query(
-		`SELECT id, workflow_id, path, method, enabled, created_at, updated_at
+		`SELECT ` + httpTriggerColumns + `
 		 FROM http_triggers ORDER BY id ASC`,
 	)
 	if err != nil {
@@ -55,7 +159,7 @@ func (r *HttpTriggerRepo) List() ([]*models.HttpTrigger, error) {
 
 func (r *HttpTriggerRepo) ListEnabled() ([]*models.HttpTrigger, error) {
 	rows, err := r.DB.Query(
-		`SELECT id, workflow_id, path, method, enabled, created_at, updated_at
+		`SELECT ` + httpTriggerColumns + `
 		 FROM http_triggers WHERE enabled = 1 ORDER BY id ASC`,
 	)
 	if err != nil {
@@ -67,34 +171,75 @@ func (r *HttpTriggerRepo) ListEnabled() ([]*models.HttpTrigger, error) {
 
 func (r *HttpTriggerRepo) Update(t *models.HttpTrigger) error {
 	_, err := r.DB.Exec(
-		`UPDATE http_triggers SET path = ?, method = ?, enabled = ? WHERE id = ?`,
-		t.Path, t.Method, t.Enabled, t.ID,
+		`UPDATE http_triggers SET path = ?, method = ?, enabled = ?, request_schema = ?, auth = ?, limits = ? WHERE id = ?`,
+		t.Path, t.Method, t.Enabled, nullableJSON(t.RequestSchema), httpAuthJSON(t.Auth), httpLimitsJSON(t.Limits), t.ID,
 	)
+	r.invalidateRoutes()
 	return err
 }
 
 func (r *HttpTriggerRepo) Delete(id int64) error {
 	_, err := r.DB.Exec("DELETE FROM http_triggers WHERE id = ?", id)
+	r.invalidateRoutes()
 	return err
 }
 
 func (r *HttpTriggerRepo) scanRow(row *sql.Row) (*models.HttpTrigger, error) {
-	t := &models.HttpTrigger{}
-	err := row.Scan(&t.ID, &t.WorkflowID, &t.Path, &t.Method, &t.Enabled, &t.CreatedAt, &t.UpdatedAt)
-	if err != nil {
-		return nil, err
-	}
-	return t, nil
+	return scanHttpTrigger(row)
 }
 
 func (r *HttpTriggerRepo) scanRows(rows *sql.Rows) ([]*models.HttpTrigger, error) {
 	var list []*models.HttpTrigger
 	for rows.Next() {
-		t := &models.HttpTrigger{}
-		if err := rows.Scan(&t.ID, &t.WorkflowID, &t.Path, &t.Method, &t.Enabled, &t.CreatedAt, &t.UpdatedAt); err != nil {
+		t, err := scanHttpTrigger(rows)
+		if err != nil {
 			return nil, err
 		}
 		list = append(list, t)
 	}
 	return list, nil
 }
+
+func scanHttpTrigger(s interface{ Scan(...interface{}) error }) (*models.HttpTrigger, error) {
+	t := &models.HttpTrigger{}
+	var schemaJSON, authJSON, limitsJSON []byte
+	if err := s.Scan(&t.ID, &t.WorkflowID, &t.Path, &t.Method, &t.Enabled, &schemaJSON, &authJSON, &limitsJSON, &t.CreatedAt, &t.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if len(schemaJSON) > 0 {
+		_ = json.Unmarshal(schemaJSON, &t.RequestSchema)
+	}
+	if len(authJSON) > 0 {
+		_ = json.Unmarshal(authJSON, &t.Auth)
+	}
+	if len(limitsJSON) > 0 {
+		_ = json.Unmarshal(limitsJSON, &t.Limits)
+	}
+	return t, nil
+}
+
+// httpAuthJSON stores NULL for a trigger without authentication.
+func httpAuthJSON(a *models.HttpTriggerAuth) interface{} {
+	if a == nil || a.Type == "" || a.Type == models.HttpAuthNone {
+		return nil
+	}
+	b, _ := json.Marshal(a)
+	return string(b)
+}
+
+func httpLimitsJSON(l models.HttpTriggerLimits) interface{} {
+	b, _ := json.Marshal(l)
+	return string(b)
+}
+
+// nullableJSON marshals a JSON column value, storing NULL for an empty map.
+func nullableJSON(m map[string]interface{}) interface{} {
+	if len(m) == 0 {
+		return nil
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil
+	}
+	return string(b)
+}
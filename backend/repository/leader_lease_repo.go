@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"eflo/backend/models"
+	"time"
+)
+
+// LeaderLeaseRepo stores leadership leases in MySQL. All times come from the database clock so
+// instances with skewed clocks agree on expiry.
+type LeaderLeaseRepo struct {
+	DB *sql.DB
+}
+
+func NewLeaderLeaseRepo(db *sql.DB) *LeaderLeaseRepo {
+	return &LeaderLeaseRepo{DB: db}
+}
+
+// TryAcquire takes the lease for holder if it is free or expired, or renews it if holder already
+// owns it. It reports whether holder owns the lease afterwards.
+func (r *LeaderLeaseRepo) TryAcquire(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	micros := ttl.Microseconds()
+	// acquired_at is assigned before holder so it still sees the previous holder
+	res, err := r.DB.ExecContext(ctx,
+		`UPDATE leader_leases SET
+		   acquired_at = IF(holder = ? AND expires_at >= NOW(6), acquired_at, NOW(6)),
+		   holder = ?, renewed_at = NOW(6), expires_at = DATE_ADD(NOW(6), INTERVAL ? MICROSECOND)
+		 WHERE name = ? AND (holder = ? OR expires_at < NOW(6))`,
+		holder, holder, micros, name, holder,
+	)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return true, nil
+	}
+	res, err = r.DB.ExecContext(ctx,
+		`INSERT IGNORE INTO leader_leases (name, holder, acquired_at, renewed_at, expires_at)
+		 VALUES (?, ?, NOW(6), NOW(6), DATE_ADD(NOW(6), INTERVAL ? MICROSECOND))`,
+		name, holder, micros,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// Release gives up the lease if holder owns it, so another instance can take over immediately.
+func (r *LeaderLeaseRepo) Release(ctx context.Context, name, holder string) error {
+	_, err := r.DB.ExecContext(ctx,
+		`UPDATE leader_leases SET expires_at = NOW(6) WHERE name = ? AND holder = ?`, name, holder,
+	)
+	return err
+}
+
+// Get returns the lease; a missing lease is returned as an invalid lease without a holder.
+func (r *LeaderLeaseRepo) Get(ctx context.Context, name string) (*models.LeaderLease, error) {
+	l := &models.LeaderLease{Name: name}
+	var acquired, renewed, expires sql.NullTime
+	err := r.DB.QueryRowContext(ctx,
+		`SELECT holder, acquired_at, renewed_at, expires_at, expires_at > NOW(6), version
+		 FROM leader_leases WHERE name = ?`, name,
+	).Scan(&l.Holder, &acquired, &renewed, &expires, &l.Valid, &l.Version)
+	if err == sql.ErrNoRows {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	l.AcquiredAt, l.RenewedAt, l.ExpiresAt = acquired.Time, renewed.Time, expires.Time
+	return l, nil
+}
+
+// BumpVersion increments the lease's change counter (creating an expired lease row if needed).
+func (r *LeaderLeaseRepo) BumpVersion(ctx context.Context, name string) error {
+	_, err := r.DB.ExecContext(ctx,
+		`INSERT INTO leader_leases (name, holder, acquired_at, renewed_at, expires_at, version)
+		 VALUES (?, '', NOW(6), NOW(6), NOW(6), 1)
+		 ON DUPLICATE KEY UPDATE version = version + 1`,
+		name,
+	)
+	return err
+}
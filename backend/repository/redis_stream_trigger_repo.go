@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"database/sql"
+	"eflo/backend/models"
+)
+
+type RedisStreamTriggerRepo struct {
+	DB *sql.DB
+}
+
+func NewRedisStreamTriggerRepo(db *sql.DB) *RedisStreamTriggerRepo {
+	return &RedisStreamTriggerRepo{DB: db}
+}
+
+const redisStreamTriggerColumns = `id, workflow_id, config_id, stream, group_name, consumer, start_id, concurrency,
+	claim_idle_sec, exec_timeout_sec, max_deliveries, enabled, last_msg_at, msg_count, created_at, updated_at`
+
+func (r *RedisStreamTriggerRepo) Create(t *models.RedisStreamTrigger) (int64, error) {
+	res, err := r.DB.Exec(
+		`INSERT INTO redis_stream_triggers
+		 (workflow_id, config_id, stream, group_name, consumer, start_id, concurrency, claim_idle_sec, exec_timeout_sec,
+		  max_deliveries, enabled)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		t.WorkflowID, t.ConfigID, t.Stream, t.Group, t.Consumer, t.StartID, t.Concurrency, t.ClaimIdleSec,
+		t.ExecTimeoutSec, t.MaxDeliveries, t.Enabled,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (r *RedisStreamTriggerRepo) GetByID(id int64) (*models.RedisStreamTrigger, error) {
+	row := r.DB.QueryRow(`SELECT `+redisStreamTriggerColumns+` FROM redis_stream_triggers WHERE id = ?`, id)
+	return scanRedisStreamTrigger(row)
+}
+
+func (r *RedisStreamTriggerRepo) List() ([]*models.RedisStreamTrigger, error) {
+	return r.query(`SELECT ` + redisStreamTriggerColumns + ` FROM redis_stream_triggers ORDER BY id ASC`)
+}
+
+func (r *RedisStreamTriggerRepo) ListEnabled() ([]*models.RedisStreamTrigger, error) {
+	return r.query(`SELECT ` + redisStreamTriggerColumns + ` FROM redis_stream_triggers WHERE enabled = 1 ORDER BY id ASC`)
+}
+
+func (r *RedisStreamTriggerRepo) Update(t *models.RedisStreamTrigger) error {
+	_, err := r.DB.Exec(
+		`UPDATE redis_stream_triggers SET workflow_id = ?, config_id = ?, stream = ?, group_name = ?, consumer = ?,
+		 start_id = ?, concurrency = ?, claim_idle_sec = ?, exec_timeout_sec = ?, max_deliveries = ?, enabled = ?
+		 WHERE id = ?`,
+		t.WorkflowID, t.ConfigID, t.Stream, t.Group, t.Consumer, t.StartID, t.Concurrency, t.ClaimIdleSec,
+		t.ExecTimeoutSec, t.MaxDeliveries, t.Enabled, t.ID,
+	)
+	return err
+}
+
+func (r *RedisStreamTriggerRepo) IncrementMsgCount(id int64) error {
+	_, err := r.DB.Exec(
+		`UPDATE redis_stream_triggers SET msg_count = msg_count + 1, last_msg_at = NOW() WHERE id = ?`, id,
+	)
+	return err
+}
+
+func (r *RedisStreamTriggerRepo) Delete(id int64) error {
+	_, err := r.DB.Exec("DELETE FROM redis_stream_triggers WHERE id = ?", id)
+	return err
+}
+
+func (r *RedisStreamTriggerRepo) query(q string) ([]*models.RedisStreamTrigger, error) {
+	rows, err := r.DB.Query(q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var triggers []*models.RedisStreamTrigger
+	for rows.Next() {
+		t, err := scanRedisStreamTrigger(rows)
+		if err != nil {
+			return nil, err
+		}
+		triggers = append(triggers, t)
+	}
+	return triggers, rows.Err()
+}
+
+func scanRedisStreamTrigger(sc interface{ Scan(...interface{}) error }) (*models.RedisStreamTrigger, error) {
+	t := &models.RedisStreamTrigger{}
+	var lastMsg sql.NullTime
+	err := sc.Scan(&t.ID, &t.WorkflowID, &t.ConfigID, &t.Stream, &t.Group, &t.Consumer, &t.StartID,
+		&t.Concurrency, &t.ClaimIdleSec, &t.ExecTimeoutSec, &t.MaxDeliveries, &t.Enabled, &lastMsg, &t.MsgCount, &t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if lastMsg.Valid {
+		t.LastMsgAt = &lastMsg.Time
+	}
+	return t, nil
+}
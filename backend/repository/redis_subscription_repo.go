@@ -15,9 +15,11 @@ func NewRedisSubscriptionRepo(db *sql.DB) *RedisSubscriptionRepo {
 
 func (r *RedisSubscriptionRepo) Create(s *models.RedisSubscription) (int64, error) {
 	res, err := r.DB.Exec(
-		`INSERT INTO redis_subscriptions (workflow_id, config_id, channel, is_pattern, enabled)
-		 VALUES (?, ?, ?, ?, ?)`,
+		`INSERT INTO redis_subscriptions (workflow_id, config_id, channel, is_pattern, enabled,
+		 max_in_flight, buffer_size, overflow_policy, exec_timeout_sec)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		s.WorkflowID, s.ConfigID, s.Channel, s.IsPattern, s.Enabled,
+		s.MaxInFlight, s.BufferSize, s.OverflowPolicy, s.ExecTimeoutSec,
 	)
 	if err != nil {
 		return 0, err
@@ -27,7 +29,7 @@ func (r *RedisSubscriptionRepo) Create(s *models.RedisSubscription) (int64, erro
 
 func (r *RedisSubscriptionRepo) GetByID(id int64) (*models.RedisSubscription, error) {
 	row := r.DB.QueryRow(
-		`SELECT id, workflow_id, config_id, channel, is_pattern, enabled, last_msg_at, msg_count, created_at, updated_at
+		`SELECT id, workflow_id, config_id, channel, is_pattern, enabled, last_msg_at, msg_count, created_at, updated_at, max_in_flight, buffer_size, overflow_policy, exec_timeout_sec
 		 FROM redis_subscriptions WHERE id = ?`, id,
 	)
 	return r.scanRow(row)
@@ -35,7 +37,7 @@ func (r *RedisSubscriptionRepo) GetByID(id int64) (*models.RedisSubscription, er
 
 func (r *RedisSubscriptionRepo) List() ([]*models.RedisSubscription, error) {
 	rows, err := r.DB.Query(
-		`SELECT id, workflow_id, config_id, channel, is_pattern, enabled, last_msg_at, msg_count, created_at, updated_at
+		`SELECT id, workflow_id, config_id, channel, is_pattern, enabled, last_msg_at, msg_count, created_at, updated_at, max_in_flight, buffer_size, overflow_policy, exec_timeout_sec
 		 FROM redis_subscriptions ORDER BY id ASC`,
 	)
 	if err != nil {
@@ -47,7 +49,7 @@ func (r *RedisSubscriptionRepo) List() ([]*models.RedisSubscription, error) {
 
 func (r *RedisSubscriptionRepo) ListEnabled() ([]*models.RedisSubscription, error) {
 	rows, err := r.DB.Query(
-		`SELECT id, workflow_id, config_id, channel, is_pattern, enabled, last_msg_at, msg_count, created_at, updated_at
+		`SELECT id, workflow_id, config_id, channel, is_pattern, enabled, last_msg_at, msg_count, created_at, updated_at, max_in_flight, buffer_size, overflow_policy, exec_timeout_sec
 		 FROM redis_subscriptions WHERE enabled = 1 ORDER BY id ASC`,
 	)
 	if err != nil {
@@ -59,8 +61,10 @@ func (r *RedisSubscriptionRepo) ListEnabled() ([]*models.RedisSubscription, erro
 
 func (r *RedisSubscriptionRepo) Update(s *models.RedisSubscription) error {
 	_, err := r.DB.Exec(
-		`UPDATE redis_subscriptions SET channel = ?, is_pattern = ?, enabled = ?, config_id = ? WHERE id = ?`,
-		s.Channel, s.IsPattern, s.Enabled, s.ConfigID, s.ID,
+		`UPDATE redis_subscriptions SET channel = ?, is_pattern = ?, enabled = ?, config_id = ?,
+		 max_in_flight = ?, buffer_size = ?, overflow_policy = ?, exec_timeout_sec = ? WHERE id = ?`,
+		s.Channel, s.IsPattern, s.Enabled, s.ConfigID,
+		s.MaxInFlight, s.BufferSize, s.OverflowPolicy, s.ExecTimeoutSec, s.ID,
 	)
 	return err
 }
@@ -72,6 +76,46 @@ func (r *RedisSubscriptionRepo) IncrementMsgCount(id int64) error {
 	return err
 }
 
+// SaveStatus stores the listener status of a subscription so any instance can report it.
+func (r *RedisSubscriptionRepo) SaveStatus(st *models.RedisSubscriptionStatus) error {
+	_, err := r.DB.Exec(
+		`INSERT INTO redis_subscription_status
+		 (subscription_id, state, instance, connected_at, last_error, last_error_at, reconnect_count, next_retry_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		 ON DUPLICATE KEY UPDATE state = VALUES(state), instance = VALUES(instance),
+		 connected_at = VALUES(connected_at), last_error = VALUES(last_error), last_error_at = VALUES(last_error_at),
+		 reconnect_count = VALUES(reconnect_count), next_retry_at = VALUES(next_retry_at)`,
+		st.SubscriptionID, st.State, st.Instance, st.ConnectedAt, st.LastError, st.LastErrorAt,
+		st.ReconnectCount, st.NextRetryAt,
+	)
+	return err
+}
+
+func (r *RedisSubscriptionRepo) GetStatus(id int64) (*models.RedisSubscriptionStatus, error) {
+	st := &models.RedisSubscriptionStatus{}
+	var connectedAt, lastErrorAt, nextRetryAt sql.NullTime
+	var lastError sql.NullString
+	err := r.DB.QueryRow(
+		`SELECT subscription_id, state, instance, connected_at, last_error, last_error_at, reconnect_count,
+		 next_retry_at, updated_at FROM redis_subscription_status WHERE subscription_id = ?`, id,
+	).Scan(&st.SubscriptionID, &st.State, &st.Instance, &connectedAt, &lastError, &lastErrorAt,
+		&st.ReconnectCount, &nextRetryAt, &st.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	st.LastError = lastError.String
+	if connectedAt.Valid {
+		st.ConnectedAt = &connectedAt.Time
+	}
+	if lastErrorAt.Valid {
+		st.LastErrorAt = &lastErrorAt.Time
+	}
+	if nextRetryAt.Valid {
+		st.NextRetryAt = &nextRetryAt.Time
+	}
+	return st, nil
+}
+
 func (r *RedisSubscriptionRepo) Delete(id int64) error {
 	_, err := r.DB.Exec("DELETE FROM redis_subscriptions WHERE id = ?", id)
 	return err
@@ -81,7 +125,8 @@ func (r *RedisSubscriptionRepo) scanRow(row *sql.Row) (*models.RedisSubscription
 	s := &models.RedisSubscription{}
 	var lastMsg sql.NullTime
 	err := row.Scan(&s.ID, &s.WorkflowID, &s.ConfigID, &s.Channel, &s.IsPattern,
-		&s.Enabled, &lastMsg, &s.MsgCount, &s.CreatedAt, &s.UpdatedAt)
+		&s.Enabled, &lastMsg, &s.MsgCount, &s.CreatedAt, &s.UpdatedAt,
+		&s.MaxInFlight, &s.BufferSize, &s.OverflowPolicy, &s.ExecTimeoutSec)
 	if err != nil {
 		return nil, err
 	}
@@ -97,7 +142,8 @@ func (r *RedisSubscriptionRepo) scanRows(rows *sql.Rows) ([]*models.RedisSubscri
 		s := &models.RedisSubscription{}
 		var lastMsg sql.NullTime
 		err := rows.Scan(&s.ID, &s.WorkflowID, &s.ConfigID, &s.Channel, &s.IsPattern,
-			&s.Enabled, &lastMsg, &s.MsgCount, &s.CreatedAt, &s.UpdatedAt)
+			&s.Enabled, &lastMsg, &s.MsgCount, &s.CreatedAt, &s.UpdatedAt,
+			&s.MaxInFlight, &s.BufferSize, &s.OverflowPolicy, &s.ExecTimeoutSec)
 		if err != nil {
 			return nil, err
 		}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"database/sql"
+	"eflo/backend/models"
+	"encoding/json"
+)
+
+type ScheduleCalendarRepo struct {
+	DB *sql.DB
+}
+
+func NewScheduleCalendarRepo(db *sql.DB) *ScheduleCalendarRepo {
+	return &ScheduleCalendarRepo{DB: db}
+}
+
+const scheduleCalendarColumns = `id, name, description, timezone, dates, windows, created_at, updated_at`
+
+func (r *ScheduleCalendarRepo) Create(c *models.ScheduleCalendar) (int64, error) {
+	dates, windows := calendarJSON(c)
+	res, err := r.DB.Exec(
+		`INSERT INTO schedule_calendars (name, description, timezone, dates, windows) VALUES (?, ?, ?, ?, ?)`,
+		c.Name, c.Description, c.Timezone, dates, windows,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (r *ScheduleCalendarRepo) GetByID(id int64) (*models.ScheduleCalendar, error) {
+	row := r.DB.QueryRow(`SELECT `+scheduleCalendarColumns+` FROM schedule_calendars WHERE id = ?`, id)
+	return scanScheduleCalendar(row)
+}
+
+func (r *ScheduleCalendarRepo) List() ([]*models.ScheduleCalendar, error) {
+	rows, err := r.DB.Query(`SELECT ` + scheduleCalendarColumns + ` FROM schedule_calendars ORDER BY name ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []*models.ScheduleCalendar
+	for rows.Next() {
+		c, err := scanScheduleCalendar(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, c)
+	}
+	return list, nil
+}
+
+// GetByIDs returns the calendars with the given ids; unknown ids are ignored.
+func (r *ScheduleCalendarRepo) GetByIDs(ids []int64) ([]*models.ScheduleCalendar, error) {
+	var list []*models.ScheduleCalendar
+	for _, id := range ids {
+		c, err := r.GetByID(id)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, c)
+	}
+	return list, nil
+}
+
+func (r *ScheduleCalendarRepo) Update(c *models.ScheduleCalendar) error {
+	dates, windows := calendarJSON(c)
+	res, err := r.DB.Exec(
+		`UPDATE schedule_calendars SET name = ?, description = ?, timezone = ?, dates = ?, windows = ? WHERE id = ?`,
+		c.Name, c.Description, c.Timezone, dates, windows, c.ID,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		if _, err := r.GetByID(c.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *ScheduleCalendarRepo) Delete(id int64) error {
+	_, err := r.DB.Exec("DELETE FROM schedule_calendars WHERE id = ?", id)
+	return err
+}
+
+func calendarJSON(c *models.ScheduleCalendar) (dates, windows string) {
+	d, _ := json.Marshal(c.Dates)
+	w, _ := json.Marshal(c.Windows)
+	return string(d), string(w)
+}
+
+func scanScheduleCalendar(sc interface{ Scan(...interface{}) error }) (*models.ScheduleCalendar, error) {
+	c := &models.ScheduleCalendar{}
+	var description sql.NullString
+	var dates, windows []byte
+	if err := sc.Scan(&c.ID, &c.Name, &description, &c.Timezone, &dates, &windows, &c.CreatedAt, &c.UpdatedAt); err != nil {
+		return nil, err
+	}
+	c.Description = description.String
+	if len(dates) > 0 {
+		_ = json.Unmarshal(dates, &c.Dates)
+	}
+	if len(windows) > 0 {
+		_ = json.Unmarshal(windows, &c.Windows)
+	}
+	if c.Dates == nil {
+		c.Dates = []string{}
+	}
+	if c.Windows == nil {
+		c.Windows = []models.CalendarWindow{}
+	}
+	return c, nil
+}
@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"eflo/backend/models"
+)
+
+type ScriptLibraryRepo struct {
+	DB *sql.DB
+}
+
+func NewScriptLibraryRepo(db *sql.DB) *ScriptLibraryRepo {
+	return &ScriptLibraryRepo{DB: db}
+}
+
+const scriptLibraryColumns = `id, name, description, code, version, created_at, updated_at`
+
+// Create inserts the library as version 1 and records the initial version snapshot.
+func (r *ScriptLibraryRepo) Create(l *models.ScriptLibrary) (int64, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(
+		`INSERT INTO script_libraries (name, description, code, version) VALUES (?, ?, ?, 1)`,
+		l.Name, l.Description, l.Code,
+	)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(
+		`INSERT INTO script_library_versions (library_id, version, code) VALUES (?, 1, ?)`,
+		id, l.Code,
+	); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	l.Version = 1
+	return id, nil
+}
+
+func (r *ScriptLibraryRepo) GetByID(id int64) (*models.ScriptLibrary, error) {
+	row := r.DB.QueryRow(`SELECT `+scriptLibraryColumns+` FROM script_libraries WHERE id = ?`, id)
+	return scanScriptLibrary(row)
+}
+
+func (r *ScriptLibraryRepo) GetByName(name string) (*models.ScriptLibrary, error) {
+	row := r.DB.QueryRow(`SELECT `+scriptLibraryColumns+` FROM script_libraries WHERE name = ?`, name)
+	return scanScriptLibrary(row)
+}
+
+func (r *ScriptLibraryRepo) List() ([]*models.ScriptLibrary, error) {
+	rows, err := r.DB.Query(`SELECT ` + scriptLibraryColumns + ` FROM script_libraries ORDER BY name ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []*models.ScriptLibrary
+	for rows.Next() {
+		l, err := scanScriptLibrary(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, l)
+	}
+	return list, nil
+}
+
+// Update saves name/description/code. When the code changes the version is bumped and a new
+// snapshot is recorded; l.Version is set to the resulting version.
+func (r *ScriptLibraryRepo) Update(l *models.ScriptLibrary) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var code string
+	var version int
+	if err := tx.QueryRow(
+		`SELECT code, version FROM script_libraries WHERE id = ? FOR UPDATE`, l.ID,
+	).Scan(&code, &version); err != nil {
+		return err
+	}
+	if code != l.Code {
+		version++
+		if _, err := tx.Exec(
+			`INSERT INTO script_library_versions (library_id, version, code) VALUES (?, ?, ?)`,
+			l.ID, version, l.Code,
+		); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(
+		`UPDATE script_libraries SET name = ?, description = ?, code = ?, version = ? WHERE id = ?`,
+		l.Name, l.Description, l.Code, version, l.ID,
+	); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	l.Version = version
+	return nil
+}
+
+func (r *ScriptLibraryRepo) Delete(id int64) error {
+	_, err := r.DB.Exec("DELETE FROM script_libraries WHERE id = ?", id)
+	return err
+}
+
+func (r *ScriptLibraryRepo) ListVersions(libraryID int64) ([]*models.ScriptLibraryVersion, error) {
+	rows, err := r.DB.Query(
+		`SELECT id, library_id, version, code, created_at
+		 FROM script_library_versions WHERE library_id = ? ORDER BY version DESC`, libraryID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []*models.ScriptLibraryVersion
+	for rows.Next() {
+		v := &models.ScriptLibraryVersion{}
+		if err := rows.Scan(&v.ID, &v.LibraryID, &v.Version, &v.Code, &v.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	return list, nil
+}
+
+func (r *ScriptLibraryRepo) GetVersion(libraryID int64, version int) (*models.ScriptLibraryVersion, error) {
+	v := &models.ScriptLibraryVersion{}
+	err := r.DB.QueryRow(
+		`SELECT id, library_id, version, code, created_at
+		 FROM script_library_versions WHERE library_id = ? AND version = ?`, libraryID, version,
+	).Scan(&v.ID, &v.LibraryID, &v.Version, &v.Code, &v.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// ResolveScript returns the library named name at the given version (0 = latest).
+// Used by the function node's require().
+func (r *ScriptLibraryRepo) ResolveScript(name string, version int) (*models.ScriptLibrary, error) {
+	l, err := r.GetByName(name)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("script library %q not found", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if version == 0 || version == l.Version {
+		return l, nil
+	}
+	v, err := r.GetVersion(l.ID, version)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("script library %q has no version %d", name, version)
+	}
+	if err != nil {
+		return nil, err
+	}
+	l.Code = v.Code
+	l.Version = v.Version
+	return l, nil
+}
+
+func scanScriptLibrary(s interface{ Scan(...interface{}) error }) (*models.ScriptLibrary, error) {
+	l := &models.ScriptLibrary{}
+	var desc sql.NullString
+	if err := s.Scan(&l.ID, &l.Name, &desc, &l.Code, &l.Version, &l.CreatedAt, &l.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if desc.Valid {
+		l.Description = desc.String
+	}
+	return l, nil
+}
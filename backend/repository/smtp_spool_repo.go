@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"database/sql"
+	"eflo/backend/models"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// SmtpSpoolRepo keeps mail accepted by the SMTP receiver until its run ends. Each message is
+// leased to the instance running it; messages whose lease lapsed are reclaimed by another.
+type SmtpSpoolRepo struct {
+	DB *sql.DB
+}
+
+func NewSmtpSpoolRepo(db *sql.DB) *SmtpSpoolRepo {
+	return &SmtpSpoolRepo{DB: db}
+}
+
+const smtpSpoolColumns = `id, trigger_id, workflow_id, payload, owner, leased_until, created_at`
+
+// Create stores a message leased to owner for lease.
+func (r *SmtpSpoolRepo) Create(m *models.SpooledMessage, owner string, lease time.Duration) (int64, error) {
+	res, err := r.DB.Exec(
+		`INSERT INTO smtp_spool (trigger_id, workflow_id, payload, owner, leased_until)
+		 VALUES (?, ?, ?, ?, DATE_ADD(NOW(6), INTERVAL ? MICROSECOND))`,
+		m.TriggerID, m.WorkflowID, nullableJSON(m.Payload), owner, lease.Microseconds(),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// Renew extends owner's lease on the given messages.
+func (r *SmtpSpoolRepo) Renew(owner string, ids []int64, lease time.Duration) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	placeholders := strings.Repeat("?,", len(ids))
+	placeholders = placeholders[:len(placeholders)-1]
+	args := []interface{}{lease.Microseconds(), owner}
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	_, err := r.DB.Exec(
+		`UPDATE smtp_spool SET leased_until = DATE_ADD(NOW(6), INTERVAL ? MICROSECOND)
+		 WHERE owner = ? AND id IN (`+placeholders+`)`, args...,
+	)
+	return err
+}
+
+// Reclaim leases up to limit messages whose lease lapsed to owner, oldest first.
+func (r *SmtpSpoolRepo) Reclaim(owner string, lease time.Duration, limit int) ([]*models.SpooledMessage, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(
+		`SELECT `+smtpSpoolColumns+` FROM smtp_spool WHERE leased_until <= NOW(6)
+		 ORDER BY id ASC LIMIT ? FOR UPDATE SKIP LOCKED`, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	var list []*models.SpooledMessage
+	for rows.Next() {
+		m, err := scanSpooledMessage(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		list = append(list, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, m := range list {
+		_, err := tx.Exec(
+			`UPDATE smtp_spool SET owner = ?, leased_until = DATE_ADD(NOW(6), INTERVAL ? MICROSECOND) WHERE id = ?`,
+			owner, lease.Microseconds(), m.ID,
+		)
+		if err != nil {
+			return nil, err
+		}
+		m.Owner = owner
+	}
+	return list, tx.Commit()
+}
+
+func (r *SmtpSpoolRepo) Delete(id int64) error {
+	_, err := r.DB.Exec("DELETE FROM smtp_spool WHERE id = ?", id)
+	return err
+}
+
+func scanSpooledMessage(sc interface{ Scan(...interface{}) error }) (*models.SpooledMessage, error) {
+	m := &models.SpooledMessage{}
+	var payload []byte
+	err := sc.Scan(&m.ID, &m.TriggerID, &m.WorkflowID, &payload, &m.Owner, &m.LeasedUntil, &m.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if len(payload) > 0 {
+		_ = json.Unmarshal(payload, &m.Payload)
+	}
+	return m, nil
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"database/sql"
+	"eflo/backend/models"
+	"encoding/json"
+)
+
+type SmtpTriggerRepo struct {
+	DB *sql.DB
+}
+
+func NewSmtpTriggerRepo(db *sql.DB) *SmtpTriggerRepo {
+	return &SmtpTriggerRepo{DB: db}
+}
+
+const smtpTriggerColumns = `id, workflow_id, recipients, enabled, max_attachment_bytes, max_in_flight, buffer_size,
+	overflow_policy, exec_timeout_sec, last_received_at, msg_count, created_at, updated_at`
+
+func (r *SmtpTriggerRepo) Create(t *models.SmtpTrigger) (int64, error) {
+	res, err := r.DB.Exec(
+		`INSERT INTO smtp_triggers (workflow_id, recipients, enabled, max_attachment_bytes,
+		 max_in_flight, buffer_size, overflow_policy, exec_timeout_sec)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		t.WorkflowID, recipientsJSON(t.Recipients), t.Enabled, t.MaxAttachmentBytes,
+		t.MaxInFlight, t.BufferSize, t.OverflowPolicy, t.ExecTimeoutSec,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (r *SmtpTriggerRepo) GetByID(id int64) (*models.SmtpTrigger, error) {
+	row := r.DB.QueryRow(`SELECT `+smtpTriggerColumns+` FROM smtp_triggers WHERE id = ?`, id)
+	return scanSmtpTrigger(row)
+}
+
+func (r *SmtpTriggerRepo) List() ([]*models.SmtpTrigger, error) {
+	rows, err := r.DB.Query(`SELECT ` + smtpTriggerColumns + ` FROM smtp_triggers ORDER BY id ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return r.scanRows(rows)
+}
+
+func (r *SmtpTriggerRepo) ListEnabled() ([]*models.SmtpTrigger, error) {
+	rows, err := r.DB.Query(`SELECT ` + smtpTriggerColumns + ` FROM smtp_triggers WHERE enabled = 1 ORDER BY id ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return r.scanRows(rows)
+}
+
+func (r *SmtpTriggerRepo) Update(t *models.SmtpTrigger) error {
+	_, err := r.DB.Exec(
+		`UPDATE smtp_triggers SET workflow_id = ?, recipients = ?, enabled = ?, max_attachment_bytes = ?,
+		 max_in_flight = ?, buffer_size = ?, overflow_policy = ?, exec_timeout_sec = ? WHERE id = ?`,
+		t.WorkflowID, recipientsJSON(t.Recipients), t.Enabled, t.MaxAttachmentBytes,
+		t.MaxInFlight, t.BufferSize, t.OverflowPolicy, t.ExecTimeoutSec, t.ID,
+	)
+	return err
+}
+
+func (r *SmtpTriggerRepo) Delete(id int64) error {
+	_, err := r.DB.Exec("DELETE FROM smtp_triggers WHERE id = ?", id)
+	return err
+}
+
+func (r *SmtpTriggerRepo) IncrementMsgCount(id int64) error {
+	_, err := r.DB.Exec(
+		`UPDATE smtp_triggers SET msg_count = msg_count + 1, last_received_at = NOW() WHERE id = ?`, id,
+	)
+	return err
+}
+
+func (r *SmtpTriggerRepo) scanRows(rows *sql.Rows) ([]*models.SmtpTrigger, error) {
+	var triggers []*models.SmtpTrigger
+	for rows.Next() {
+		t, err := scanSmtpTrigger(rows)
+		if err != nil {
+			return nil, err
+		}
+		triggers = append(triggers, t)
+	}
+	return triggers, nil
+}
+
+func scanSmtpTrigger(sc interface{ Scan(...interface{}) error }) (*models.SmtpTrigger, error) {
+	t := &models.SmtpTrigger{}
+	var recipients []byte
+	var lastReceived sql.NullTime
+	err := sc.Scan(&t.ID, &t.WorkflowID, &recipients, &t.Enabled, &t.MaxAttachmentBytes,
+		&t.MaxInFlight, &t.BufferSize, &t.OverflowPolicy, &t.ExecTimeoutSec,
+		&lastReceived, &t.MsgCount, &t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if lastReceived.Valid {
+		t.LastReceivedAt = &lastReceived.Time
+	}
+	t.Recipients = []string{}
+	if len(recipients) > 0 {
+		_ = json.Unmarshal(recipients, &t.Recipients)
+	}
+	return t, nil
+}
+
+func recipientsJSON(recipients []string) string {
+	if recipients == nil {
+		recipients = []string{}
+	}
+	b, _ := json.Marshal(recipients)
+	return string(b)
+}
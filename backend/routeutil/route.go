@@ -0,0 +1,158 @@
+// Package routeutil parses and matches HTTP trigger path patterns.
+//
+// A pattern is a slash-separated path whose segments are literals, named parameters ({id})
+// matching one segment, or - in last position only - a wildcard (* or {name*}) matching the rest
+// of the path, possibly empty. "orders/{id}/items" matches "orders/42/items" with id=42;
+// "files/{path*}" matches "files/a/b.txt" with path=a/b.txt (an unnamed * is reported as "*").
+package routeutil
+
+import (
+	"fmt"
+	"strings"
+)
+
+type segmentKind int
+
+// Segment kinds, in increasing order of precedence.
+const (
+	segWildcard segmentKind = iota
+	segParam
+	segLiteral
+)
+
+type segment struct {
+	kind  segmentKind
+	value string // literal text, or parameter name
+}
+
+// Route is a parsed path pattern.
+type Route struct {
+	Pattern  string
+	segments []segment
+}
+
+// Parse parses a pattern. Leading and trailing slashes are ignored.
+func Parse(pattern string) (*Route, error) {
+	r := &Route{Pattern: pattern}
+	trimmed := strings.Trim(pattern, "/")
+	if trimmed == "" {
+		return r, nil
+	}
+	names := make(map[string]bool)
+	parts := strings.Split(trimmed, "/")
+	for i, p := range parts {
+		last := i == len(parts)-1
+		switch {
+		case p == "":
+			return nil, fmt.Errorf("empty path segment in %q", pattern)
+		case p == "*":
+			if !last {
+				return nil, fmt.Errorf("wildcard * must be the last segment of %q", pattern)
+			}
+			r.segments = append(r.segments, segment{kind: segWildcard, value: "*"})
+		case strings.HasPrefix(p, "{") && strings.HasSuffix(p, "}"):
+			name := p[1 : len(p)-1]
+			kind := segParam
+			if strings.HasSuffix(name, "*") {
+				if !last {
+					return nil, fmt.Errorf("wildcard %s must be the last segment of %q", p, pattern)
+				}
+				name, kind = strings.TrimSuffix(name, "*"), segWildcard
+			}
+			if !validName(name) {
+				return nil, fmt.Errorf("invalid parameter name %q in %q", name, pattern)
+			}
+			if names[name] {
+				return nil, fmt.Errorf("duplicate parameter %q in %q", name, pattern)
+			}
+			names[name] = true
+			r.segments = append(r.segments, segment{kind: kind, value: name})
+		case strings.ContainsAny(p, "{}*"):
+			return nil, fmt.Errorf("invalid segment %q in %q (use {name} or a final *)", p, pattern)
+		default:
+			r.segments = append(r.segments, segment{kind: segLiteral, value: p})
+		}
+	}
+	return r, nil
+}
+
+func validName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		if c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9' {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// Match matches a request path against the route, returning the parameter values.
+func (r *Route) Match(path string) (map[string]string, bool) {
+	var parts []string
+	if trimmed := strings.Trim(path, "/"); trimmed != "" {
+		parts = strings.Split(trimmed, "/")
+	}
+	params := make(map[string]string)
+	for i, s := range r.segments {
+		if s.kind == segWildcard {
+			params[s.value] = strings.Join(parts[i:], "/")
+			return params, true
+		}
+		if i >= len(parts) {
+			return nil, false
+		}
+		switch s.kind {
+		case segLiteral:
+			if parts[i] != s.value {
+				return nil, false
+			}
+		case segParam:
+			if parts[i] == "" {
+				return nil, false
+			}
+			params[s.value] = parts[i]
+		}
+	}
+	if len(parts) != len(r.segments) {
+		return nil, false
+	}
+	return params, true
+}
+
+// Compare orders two routes by precedence for a path both match: at the first segment where
+// they differ in kind, a literal beats a parameter, which beats a wildcard; a route that ends
+// earlier only wins over one continuing with a wildcard. It returns >0 when a takes precedence,
+// <0 when b does and 0 when they have the same shape.
+func Compare(a, b *Route) int {
+	for i := 0; i < len(a.segments) || i < len(b.segments); i++ {
+		switch {
+		case i >= len(a.segments):
+			return 1 // b continues with a wildcard matching nothing
+		case i >= len(b.segments):
+			return -1
+		}
+		if ka, kb := a.segments[i].kind, b.segments[i].kind; ka != kb {
+			return int(ka) - int(kb)
+		}
+	}
+	return 0
+}
+
+// Conflicts reports whether two routes match the same paths with equal precedence, so neither
+// could be chosen: the same literals and the same parameter and wildcard positions, whatever the
+// parameter names ("orders/{id}" and "orders/{orderId}").
+func Conflicts(a, b *Route) bool {
+	if len(a.segments) != len(b.segments) {
+		return false
+	}
+	for i := range a.segments {
+		sa, sb := a.segments[i], b.segments[i]
+		if sa.kind != sb.kind || sa.kind == segLiteral && sa.value != sb.value {
+			return false
+		}
+	}
+	return true
+}
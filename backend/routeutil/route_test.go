@@ -0,0 +1,171 @@
+package routeutil
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func mustParse(t *testing.T, pattern string) *Route {
+	t.Helper()
+	r, err := Parse(pattern)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", pattern, err)
+	}
+	return r
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		pattern string
+		wantErr string // "" = valid
+	}{
+		{pattern: ""},
+		{pattern: "/"},
+		{pattern: "orders"},
+		{pattern: "/orders/{id}/items/"},
+		{pattern: "files/*"},
+		{pattern: "files/{path*}"},
+		{pattern: "{a}/{b_2}"},
+		{pattern: "orders//items", wantErr: "empty path segment"},
+		{pattern: "*/items", wantErr: "must be the last segment"},
+		{pattern: "{rest*}/items", wantErr: "must be the last segment"},
+		{pattern: "orders/{}", wantErr: "invalid parameter name"},
+		{pattern: "orders/{2id}", wantErr: "invalid parameter name"},
+		{pattern: "orders/{order-id}", wantErr: "invalid parameter name"},
+		{pattern: "{id}/x/{id}", wantErr: "duplicate parameter"},
+		{pattern: "{id}/{id*}", wantErr: "duplicate parameter"},
+		{pattern: "orders/{id", wantErr: "invalid segment"},
+		{pattern: "orders/id}", wantErr: "invalid segment"},
+		{pattern: "orders/v*", wantErr: "invalid segment"},
+		{pattern: "pre{id}", wantErr: "invalid segment"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			r, err := Parse(tt.pattern)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Parse(%q): %v", tt.pattern, err)
+				}
+				if r.Pattern != tt.pattern {
+					t.Errorf("Pattern = %q, want %q", r.Pattern, tt.pattern)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Parse(%q) error = %v, want it to mention %q", tt.pattern, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    map[string]string // nil = no match
+	}{
+		{"", "/", map[string]string{}},
+		{"/", "", map[string]string{}},
+		{"/", "orders", nil},
+		{"orders", "orders", map[string]string{}},
+		{"orders", "/orders/", map[string]string{}},
+		{"orders", "Orders", nil},
+		{"orders", "orders/1", nil},
+		{"orders/{id}", "orders/42", map[string]string{"id": "42"}},
+		{"orders/{id}", "orders", nil},
+		{"orders/{id}", "orders/42/items", nil},
+		{"orders/{id}", "orders//", nil},
+		{"orders/{id}/items/{item}", "orders/42/items/7", map[string]string{"id": "42", "item": "7"}},
+		{"orders/{id}/items", "orders/42/lines", nil},
+		{"files/{path*}", "files/a/b.txt", map[string]string{"path": "a/b.txt"}},
+		{"files/{path*}", "files", map[string]string{"path": ""}},
+		{"files/*", "files/a/b", map[string]string{"*": "a/b"}},
+		{"files/*", "other/a", nil},
+		{"*", "anything/at/all", map[string]string{"*": "anything/at/all"}},
+		{"*", "/", map[string]string{"*": ""}},
+		{"{tenant}/files/{rest*}", "acme/files/x/y", map[string]string{"tenant": "acme", "rest": "x/y"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			got, ok := mustParse(t, tt.pattern).Match(tt.path)
+			if tt.want == nil {
+				if ok {
+					t.Errorf("Match(%q) = %v, want no match", tt.path, got)
+				}
+				return
+			}
+			if !ok || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Match(%q) = %v, %v; want %v", tt.path, got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int // sign only
+	}{
+		{"orders/new", "orders/{id}", 1},
+		{"orders/{id}", "orders/*", 1},
+		{"orders/new", "orders/{rest*}", 1},
+		{"orders/{id}", "{section}/new", 1}, // the first differing segment decides
+		{"orders", "orders/*", 1},           // ends earlier than a wildcard matching nothing
+		{"orders/*", "orders", -1},
+		{"orders/{id}", "orders/{orderId}", 0},
+		{"orders/{id}", "users/{id}", 0}, // same shape; no path matches both
+		{"*", "{a}/{b*}", -1},
+		{"", "*", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+" vs "+tt.b, func(t *testing.T) {
+			a, b := mustParse(t, tt.a), mustParse(t, tt.b)
+			if got := Compare(a, b); sign(got) != tt.want {
+				t.Errorf("Compare(%q, %q) = %d, want sign %d", tt.a, tt.b, got, tt.want)
+			}
+			if got := Compare(b, a); sign(got) != -tt.want {
+				t.Errorf("Compare(%q, %q) = %d, want sign %d", tt.b, tt.a, got, -tt.want)
+			}
+		})
+	}
+}
+
+func TestConflicts(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"orders/{id}", "orders/{orderId}", true},
+		{"/orders/{id}/", "orders/{id}", true},
+		{"files/*", "files/{path*}", true},
+		{"orders", "orders", true},
+		{"", "/", true},
+		{"orders/{id}", "orders/new", false},
+		{"orders/{id}", "users/{id}", false},
+		{"orders/{id}", "orders/{id}/items", false},
+		{"orders/{id}", "orders/*", false},
+		{"Orders", "orders", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+" vs "+tt.b, func(t *testing.T) {
+			a, b := mustParse(t, tt.a), mustParse(t, tt.b)
+			if got := Conflicts(a, b); got != tt.want {
+				t.Errorf("Conflicts(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+			if got := Conflicts(b, a); got != tt.want {
+				t.Errorf("Conflicts(%q, %q) = %v, want %v", tt.b, tt.a, got, tt.want)
+			}
+		})
+	}
+}
+
+func sign(n int) int {
+	switch {
+	case n > 0:
+		return 1
+	case n < 0:
+		return -1
+	}
+	return 0
+}
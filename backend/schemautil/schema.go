@@ -0,0 +1,584 @@
+// Package schemautil validates JSON values (as decoded by encoding/json) against JSON Schema.
+//
+// Supported keywords (draft-07 / 2019-09 subset): type, enum, const, properties, required,
+// additionalProperties, patternProperties, propertyNames, minProperties, maxProperties, items
+// (schema or tuple), additionalItems, contains, minItems, maxItems, uniqueItems, minimum,
+// maximum, exclusiveMinimum, exclusiveMaximum, multipleOf, minLength, maxLength, pattern,
+// format (email, date-time, date, time, uri, uuid, ipv4, ipv6, hostname), allOf, anyOf, oneOf,
+// not, if/then/else and local $ref ("#", "#/definitions/...", "#/$defs/...").
+package schemautil
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Violation describes one place where a value does not satisfy the schema.
+// Path uses the same syntax as workflow placeholders, e.g. "$.items[0].id".
+type Violation struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (v Violation) String() string {
+	return v.Path + ": " + v.Message
+}
+
+// Parse accepts a schema as a decoded JSON object, a boolean, a JSON string or raw bytes.
+func Parse(raw interface{}) (interface{}, error) {
+	switch v := raw.(type) {
+	case nil:
+		return nil, fmt.Errorf("schema is empty")
+	case map[string]interface{}, bool:
+		return v, nil
+	case string:
+		return parseBytes([]byte(v))
+	case []byte:
+		return parseBytes(v)
+	case json.RawMessage:
+		return parseBytes(v)
+	}
+	return nil, fmt.Errorf("schema must be an object (got %T)", raw)
+}
+
+func parseBytes(b []byte) (interface{}, error) {
+	if strings.TrimSpace(string(b)) == "" {
+		return nil, fmt.Errorf("schema is empty")
+	}
+	var schema interface{}
+	if err := json.Unmarshal(b, &schema); err != nil {
+		return nil, fmt.Errorf("schema is not valid JSON: %w", err)
+	}
+	switch schema.(type) {
+	case map[string]interface{}, bool:
+		return schema, nil
+	}
+	return nil, fmt.Errorf("schema must be an object")
+}
+
+// Validate checks value against schema and returns all violations (nil when valid).
+func Validate(schema interface{}, value interface{}) []Violation {
+	v := &validator{root: schema}
+	v.validate(schema, value, "$")
+	return v.violations
+}
+
+type validator struct {
+	root       interface{}
+	violations []Violation
+	depth      int
+}
+
+func (v *validator) fail(path, format string, args ...interface{}) {
+	v.violations = append(v.violations, Violation{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// sub runs a validation in isolation and returns its violations without recording them.
+func (v *validator) sub(schema, value interface{}, path string) []Violation {
+	child := &validator{root: v.root, depth: v.depth}
+	child.validate(schema, value, path)
+	return child.violations
+}
+
+func (v *validator) validate(schema interface{}, value interface{}, path string) {
+	switch s := schema.(type) {
+	case bool:
+		if !s {
+			v.fail(path, "no value is allowed here")
+		}
+		return
+	case map[string]interface{}:
+		v.validateObjectSchema(s, value, path)
+	}
+}
+
+func (v *validator) validateObjectSchema(s map[string]interface{}, value interface{}, path string) {
+	if ref, ok := s["$ref"].(string); ok {
+		target, err := v.resolveRef(ref)
+		if err != nil {
+			v.fail(path, "%v", err)
+			return
+		}
+		v.depth++
+		if v.depth > 64 {
+			v.fail(path, "$ref nesting too deep")
+			return
+		}
+		v.validate(target, value, path)
+		v.depth--
+	}
+
+	if t, ok := s["type"]; ok {
+		if !matchesType(t, value) {
+			v.fail(path, "expected %s, got %s", describeType(t), typeOf(value))
+			return
+		}
+	}
+	if enum, ok := s["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			if jsonEqual(e, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			v.fail(path, "must be one of %s", compactJSON(enum))
+		}
+	}
+	if c, ok := s["const"]; ok && !jsonEqual(c, value) {
+		v.fail(path, "must equal %s", compactJSON(c))
+	}
+
+	switch val := value.(type) {
+	case map[string]interface{}:
+		v.validateObject(s, val, path)
+	case []interface{}:
+		v.validateArray(s, val, path)
+	case string:
+		v.validateString(s, val, path)
+	default:
+		if f, ok := toFloat(value); ok {
+			v.validateNumber(s, f, path)
+		}
+	}
+
+	if all, ok := s["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			v.validate(sub, value, path)
+		}
+	}
+	if anyOf, ok := s["anyOf"].([]interface{}); ok {
+		passed := false
+		for _, sub := range anyOf {
+			if len(v.sub(sub, value, path)) == 0 {
+				passed = true
+				break
+			}
+		}
+		if !passed {
+			v.fail(path, "must match at least one schema in anyOf")
+		}
+	}
+	if one, ok := s["oneOf"].([]interface{}); ok {
+		matches := 0
+		for _, sub := range one {
+			if len(v.sub(sub, value, path)) == 0 {
+				matches++
+			}
+		}
+		if matches != 1 {
+			v.fail(path, "must match exactly one schema in oneOf (matched %d)", matches)
+		}
+	}
+	if not, ok := s["not"]; ok {
+		if len(v.sub(not, value, path)) == 0 {
+			v.fail(path, "must not match the schema in not")
+		}
+	}
+	if cond, ok := s["if"]; ok {
+		if len(v.sub(cond, value, path)) == 0 {
+			if then, ok := s["then"]; ok {
+				v.validate(then, value, path)
+			}
+		} else if els, ok := s["else"]; ok {
+			v.validate(els, value, path)
+		}
+	}
+}
+
+func (v *validator) validateObject(s map[string]interface{}, obj map[string]interface{}, path string) {
+	if req, ok := s["required"].([]interface{}); ok {
+		for _, r := range req {
+			name, _ := r.(string)
+			if _, exists := obj[name]; !exists {
+				v.fail(childPath(path, name), "is required")
+			}
+		}
+	}
+	if n, ok := toInt(s["minProperties"]); ok && len(obj) < n {
+		v.fail(path, "must have at least %d properties", n)
+	}
+	if n, ok := toInt(s["maxProperties"]); ok && len(obj) > n {
+		v.fail(path, "must have at most %d properties", n)
+	}
+
+	props, _ := s["properties"].(map[string]interface{})
+	patternProps, _ := s["patternProperties"].(map[string]interface{})
+	additional, hasAdditional := s["additionalProperties"]
+	propertyNames, hasPropertyNames := s["propertyNames"]
+
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		val := obj[k]
+		p := childPath(path, k)
+		if hasPropertyNames {
+			for _, viol := range v.sub(propertyNames, k, p) {
+				v.fail(p, "property name %s", viol.Message)
+			}
+		}
+		matched := false
+		if ps, ok := props[k]; ok {
+			matched = true
+			v.validate(ps, val, p)
+		}
+		for pattern, ps := range patternProps {
+			re, err := compileRegex(pattern)
+			if err != nil {
+				v.fail(path, "invalid patternProperties regex %q", pattern)
+				continue
+			}
+			if re.MatchString(k) {
+				matched = true
+				v.validate(ps, val, p)
+			}
+		}
+		if !matched && hasAdditional {
+			if b, ok := additional.(bool); ok && !b {
+				v.fail(p, "additional property is not allowed")
+			} else {
+				v.validate(additional, val, p)
+			}
+		}
+	}
+}
+
+func (v *validator) validateArray(s map[string]interface{}, arr []interface{}, path string) {
+	if n, ok := toInt(s["minItems"]); ok && len(arr) < n {
+		v.fail(path, "must have at least %d items", n)
+	}
+	if n, ok := toInt(s["maxItems"]); ok && len(arr) > n {
+		v.fail(path, "must have at most %d items", n)
+	}
+	if unique, _ := s["uniqueItems"].(bool); unique {
+		for i := 0; i < len(arr); i++ {
+			for j := i + 1; j < len(arr); j++ {
+				if jsonEqual(arr[i], arr[j]) {
+					v.fail(path, "items %d and %d are equal; items must be unique", i, j)
+				}
+			}
+		}
+	}
+	switch items := s["items"].(type) {
+	case []interface{}:
+		for i, val := range arr {
+			if i < len(items) {
+				v.validate(items[i], val, indexPath(path, i))
+			} else if add, ok := s["additionalItems"]; ok {
+				v.validate(add, val, indexPath(path, i))
+			}
+		}
+	case map[string]interface{}, bool:
+		for i, val := range arr {
+			v.validate(items, val, indexPath(path, i))
+		}
+	}
+	if contains, ok := s["contains"]; ok {
+		found := false
+		for i, val := range arr {
+			if len(v.sub(contains, val, indexPath(path, i))) == 0 {
+				found = true
+				break
+			}
+		}
+		if !found {
+			v.fail(path, "must contain at least one matching item")
+		}
+	}
+}
+
+func (v *validator) validateString(s map[string]interface{}, str string, path string) {
+	length := utf8.RuneCountInString(str)
+	if n, ok := toInt(s["minLength"]); ok && length < n {
+		v.fail(path, "must be at least %d characters", n)
+	}
+	if n, ok := toInt(s["maxLength"]); ok && length > n {
+		v.fail(path, "must be at most %d characters", n)
+	}
+	if pattern, ok := s["pattern"].(string); ok {
+		re, err := compileRegex(pattern)
+		if err != nil {
+			v.fail(path, "invalid pattern %q", pattern)
+		} else if !re.MatchString(str) {
+			v.fail(path, "must match pattern %q", pattern)
+		}
+	}
+	if format, ok := s["format"].(string); ok {
+		if msg := checkFormat(format, str); msg != "" {
+			v.fail(path, "%s", msg)
+		}
+	}
+}
+
+func (v *validator) validateNumber(s map[string]interface{}, f float64, path string) {
+	if min, ok := toFloat(s["minimum"]); ok && f < min {
+		v.fail(path, "must be >= %v", min)
+	}
+	if max, ok := toFloat(s["maximum"]); ok && f > max {
+		v.fail(path, "must be <= %v", max)
+	}
+	// draft-04 used booleans for exclusiveMinimum/Maximum; later drafts use numbers.
+	if b, ok := s["exclusiveMinimum"].(bool); ok && b {
+		if min, ok := toFloat(s["minimum"]); ok && f <= min {
+			v.fail(path, "must be > %v", min)
+		}
+	} else if min, ok := toFloat(s["exclusiveMinimum"]); ok && f <= min {
+		v.fail(path, "must be > %v", min)
+	}
+	if b, ok := s["exclusiveMaximum"].(bool); ok && b {
+		if max, ok := toFloat(s["maximum"]); ok && f >= max {
+			v.fail(path, "must be < %v", max)
+		}
+	} else if max, ok := toFloat(s["exclusiveMaximum"]); ok && f >= max {
+		v.fail(path, "must be < %v", max)
+	}
+	if m, ok := toFloat(s["multipleOf"]); ok && m > 0 {
+		q := f / m
+		if math.Abs(q-math.Round(q)) > 1e-9 {
+			v.fail(path, "must be a multiple of %v", m)
+		}
+	}
+}
+
+func (v *validator) resolveRef(ref string) (interface{}, error) {
+	if ref == "#" {
+		return v.root, nil
+	}
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("unsupported $ref %q (only local refs are supported)", ref)
+	}
+	var cur interface{} = v.root
+	for _, part := range strings.Split(ref[2:], "/") {
+		part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+		if unescaped, err := url.PathUnescape(part); err == nil {
+			part = unescaped
+		}
+		switch c := cur.(type) {
+		case map[string]interface{}:
+			next, ok := c[part]
+			if !ok {
+				return nil, fmt.Errorf("$ref %q not found", ref)
+			}
+			cur = next
+		case []interface{}:
+			i, err := strconv.Atoi(part)
+			if err != nil || i < 0 || i >= len(c) {
+				return nil, fmt.Errorf("$ref %q not found", ref)
+			}
+			cur = c[i]
+		default:
+			return nil, fmt.Errorf("$ref %q not found", ref)
+		}
+	}
+	return cur, nil
+}
+
+func matchesType(t interface{}, value interface{}) bool {
+	switch tt := t.(type) {
+	case string:
+		return matchesSingleType(tt, value)
+	case []interface{}:
+		for _, x := range tt {
+			if s, ok := x.(string); ok && matchesSingleType(s, value) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func matchesSingleType(t string, value interface{}) bool {
+	switch t {
+	case "null":
+		return value == nil
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := toFloat(value)
+		return ok
+	case "integer":
+		f, ok := toFloat(value)
+		return ok && f == math.Trunc(f)
+	}
+	return false
+}
+
+func describeType(t interface{}) string {
+	if list, ok := t.([]interface{}); ok {
+		parts := make([]string, 0, len(list))
+		for _, x := range list {
+			parts = append(parts, fmt.Sprintf("%v", x))
+		}
+		return strings.Join(parts, " or ")
+	}
+	return fmt.Sprintf("%v", t)
+}
+
+func typeOf(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	}
+	if _, ok := toFloat(value); ok {
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func toInt(v interface{}) (int, bool) {
+	f, ok := toFloat(v)
+	return int(f), ok
+}
+
+func jsonEqual(a, b interface{}) bool {
+	fa, aNum := toFloat(a)
+	fb, bNum := toFloat(b)
+	if aNum && bNum {
+		return fa == fb
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func compactJSON(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(b)
+}
+
+var identRE = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+func childPath(parent, key string) string {
+	if identRE.MatchString(key) {
+		return parent + "." + key
+	}
+	return parent + "['" + strings.ReplaceAll(key, "'", `\'`) + "']"
+}
+
+func indexPath(parent string, i int) string {
+	return parent + "[" + strconv.Itoa(i) + "]"
+}
+
+var regexCache sync.Map // pattern -> *regexp.Regexp
+
+func compileRegex(pattern string) (*regexp.Regexp, error) {
+	if re, ok := regexCache.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	regexCache.Store(pattern, re)
+	return re, nil
+}
+
+var (
+	uuidRE     = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	hostnameRE = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)(\.[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$`)
+)
+
+// checkFormat returns an error message if str does not match a known format; unknown formats pass.
+func checkFormat(format, str string) string {
+	switch format {
+	case "email":
+		if addr, err := mail.ParseAddress(str); err != nil || addr.Address != str {
+			return "must be a valid email address"
+		}
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, str); err != nil {
+			return "must be an RFC 3339 date-time"
+		}
+	case "date":
+		if _, err := time.Parse("2006-01-02", str); err != nil {
+			return "must be a date (YYYY-MM-DD)"
+		}
+	case "time":
+		if _, err := time.Parse("15:04:05Z07:00", str); err != nil {
+			if _, err := time.Parse("15:04:05", str); err != nil {
+				return "must be a time (HH:MM:SS)"
+			}
+		}
+	case "uri":
+		if u, err := url.Parse(str); err != nil || u.Scheme == "" {
+			return "must be an absolute URI"
+		}
+	case "uuid":
+		if !uuidRE.MatchString(str) {
+			return "must be a UUID"
+		}
+	case "ipv4":
+		if ip := net.ParseIP(str); ip == nil || ip.To4() == nil || strings.Contains(str, ":") {
+			return "must be an IPv4 address"
+		}
+	case "ipv6":
+		if ip := net.ParseIP(str); ip == nil || !strings.Contains(str, ":") {
+			return "must be an IPv6 address"
+		}
+	case "hostname":
+		if len(str) > 253 || !hostnameRE.MatchString(str) {
+			return "must be a hostname"
+		}
+	}
+	return ""
+}
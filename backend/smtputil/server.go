@@ -0,0 +1,445 @@
+// Package smtputil is a minimal inbound SMTP server (RFC 5321): it accepts mail for recipients
+// approved by a Backend and hands each complete message to it. It never relays.
+package smtputil
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Backend decides which recipients are accepted and receives complete messages.
+type Backend interface {
+	// AcceptRecipient reports whether mail for the (lowercased) address is accepted.
+	AcceptRecipient(addr string) (bool, error)
+	// Deliver handles a received message. An error is reported to the client as a temporary
+	// failure, so the sending server retries later.
+	Deliver(ctx context.Context, env Envelope, data []byte) error
+}
+
+// Envelope is the SMTP transaction of a message.
+type Envelope struct {
+	From       string   // reverse path; empty for bounces
+	Recipients []string // accepted forward paths, lowercased
+	RemoteAddr string
+	Helo       string
+	TLS        bool
+}
+
+// Server listens for SMTP connections.
+type Server struct {
+	Addr            string
+	Hostname        string      // announced in the greeting and EHLO reply
+	MaxMessageBytes int         // larger messages are refused (0 = no limit)
+	MaxRecipients   int         // per message (default 100)
+	TLSConfig       *tls.Config // enables STARTTLS
+	Backend         Backend
+
+	mu       sync.Mutex
+	ln       net.Listener
+	closed   bool
+	conns    map[net.Conn]struct{}
+	ctx      context.Context // cancelled by Close
+	cancel   context.CancelFunc
+	sessions sync.WaitGroup
+}
+
+const (
+	commandTimeout = 5 * time.Minute
+	dataTimeout    = 10 * time.Minute
+	maxLineLength  = 4096
+	maxBadCommands = 10
+)
+
+// ErrServerClosed is returned by Serve after Close.
+var ErrServerClosed = errors.New("smtp: server closed")
+
+// ListenAndServe listens on Addr and serves connections until Close.
+func (s *Server) ListenAndServe() error {
+	ln, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ln)
+}
+
+// Serve accepts connections on ln until Close.
+func (s *Server) Serve(ln net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		_ = ln.Close()
+		return ErrServerClosed
+	}
+	s.init()
+	s.ln = ln
+	s.mu.Unlock()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if s.ctx.Err() != nil {
+				return ErrServerClosed
+			}
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			_ = conn.Close()
+			return ErrServerClosed
+		}
+		s.conns[conn] = struct{}{}
+		s.sessions.Add(1)
+		s.mu.Unlock()
+		go func() {
+			defer s.sessions.Done()
+			s.serveConn(conn)
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+// Close stops listening, closes open connections and waits for their sessions to end.
+// Messages being delivered see their context cancelled.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	s.init()
+	s.cancel()
+	var err error
+	if s.ln != nil {
+		err = s.ln.Close()
+	}
+	for c := range s.conns {
+		_ = c.Close()
+	}
+	s.mu.Unlock()
+	s.sessions.Wait()
+	return err
+}
+
+// init sets up the connection registry and context; s.mu must be held.
+func (s *Server) init() {
+	if s.conns == nil {
+		s.conns = make(map[net.Conn]struct{})
+		s.ctx, s.cancel = context.WithCancel(context.Background())
+	}
+}
+
+// session is the state of one SMTP connection.
+type session struct {
+	s        *Server
+	conn     net.Conn
+	r        *bufio.Reader
+	w        *bufio.Writer
+	helo     string
+	tls      bool
+	from     *string
+	rcpts    []string
+	badCount int
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer conn.Close()
+	ss := &session{s: s, conn: conn, r: bufio.NewReader(conn), w: bufio.NewWriter(conn)}
+	ss.reply(220, s.hostname()+" ESMTP eflo")
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(commandTimeout))
+		line, err := ss.readLine()
+		if err != nil {
+			if errors.Is(err, errLineTooLong) {
+				ss.reply(500, "5.5.2 Line too long")
+				continue
+			}
+			return
+		}
+		if !ss.handle(line) {
+			return
+		}
+		if ss.badCount >= maxBadCommands {
+			ss.reply(421, "4.7.0 Too many errors, closing connection")
+			return
+		}
+	}
+}
+
+func (s *Server) hostname() string {
+	if s.Hostname != "" {
+		return s.Hostname
+	}
+	return "localhost"
+}
+
+var errLineTooLong = errors.New("line too long")
+
+// readLine reads a CRLF (or LF) terminated command line.
+func (ss *session) readLine() (string, error) {
+	var buf []byte
+	for {
+		chunk, isPrefix, err := ss.r.ReadLine()
+		if err != nil {
+			return "", err
+		}
+		if len(buf)+len(chunk) > maxLineLength {
+			// Skip the rest of the line
+			for isPrefix && err == nil {
+				_, isPrefix, err = ss.r.ReadLine()
+			}
+			return "", errLineTooLong
+		}
+		buf = append(buf, chunk...)
+		if !isPrefix {
+			return string(buf), nil
+		}
+	}
+}
+
+func (ss *session) reply(code int, lines ...string) {
+	for i, l := range lines {
+		sep := " "
+		if i < len(lines)-1 {
+			sep = "-"
+		}
+		fmt.Fprintf(ss.w, "%d%s%s\r\n", code, sep, l)
+	}
+	_ = ss.w.Flush()
+}
+
+func (ss *session) reset() {
+	ss.from = nil
+	ss.rcpts = nil
+}
+
+// handle runs one command; false ends the session.
+func (ss *session) handle(line string) bool {
+	verb, arg := line, ""
+	if i := strings.IndexByte(line, ' '); i >= 0 {
+		verb, arg = line[:i], strings.TrimSpace(line[i+1:])
+	}
+	switch strings.ToUpper(verb) {
+	case "HELO", "EHLO":
+		if arg == "" {
+			ss.bad(501, "5.5.4 Domain required")
+			return true
+		}
+		ss.helo = arg
+		ss.reset()
+		if strings.ToUpper(verb) == "HELO" {
+			ss.reply(250, ss.s.hostname())
+			return true
+		}
+		ext := []string{ss.s.hostname() + " greets " + arg, "8BITMIME", "SMTPUTF8", "ENHANCEDSTATUSCODES"}
+		if ss.s.MaxMessageBytes > 0 {
+			ext = append(ext, "SIZE "+strconv.Itoa(ss.s.MaxMessageBytes))
+		} else {
+			ext = append(ext, "SIZE")
+		}
+		if ss.s.TLSConfig != nil && !ss.tls {
+			ext = append(ext, "STARTTLS")
+		}
+		ss.reply(250, ext...)
+	case "STARTTLS":
+		if ss.s.TLSConfig == nil || ss.tls {
+			ss.bad(502, "5.5.1 STARTTLS not available")
+			return true
+		}
+		ss.reply(220, "2.0.0 Ready to start TLS")
+		tc := tls.Server(ss.conn, ss.s.TLSConfig)
+		_ = tc.SetDeadline(time.Now().Add(commandTimeout))
+		if err := tc.Handshake(); err != nil {
+			return false
+		}
+		_ = tc.SetDeadline(time.Time{})
+		ss.conn, ss.tls = tc, true
+		ss.r, ss.w = bufio.NewReader(tc), bufio.NewWriter(tc)
+		// The client starts over with EHLO
+		ss.helo = ""
+		ss.reset()
+	case "MAIL":
+		ss.mail(arg)
+	case "RCPT":
+		ss.rcpt(arg)
+	case "DATA":
+		return ss.data()
+	case "RSET":
+		ss.reset()
+		ss.reply(250, "2.0.0 OK")
+	case "NOOP":
+		ss.reply(250, "2.0.0 OK")
+	case "VRFY":
+		ss.reply(252, "2.5.0 Cannot verify user")
+	case "HELP":
+		ss.reply(214, "2.0.0 Commands: HELO EHLO STARTTLS MAIL RCPT DATA RSET NOOP VRFY QUIT")
+	case "QUIT":
+		ss.reply(221, "2.0.0 Bye")
+		return false
+	default:
+		ss.bad(500, "5.5.2 Command not recognized")
+	}
+	return true
+}
+
+func (ss *session) bad(code int, msg string) {
+	ss.badCount++
+	ss.reply(code, msg)
+}
+
+func (ss *session) mail(arg string) {
+	if ss.helo == "" {
+		ss.bad(503, "5.5.1 Send EHLO first")
+		return
+	}
+	if ss.from != nil {
+		ss.bad(503, "5.5.1 Sender already given")
+		return
+	}
+	path, params, ok := parsePath(arg, "FROM:")
+	if !ok {
+		ss.bad(501, "5.5.4 Syntax: MAIL FROM:<address>")
+		return
+	}
+	for _, p := range params {
+		k, v, _ := strings.Cut(p, "=")
+		if strings.EqualFold(k, "SIZE") && ss.s.MaxMessageBytes > 0 {
+			if n, err := strconv.Atoi(v); err == nil && n > ss.s.MaxMessageBytes {
+				ss.reply(552, "5.3.4 Message size exceeds fixed limit")
+				return
+			}
+		}
+	}
+	ss.from = &path
+	ss.reply(250, "2.1.0 OK")
+}
+
+func (ss *session) rcpt(arg string) {
+	if ss.from == nil {
+		ss.bad(503, "5.5.1 Send MAIL first")
+		return
+	}
+	path, _, ok := parsePath(arg, "TO:")
+	if !ok || path == "" {
+		ss.bad(501, "5.5.4 Syntax: RCPT TO:<address>")
+		return
+	}
+	max := ss.s.MaxRecipients
+	if max <= 0 {
+		max = 100
+	}
+	if len(ss.rcpts) >= max {
+		ss.reply(452, "4.5.3 Too many recipients")
+		return
+	}
+	addr := strings.ToLower(path)
+	accepted, err := ss.s.Backend.AcceptRecipient(addr)
+	if err != nil {
+		log.Printf("[SMTP] Recipient lookup for %s failed: %v", addr, err)
+		ss.reply(451, "4.3.0 Temporary lookup failure")
+		return
+	}
+	if !accepted {
+		ss.bad(550, "5.1.1 Mailbox unavailable")
+		return
+	}
+	ss.rcpts = append(ss.rcpts, addr)
+	ss.reply(250, "2.1.5 OK")
+}
+
+// data receives the message body; false ends the session (connection lost).
+func (ss *session) data() bool {
+	if ss.from == nil || len(ss.rcpts) == 0 {
+		ss.bad(503, "5.5.1 Send MAIL and RCPT first")
+		return true
+	}
+	ss.reply(354, "End data with <CR><LF>.<CR><LF>")
+	_ = ss.conn.SetReadDeadline(time.Now().Add(dataTimeout))
+
+	dr := textproto.NewReader(ss.r).DotReader()
+	var buf bytes.Buffer
+	limit := int64(ss.s.MaxMessageBytes)
+	var err error
+	if limit > 0 {
+		_, err = io.Copy(&buf, io.LimitReader(dr, limit+1))
+		if err == nil && int64(buf.Len()) > limit {
+			// Drain the rest of the message before refusing it
+			if _, err = io.Copy(io.Discard, dr); err == nil {
+				ss.reset()
+				ss.reply(552, "5.3.4 Message size exceeds fixed limit")
+				return true
+			}
+		}
+	} else {
+		_, err = io.Copy(&buf, dr)
+	}
+	if err != nil {
+		return false
+	}
+
+	env := Envelope{
+		From:       *ss.from,
+		Recipients: ss.rcpts,
+		RemoteAddr: ss.conn.RemoteAddr().String(),
+		Helo:       ss.helo,
+		TLS:        ss.tls,
+	}
+	ss.reset()
+	if err := ss.s.Backend.Deliver(ss.s.ctx, env, buf.Bytes()); err != nil {
+		log.Printf("[SMTP] Delivery from %s failed: %v", env.RemoteAddr, err)
+		ss.reply(451, "4.3.0 "+oneLine(err.Error()))
+		return true
+	}
+	ss.reply(250, "2.0.0 OK: queued")
+	return true
+}
+
+// parsePath parses "FROM:<path> PARAM=value ..." (or "TO:..."), allowing a space after the colon.
+func parsePath(arg, prefix string) (path string, params []string, ok bool) {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return "", nil, false
+	}
+	rest := strings.TrimSpace(arg[len(prefix):])
+	if !strings.HasPrefix(rest, "<") {
+		return "", nil, false
+	}
+	end := strings.IndexByte(rest, '>')
+	if end < 0 {
+		return "", nil, false
+	}
+	path = rest[1:end]
+	// Drop a source route (<@a,@b:user@host>)
+	if strings.HasPrefix(path, "@") {
+		if i := strings.IndexByte(path, ':'); i >= 0 {
+			path = path[i+1:]
+		}
+	}
+	if path != "" && (strings.Count(path, "@") < 1 || strings.ContainsAny(path, " \t")) {
+		return "", nil, false
+	}
+	return path, strings.Fields(rest[end+1:]), true
+}
+
+func oneLine(s string) string {
+	s = strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+	if len(s) > 200 {
+		s = s[:200]
+	}
+	return s
+}
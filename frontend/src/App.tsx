@@ -28,6 +28,7 @@ export default function App() {
   const [showScheduleManager, setShowScheduleManager] = useState(false);
   const [showRedisSubManager, setShowRedisSubManager] = useState(false);
   const [showEmailTriggerManager, setShowEmailTriggerManager] = useState(false);
+  const [showSmtpTriggerManager, setShowSmtpTriggerManager] = useState(false);
   const [showHttpTriggerManager, setShowHttpTriggerManager] = useState(false);
   const [showConfigStoreManager, setShowConfigStoreManager] = useState(false);
   const isDragging = useRef(false);
@@ -153,6 +154,8 @@ export default function App() {
             setShowRedisSubManager={setShowRedisSubManager}
             showEmailTriggerManager={showEmailTriggerManager}
             setShowEmailTriggerManager={setShowEmailTriggerManager}
+            showSmtpTriggerManager={showSmtpTriggerManager}
+            setShowSmtpTriggerManager={setShowSmtpTriggerManager}
             showHttpTriggerManager={showHttpTriggerManager}
             setShowHttpTriggerManager={setShowHttpTriggerManager}
             showConfigStoreManager={showConfigStoreManager}
@@ -192,6 +195,7 @@ export default function App() {
                   onOpenScheduleManager={() => setShowScheduleManager(true)}
                   onOpenRedisSubManager={() => setShowRedisSubManager(true)}
                   onOpenEmailTriggerManager={() => setShowEmailTriggerManager(true)}
+                  onOpenSmtpTriggerManager={() => setShowSmtpTriggerManager(true)}
                   onOpenHttpTriggerManager={() => setShowHttpTriggerManager(true)}
                 />
               </div>
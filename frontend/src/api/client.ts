@@ -185,10 +185,20 @@ export interface CronSchedule {
   enabled: boolean;
   lastRunAt?: string;
   nextRunAt?: string;
+  overlapPolicy: OverlapPolicy;
+  misfirePolicy: MisfirePolicy;
+  misfireMax: number;
+  jitterSec: number;
+  execTimeoutSec: number;
+  calendarIds: number[];
+  payload?: Record<string, any>;
   createdAt: string;
   updatedAt: string;
 }
 
+export type OverlapPolicy = 'allow' | 'skip' | 'queue' | 'cancel_previous';
+export type MisfirePolicy = 'skip' | 'run_once' | 'run_all';
+
 export const getSchedules = (workflowId?: number) =>
   api.get<CronSchedule[]>('/schedules', { params: workflowId ? { workflowId } : {} });
 export const getSchedule = (id: number) => api.get<CronSchedule>(`/schedules/${id}`);
@@ -197,8 +207,31 @@ export const updateSchedule = (id: number, data: Partial<CronSchedule>) =>
   api.put<CronSchedule>(`/schedules/${id}`, data);
 export const deleteSchedule = (id: number) => api.delete(`/schedules/${id}`);
 
+export interface ScheduleCalendar {
+  id: number;
+  name: string;
+  description: string;
+  timezone: string;
+  dates: string[];
+  windows: { start: string; end: string; label?: string }[];
+  createdAt: string;
+  updatedAt: string;
+}
+
+export const getScheduleCalendars = () => api.get<ScheduleCalendar[]>('/schedule-calendars');
+
+// Concurrency, buffering and timeout shared by event-driven triggers
+export type TriggerOverflowPolicy = 'block' | 'drop_oldest' | 'reject';
+
+export interface TriggerLimits {
+  maxInFlight: number;
+  bufferSize: number;
+  overflowPolicy: TriggerOverflowPolicy;
+  execTimeoutSec: number;
+}
+
 // Redis Subscriptions
-export interface RedisSubscription {
+export interface RedisSubscription extends TriggerLimits {
   id: number;
   workflowId: number;
   configId: number;
@@ -219,16 +252,58 @@ export const updateRedisSubscription = (id: number, data: Partial<RedisSubscript
   api.put<RedisSubscription>(`/redis-subscriptions/${id}`, data);
 export const deleteRedisSubscription = (id: number) => api.delete(`/redis-subscriptions/${id}`);
 
+// Redis Stream triggers (consumer groups)
+export interface RedisStreamTrigger {
+  id: number;
+  workflowId: number;
+  configId: number;
+  stream: string;
+  group: string;
+  consumer: string;
+  startId: string;
+  concurrency: number;
+  claimIdleSec: number;
+  execTimeoutSec: number;
+  maxDeliveries: number;
+  enabled: boolean;
+  lastMsgAt?: string;
+  msgCount: number;
+  createdAt: string;
+  updatedAt: string;
+}
+
+export const getRedisStreamTriggers = () => api.get<RedisStreamTrigger[]>('/redis-streams');
+export const getRedisStreamTrigger = (id: number) => api.get<RedisStreamTrigger>(`/redis-streams/${id}`);
+export const createRedisStreamTrigger = (data: Partial<RedisStreamTrigger>) =>
+  api.post<RedisStreamTrigger>('/redis-streams', data);
+export const updateRedisStreamTrigger = (id: number, data: Partial<RedisStreamTrigger>) =>
+  api.put<RedisStreamTrigger>(`/redis-streams/${id}`, data);
+export const deleteRedisStreamTrigger = (id: number) => api.delete(`/redis-streams/${id}`);
+
 // Email Triggers
-export interface EmailTrigger {
+export interface EmailAction {
+  type: 'move' | 'copy' | 'flag' | 'unflag' | 'delete';
+  mailbox?: string;
+  flags?: string[];
+}
+
+export interface EmailTrigger extends TriggerLimits {
   id: number;
   workflowId: number;
   configId: number;
   mailbox: string;
   pollIntervalSec: number;
   markSeen: boolean;
+  useIdle: boolean;
   maxFetch: number;
+  maxMessageBytes: number;
+  maxAttachmentBytes: number;
   enabled: boolean;
+  uidValidity: number;
+  lastUid: number;
+  startFrom: 'new' | 'unseen';
+  onSuccess: EmailAction[] | null;
+  onFailure: EmailAction[] | null;
   lastPollAt?: string;
   msgCount: number;
   createdAt: string;
@@ -243,13 +318,71 @@ export const updateEmailTrigger = (id: number, data: Partial<EmailTrigger>) =>
   api.put<EmailTrigger>(`/email-triggers/${id}`, data);
 export const deleteEmailTrigger = (id: number) => api.delete(`/email-triggers/${id}`);
 
+// SMTP Triggers (mail received by the embedded SMTP receiver)
+export interface SmtpTrigger extends TriggerLimits {
+  id: number;
+  workflowId: number;
+  recipients: string[];
+  enabled: boolean;
+  maxAttachmentBytes: number;
+  lastReceivedAt?: string;
+  msgCount: number;
+  createdAt: string;
+  updatedAt: string;
+}
+
+export const getSmtpTriggers = () => api.get<SmtpTrigger[]>('/smtp-triggers');
+export const getSmtpTrigger = (id: number) => api.get<SmtpTrigger>(`/smtp-triggers/${id}`);
+export const createSmtpTrigger = (data: Partial<SmtpTrigger>) =>
+  api.post<SmtpTrigger>('/smtp-triggers', data);
+export const updateSmtpTrigger = (id: number, data: Partial<SmtpTrigger>) =>
+  api.put<SmtpTrigger>(`/smtp-triggers/${id}`, data);
+export const deleteSmtpTrigger = (id: number) => api.delete(`/smtp-triggers/${id}`);
+
 // HTTP Triggers (HTTP-in / HTTP-out like Node-RED)
+export type HttpAuthType = 'none' | 'api_key' | 'hmac' | 'basic' | 'jwt';
+
+// Authentication policy of an HTTP trigger; secretKey names a config store entry.
+export interface HttpTriggerAuth {
+  type: HttpAuthType;
+  secretKey?: string;
+  header?: string;
+  queryParam?: string;
+  algorithm?: 'sha1' | 'sha256' | 'sha512';
+  encoding?: 'hex' | 'base64';
+  prefix?: string;
+  format?: 'plain' | 'stripe';
+  timestampHeader?: string;
+  signedPayload?: string;
+  toleranceSec?: number;
+  username?: string;
+  jwksUrl?: string;
+  issuer?: string;
+  audience?: string;
+}
+
+// Body size, rate, concurrency and client IP limits of an HTTP trigger (0 = default / unlimited).
+export interface HttpTriggerLimits {
+  maxBodyBytes: number;
+  ratePerSec: number;
+  burst: number;
+  rateKey: 'global' | 'ip' | 'header';
+  rateKeyHeader?: string;
+  maxConcurrent: number;
+  allowCidrs?: string[];
+  denyCidrs?: string[];
+  clientIpHeader?: string;
+}
+
 export interface HttpTrigger {
   id: number;
   workflowId: number;
   path: string;
   method: string;
   enabled: boolean;
+  auth?: HttpTriggerAuth;
+  limits?: HttpTriggerLimits;
+  requestSchema?: Record<string, any>;
   createdAt: string;
   updatedAt: string;
 }
@@ -262,6 +395,43 @@ export const updateHttpTrigger = (id: number, data: Partial<HttpTrigger>) =>
   api.put<HttpTrigger>(`/http-triggers/${id}`, data);
 export const deleteHttpTrigger = (id: number) => api.delete(`/http-triggers/${id}`);
 
+// Dead letters (trigger events whose run failed)
+export interface DeadLetter {
+  id: number;
+  triggerType: 'redis' | 'redis_stream' | 'email' | 'http';
+  triggerId: number;
+  workflowId: number;
+  payload?: Record<string, unknown>;
+  executionId?: number;
+  error: string;
+  status: 'pending' | 'replayed';
+  replayCount: number;
+  replayExecutionId?: number;
+  replayedAt?: string;
+  createdAt: string;
+  updatedAt: string;
+}
+
+export interface DeadLetterFilter {
+  triggerType?: string;
+  triggerId?: number;
+  workflowId?: number;
+  status?: string;
+  since?: string;
+  before?: string;
+}
+
+export const getDeadLetters = (params?: DeadLetterFilter & { limit?: number; offset?: number }) =>
+  api.get<DeadLetter[]>('/dead-letters', { params });
+export const getDeadLetter = (id: number) => api.get<DeadLetter>(`/dead-letters/${id}`);
+export const replayDeadLetter = (id: number) =>
+  api.post<{ deadLetterId: number; executionId: number; status: string; error?: string }>(`/dead-letters/${id}/replay`);
+export const replayDeadLetters = (data: DeadLetterFilter & { ids?: number[]; limit?: number }) =>
+  api.post<{ count: number; ids: number[] }>('/dead-letters/replay', data);
+export const deleteDeadLetter = (id: number) => api.delete(`/dead-letters/${id}`);
+export const purgeDeadLetters = (params: DeadLetterFilter & { all?: boolean }) =>
+  api.delete<{ deleted: number }>('/dead-letters', { params });
+
 // Config Store (key-value for secrets, tokens)
 export interface ConfigStoreEntryMasked {
   key: string;
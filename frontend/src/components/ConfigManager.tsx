@@ -44,6 +44,9 @@ interface ConfigFormState {
   tls: boolean;
   imapHost: string;
   imapPort: number;
+  imapSecurity: string;
+  imapAuth: string;
+  imapTokenKey: string;
   // SSH-specific
   authMethod: string;
   privateKey: string;
@@ -64,6 +67,9 @@ const defaultForm: ConfigFormState = {
   tls: true,
   imapHost: '',
   imapPort: 993,
+  imapSecurity: '',
+  imapAuth: 'password',
+  imapTokenKey: '',
   authMethod: 'password',
   privateKey: '',
   driver: 'mysql',
@@ -101,6 +107,9 @@ export default function ConfigManager({ open, onClose }: { open: boolean; onClos
       tls: cfg.config?.tls !== false,
       imapHost: (cfg.config?.imapHost as string) || '',
       imapPort: (cfg.config?.imapPort as number) || 993,
+      imapSecurity: (cfg.config?.imapSecurity as string) || '',
+      imapAuth: (cfg.config?.imapAuth as string) || 'password',
+      imapTokenKey: (cfg.config?.imapTokenKey as string) || '',
       authMethod: (cfg.config?.authMethod as string) || 'password',
       privateKey: (cfg.config?.privateKey as string) || '',
       driver: (cfg.config?.driver as string) || 'mysql',
@@ -158,6 +167,9 @@ export default function ConfigManager({ open, onClose }: { open: boolean; onClos
         tls: form.tls,
         imapHost: form.imapHost || '',
         imapPort: form.imapPort || 993,
+        imapSecurity: form.imapSecurity,
+        imapAuth: form.imapAuth,
+        imapTokenKey: form.imapAuth === 'xoauth2' ? form.imapTokenKey : '',
       };
     } else if (form.type === 'ssh') {
       configData = {
@@ -463,6 +475,46 @@ export default function ConfigManager({ open, onClose }: { open: boolean; onClos
                   onChange={(val) => setForm({ ...form, imapPort: val || 993 })}
                 />
               </div>
+              <div>
+                <Text strong style={{ fontSize: 11, display: 'block', marginBottom: 2 }}>IMAP Security</Text>
+                <Select
+                  size="small"
+                  style={{ width: '100%' }}
+                  value={form.imapSecurity}
+                  onChange={(val) => setForm({ ...form, imapSecurity: val })}
+                  options={[
+                    { value: '', label: 'Auto (STARTTLS on 143, TLS otherwise)' },
+                    { value: 'tls', label: 'TLS' },
+                    { value: 'starttls', label: 'STARTTLS' },
+                    { value: 'none', label: 'None (unencrypted)' },
+                  ]}
+                />
+              </div>
+              <div>
+                <Text strong style={{ fontSize: 11, display: 'block', marginBottom: 2 }}>IMAP Authentication</Text>
+                <Select
+                  size="small"
+                  style={{ width: '100%' }}
+                  value={form.imapAuth}
+                  onChange={(val) => setForm({ ...form, imapAuth: val })}
+                  options={[
+                    { value: 'password', label: 'Password (LOGIN)' },
+                    { value: 'xoauth2', label: 'OAuth2 (XOAUTH2)' },
+                  ]}
+                />
+              </div>
+              {form.imapAuth === 'xoauth2' && (
+                <div>
+                  <Text strong style={{ fontSize: 11, display: 'block', marginBottom: 2 }}>Access Token Key</Text>
+                  <Input
+                    size="small"
+                    placeholder="gmail_access_token"
+                    value={form.imapTokenKey}
+                    onChange={(e) => setForm({ ...form, imapTokenKey: e.target.value })}
+                  />
+                  <Text type="secondary" style={{ fontSize: 9 }}>Config Store key holding the OAuth2 access token (read on each connect).</Text>
+                </div>
+              )}
             </>
           )}
 
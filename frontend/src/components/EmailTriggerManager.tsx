@@ -20,7 +20,8 @@ import {
   InboxOutlined,
 } from '@ant-design/icons';
 import { useWorkflowStore } from '../store/workflowStore';
-import type { EmailTrigger } from '../api/client';
+import type { EmailAction, EmailTrigger, TriggerLimits } from '../api/client';
+import TriggerLimitFields, { defaultTriggerLimits, limitsOf } from './TriggerLimitFields';
 
 const { Text } = Typography;
 
@@ -30,8 +31,15 @@ interface TriggerFormState {
   mailbox: string;
   pollIntervalSec: number;
   markSeen: boolean;
+  useIdle: boolean;
   maxFetch: number;
+  maxMessageBytes: number;
+  maxAttachmentBytes: number;
+  startFrom: 'new' | 'unseen';
   enabled: boolean;
+  onSuccess: EmailAction[];
+  onFailure: EmailAction[];
+  limits: TriggerLimits;
 }
 
 const defaultForm: TriggerFormState = {
@@ -40,10 +48,70 @@ const defaultForm: TriggerFormState = {
   mailbox: 'INBOX',
   pollIntervalSec: 60,
   markSeen: true,
+  useIdle: true,
   maxFetch: 10,
+  maxMessageBytes: 25 << 20,
+  maxAttachmentBytes: 10 << 20,
+  startFrom: 'new',
   enabled: true,
+  onSuccess: [],
+  onFailure: [],
+  limits: defaultTriggerLimits,
 };
 
+const ACTION_OPTIONS = [
+  { value: 'move', label: 'Move' },
+  { value: 'copy', label: 'Copy' },
+  { value: 'flag', label: 'Add flags' },
+  { value: 'unflag', label: 'Remove flags' },
+  { value: 'delete', label: 'Delete' },
+];
+
+// EmailActionsEditor edits a list of post-processing actions (applied in order to the message).
+function EmailActionsEditor({ value, onChange }: { value: EmailAction[]; onChange: (v: EmailAction[]) => void }) {
+  const update = (i: number, a: EmailAction) => onChange(value.map((x, j) => (j === i ? a : x)));
+  return (
+    <Space direction="vertical" size={4} style={{ width: '100%' }}>
+      {value.map((a, i) => (
+        <Space key={i} size={4} style={{ width: '100%' }}>
+          <Select
+            size="small"
+            style={{ width: 110 }}
+            value={a.type}
+            onChange={(type) => update(i, { type })}
+            options={ACTION_OPTIONS}
+          />
+          {(a.type === 'move' || a.type === 'copy') && (
+            <Input
+              size="small"
+              style={{ width: 190 }}
+              placeholder="Mailbox (e.g. Archive)"
+              value={a.mailbox || ''}
+              onChange={(e) => update(i, { ...a, mailbox: e.target.value })}
+            />
+          )}
+          {(a.type === 'flag' || a.type === 'unflag') && (
+            <Select
+              size="small"
+              mode="tags"
+              style={{ width: 190 }}
+              placeholder="\Flagged, processed"
+              tokenSeparators={[',', ' ']}
+              value={a.flags || []}
+              onChange={(flags) => update(i, { ...a, flags })}
+              open={false}
+            />
+          )}
+          <Button size="small" type="text" danger icon={<DeleteOutlined />} onClick={() => onChange(value.filter((_, j) => j !== i))} />
+        </Space>
+      ))}
+      <Button size="small" type="dashed" icon={<PlusOutlined />} onClick={() => onChange([...value, { type: 'flag', flags: [] }])}>
+        Add action
+      </Button>
+    </Space>
+  );
+}
+
 export default function EmailTriggerManager({ open, onClose }: { open: boolean; onClose: () => void }) {
   const {
     emailTriggers,
@@ -83,8 +151,15 @@ export default function EmailTriggerManager({ open, onClose }: { open: boolean;
       mailbox: t.mailbox || 'INBOX',
       pollIntervalSec: t.pollIntervalSec || 60,
       markSeen: t.markSeen,
+      useIdle: t.useIdle,
       maxFetch: t.maxFetch || 10,
+      maxMessageBytes: t.maxMessageBytes || defaultForm.maxMessageBytes,
+      maxAttachmentBytes: t.maxAttachmentBytes || defaultForm.maxAttachmentBytes,
+      startFrom: t.startFrom || 'new',
       enabled: t.enabled,
+      onSuccess: t.onSuccess || [],
+      onFailure: t.onFailure || [],
+      limits: limitsOf(t),
     });
     setEditingId(t.id);
     setFormOpen(true);
@@ -99,12 +174,14 @@ export default function EmailTriggerManager({ open, onClose }: { open: boolean;
       messageApi.warning('Select an email config');
       return;
     }
+    const { limits, ...fields } = form;
+    const payload: Partial<EmailTrigger> = { ...fields, ...limits, workflowId: form.workflowId!, configId: form.configId! };
     try {
       if (editingId) {
-        await editEmailTrigger(editingId, { ...form, workflowId: form.workflowId!, configId: form.configId! });
+        await editEmailTrigger(editingId, payload);
         messageApi.success('Trigger updated');
       } else {
-        await addEmailTrigger({ ...form, workflowId: form.workflowId!, configId: form.configId! });
+        await addEmailTrigger(payload);
         messageApi.success('Trigger created');
       }
       setFormOpen(false);
@@ -185,7 +262,7 @@ export default function EmailTriggerManager({ open, onClose }: { open: boolean;
 
   return (
     <Modal
-      title={<Space><InboxOutlined /><span>Email Triggers (IMAP Polling)</span></Space>}
+      title={<Space><InboxOutlined /><span>Email Triggers (IMAP)</span></Space>}
       open={open}
       onCancel={onClose}
       footer={null}
@@ -214,7 +291,7 @@ export default function EmailTriggerManager({ open, onClose }: { open: boolean;
         onOk={handleSave}
         onCancel={() => setFormOpen(false)}
         okText={editingId ? 'Update' : 'Create'}
-        width={400}
+        width={440}
       >
         <Space direction="vertical" size={8} style={{ width: '100%' }}>
           <div>
@@ -274,16 +351,71 @@ export default function EmailTriggerManager({ open, onClose }: { open: boolean;
               onChange={(val) => setForm({ ...form, maxFetch: val || 10 })}
             />
           </div>
+          <div style={{ display: 'flex', gap: 8 }}>
+            <div style={{ flex: 1 }}>
+              <Text strong style={{ fontSize: 11, display: 'block', marginBottom: 2 }}>Max Message Size (MB)</Text>
+              <InputNumber
+                size="small"
+                style={{ width: '100%' }}
+                min={1}
+                value={Math.round(form.maxMessageBytes / (1 << 20))}
+                onChange={(val) => setForm({ ...form, maxMessageBytes: (val || 25) << 20 })}
+              />
+            </div>
+            <div style={{ flex: 1 }}>
+              <Text strong style={{ fontSize: 11, display: 'block', marginBottom: 2 }}>Max Attachment Size (MB)</Text>
+              <InputNumber
+                size="small"
+                style={{ width: '100%' }}
+                min={1}
+                value={Math.round(form.maxAttachmentBytes / (1 << 20))}
+                onChange={(val) => setForm({ ...form, maxAttachmentBytes: (val || 10) << 20 })}
+              />
+            </div>
+          </div>
+          <Text type="secondary" style={{ fontSize: 9 }}>
+            Larger messages are delivered with headers only (truncated); larger attachments are listed without content.
+          </Text>
+          <div>
+            <Text strong style={{ fontSize: 11, display: 'block', marginBottom: 2 }}>Start From</Text>
+            <Select
+              size="small"
+              style={{ width: '100%' }}
+              value={form.startFrom}
+              onChange={(val) => setForm({ ...form, startFrom: val })}
+              options={[
+                { value: 'new', label: 'New mail only' },
+                { value: 'unseen', label: 'Oldest unread message' },
+              ]}
+            />
+            <Text type="secondary" style={{ fontSize: 9 }}>
+              Where the first poll begins; afterwards every message arriving in the mailbox runs once.
+            </Text>
+          </div>
           <div style={{ display: 'flex', gap: 16 }}>
             <div>
               <Text strong style={{ fontSize: 11, display: 'block', marginBottom: 2 }}>Mark as Read</Text>
               <Switch size="small" checked={form.markSeen} onChange={(v) => setForm({ ...form, markSeen: v })} />
             </div>
+            <div>
+              <Text strong style={{ fontSize: 11, display: 'block', marginBottom: 2 }}>IMAP IDLE</Text>
+              <Switch size="small" checked={form.useIdle} onChange={(v) => setForm({ ...form, useIdle: v })} />
+            </div>
             <div>
               <Text strong style={{ fontSize: 11, display: 'block', marginBottom: 2 }}>Enabled</Text>
               <Switch size="small" checked={form.enabled} onChange={(v) => setForm({ ...form, enabled: v })} />
             </div>
           </div>
+          <div>
+            <Text strong style={{ fontSize: 11, display: 'block', marginBottom: 2 }}>On Success</Text>
+            <EmailActionsEditor value={form.onSuccess} onChange={(v) => setForm({ ...form, onSuccess: v })} />
+          </div>
+          <div>
+            <Text strong style={{ fontSize: 11, display: 'block', marginBottom: 2 }}>On Failure</Text>
+            <EmailActionsEditor value={form.onFailure} onChange={(v) => setForm({ ...form, onFailure: v })} />
+            <Text type="secondary" style={{ fontSize: 9 }}>Applied to the message after its run, in order. Move and delete must come last.</Text>
+          </div>
+          <TriggerLimitFields limits={form.limits} onChange={(limits) => setForm({ ...form, limits })} />
         </Space>
       </Modal>
     </Modal>
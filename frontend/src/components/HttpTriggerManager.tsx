@@ -1,8 +1,10 @@
 import { useEffect, useState } from 'react';
 import {
+  AutoComplete,
   Button,
   Modal,
   Input,
+  InputNumber,
   Select,
   Table,
   Space,
@@ -19,32 +21,64 @@ import {
   GlobalOutlined,
 } from '@ant-design/icons';
 import { useWorkflowStore } from '../store/workflowStore';
-import type { HttpTrigger } from '../api/client';
+import type { HttpTrigger, HttpTriggerAuth, HttpTriggerLimits, HttpAuthType } from '../api/client';
 
 const { Text } = Typography;
+const { TextArea } = Input;
 
 const METHODS = ['GET', 'POST', 'PUT', 'PATCH', 'DELETE'];
 
+const AUTH_TYPES: { value: HttpAuthType; label: string }[] = [
+  { value: 'none', label: 'None (public)' },
+  { value: 'api_key', label: 'API key' },
+  { value: 'hmac', label: 'HMAC signature' },
+  { value: 'basic', label: 'Basic auth' },
+  { value: 'jwt', label: 'JWT bearer' },
+];
+
+// Signature conventions of common webhook senders
+const HMAC_PRESETS: Record<string, Partial<HttpTriggerAuth>> = {
+  github: { header: 'X-Hub-Signature-256', prefix: 'sha256=', format: 'plain', timestampHeader: '', signedPayload: '', algorithm: 'sha256', encoding: 'hex' },
+  stripe: { header: 'Stripe-Signature', prefix: '', format: 'stripe', timestampHeader: '', signedPayload: '{timestamp}.{body}', algorithm: 'sha256', encoding: 'hex' },
+  slack: { header: 'X-Slack-Signature', prefix: 'v0=', format: 'plain', timestampHeader: 'X-Slack-Request-Timestamp', signedPayload: 'v0:{timestamp}:{body}', algorithm: 'sha256', encoding: 'hex' },
+};
+
 interface FormState {
   workflowId: number | undefined;
   path: string;
   method: string;
   enabled: boolean;
+  auth: HttpTriggerAuth;
+  limits: HttpTriggerLimits;
+  requestSchema: string; // JSON text; empty = no schema
 }
 
+const defaultLimits: HttpTriggerLimits = {
+  maxBodyBytes: 10 << 20,
+  ratePerSec: 0,
+  burst: 0,
+  rateKey: 'global',
+  maxConcurrent: 0,
+};
+
 const defaultForm: FormState = {
   workflowId: undefined,
   path: '',
   method: 'POST',
   enabled: true,
+  auth: { type: 'none' },
+  limits: defaultLimits,
+  requestSchema: '',
 };
 
 export default function HttpTriggerManager({ open, onClose }: { open: boolean; onClose: () => void }) {
   const {
     httpTriggers,
     workflows,
+    configStoreEntries,
     fetchHttpTriggers,
     fetchWorkflows,
+    fetchConfigStore,
     addHttpTrigger,
     editHttpTrigger,
     removeHttpTrigger,
@@ -58,6 +92,7 @@ export default function HttpTriggerManager({ open, onClose }: { open: boolean; o
     if (open) {
       fetchHttpTriggers();
       fetchWorkflows();
+      fetchConfigStore();
     }
   }, [open]);
 
@@ -73,6 +108,9 @@ export default function HttpTriggerManager({ open, onClose }: { open: boolean; o
       path: t.path,
       method: t.method || 'POST',
       enabled: t.enabled,
+      auth: t.auth || { type: 'none' },
+      limits: { ...defaultLimits, ...t.limits },
+      requestSchema: t.requestSchema ? JSON.stringify(t.requestSchema, null, 2) : '',
     });
     setEditingId(t.id);
     setFormOpen(true);
@@ -87,12 +125,37 @@ export default function HttpTriggerManager({ open, onClose }: { open: boolean; o
       messageApi.warning('Path is required (e.g. webhook or api/events)');
       return;
     }
+    const { auth } = form;
+    if (auth.type !== 'none' && auth.type !== 'jwt' && !auth.secretKey) {
+      messageApi.warning('Select the config store key holding the secret');
+      return;
+    }
+    if (auth.type === 'jwt' && !auth.secretKey === !auth.jwksUrl) {
+      messageApi.warning('Set either a JWKS URL or a config store key with the shared secret');
+      return;
+    }
+    let requestSchema: Record<string, any> | undefined;
+    if (form.requestSchema.trim()) {
+      try {
+        requestSchema = JSON.parse(form.requestSchema);
+      } catch {
+        messageApi.warning('Request schema must be valid JSON');
+        return;
+      }
+      if (requestSchema === null || typeof requestSchema !== 'object' || Array.isArray(requestSchema)) {
+        messageApi.warning('Request schema must be a JSON object');
+        return;
+      }
+    }
     const path = form.path.trim().replace(/^\/+/, '').replace(/^api\/in\/?/, '');
     const payload: Partial<HttpTrigger> = {
       workflowId: form.workflowId,
       path: path || 'webhook',
       method: form.method,
       enabled: form.enabled,
+      auth: form.auth,
+      limits: form.limits,
+      requestSchema,
     };
     try {
       if (editingId) {
@@ -103,8 +166,8 @@ export default function HttpTriggerManager({ open, onClose }: { open: boolean; o
         messageApi.success('HTTP trigger created');
       }
       setFormOpen(false);
-    } catch {
-      messageApi.error('Failed to save HTTP trigger');
+    } catch (err: any) {
+      messageApi.error(typeof err?.response?.data === 'string' ? err.response.data : 'Failed to save HTTP trigger');
     }
   };
 
@@ -151,6 +214,17 @@ export default function HttpTriggerManager({ open, onClose }: { open: boolean; o
         <Text code style={{ fontSize: 10 }}>{path || '/'}</Text>
       ),
     },
+    {
+      title: 'Auth',
+      key: 'auth',
+      width: 80,
+      render: (_: unknown, record: HttpTrigger) =>
+        record.auth && record.auth.type !== 'none' ? (
+          <Tag color="green" style={{ fontSize: 10 }}>{record.auth.type}</Tag>
+        ) : (
+          <Text type="secondary" style={{ fontSize: 10 }}>public</Text>
+        ),
+    },
     {
       title: 'URL',
       key: 'url',
@@ -223,7 +297,7 @@ export default function HttpTriggerManager({ open, onClose }: { open: boolean; o
         onOk={handleSave}
         onCancel={() => setFormOpen(false)}
         okText={editingId ? 'Update' : 'Create'}
-        width={400}
+        width={480}
       >
         <Space direction="vertical" size={8} style={{ width: '100%' }}>
           <div>
@@ -241,12 +315,14 @@ export default function HttpTriggerManager({ open, onClose }: { open: boolean; o
             <Text strong style={{ fontSize: 10, display: 'block', marginBottom: 1 }}>Path</Text>
             <Input
               size="small"
-              placeholder="webhook or api/events"
+              placeholder="webhook or orders/{id}/items"
               value={form.path}
               onChange={(e) => setForm({ ...form, path: e.target.value })}
               addonBefore="/api/in/"
             />
-            <Text type="secondary" style={{ fontSize: 9 }}>URL path after /api/in/ (e.g. webhook)</Text>
+            <Text type="secondary" style={{ fontSize: 9 }}>
+              URL path after /api/in/. &#123;name&#125; matches one segment and a final * or &#123;name*&#125; the rest; values are in input.params. Literal segments win over parameters, parameters over wildcards.
+            </Text>
           </div>
           <div>
             <Text strong style={{ fontSize: 10, display: 'block', marginBottom: 1 }}>Method</Text>
@@ -258,6 +334,38 @@ export default function HttpTriggerManager({ open, onClose }: { open: boolean; o
               options={METHODS.map((m) => ({ value: m, label: m }))}
             />
           </div>
+          <div>
+            <Text strong style={{ fontSize: 10, display: 'block', marginBottom: 1 }}>Authentication</Text>
+            <Select
+              size="small"
+              style={{ width: '100%' }}
+              value={form.auth.type}
+              onChange={(type: HttpAuthType) => setForm({ ...form, auth: { type } })}
+              options={AUTH_TYPES}
+            />
+          </div>
+          {form.auth.type !== 'none' && (
+            <AuthFields
+              auth={form.auth}
+              secretKeys={configStoreEntries.map((e) => e.key)}
+              onChange={(auth) => setForm({ ...form, auth })}
+            />
+          )}
+          <LimitFields limits={form.limits} onChange={(limits) => setForm({ ...form, limits })} />
+          <div>
+            <Text strong style={{ fontSize: 10, display: 'block', marginBottom: 1 }}>Request Schema (JSON)</Text>
+            <TextArea
+              size="small"
+              rows={4}
+              style={{ fontFamily: 'monospace', fontSize: 10 }}
+              placeholder='{"type": "object", "required": ["id"]}'
+              value={form.requestSchema}
+              onChange={(e) => setForm({ ...form, requestSchema: e.target.value })}
+            />
+            <Text type="secondary" style={{ fontSize: 9 }}>
+              Optional JSON Schema for the request body; requests that don't match get 400 with the violations.
+            </Text>
+          </div>
           <div>
             <Text strong style={{ fontSize: 10, display: 'block', marginBottom: 1 }}>Enabled</Text>
             <Switch size="small" checked={form.enabled} onChange={(v) => setForm({ ...form, enabled: v })} />
@@ -267,3 +375,239 @@ export default function HttpTriggerManager({ open, onClose }: { open: boolean; o
     </Modal>
   );
 }
+
+function Field({ label, hint, children }: { label: string; hint?: string; children: React.ReactNode }) {
+  return (
+    <div>
+      <Text strong style={{ fontSize: 10, display: 'block', marginBottom: 1 }}>{label}</Text>
+      {children}
+      {hint && <Text type="secondary" style={{ fontSize: 9 }}>{hint}</Text>}
+    </div>
+  );
+}
+
+// AuthFields edits the settings of the selected auth type. Requests failing the policy get 401
+// before the workflow runs; the caller's identity is passed as input.auth.
+function AuthFields({
+  auth,
+  secretKeys,
+  onChange,
+}: {
+  auth: HttpTriggerAuth;
+  secretKeys: string[];
+  onChange: (auth: HttpTriggerAuth) => void;
+}) {
+  const set = (patch: Partial<HttpTriggerAuth>) => onChange({ ...auth, ...patch });
+  const secretKey = (label: string, hint?: string) => (
+    <Field label={label} hint={hint || 'Config store key; the secret itself is never stored on the trigger.'}>
+      <AutoComplete
+        size="small"
+        style={{ width: '100%' }}
+        placeholder="e.g. github_webhook_secret"
+        value={auth.secretKey}
+        onChange={(v) => set({ secretKey: v })}
+        options={secretKeys.map((k) => ({ value: k }))}
+        filterOption={(input, option) => String(option?.value ?? '').toLowerCase().includes(input.toLowerCase())}
+      />
+    </Field>
+  );
+
+  switch (auth.type) {
+    case 'api_key':
+      return (
+        <>
+          {secretKey('API Key (config store key)')}
+          <Field label="Header" hint="Default X-API-Key. With Authorization, a Bearer prefix is accepted.">
+            <Input size="small" placeholder="X-API-Key" value={auth.header} onChange={(e) => set({ header: e.target.value })} />
+          </Field>
+          <Field label="Query Parameter" hint="Optional: also accept the key as ?name=...">
+            <Input size="small" placeholder="api_key" value={auth.queryParam} onChange={(e) => set({ queryParam: e.target.value })} />
+          </Field>
+        </>
+      );
+
+    case 'hmac':
+      return (
+        <>
+          <Field label="Preset">
+            <Select
+              size="small"
+              style={{ width: '100%' }}
+              placeholder="Fill in a known sender's convention..."
+              value={undefined}
+              onChange={(p: string) => set(HMAC_PRESETS[p])}
+              options={[
+                { value: 'github', label: 'GitHub (X-Hub-Signature-256)' },
+                { value: 'stripe', label: 'Stripe (Stripe-Signature)' },
+                { value: 'slack', label: 'Slack (X-Slack-Signature)' },
+              ]}
+            />
+          </Field>
+          {secretKey('Signing Secret (config store key)')}
+          <Field label="Signature Header">
+            <Input size="small" placeholder="X-Hub-Signature-256" value={auth.header} onChange={(e) => set({ header: e.target.value })} />
+          </Field>
+          <Space size={8} style={{ width: '100%' }}>
+            <Field label="Algorithm">
+              <Select
+                size="small"
+                style={{ width: 110 }}
+                value={auth.algorithm || 'sha256'}
+                onChange={(v: string) => set({ algorithm: v as HttpTriggerAuth['algorithm'] })}
+                options={['sha256', 'sha1', 'sha512'].map((a) => ({ value: a, label: a }))}
+              />
+            </Field>
+            <Field label="Encoding">
+              <Select
+                size="small"
+                style={{ width: 100 }}
+                value={auth.encoding || 'hex'}
+                onChange={(v: string) => set({ encoding: v as HttpTriggerAuth['encoding'] })}
+                options={[{ value: 'hex', label: 'hex' }, { value: 'base64', label: 'base64' }]}
+              />
+            </Field>
+            <Field label="Header Format">
+              <Select
+                size="small"
+                style={{ width: 140 }}
+                value={auth.format || 'plain'}
+                onChange={(v: string) => set({ format: v as HttpTriggerAuth['format'] })}
+                options={[{ value: 'plain', label: 'plain' }, { value: 'stripe', label: 't=...,v1=...' }]}
+              />
+            </Field>
+          </Space>
+          {auth.format !== 'stripe' && (
+            <>
+              <Field label="Signature Prefix" hint="Stripped from the header value, e.g. sha256=">
+                <Input size="small" placeholder="sha256=" value={auth.prefix} onChange={(e) => set({ prefix: e.target.value })} />
+              </Field>
+              <Field label="Timestamp Header" hint="Optional: binds the signature to a Unix timestamp to block replays.">
+                <Input size="small" placeholder="X-Slack-Request-Timestamp" value={auth.timestampHeader} onChange={(e) => set({ timestampHeader: e.target.value })} />
+              </Field>
+            </>
+          )}
+          {(auth.format === 'stripe' || auth.timestampHeader) && (
+            <Space size={8} style={{ width: '100%' }}>
+              <Field label="Signed Payload">
+                <Input size="small" style={{ width: 200 }} placeholder="{timestamp}.{body}" value={auth.signedPayload} onChange={(e) => set({ signedPayload: e.target.value })} />
+              </Field>
+              <Field label="Tolerance (s)">
+                <InputNumber size="small" min={1} placeholder="300" value={auth.toleranceSec} onChange={(v) => set({ toleranceSec: v || undefined })} />
+              </Field>
+            </Space>
+          )}
+        </>
+      );
+
+    case 'basic':
+      return (
+        <>
+          <Field label="Username">
+            <Input size="small" value={auth.username} onChange={(e) => set({ username: e.target.value })} />
+          </Field>
+          {secretKey('Password (config store key)')}
+        </>
+      );
+
+    case 'jwt':
+      return (
+        <>
+          <Field label="JWKS URL" hint="RS*, PS* and ES* tokens are verified with the published keys (cached, refreshed on rotation).">
+            <Input size="small" placeholder="https://issuer.example.com/.well-known/jwks.json" value={auth.jwksUrl} onChange={(e) => set({ jwksUrl: e.target.value })} />
+          </Field>
+          {secretKey('Shared Secret (config store key)', 'Instead of a JWKS: HS256/384/512 tokens signed with this secret.')}
+          <Field label="Issuer" hint="Optional: required iss claim">
+            <Input size="small" value={auth.issuer} onChange={(e) => set({ issuer: e.target.value })} />
+          </Field>
+          <Field label="Audience" hint="Optional: required aud claim">
+            <Input size="small" value={auth.audience} onChange={(e) => set({ audience: e.target.value })} />
+          </Field>
+          <Field label="Clock Skew (s)">
+            <InputNumber size="small" min={0} placeholder="60" value={auth.toleranceSec} onChange={(v) => set({ toleranceSec: v || undefined })} />
+          </Field>
+        </>
+      );
+  }
+  return null;
+}
+
+// LimitFields edits the trigger's limits. Oversized bodies get 413, throttled requests 429 with
+// Retry-After and refused client IPs 403; rate and concurrency are counted per API instance.
+function LimitFields({ limits, onChange }: { limits: HttpTriggerLimits; onChange: (limits: HttpTriggerLimits) => void }) {
+  const set = (patch: Partial<HttpTriggerLimits>) => onChange({ ...limits, ...patch });
+  return (
+    <>
+      <Text strong style={{ fontSize: 11, display: 'block', marginTop: 4 }}>Limits</Text>
+      <Space size={8} style={{ width: '100%' }} wrap>
+        <Field label="Max Body (KB)">
+          <InputNumber
+            size="small"
+            min={1}
+            style={{ width: 100 }}
+            value={Math.round(limits.maxBodyBytes / 1024) || undefined}
+            placeholder="10240"
+            onChange={(v) => set({ maxBodyBytes: (v || 0) * 1024 })}
+          />
+        </Field>
+        <Field label="Requests / sec">
+          <InputNumber size="small" min={0} step={0.5} style={{ width: 90 }} placeholder="no limit" value={limits.ratePerSec || undefined} onChange={(v) => set({ ratePerSec: v || 0 })} />
+        </Field>
+        <Field label="Burst">
+          <InputNumber size="small" min={0} style={{ width: 70 }} value={limits.burst || undefined} onChange={(v) => set({ burst: v || 0 })} />
+        </Field>
+        <Field label="Max Concurrent">
+          <InputNumber size="small" min={0} style={{ width: 90 }} placeholder="no limit" value={limits.maxConcurrent || undefined} onChange={(v) => set({ maxConcurrent: v || 0 })} />
+        </Field>
+      </Space>
+      {limits.ratePerSec > 0 && (
+        <Space size={8} style={{ width: '100%' }}>
+          <Field label="Rate Limit Per">
+            <Select
+              size="small"
+              style={{ width: 130 }}
+              value={limits.rateKey || 'global'}
+              onChange={(v: string) => set({ rateKey: v as HttpTriggerLimits['rateKey'] })}
+              options={[
+                { value: 'global', label: 'All callers' },
+                { value: 'ip', label: 'Client IP' },
+                { value: 'header', label: 'Header value' },
+              ]}
+            />
+          </Field>
+          {limits.rateKey === 'header' && (
+            <Field label="Header">
+              <Input size="small" style={{ width: 160 }} placeholder="X-API-Key" value={limits.rateKeyHeader} onChange={(e) => set({ rateKeyHeader: e.target.value })} />
+            </Field>
+          )}
+        </Space>
+      )}
+      <Field label="Allowed IPs / CIDRs" hint="Empty: any client not denied.">
+        <Select
+          size="small"
+          mode="tags"
+          style={{ width: '100%' }}
+          placeholder="10.0.0.0/8, 203.0.113.7"
+          tokenSeparators={[',', ' ']}
+          value={limits.allowCidrs || []}
+          onChange={(allowCidrs: string[]) => set({ allowCidrs })}
+          open={false}
+        />
+      </Field>
+      <Field label="Denied IPs / CIDRs">
+        <Select
+          size="small"
+          mode="tags"
+          style={{ width: '100%' }}
+          placeholder="198.51.100.0/24"
+          tokenSeparators={[',', ' ']}
+          value={limits.denyCidrs || []}
+          onChange={(denyCidrs: string[]) => set({ denyCidrs })}
+          open={false}
+        />
+      </Field>
+      <Field label="Client IP Header" hint="Only behind a trusted proxy: its last entry is used as the client IP.">
+        <Input size="small" placeholder="X-Forwarded-For" value={limits.clientIpHeader} onChange={(e) => set({ clientIpHeader: e.target.value })} />
+      </Field>
+    </>
+  );
+}
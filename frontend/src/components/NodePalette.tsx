@@ -24,6 +24,9 @@ import {
   ForwardOutlined,
   ApiOutlined,
   SafetyCertificateOutlined,
+  AimOutlined,
+  SwapOutlined,
+  CheckCircleOutlined,
 } from '@ant-design/icons';
 import type { ReactNode } from 'react';
 import { useWorkflowStore } from '../store/workflowStore';
@@ -66,6 +69,7 @@ const CATEGORIES: { title: string; items: NodeItem[] }[] = [
     items: [
       { type: 'email', label: 'Send Email', icon: <MailOutlined />, color: '#fff', bg: '#8e44ad' },
       { type: 'email_receive', label: 'Receive Email', icon: <InboxOutlined />, color: '#fff', bg: '#6c3483' },
+      { type: 'imap', label: 'IMAP Action', icon: <InboxOutlined />, color: '#fff', bg: '#5b2c6f' },
     ]
   },
   {
@@ -83,6 +87,9 @@ const CATEGORIES: { title: string; items: NodeItem[] }[] = [
       { type: 'delay', label: 'Delay', icon: <ClockCircleOutlined />, color: '#fff', bg: '#f4c542' },
       { type: 'transform', label: 'Transform', icon: <ToolOutlined />, color: '#fff', bg: '#f49756' },
       { type: 'function', label: 'Function', icon: <CodeOutlined />, color: '#fff', bg: '#9b59b6' },
+      { type: 'jsonpath', label: 'JSON Path', icon: <AimOutlined />, color: '#fff', bg: '#d35400' },
+      { type: 'mapper', label: 'Mapper', icon: <SwapOutlined />, color: '#fff', bg: '#1abc9c' },
+      { type: 'validate', label: 'Validate', icon: <CheckCircleOutlined />, color: '#fff', bg: '#27ae60' },
     ],
   },
   {
@@ -115,6 +122,7 @@ export interface TriggerTabCallbacks {
   onOpenScheduleManager?: () => void;
   onOpenRedisSubManager?: () => void;
   onOpenEmailTriggerManager?: () => void;
+  onOpenSmtpTriggerManager?: () => void;
   onOpenHttpTriggerManager?: () => void;
 }
 
@@ -124,6 +132,7 @@ const TRIGGER_ITEMS: { key: string; label: string; icon: ReactNode; bg: string;
   { key: 'cron', label: 'Cron Schedules', icon: <FieldTimeOutlined />, bg: '#2e7d32', onClickKey: 'onOpenScheduleManager' },
   { key: 'redis', label: 'Redis Subscriptions', icon: <NotificationOutlined />, bg: '#c0392b', onClickKey: 'onOpenRedisSubManager' },
   { key: 'email', label: 'Email Triggers', icon: <InboxOutlined />, bg: '#6c3483', onClickKey: 'onOpenEmailTriggerManager' },
+  { key: 'smtp', label: 'SMTP Triggers', icon: <MailOutlined />, bg: '#884ea0', onClickKey: 'onOpenSmtpTriggerManager' },
   { key: 'http', label: 'HTTP Triggers', icon: <GlobalOutlined />, bg: '#3498db', onClickKey: 'onOpenHttpTriggerManager' },
 ];
 
@@ -131,11 +140,12 @@ export default function NodePalette({
   onOpenScheduleManager,
   onOpenRedisSubManager,
   onOpenEmailTriggerManager,
+  onOpenSmtpTriggerManager,
   onOpenHttpTriggerManager,
 }: NodePaletteProps = {}) {
   const { workflows, currentWorkflow, loadWorkflow, openTabs } = useWorkflowStore();
   const [search, setSearch] = useState('');
-  const triggerCallbacks = { onOpenScheduleManager, onOpenRedisSubManager, onOpenEmailTriggerManager, onOpenHttpTriggerManager };
+  const triggerCallbacks = { onOpenScheduleManager, onOpenRedisSubManager, onOpenEmailTriggerManager, onOpenSmtpTriggerManager, onOpenHttpTriggerManager };
 
   const filteredCategories = useMemo(() => {
     if (!search.trim()) return CATEGORIES;
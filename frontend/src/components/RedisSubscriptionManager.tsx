@@ -19,7 +19,8 @@ import {
   NotificationOutlined,
 } from '@ant-design/icons';
 import { useWorkflowStore } from '../store/workflowStore';
-import type { RedisSubscription } from '../api/client';
+import type { RedisSubscription, TriggerLimits } from '../api/client';
+import TriggerLimitFields, { defaultTriggerLimits, limitsOf } from './TriggerLimitFields';
 
 const { Text } = Typography;
 
@@ -29,6 +30,7 @@ interface FormState {
   channel: string;
   isPattern: boolean;
   enabled: boolean;
+  limits: TriggerLimits;
 }
 
 const defaultForm: FormState = {
@@ -37,6 +39,7 @@ const defaultForm: FormState = {
   channel: '',
   isPattern: false,
   enabled: true,
+  limits: defaultTriggerLimits,
 };
 
 export default function RedisSubscriptionManager({ open, onClose }: { open: boolean; onClose: () => void }) {
@@ -73,6 +76,7 @@ export default function RedisSubscriptionManager({ open, onClose }: { open: bool
       channel: s.channel,
       isPattern: s.isPattern,
       enabled: s.enabled,
+      limits: limitsOf(s),
     });
     setEditingId(s.id);
     setFormOpen(true);
@@ -89,6 +93,7 @@ export default function RedisSubscriptionManager({ open, onClose }: { open: bool
       channel: form.channel.trim(),
       isPattern: form.isPattern,
       enabled: form.enabled,
+      ...form.limits,
     };
     try {
       if (editingId) {
@@ -269,6 +274,7 @@ export default function RedisSubscriptionManager({ open, onClose }: { open: bool
             <Text strong style={{ fontSize: 10, display: 'block', marginBottom: 1 }}>Enabled</Text>
             <Switch size="small" checked={form.enabled} onChange={(v) => setForm({ ...form, enabled: v })} />
           </div>
+          <TriggerLimitFields limits={form.limits} onChange={(limits) => setForm({ ...form, limits })} />
         </Space>
       </Modal>
     </Modal>
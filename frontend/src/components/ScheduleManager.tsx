@@ -3,6 +3,7 @@ import {
   Button,
   Modal,
   Input,
+  InputNumber,
   Select,
   Table,
   Space,
@@ -19,15 +20,23 @@ import {
   FieldTimeOutlined,
 } from '@ant-design/icons';
 import { useWorkflowStore } from '../store/workflowStore';
-import type { CronSchedule } from '../api/client';
+import { getScheduleCalendars, type CronSchedule, type MisfirePolicy, type OverlapPolicy, type ScheduleCalendar } from '../api/client';
 
 const { Text } = Typography;
+const { TextArea } = Input;
 
 interface ScheduleFormState {
   workflowId: number | undefined;
   expression: string;
   timezone: string;
   enabled: boolean;
+  overlapPolicy: OverlapPolicy;
+  misfirePolicy: MisfirePolicy;
+  misfireMax: number;
+  jitterSec: number;
+  execTimeoutSec: number;
+  calendarIds: number[];
+  payload: string; // JSON text; empty = no payload
 }
 
 const defaultForm: ScheduleFormState = {
@@ -35,6 +44,13 @@ const defaultForm: ScheduleFormState = {
   expression: '*/5 * * * *',
   timezone: 'UTC',
   enabled: true,
+  overlapPolicy: 'allow',
+  misfirePolicy: 'skip',
+  misfireMax: 10,
+  jitterSec: 0,
+  execTimeoutSec: 300,
+  calendarIds: [],
+  payload: '',
 };
 
 export default function ScheduleManager({ open, onClose }: { open: boolean; onClose: () => void }) {
@@ -43,12 +59,16 @@ export default function ScheduleManager({ open, onClose }: { open: boolean; onCl
   const [form, setForm] = useState<ScheduleFormState>(defaultForm);
   const [editingId, setEditingId] = useState<number | null>(null);
   const [formOpen, setFormOpen] = useState(false);
+  const [calendars, setCalendars] = useState<ScheduleCalendar[]>([]);
   const [messageApi, contextHolder] = message.useMessage();
 
   useEffect(() => {
     if (open) {
       fetchSchedules();
       fetchWorkflows();
+      getScheduleCalendars()
+        .then((res) => setCalendars(res.data || []))
+        .catch(() => setCalendars([]));
     }
   }, [open]);
 
@@ -64,6 +84,13 @@ export default function ScheduleManager({ open, onClose }: { open: boolean; onCl
       expression: s.expression,
       timezone: s.timezone,
       enabled: s.enabled,
+      overlapPolicy: s.overlapPolicy || 'allow',
+      misfirePolicy: s.misfirePolicy || 'skip',
+      misfireMax: s.misfireMax || 10,
+      jitterSec: s.jitterSec || 0,
+      execTimeoutSec: s.execTimeoutSec || 300,
+      calendarIds: s.calendarIds || [],
+      payload: s.payload ? JSON.stringify(s.payload, null, 2) : '',
     });
     setEditingId(s.id);
     setFormOpen(true);
@@ -78,11 +105,31 @@ export default function ScheduleManager({ open, onClose }: { open: boolean; onCl
       messageApi.warning('Cron expression is required');
       return;
     }
+    let schedulePayload: Record<string, any> | undefined;
+    if (form.payload.trim()) {
+      try {
+        schedulePayload = JSON.parse(form.payload);
+      } catch {
+        messageApi.warning('Payload must be valid JSON');
+        return;
+      }
+      if (schedulePayload === null || typeof schedulePayload !== 'object' || Array.isArray(schedulePayload)) {
+        messageApi.warning('Payload must be a JSON object');
+        return;
+      }
+    }
     const payload: Partial<CronSchedule> = {
       workflowId: form.workflowId,
       expression: form.expression.trim(),
       timezone: form.timezone,
       enabled: form.enabled,
+      overlapPolicy: form.overlapPolicy,
+      misfirePolicy: form.misfirePolicy,
+      misfireMax: form.misfireMax,
+      jitterSec: form.jitterSec,
+      execTimeoutSec: form.execTimeoutSec,
+      calendarIds: form.calendarIds,
+      payload: schedulePayload,
     };
     try {
       if (editingId) {
@@ -295,6 +342,96 @@ export default function ScheduleManager({ open, onClose }: { open: boolean; onCl
               ]}
             />
           </div>
+          <div>
+            <Text strong style={{ fontSize: 11, display: 'block', marginBottom: 2 }}>If Still Running</Text>
+            <Select
+              size="small"
+              style={{ width: '100%' }}
+              value={form.overlapPolicy}
+              onChange={(val) => setForm({ ...form, overlapPolicy: val })}
+              options={[
+                { value: 'allow', label: 'Start another run' },
+                { value: 'skip', label: 'Skip this run' },
+                { value: 'queue', label: 'Queue until the previous run ends' },
+                { value: 'cancel_previous', label: 'Cancel the previous run' },
+              ]}
+            />
+          </div>
+          <div style={{ display: 'flex', gap: 8 }}>
+            <div style={{ flex: 1 }}>
+              <Text strong style={{ fontSize: 11, display: 'block', marginBottom: 2 }}>Missed Runs (on startup)</Text>
+              <Select
+                size="small"
+                style={{ width: '100%' }}
+                value={form.misfirePolicy}
+                onChange={(val) => setForm({ ...form, misfirePolicy: val })}
+                options={[
+                  { value: 'skip', label: 'Skip' },
+                  { value: 'run_once', label: 'Run once' },
+                  { value: 'run_all', label: 'Run all' },
+                ]}
+              />
+            </div>
+            {form.misfirePolicy === 'run_all' && (
+              <div style={{ width: 90 }}>
+                <Text strong style={{ fontSize: 11, display: 'block', marginBottom: 2 }}>Max Catch-up</Text>
+                <InputNumber
+                  size="small"
+                  min={1}
+                  style={{ width: '100%' }}
+                  value={form.misfireMax}
+                  onChange={(v) => setForm({ ...form, misfireMax: v ?? 10 })}
+                />
+              </div>
+            )}
+          </div>
+          <div>
+            <Text strong style={{ fontSize: 11, display: 'block', marginBottom: 2 }}>Jitter (seconds)</Text>
+            <InputNumber
+              size="small"
+              min={0}
+              style={{ width: '100%' }}
+              value={form.jitterSec}
+              onChange={(v) => setForm({ ...form, jitterSec: v ?? 0 })}
+            />
+            <Text type="secondary" style={{ fontSize: 9 }}>Random start delay of up to this many seconds.</Text>
+          </div>
+          <div>
+            <Text strong style={{ fontSize: 11, display: 'block', marginBottom: 2 }}>Timeout (seconds)</Text>
+            <InputNumber
+              size="small"
+              min={1}
+              style={{ width: '100%' }}
+              value={form.execTimeoutSec}
+              onChange={(v) => setForm({ ...form, execTimeoutSec: v || 300 })}
+            />
+            <Text type="secondary" style={{ fontSize: 9 }}>Each run is cancelled after this many seconds.</Text>
+          </div>
+          <div>
+            <Text strong style={{ fontSize: 11, display: 'block', marginBottom: 2 }}>Exclusion Calendars</Text>
+            <Select
+              size="small"
+              mode="multiple"
+              style={{ width: '100%' }}
+              placeholder="None"
+              value={form.calendarIds}
+              onChange={(val) => setForm({ ...form, calendarIds: val })}
+              options={calendars.map((c) => ({ value: c.id, label: c.name }))}
+            />
+            <Text type="secondary" style={{ fontSize: 9 }}>Runs falling on these calendars' dates or windows are skipped.</Text>
+          </div>
+          <div>
+            <Text strong style={{ fontSize: 11, display: 'block', marginBottom: 2 }}>Payload (JSON)</Text>
+            <TextArea
+              size="small"
+              rows={3}
+              style={{ fontFamily: 'monospace', fontSize: 10 }}
+              placeholder='{"report": "daily"}'
+              value={form.payload}
+              onChange={(e) => setForm({ ...form, payload: e.target.value })}
+            />
+            <Text type="secondary" style={{ fontSize: 9 }}>Passed to every run as input.payload.</Text>
+          </div>
           <div>
             <Text strong style={{ fontSize: 11, display: 'block', marginBottom: 2 }}>Enabled</Text>
             <Switch
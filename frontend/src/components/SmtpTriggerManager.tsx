@@ -0,0 +1,253 @@
+import { useEffect, useState } from 'react';
+import {
+  Button,
+  Modal,
+  InputNumber,
+  Select,
+  Table,
+  Space,
+  Switch,
+  Popconfirm,
+  Typography,
+  Tag,
+  message,
+} from 'antd';
+import {
+  PlusOutlined,
+  EditOutlined,
+  DeleteOutlined,
+  MailOutlined,
+} from '@ant-design/icons';
+import { useWorkflowStore } from '../store/workflowStore';
+import type { SmtpTrigger } from '../api/client';
+
+const { Text } = Typography;
+
+interface FormState {
+  workflowId: number | undefined;
+  recipients: string[];
+  maxInFlight: number;
+  enabled: boolean;
+}
+
+const defaultForm: FormState = {
+  workflowId: undefined,
+  recipients: [],
+  maxInFlight: 1,
+  enabled: true,
+};
+
+export default function SmtpTriggerManager({ open, onClose }: { open: boolean; onClose: () => void }) {
+  const {
+    smtpTriggers,
+    workflows,
+    fetchSmtpTriggers,
+    fetchWorkflows,
+    addSmtpTrigger,
+    editSmtpTrigger,
+    removeSmtpTrigger,
+  } = useWorkflowStore();
+  const [form, setForm] = useState<FormState>(defaultForm);
+  const [editing, setEditing] = useState<SmtpTrigger | null>(null);
+  const [formOpen, setFormOpen] = useState(false);
+  const [messageApi, contextHolder] = message.useMessage();
+
+  useEffect(() => {
+    if (open) {
+      fetchSmtpTriggers();
+      fetchWorkflows();
+    }
+  }, [open]);
+
+  const openNew = () => {
+    setForm(defaultForm);
+    setEditing(null);
+    setFormOpen(true);
+  };
+
+  const openEdit = (t: SmtpTrigger) => {
+    setForm({
+      workflowId: t.workflowId,
+      recipients: t.recipients || [],
+      maxInFlight: t.maxInFlight || 1,
+      enabled: t.enabled,
+    });
+    setEditing(t);
+    setFormOpen(true);
+  };
+
+  const handleSave = async () => {
+    if (!form.workflowId) {
+      messageApi.warning('Select a workflow');
+      return;
+    }
+    if (form.recipients.length === 0) {
+      messageApi.warning('Add at least one recipient address or @domain');
+      return;
+    }
+    try {
+      if (editing) {
+        await editSmtpTrigger(editing.id, { ...editing, ...form, workflowId: form.workflowId! });
+        messageApi.success('Trigger updated');
+      } else {
+        await addSmtpTrigger({ ...form, workflowId: form.workflowId! });
+        messageApi.success('Trigger created');
+      }
+      setFormOpen(false);
+    } catch (err: any) {
+      messageApi.error(typeof err?.response?.data === 'string' ? err.response.data : 'Failed to save trigger');
+    }
+  };
+
+  const handleDelete = async (id: number) => {
+    try {
+      await removeSmtpTrigger(id);
+      messageApi.success('Trigger deleted');
+    } catch {
+      messageApi.error('Failed to delete trigger');
+    }
+  };
+
+  const handleToggle = async (t: SmtpTrigger, enabled: boolean) => {
+    try {
+      await editSmtpTrigger(t.id, { ...t, enabled });
+      messageApi.success(enabled ? 'Trigger enabled' : 'Trigger disabled');
+    } catch {
+      messageApi.error('Failed to update trigger');
+    }
+  };
+
+  const columns = [
+    {
+      title: 'Workflow',
+      key: 'workflow',
+      render: (_: any, record: SmtpTrigger) => {
+        const wf = workflows.find((w) => w.id === record.workflowId);
+        return <Text strong style={{ fontSize: 11 }}>{wf?.name || `#${record.workflowId}`}</Text>;
+      },
+    },
+    {
+      title: 'Recipients',
+      key: 'recipients',
+      render: (_: any, record: SmtpTrigger) => (
+        <Space size={2} wrap>
+          {(record.recipients || []).map((r) => (
+            <Tag key={r} style={{ fontSize: 10, margin: 0 }}>{r}</Tag>
+          ))}
+        </Space>
+      ),
+    },
+    {
+      title: 'Msgs',
+      dataIndex: 'msgCount',
+      key: 'msgCount',
+      width: 50,
+      render: (v: number) => <Text style={{ fontSize: 10 }}>{v}</Text>,
+    },
+    {
+      title: 'On',
+      key: 'enabled',
+      width: 50,
+      render: (_: any, record: SmtpTrigger) => (
+        <Switch size="small" checked={record.enabled} onChange={(v) => handleToggle(record, v)} />
+      ),
+    },
+    {
+      title: '',
+      key: 'actions',
+      width: 60,
+      render: (_: any, record: SmtpTrigger) => (
+        <Space size={4}>
+          <Button size="small" type="text" icon={<EditOutlined />} onClick={() => openEdit(record)} />
+          <Popconfirm title="Delete this trigger?" onConfirm={() => handleDelete(record.id)} okText="Delete" okButtonProps={{ danger: true }}>
+            <Button size="small" type="text" danger icon={<DeleteOutlined />} />
+          </Popconfirm>
+        </Space>
+      ),
+    },
+  ];
+
+  return (
+    <Modal
+      title={<Space><MailOutlined /><span>SMTP Triggers</span></Space>}
+      open={open}
+      onCancel={onClose}
+      footer={null}
+      width={620}
+    >
+      {contextHolder}
+      <div style={{ marginBottom: 8 }}>
+        <Button size="small" type="primary" icon={<PlusOutlined />} onClick={openNew}>
+          Add SMTP Trigger
+        </Button>
+      </div>
+      <Text type="secondary" style={{ fontSize: 10, display: 'block', marginBottom: 8 }}>
+        Mail delivered to eflo's SMTP receiver (SMTP_RECEIVER_PORT) for these recipients runs the workflow with the parsed message, like a Receive Email trigger.
+      </Text>
+
+      <Table
+        dataSource={smtpTriggers}
+        columns={columns}
+        rowKey="id"
+        size="small"
+        pagination={false}
+        style={{ fontSize: 12 }}
+        locale={{ emptyText: 'No SMTP triggers yet.' }}
+      />
+
+      <Modal
+        title={editing ? 'Edit SMTP Trigger' : 'New SMTP Trigger'}
+        open={formOpen}
+        onOk={handleSave}
+        onCancel={() => setFormOpen(false)}
+        okText={editing ? 'Update' : 'Create'}
+        width={440}
+      >
+        <Space direction="vertical" size={8} style={{ width: '100%' }}>
+          <div>
+            <Text strong style={{ fontSize: 11, display: 'block', marginBottom: 2 }}>Workflow</Text>
+            <Select
+              size="small"
+              style={{ width: '100%' }}
+              placeholder="Select workflow..."
+              value={form.workflowId}
+              onChange={(val) => setForm({ ...form, workflowId: val })}
+              options={workflows.map((w) => ({ value: w.id, label: w.name }))}
+            />
+          </div>
+          <div>
+            <Text strong style={{ fontSize: 11, display: 'block', marginBottom: 2 }}>Recipients</Text>
+            <Select
+              size="small"
+              mode="tags"
+              style={{ width: '100%' }}
+              placeholder="alerts@eflo.example.com, @eflo.example.com"
+              tokenSeparators={[',', ' ']}
+              value={form.recipients}
+              onChange={(recipients) => setForm({ ...form, recipients })}
+              open={false}
+            />
+            <Text type="secondary" style={{ fontSize: 9 }}>
+              Addresses, or @domain for every address of a domain. An address match wins over a domain match.
+            </Text>
+          </div>
+          <div>
+            <Text strong style={{ fontSize: 11, display: 'block', marginBottom: 2 }}>Max Concurrent Runs</Text>
+            <InputNumber
+              size="small"
+              style={{ width: '100%' }}
+              min={1}
+              max={100}
+              value={form.maxInFlight}
+              onChange={(val) => setForm({ ...form, maxInFlight: val || 1 })}
+            />
+          </div>
+          <div>
+            <Text strong style={{ fontSize: 11, display: 'block', marginBottom: 2 }}>Enabled</Text>
+            <Switch size="small" checked={form.enabled} onChange={(v) => setForm({ ...form, enabled: v })} />
+          </div>
+        </Space>
+      </Modal>
+    </Modal>
+  );
+}
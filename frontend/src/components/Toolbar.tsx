@@ -29,6 +29,7 @@ import ConfigStoreManager from './ConfigStoreManager';
 import ScheduleManager from './ScheduleManager';
 import RedisSubscriptionManager from './RedisSubscriptionManager';
 import EmailTriggerManager from './EmailTriggerManager';
+import SmtpTriggerManager from './SmtpTriggerManager';
 import HttpTriggerManager from './HttpTriggerManager';
 import {getNodesBounds, getViewportForBounds, useReactFlow} from "@xyflow/react";
 import {toPng} from "html-to-image";
@@ -46,6 +47,8 @@ interface ToolbarProps {
   setShowRedisSubManager: (v: boolean) => void;
   showEmailTriggerManager: boolean;
   setShowEmailTriggerManager: (v: boolean) => void;
+  showSmtpTriggerManager: boolean;
+  setShowSmtpTriggerManager: (v: boolean) => void;
   showHttpTriggerManager: boolean;
   setShowHttpTriggerManager: (v: boolean) => void;
   showConfigStoreManager: boolean;
@@ -63,6 +66,8 @@ export default function Toolbar({
   setShowRedisSubManager,
   showEmailTriggerManager,
   setShowEmailTriggerManager,
+  showSmtpTriggerManager,
+  setShowSmtpTriggerManager,
   showHttpTriggerManager,
   setShowHttpTriggerManager,
   showConfigStoreManager,
@@ -430,6 +435,7 @@ export default function Toolbar({
       <ScheduleManager open={showScheduleManager} onClose={() => setShowScheduleManager(false)} />
       <RedisSubscriptionManager open={showRedisSubManager} onClose={() => setShowRedisSubManager(false)} />
       <EmailTriggerManager open={showEmailTriggerManager} onClose={() => setShowEmailTriggerManager(false)} />
+      <SmtpTriggerManager open={showSmtpTriggerManager} onClose={() => setShowSmtpTriggerManager(false)} />
       <HttpTriggerManager open={showHttpTriggerManager} onClose={() => setShowHttpTriggerManager(false)} />
 
       <Modal
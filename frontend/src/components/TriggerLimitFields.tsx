@@ -0,0 +1,64 @@
+import { InputNumber, Select, Space, Typography } from 'antd';
+import type { TriggerLimits, TriggerOverflowPolicy } from '../api/client';
+
+const { Text } = Typography;
+
+export const defaultTriggerLimits: TriggerLimits = {
+  maxInFlight: 1,
+  bufferSize: 100,
+  overflowPolicy: 'block',
+  execTimeoutSec: 300,
+};
+
+/** limitsOf picks the concurrency, buffering and timeout settings of a stored trigger. */
+export function limitsOf(t: Partial<TriggerLimits>): TriggerLimits {
+  return {
+    maxInFlight: t.maxInFlight || defaultTriggerLimits.maxInFlight,
+    bufferSize: t.bufferSize || defaultTriggerLimits.bufferSize,
+    overflowPolicy: t.overflowPolicy || defaultTriggerLimits.overflowPolicy,
+    execTimeoutSec: t.execTimeoutSec || defaultTriggerLimits.execTimeoutSec,
+  };
+}
+
+/* Concurrency, buffering and timeout of an event-driven trigger */
+export default function TriggerLimitFields({ limits, onChange }: { limits: TriggerLimits; onChange: (limits: TriggerLimits) => void }) {
+  const set = (patch: Partial<TriggerLimits>) => onChange({ ...limits, ...patch });
+  const label = (text: string) => (
+    <Text strong style={{ fontSize: 10, display: 'block', marginBottom: 1 }}>{text}</Text>
+  );
+  return (
+    <>
+      <Space size={8} style={{ width: '100%' }} wrap>
+        <div>
+          {label('Max Concurrent')}
+          <InputNumber size="small" min={1} max={100} style={{ width: 90 }} value={limits.maxInFlight} onChange={(v) => set({ maxInFlight: v || 1 })} />
+        </div>
+        <div>
+          {label('Buffer')}
+          <InputNumber size="small" min={1} style={{ width: 80 }} value={limits.bufferSize} onChange={(v) => set({ bufferSize: v || 100 })} />
+        </div>
+        <div>
+          {label('When Full')}
+          <Select
+            size="small"
+            style={{ width: 120 }}
+            value={limits.overflowPolicy}
+            onChange={(v: TriggerOverflowPolicy) => set({ overflowPolicy: v })}
+            options={[
+              { value: 'block', label: 'Wait' },
+              { value: 'drop_oldest', label: 'Drop oldest' },
+              { value: 'reject', label: 'Reject new' },
+            ]}
+          />
+        </div>
+        <div>
+          {label('Timeout (sec)')}
+          <InputNumber size="small" min={1} style={{ width: 80 }} value={limits.execTimeoutSec} onChange={(v) => set({ execTimeoutSec: v || 300 })} />
+        </div>
+      </Space>
+      <Text type="secondary" style={{ fontSize: 9 }}>
+        Events beyond the concurrent runs wait in the buffer; when it is full the trigger waits, drops the oldest event or rejects the new one.
+      </Text>
+    </>
+  );
+}
@@ -12,20 +12,33 @@ export const CRON_NODE_DOC: NodeDoc = {
   properties: [
     { name: 'expression', type: 'string', desc: 'Cron expression (min hour dom month dow)', required: true },
     { name: 'timezone', type: 'select', desc: 'Timezone for the schedule (default: UTC)', required: false },
-    { name: 'payload', type: 'json', desc: 'Optional JSON data passed to downstream nodes', required: false },
+    { name: 'payload', type: 'json', desc: 'Optional default JSON data; a schedule\'s own payload is merged over it', required: false },
   ],
-  sampleInput: {},
+  sampleInput: {
+    scheduleId: 3,
+    scheduledAt: '2026-02-24T10:00:00Z',
+    firedAt: '2026-02-24T10:00:01Z',
+    timezone: 'UTC',
+    payload: { region: 'eu' },
+  },
   sampleOutput: {
     triggered: true,
-    triggeredAt: '2026-02-24T10:00:00Z',
+    scheduleId: 3,
+    scheduledAt: '2026-02-24T10:00:00Z',
+    firedAt: '2026-02-24T10:00:01Z',
+    triggeredAt: '2026-02-24T10:00:01Z',
     nextRun: '2026-02-24T10:05:00Z',
     expression: '*/5 * * * *',
     timezone: 'UTC',
+    catchUp: false,
+    payload: { region: 'eu' },
   },
   tips: [
     'Common presets: "* * * * *" (every min), "0 * * * *" (hourly), "0 0 * * *" (daily).',
     'Remember to create a Schedule via the 🕐 toolbar button to activate.',
     'The cron node acts as a trigger — it has no target handle.',
+    'Scheduled runs use the schedule\'s expression, timezone and fire times; several schedules with different payloads can share one workflow.',
+    'scheduledAt is the planned tick, firedAt the actual start (after jitter or queueing); catchUp is true for missed-run catch-ups.',
   ],
 };
 
@@ -1,4 +1,4 @@
-import { Input, Select, Typography } from 'antd';
+import { Input, Select, Switch, Typography } from 'antd';
 import type { NodeConfigProps, NodeDoc } from './types';
 
 const { Text } = Typography;
@@ -7,17 +7,22 @@ const { TextArea } = Input;
 export const EMAIL_NODE_DOC: NodeDoc = {
   title: 'Send Email',
   description:
-    'Sends an email via SMTP using a configured email server. Supports To, CC, BCC, subject, plain text or HTML body.',
+    'Sends an email via SMTP using a configured email server. Supports To, CC, BCC, Reply-To, custom headers, a plain text and/or HTML body, attachments and inline images.',
   usage:
-    'Select an email config (create one in ⚙ Connection Configs with SMTP details). Set the recipient, subject, and body. Multiple recipients can be comma-separated.',
+    'Select an email config (create one in ⚙ Connection Configs with SMTP details). Set the recipient, subject, and body. Multiple recipients can be comma-separated. Subject, bodies and header values are templates: {{path}} is replaced with input data.',
   properties: [
     { name: 'configId', type: 'select', desc: 'Email (SMTP) server configuration', required: true },
     { name: 'to', type: 'string', desc: 'Recipient email(s), comma-separated', required: true },
     { name: 'cc', type: 'string', desc: 'CC recipients, comma-separated', required: false },
     { name: 'bcc', type: 'string', desc: 'BCC recipients, comma-separated', required: false },
-    { name: 'subject', type: 'string', desc: 'Email subject line', required: true },
-    { name: 'body', type: 'string', desc: 'Email body content', required: true },
-    { name: 'contentType', type: 'select', desc: 'text/plain or text/html', required: false },
+    { name: 'replyTo', type: 'string', desc: 'Reply-To address(es), comma-separated', required: false },
+    { name: 'subject', type: 'string', desc: 'Email subject line (non-ASCII is encoded per RFC 2047)', required: true },
+    { name: 'body', type: 'string', desc: 'Plain text body (the HTML body when content type is text/html)', required: true },
+    { name: 'html', type: 'string', desc: 'HTML body; sent with the plain text body as multipart/alternative', required: false },
+    { name: 'contentType', type: 'select', desc: 'text/plain or text/html (for a single body)', required: false },
+    { name: 'headers', type: 'json', desc: 'Custom headers as JSON object (e.g. {"X-Order-Id": "{{orderId}}"})', required: false },
+    { name: 'attachments', type: 'json', desc: 'Attachments: [{filename, content (base64) | path, contentType?, inline?, contentId?}] (default: input.attachments; path only here, not in input)', required: false },
+    { name: 'attachInput', type: 'boolean', desc: 'Attach the input itself, e.g. the output of a Read File node', required: false },
   ],
   sampleInput: { userName: 'John', userEmail: 'john@example.com' },
   sampleOutput: {
@@ -25,8 +30,11 @@ export const EMAIL_NODE_DOC: NodeDoc = {
     to: 'john@example.com',
     cc: '',
     bcc: '',
+    replyTo: '',
     subject: 'Welcome John!',
     from: 'noreply@myapp.com',
+    messageId: '<1771927203.9f2c41d0@myapp.com>',
+    attachments: 0,
     smtpHost: 'smtp.gmail.com',
     sentAt: '2026-02-24T10:00:03Z',
     recipients: 1,
@@ -35,8 +43,12 @@ export const EMAIL_NODE_DOC: NodeDoc = {
     'Create an email config first in ⚙ Connection Configs (type: Email).',
     'For Gmail, use an App Password (not your regular password).',
     'Port 587 = STARTTLS (recommended), Port 465 = SSL/TLS.',
-    'Use text/html content type for rich formatted emails.',
-    'To, subject, and body can be populated from upstream node data.',
+    'Fill both Body and HTML Body to send a plain text fallback with the HTML version.',
+    'To, subject, and body can be populated from upstream node data, e.g. "Welcome {{userName}}!".',
+    'Attachment content is base64 unless an encoding other than base64 is given (Read File output is attached as-is).',
+    'Files on disk (path) can only be attached from this property; attachments from the input must carry their content.',
+    'Inline images are referenced from the HTML body as <img src="cid:logo"> with contentId "logo".',
+    'Headers set by the node (From, To, Subject, Content-Type, ...) cannot be overridden.',
   ],
 };
 
@@ -90,6 +102,15 @@ export default function EmailNodeConfig({ properties, updateProp, configs }: Nod
           onChange={(e) => updateProp('bcc', e.target.value)}
         />
       </div>
+      <div>
+        <Text strong style={{ fontSize: 10, display: 'block', marginBottom: 1 }}>Reply-To</Text>
+        <Input
+          size="small"
+          placeholder="(optional)"
+          value={properties.replyTo || ''}
+          onChange={(e) => updateProp('replyTo', e.target.value)}
+        />
+      </div>
       <div>
         <Text strong style={{ fontSize: 10, display: 'block', marginBottom: 1 }}>Subject</Text>
         <Input
@@ -110,6 +131,17 @@ export default function EmailNodeConfig({ properties, updateProp, configs }: Nod
           onChange={(e) => updateProp('body', e.target.value)}
         />
       </div>
+      <div>
+        <Text strong style={{ fontSize: 10, display: 'block', marginBottom: 1 }}>HTML Body</Text>
+        <TextArea
+          size="small"
+          rows={4}
+          style={{ fontSize: 10, fontFamily: 'monospace' }}
+          placeholder="<p>Hello {{userName}}</p> (optional)"
+          value={properties.html || ''}
+          onChange={(e) => updateProp('html', e.target.value)}
+        />
+      </div>
       <div>
         <Text strong style={{ fontSize: 10, display: 'block', marginBottom: 1 }}>Content Type</Text>
         <Select
@@ -123,6 +155,36 @@ export default function EmailNodeConfig({ properties, updateProp, configs }: Nod
           ]}
         />
       </div>
+      <div>
+        <Text strong style={{ fontSize: 10, display: 'block', marginBottom: 1 }}>Headers (JSON)</Text>
+        <TextArea
+          size="small"
+          rows={2}
+          style={{ fontSize: 10, fontFamily: 'monospace' }}
+          placeholder='{"X-Order-Id": "{{orderId}}"}'
+          value={typeof properties.headers === 'string' ? properties.headers : properties.headers ? JSON.stringify(properties.headers) : ''}
+          onChange={(e) => updateProp('headers', e.target.value)}
+        />
+      </div>
+      <div>
+        <Text strong style={{ fontSize: 10, display: 'block', marginBottom: 1 }}>Attachments (JSON)</Text>
+        <TextArea
+          size="small"
+          rows={2}
+          style={{ fontSize: 10, fontFamily: 'monospace' }}
+          placeholder='[{"path": "/data/report.pdf"}] (default: input.attachments)'
+          value={typeof properties.attachments === 'string' ? properties.attachments : properties.attachments ? JSON.stringify(properties.attachments) : ''}
+          onChange={(e) => updateProp('attachments', e.target.value)}
+        />
+      </div>
+      <div style={{ display: 'flex', alignItems: 'center', gap: 6 }}>
+        <Switch
+          size="small"
+          checked={!!properties.attachInput}
+          onChange={(val) => updateProp('attachInput', val)}
+        />
+        <Text style={{ fontSize: 10 }}>Attach input (Read File output)</Text>
+      </div>
     </>
   );
 }
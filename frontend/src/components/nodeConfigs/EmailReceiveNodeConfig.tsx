@@ -6,7 +6,7 @@ const { Text } = Typography;
 export const EMAIL_RECEIVE_NODE_DOC: NodeDoc = {
   title: 'Receive Email Trigger',
   description:
-    'A trigger node that polls an IMAP mailbox for unread emails and triggers a workflow execution for each new message. Uses the same email configuration as Send Email (with auto-derived IMAP host).',
+    'A trigger node that watches an IMAP mailbox (IDLE push or polling) and triggers a workflow execution for each new message. Uses the same email configuration as Send Email (with auto-derived IMAP host).',
   usage:
     'Select an email config (the IMAP host is derived from the SMTP host, e.g. smtp.gmail.com → imap.gmail.com, or set imapHost/imapPort in the config). Set the mailbox folder, poll interval, and whether to mark emails as read. Then create an Email Trigger via the 📨 toolbar button to activate.',
   properties: [
@@ -20,23 +20,42 @@ export const EMAIL_RECEIVE_NODE_DOC: NodeDoc = {
   sampleOutput: {
     triggered: true,
     triggeredAt: '2026-02-25T10:00:00Z',
-    from: 'sender@example.com',
+    from: 'Jane Doe <sender@example.com>',
+    fromAddress: 'sender@example.com',
+    fromName: 'Jane Doe',
     to: 'you@gmail.com',
     subject: 'Order Confirmation #12345',
     date: 'Tue, 25 Feb 2026 09:59:45 +0000',
     messageId: '<abc123@mail.example.com>',
-    seqNum: 42,
+    text: 'Thank you for your order...',
+    html: '<p>Thank you for your order...</p>',
+    attachments: [
+      { filename: 'invoice.pdf', contentType: 'application/pdf', size: 48213, inline: false, content: 'JVBERi0xLjQK...' },
+    ],
+    size: 65536,
+    flags: ['\\Recent'],
+    uid: 4217,
+    uidValidity: 1712345678,
+    mailbox: 'INBOX',
     fetchedAt: '2026-02-25T10:00:01Z',
     triggerId: 1,
     receivedAt: '2026-02-25T10:00:01Z',
   },
   tips: [
     'Uses the same email config as Send Email — IMAP host is auto-derived (smtp.gmail.com → imap.gmail.com).',
-    'For custom IMAP hosts, add "imapHost" and "imapPort" to the config JSON.',
-    'Only UNSEEN emails are fetched. Enable "Mark as Read" to avoid processing the same email twice.',
+    'For custom IMAP hosts, add "imapHost" and "imapPort" to the config JSON. Port 143 uses STARTTLS; set "imapSecurity" (tls, starttls, none) to override.',
+    'For OAuth2 providers (Gmail, Outlook) set IMAP Authentication to XOAUTH2 and keep the access token in the Config Store under the configured key; refresh it there (e.g. with a scheduled Set Config Store workflow).',
+    'Mailbox names may contain spaces and non-ASCII characters (e.g. "Sent Items", "Entwürfe").',
+    'New emails are tracked by IMAP UID, so each is processed exactly once whether or not it is read. "Mark as Read" only sets the \\Seen flag.',
+    'With IMAP IDLE on (the default) new mail arrives within seconds; servers without IDLE are polled at the poll interval.',
+    'On first start, or if the mailbox is recreated (UIDVALIDITY changes), only mail arriving afterwards is processed.',
     'Each email triggers a separate workflow execution with from, to, subject, date, etc. as input.',
+    'The full message is parsed: "text" and "html" bodies (decoded to UTF-8) and "attachments" with base64 content.',
+    'Size limits are set on the Email Trigger: larger messages arrive header-only with truncated: true, larger attachments without content (omitted: true).',
     'For Gmail: enable IMAP in Settings → Forwarding and POP/IMAP, and use an App Password.',
-    'Create an Email Trigger via the 📨 toolbar button to activate polling.',
+    'Create an Email Trigger via the 📨 toolbar button to activate it.',
+    'Without a mailbox: create an SMTP Trigger for an address or @domain and send mail to eflo\'s SMTP receiver (SMTP_RECEIVER_PORT). The same node starts the flow; input adds envelopeFrom, recipients and remoteAddr (no uid or mailbox).',
+    'On Success / On Failure actions on the trigger file the message after its run (move, copy, flag, delete); use the IMAP Action node to do the same from inside the workflow.',
     'Minimum poll interval is 10 seconds. Use 60+ seconds for production.',
   ],
 };
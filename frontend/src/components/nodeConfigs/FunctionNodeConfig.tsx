@@ -14,7 +14,9 @@ export const FUNCTION_NODE_DOC: NodeDoc = {
     'Write JavaScript in the code editor. Use `input` for data from the previous node and `config` for secrets (e.g. config.token, config["API_KEY"]). Assign to `returnValue` to pass data downstream. If you do not set `returnValue`, the workflow ends at this node.',
   properties: [
     { name: 'code', type: 'string', desc: 'JavaScript code to execute', required: true },
-    { name: 'timeoutMs', type: 'number', desc: 'Max execution time in ms (default: 10000)', required: false },
+    { name: 'timeoutMs', type: 'number', desc: 'Max execution time in ms, including awaited fetch calls (default: 10000)', required: false },
+    { name: 'fetchAllowedHosts', type: 'string[]', desc: 'Hosts fetch() may call (default: any public http/https host; internal addresses need FUNCTION_FETCH_ALLOW_PRIVATE)', required: false },
+    { name: 'fetchMaxBytes', type: 'number', desc: 'Max fetch response body size in bytes (default: 5242880)', required: false },
   ],
   sampleInput: { value: 10, name: 'Widget' },
   sampleOutput: {
@@ -28,7 +30,10 @@ export const FUNCTION_NODE_DOC: NodeDoc = {
     'Return an object to define the entire output: returnValue = { ...input, doubled: input.value * 2 };',
     'Return a primitive or array and it appears as output.value for the next node.',
     'Return values are JSON-serialized; avoid functions or non-serializable values.',
-    'Scripts run in a sandbox (no Node.js APIs); use for data transformation only.',
+    'Top-level `await` works: const res = await fetch(url, { method: "POST", body: { id: input.id } }); const data = await res.json();',
+    'Load shared helpers from Script Libraries with require: const util = require("util-lib"); pin a version with require("util-lib@3").',
+    'console.log/info/warn/error/debug output is captured in the node output as `_console` and shown in the execution log.',
+    'Scripts run in a sandbox (no Node.js APIs); `fetch` is the only I/O and supports http/https only.',
     'Use the timeout to avoid infinite loops blocking the workflow.',
   ],
 };
@@ -6,30 +6,35 @@ const { Text } = Typography;
 export const HTTP_IN_NODE_DOC: NodeDoc = {
   title: 'HTTP In',
   description:
-    'Trigger node for HTTP-in flows (like Node-RED). When a request hits the path registered in HTTP Triggers, the workflow runs with the request data as input. This node passes through payload, headers, query, method, and path.',
+    'Trigger node for HTTP-in flows (like Node-RED). When a request hits the path registered in HTTP Triggers, the workflow runs with the request data as input. This node passes through payload, headers, query, params, method, and path.',
   usage:
-    'Add an HTTP Trigger in the toolbar (HTTP Triggers). Set workflow, path (e.g. webhook), and method. Use HTTP-out node in the flow to send the response. Request body (JSON) is in input.payload and input.body; headers in input.headers; query in input.query.',
+    'Add an HTTP Trigger in the toolbar (HTTP Triggers). Set workflow, path (e.g. webhook or orders/{id}/items), and method. Use HTTP-out node in the flow to send the response. Request body (JSON) is in input.payload and input.body; headers in input.headers; query in input.query; route parameters in input.params. With an auth policy on the trigger, the verified caller is in input.auth.',
   properties: [],
   sampleInput: {
     method: 'POST',
-    path: 'webhook',
+    path: 'orders/42/items',
     payload: { name: 'test' },
     headers: { 'content-type': 'application/json' },
     query: {},
+    params: { id: '42' },
   },
   sampleOutput: {
     method: 'POST',
-    path: 'webhook',
+    path: 'orders/42/items',
     payload: { name: 'test' },
     body: { name: 'test' },
     headers: {},
     query: {},
+    params: { id: '42' },
     triggered: true,
     triggeredAt: '2025-02-27T12:00:00Z',
   },
   tips: [
     'Register the endpoint in ⚙ HTTP Triggers (toolbar).',
     'Request body is available as payload and body; use HTTP-out to respond.',
+    'Paths can hold parameters: orders/{id} gives {{params.id}}; files/{path*} (or files/*) captures the rest of the path. A literal route like orders/new wins over orders/{id}.',
+    'Protect the endpoint with an API key, HMAC signature, Basic auth or JWT in the trigger settings; rejected requests get 401 and never start a run. input.auth holds e.g. the Basic username or the JWT claims.',
+    'Trigger limits cap the body size (413), request rate and concurrent runs (429 with Retry-After) and client IPs (403); the default body limit is 10 MB.',
     'Use one HTTP-out node in the flow to send the response back to the client.',
   ],
 };
@@ -38,7 +43,7 @@ export default function HttpInNodeConfig(_props: NodeConfigProps) {
   return (
     <div>
       <Text type="secondary" style={{ fontSize: 10 }}>
-        Request data (payload, headers, query) is passed from the HTTP trigger. Add an HTTP-out node to send the response.
+        Request data (payload, headers, query, route params) is passed from the HTTP trigger. Add an HTTP-out node to send the response.
       </Text>
     </div>
   );
@@ -0,0 +1,110 @@
+import { Input, Select, Typography } from 'antd';
+import type { NodeConfigProps, NodeDoc } from './types';
+
+const { Text } = Typography;
+
+export const IMAP_NODE_DOC: NodeDoc = {
+  title: 'IMAP Action',
+  description:
+    'Files an email on the IMAP server by UID: move or copy it to another mailbox, add or remove flags/keywords, or delete it. Placed after a Receive Email trigger it acts on the triggering message.',
+  usage:
+    'Select the same email config as the trigger and choose an action. The message UID, mailbox and UIDVALIDITY are taken from the input (uid, mailbox, uidValidity) unless set here.',
+  properties: [
+    { name: 'configId', type: 'select', desc: 'Email server configuration (must have IMAP access)', required: true },
+    { name: 'action', type: 'select', desc: 'move, copy, flag, unflag or delete', required: true },
+    { name: 'targetMailbox', type: 'string', desc: 'Destination mailbox for move/copy', required: false },
+    { name: 'flags', type: 'string', desc: 'Flags or keywords for flag/unflag, comma-separated (e.g. \\Flagged, processed)', required: false },
+    { name: 'mailbox', type: 'string', desc: 'Mailbox holding the message (default: input.mailbox or INBOX)', required: false },
+    { name: 'uid', type: 'number', desc: 'Message UID (default: input.uid)', required: false },
+  ],
+  sampleInput: { uid: 4217, mailbox: 'INBOX', uidValidity: 1712345678, subject: 'Order Confirmation #12345' },
+  sampleOutput: {
+    action: 'move',
+    uid: 4217,
+    mailbox: 'INBOX',
+    targetMailbox: 'Processed/Orders',
+    done: true,
+  },
+  tips: [
+    'Move and delete remove the message from its mailbox; use them as the last IMAP action on a message.',
+    'Servers without MOVE get COPY + delete; without UIDPLUS, delete expunges every message flagged \\Deleted in the mailbox.',
+    'If the mailbox was recreated since the trigger fetched the message (UIDVALIDITY changed) the node fails instead of touching another message.',
+    'For filing without a node, set On Success / On Failure actions on the Email Trigger.',
+  ],
+};
+
+export default function ImapNodeConfig({ properties, updateProp, configs }: NodeConfigProps) {
+  const emailConfigs = configs?.filter((c) => c.type === 'email') ?? [];
+  const action = properties.action;
+  return (
+    <>
+      <div>
+        <Text strong style={{ fontSize: 10, display: 'block', marginBottom: 1 }}>Email Config</Text>
+        <Select
+          size="small"
+          style={{ width: '100%' }}
+          placeholder="Select email server..."
+          value={properties.configId || undefined}
+          onChange={(val) => updateProp('configId', val)}
+          options={emailConfigs.map((c) => ({
+            value: c.id,
+            label: `${c.name} (${c.config?.imapHost || c.config?.host || 'imap'})`,
+          }))}
+          notFoundContent={
+            <Text type="secondary" style={{ fontSize: 10, padding: 4 }}>
+              No email configs. Add one in ⚙ Configs.
+            </Text>
+          }
+        />
+      </div>
+      <div>
+        <Text strong style={{ fontSize: 10, display: 'block', marginBottom: 1 }}>Action</Text>
+        <Select
+          size="small"
+          style={{ width: '100%' }}
+          placeholder="Select action..."
+          value={action || undefined}
+          onChange={(val) => updateProp('action', val)}
+          options={[
+            { value: 'move', label: 'Move to mailbox' },
+            { value: 'copy', label: 'Copy to mailbox' },
+            { value: 'flag', label: 'Add flags / keywords' },
+            { value: 'unflag', label: 'Remove flags / keywords' },
+            { value: 'delete', label: 'Delete (expunge)' },
+          ]}
+        />
+      </div>
+      {(action === 'move' || action === 'copy') && (
+        <div>
+          <Text strong style={{ fontSize: 10, display: 'block', marginBottom: 1 }}>Target Mailbox</Text>
+          <Input
+            size="small"
+            placeholder="Archive"
+            value={properties.targetMailbox || ''}
+            onChange={(e) => updateProp('targetMailbox', e.target.value)}
+          />
+        </div>
+      )}
+      {(action === 'flag' || action === 'unflag') && (
+        <div>
+          <Text strong style={{ fontSize: 10, display: 'block', marginBottom: 1 }}>Flags</Text>
+          <Input
+            size="small"
+            placeholder="\Flagged, processed"
+            value={properties.flags || ''}
+            onChange={(e) => updateProp('flags', e.target.value)}
+          />
+        </div>
+      )}
+      <div>
+        <Text strong style={{ fontSize: 10, display: 'block', marginBottom: 1 }}>Mailbox</Text>
+        <Input
+          size="small"
+          placeholder="(from input, or INBOX)"
+          value={properties.mailbox || ''}
+          onChange={(e) => updateProp('mailbox', e.target.value)}
+        />
+      </div>
+    </>
+  );
+}
@@ -0,0 +1,91 @@
+import { Input, Switch, Typography } from 'antd';
+import type { NodeConfigProps, NodeDoc } from './types';
+
+const { Text } = Typography;
+const { TextArea } = Input;
+
+export const JSONPATH_NODE_DOC: NodeDoc = {
+  title: 'JSON Path',
+  description:
+    'Extracts values from the upstream data with path expressions: dotted keys, array indices, wildcards and filters. The extracted values are added to the output along with all input data.',
+  usage:
+    'Set a single Path and the key to store its value under, or list several extractions in Paths (JSON object of outputKey -> path). Paths that match nothing yield the default, or fail the node when Required is on.',
+  properties: [
+    { name: 'path', type: 'string', desc: 'Path to extract (e.g. json.items[0].id, json.items[*].sku, json.items[?(@.qty > 0)])' },
+    { name: 'outputKey', type: 'string', desc: 'Output key for the single path (default: result)' },
+    { name: 'paths', type: 'object', desc: 'JSON object of outputKey -> path, for several extractions at once' },
+    { name: 'default', type: 'any', desc: 'Value used when a path matches nothing' },
+    { name: 'required', type: 'boolean', desc: 'Fail the node when a path matches nothing' },
+  ],
+  sampleInput: { json: { items: [{ sku: 'A-1', qty: 2 }, { sku: 'B-7', qty: 0 }] } },
+  sampleOutput: {
+    result: ['A-1'],
+    found: true,
+    path: 'json.items[?(@.qty > 0)].sku',
+    json: { items: [{ sku: 'A-1', qty: 2 }, { sku: 'B-7', qty: 0 }] },
+  },
+  tips: [
+    'Index from the end with negative indices: json.items[-1]',
+    "Keys containing dots can be quoted: json['order.id']",
+    'Slices, unions and recursive descent: json.items[0:2], json.items[0,2], json..email',
+    'Filters are expr-lang expressions with @ as the current element: json.items[?(@.price > 10 && @.inStock)]',
+    'A path is "not found" when it resolves to nothing or to an empty list.',
+  ],
+};
+
+export default function JsonPathNodeConfig({ properties, updateProp }: NodeConfigProps) {
+  return (
+    <>
+      <div>
+        <Text strong style={{ fontSize: 10, display: 'block', marginBottom: 1 }}>Path</Text>
+        <Input
+          size="small"
+          style={{ fontFamily: 'monospace', fontSize: 10 }}
+          placeholder="json.items[?(@.qty > 0)].sku"
+          value={properties.path || ''}
+          onChange={(e) => updateProp('path', e.target.value)}
+        />
+      </div>
+      <div>
+        <Text strong style={{ fontSize: 10, display: 'block', marginBottom: 1 }}>Output Key</Text>
+        <Input
+          size="small"
+          placeholder="result"
+          value={properties.outputKey || ''}
+          onChange={(e) => updateProp('outputKey', e.target.value)}
+        />
+      </div>
+      <div>
+        <Text strong style={{ fontSize: 10, display: 'block', marginBottom: 1 }}>Paths (JSON)</Text>
+        <TextArea
+          size="small"
+          rows={3}
+          style={{ fontFamily: 'monospace', fontSize: 10 }}
+          placeholder='{"orderId": "json.id", "firstSku": "json.items[0].sku"}'
+          value={typeof properties.paths === 'string' ? properties.paths : properties.paths ? JSON.stringify(properties.paths) : ''}
+          onChange={(e) => updateProp('paths', e.target.value)}
+        />
+        <Text type="secondary" style={{ fontSize: 9 }}>
+          Several extractions at once: output key → path.
+        </Text>
+      </div>
+      <div>
+        <Text strong style={{ fontSize: 10, display: 'block', marginBottom: 1 }}>Default</Text>
+        <Input
+          size="small"
+          placeholder="Used when a path matches nothing"
+          value={properties.default == null ? '' : String(properties.default)}
+          onChange={(e) => updateProp('default', e.target.value === '' ? undefined : e.target.value)}
+        />
+      </div>
+      <div style={{ display: 'flex', alignItems: 'center', gap: 6 }}>
+        <Switch
+          size="small"
+          checked={!!properties.required}
+          onChange={(val) => updateProp('required', val)}
+        />
+        <Text style={{ fontSize: 10 }}>Required (fail when nothing matches)</Text>
+      </div>
+    </>
+  );
+}
@@ -0,0 +1,64 @@
+import { Input, Select, Typography } from 'antd';
+import type { NodeConfigProps, NodeDoc } from './types';
+
+const { Text } = Typography;
+const { TextArea } = Input;
+
+export const MAPPER_NODE_DOC: NodeDoc = {
+  title: 'Mapper',
+  description:
+    'Builds a new object from declarative target → source rules, so field renames and reshaping between APIs need no code. Each rule reads a path, evaluates an expression or sets a constant, and writes the result at a target path.',
+  usage:
+    'List the rules in Mappings as JSON. A rule is {"target": "...", "source": "..."} with an optional default, type and required flag; use "expression" (expr-lang) or "value" (constant) instead of "source" when needed. The shorthand {"target": "source", ...} maps paths directly.',
+  properties: [
+    { name: 'mappings', type: 'array', desc: 'JSON list of rules {target, source | expression | value, default?, type?, required?}, or an object of target -> source', required: true },
+    { name: 'unmapped', type: 'string', desc: '"drop" (default): output is only the mapped object; "keep": copy the input fields first' },
+  ],
+  sampleInput: { user: { fullName: 'Ada Lovelace', email: 'ada@example.com' }, price: 20, qty: 3 },
+  sampleOutput: {
+    customer: { name: 'Ada Lovelace', email: 'ada@example.com' },
+    total: 60,
+    status: 'new',
+  },
+  tips: [
+    'Targets may be nested and index arrays: customer.name, items[0].id',
+    'Sources use the same path syntax as the JSON Path node: labels[*].name',
+    'type converts the value: string, number, integer, boolean, array, object, json.',
+    'default applies when the source is missing or null; required fails the node instead.',
+    'Example: [{"target": "customer.name", "source": "user.fullName"}, {"target": "total", "expression": "price * qty"}, {"target": "status", "value": "new"}]',
+  ],
+};
+
+export default function MapperNodeConfig({ properties, updateProp }: NodeConfigProps) {
+  return (
+    <>
+      <div>
+        <Text strong style={{ fontSize: 10, display: 'block', marginBottom: 1 }}>Mappings (JSON)</Text>
+        <TextArea
+          size="small"
+          rows={6}
+          style={{ fontFamily: 'monospace', fontSize: 10 }}
+          placeholder={'[\n  {"target": "customer.name", "source": "user.fullName"},\n  {"target": "total", "expression": "price * qty", "type": "number"}\n]'}
+          value={typeof properties.mappings === 'string' ? properties.mappings : properties.mappings ? JSON.stringify(properties.mappings, null, 2) : ''}
+          onChange={(e) => updateProp('mappings', e.target.value)}
+        />
+        <Text type="secondary" style={{ fontSize: 9 }}>
+          Rules: target + source, expression or value; optional default, type, required.
+        </Text>
+      </div>
+      <div>
+        <Text strong style={{ fontSize: 10, display: 'block', marginBottom: 1 }}>Unmapped Fields</Text>
+        <Select
+          size="small"
+          style={{ width: '100%' }}
+          value={properties.unmapped || 'drop'}
+          onChange={(val) => updateProp('unmapped', val)}
+          options={[
+            { value: 'drop', label: 'Drop (output only mapped fields)' },
+            { value: 'keep', label: 'Keep (copy input fields first)' },
+          ]}
+        />
+      </div>
+    </>
+  );
+}
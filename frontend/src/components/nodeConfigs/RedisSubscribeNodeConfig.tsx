@@ -28,6 +28,7 @@ export const REDIS_SUBSCRIBE_NODE_DOC: NodeDoc = {
     'The message payload is available as the "message" field.',
     'Create a subscription via the 🔔 toolbar button to activate.',
     'Each received message triggers a separate workflow execution.',
+    'Redis Stream triggers (/api/redis-streams) also start at this node: "message" holds the entry fields, with "id", "stream", "group" and "consumer" alongside. The entry is acknowledged once the execution completes.',
   ],
 };
 
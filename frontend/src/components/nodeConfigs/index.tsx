@@ -8,11 +8,15 @@ import ConditionNodeConfig, { CONDITION_NODE_DOC } from './ConditionNodeConfig';
 import LogNodeConfig, { LOG_NODE_DOC } from './LogNodeConfig';
 import TransformNodeConfig, { TRANSFORM_NODE_DOC } from './TransformNodeConfig';
 import FunctionNodeConfig, { FUNCTION_NODE_DOC } from './FunctionNodeConfig';
+import JsonPathNodeConfig, { JSONPATH_NODE_DOC } from './JsonPathNodeConfig';
+import MapperNodeConfig, { MAPPER_NODE_DOC } from './MapperNodeConfig';
+import ValidateNodeConfig, { VALIDATE_NODE_DOC } from './ValidateNodeConfig';
 import RedisNodeConfig, { REDIS_NODE_DOC } from './RedisNodeConfig';
 import CronNodeConfig, { CRON_NODE_DOC } from './CronNodeConfig';
 import RedisSubscribeNodeConfig, { REDIS_SUBSCRIBE_NODE_DOC } from './RedisSubscribeNodeConfig';
 import EmailNodeConfig, { EMAIL_NODE_DOC } from './EmailNodeConfig';
 import EmailReceiveNodeConfig, { EMAIL_RECEIVE_NODE_DOC } from './EmailReceiveNodeConfig';
+import ImapNodeConfig, { IMAP_NODE_DOC } from './ImapNodeConfig';
 import ReadFileNodeConfig, { READ_FILE_NODE_DOC } from './ReadFileNodeConfig';
 import WriteFileNodeConfig, { WRITE_FILE_NODE_DOC } from './WriteFileNodeConfig';
 import ExecNodeConfig, { EXEC_NODE_DOC } from './ExecNodeConfig';
@@ -39,11 +43,15 @@ export const NODE_CONFIG_MAP: Record<string, NodeConfigComponent> = {
   log: LogNodeConfig,
   transform: TransformNodeConfig,
   function: FunctionNodeConfig,
+  jsonpath: JsonPathNodeConfig,
+  mapper: MapperNodeConfig,
+  validate: ValidateNodeConfig,
   redis: RedisNodeConfig,
   cron: CronNodeConfig,
   redis_subscribe: RedisSubscribeNodeConfig,
   email: EmailNodeConfig,
   email_receive: EmailReceiveNodeConfig,
+  imap: ImapNodeConfig,
   read_file: ReadFileNodeConfig,
   write_file: WriteFileNodeConfig,
   exec: ExecNodeConfig,
@@ -68,11 +76,15 @@ export const NODE_DOCS: Record<string, NodeDoc> = {
   log: LOG_NODE_DOC,
   transform: TRANSFORM_NODE_DOC,
   function: FUNCTION_NODE_DOC,
+  jsonpath: JSONPATH_NODE_DOC,
+  mapper: MAPPER_NODE_DOC,
+  validate: VALIDATE_NODE_DOC,
   redis: REDIS_NODE_DOC,
   cron: CRON_NODE_DOC,
   redis_subscribe: REDIS_SUBSCRIBE_NODE_DOC,
   email: EMAIL_NODE_DOC,
   email_receive: EMAIL_RECEIVE_NODE_DOC,
+  imap: IMAP_NODE_DOC,
   read_file: READ_FILE_NODE_DOC,
   write_file: WRITE_FILE_NODE_DOC,
   exec: EXEC_NODE_DOC,
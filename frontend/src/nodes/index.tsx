@@ -21,6 +21,9 @@ import {
   ForwardOutlined,
   ApiOutlined,
   SafetyCertificateOutlined,
+  AimOutlined,
+  SwapOutlined,
+  CheckCircleOutlined,
 } from '@ant-design/icons';
 import { PRIMARY } from '../theme';
 
@@ -178,6 +181,49 @@ function TransformNode({ data }: NodeProps) {
   );
 }
 
+function JsonPathNode({ data }: NodeProps) {
+  const props = (data as any).properties || {};
+  return (
+    <FlowNode
+      icon={<AimOutlined />}
+      bg="#d35400"
+      label={(data as any).label || 'JSON Path'}
+      subtitle={props.path ? props.path.substring(0, 20) : props.paths ? 'multiple paths' : ''}
+    />
+  );
+}
+
+function MapperNode({ data }: NodeProps) {
+  const props = (data as any).properties || {};
+  let count = 0;
+  if (Array.isArray(props.mappings)) count = props.mappings.length;
+  else if (props.mappings && typeof props.mappings === 'object') count = Object.keys(props.mappings).length;
+  return (
+    <FlowNode
+      icon={<SwapOutlined />}
+      bg="#1abc9c"
+      label={(data as any).label || 'Mapper'}
+      subtitle={count ? `${count} mapping${count === 1 ? '' : 's'}` : ''}
+    />
+  );
+}
+
+function ValidateNode({ data }: NodeProps) {
+  const props = (data as any).properties || {};
+  return (
+    <FlowNode
+      icon={<CheckCircleOutlined />}
+      bg="#27ae60"
+      label={(data as any).label || 'Validate'}
+      subtitle={props.schemaKey && !props.schema ? props.schemaKey : props.path || ''}
+      sourceHandles={[
+        { id: 'valid', left: '30%', label: 'Valid' },
+        { id: 'invalid', left: '70%', label: 'Invalid' },
+      ]}
+    />
+  );
+}
+
 function FunctionNode({ data }: NodeProps) {
   const props = (data as any).properties || {};
   const code = (props.code as string) || '';
@@ -254,6 +300,18 @@ function EmailReceiveNode({ data }: NodeProps) {
   );
 }
 
+function ImapNode({ data }: NodeProps) {
+  const props = (data as any).properties || {};
+  return (
+    <FlowNode
+      icon={<InboxOutlined />}
+      bg="#5b2c6f"
+      label={(data as any).label || 'IMAP Action'}
+      subtitle={props.action ? `${props.action}${props.targetMailbox ? ' → ' + props.targetMailbox : ''}` : 'action...'}
+    />
+  );
+}
+
 function ReadFileNode({ data }: NodeProps) {
   const props = (data as any).properties || {};
   return (
@@ -432,11 +490,15 @@ export const nodeTypes = {
   log: LogNode,
   transform: TransformNode,
   function: FunctionNode,
+  jsonpath: JsonPathNode,
+  mapper: MapperNode,
+  validate: ValidateNode,
   redis: RedisNode,
   cron: CronNode,
   redis_subscribe: RedisSubscribeNode,
   email: EmailNode,
   email_receive: EmailReceiveNode,
+  imap: ImapNode,
   read_file: ReadFileNode,
   write_file: WriteFileNode,
   exec: ExecNode,
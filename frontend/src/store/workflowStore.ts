@@ -35,6 +35,10 @@ import {
   createEmailTrigger,
   updateEmailTrigger as updateEmailTriggerApi,
   deleteEmailTrigger as deleteEmailTriggerApi,
+  getSmtpTriggers,
+  createSmtpTrigger,
+  updateSmtpTrigger as updateSmtpTriggerApi,
+  deleteSmtpTrigger as deleteSmtpTriggerApi,
   getHttpTriggers,
   createHttpTrigger,
   updateHttpTrigger as updateHttpTriggerApi,
@@ -49,6 +53,7 @@ import {
   type CronSchedule,
   type RedisSubscription,
   type EmailTrigger,
+  type SmtpTrigger,
   type HttpTrigger,
   type ConfigStoreEntryMasked,
 } from '../api/client';
@@ -99,6 +104,7 @@ interface WorkflowState {
   emailTriggers: EmailTrigger[];
 
   // HTTP trigger state
+  smtpTriggers: SmtpTrigger[];
   httpTriggers: HttpTrigger[];
 
   // Config store state (key-value secrets/tokens)
@@ -163,6 +169,10 @@ interface WorkflowState {
   removeEmailTrigger: (id: number) => Promise<void>;
 
   // Actions - HTTP triggers
+  fetchSmtpTriggers: () => Promise<void>;
+  addSmtpTrigger: (data: Partial<SmtpTrigger>) => Promise<void>;
+  editSmtpTrigger: (id: number, data: Partial<SmtpTrigger>) => Promise<void>;
+  removeSmtpTrigger: (id: number) => Promise<void>;
   fetchHttpTriggers: () => Promise<void>;
   addHttpTrigger: (data: Partial<HttpTrigger>) => Promise<void>;
   editHttpTrigger: (id: number, data: Partial<HttpTrigger>) => Promise<void>;
@@ -221,6 +231,7 @@ export const useWorkflowStore = create<WorkflowState>((set, get) => ({
   schedules: [],
   redisSubs: [],
   emailTriggers: [],
+  smtpTriggers: [],
   httpTriggers: [],
   configStoreEntries: [],
 
@@ -628,6 +639,27 @@ export const useWorkflowStore = create<WorkflowState>((set, get) => ({
     await get().fetchEmailTriggers();
   },
 
+  // SMTP trigger actions
+  fetchSmtpTriggers: async () => {
+    const res = await getSmtpTriggers();
+    set({ smtpTriggers: res.data || [] });
+  },
+
+  addSmtpTrigger: async (data: Partial<SmtpTrigger>) => {
+    await createSmtpTrigger(data);
+    await get().fetchSmtpTriggers();
+  },
+
+  editSmtpTrigger: async (id: number, data: Partial<SmtpTrigger>) => {
+    await updateSmtpTriggerApi(id, data);
+    await get().fetchSmtpTriggers();
+  },
+
+  removeSmtpTrigger: async (id: number) => {
+    await deleteSmtpTriggerApi(id);
+    await get().fetchSmtpTriggers();
+  },
+
   // HTTP trigger actions
   fetchHttpTriggers: async () => {
     const res = await getHttpTriggers();
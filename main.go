@@ -1,9 +1,17 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"eflo/backend/api"
 	"eflo/backend/config"
@@ -44,43 +52,169 @@ func main() {
 	configStoreRepo := repository.NewConfigStoreRepo(database)
 	cronRepo := repository.NewCronScheduleRepo(database)
 	redisSubRepo := repository.NewRedisSubscriptionRepo(database)
+	redisStreamRepo := repository.NewRedisStreamTriggerRepo(database)
 	emailTriggerRepo := repository.NewEmailTriggerRepo(database)
+	smtpTriggerRepo := repository.NewSmtpTriggerRepo(database)
 	httpTriggerRepo := repository.NewHttpTriggerRepo(database)
 	kbArticleRepo := repository.NewKBArticleRepo(database)
+	scriptLibRepo := repository.NewScriptLibraryRepo(database)
+	calendarRepo := repository.NewScheduleCalendarRepo(database)
+	deadLetterRepo := repository.NewDeadLetterRepo(database)
 
 	// Register node types
 	nodes.RegisterAll()
+	nodes.ConfigureFunctionRuntime(cfg.FunctionPoolSize)
+	if err := nodes.ConfigureFunctionFetch(cfg.FunctionFetchAllowPrivate); err != nil {
+		log.Fatalf("FUNCTION_FETCH_ALLOW_PRIVATE: %v", err)
+	}
 
 	// Initialize engine
-	eng := engine.NewEngine(execRepo, execLogRepo, configRepo, configStoreRepo, workflowRepo)
+	eng := engine.NewEngine(execRepo, execLogRepo, configRepo, configStoreRepo, workflowRepo, scriptLibRepo)
+	eng.DeadLetters = deadLetterRepo
 
-	// Initialize and start cron scheduler
-	scheduler := engine.NewScheduler(eng, workflowRepo, cronRepo)
-	if err := scheduler.Start(); err != nil {
-		log.Printf("Warning: Failed to start scheduler: %v", err)
+	for _, role := range cfg.Roles {
+		if role != "api" && role != "scheduler" && role != "worker" {
+			log.Fatalf("Unknown role %q in ROLES (want api, scheduler, worker)", role)
+		}
 	}
-	defer scheduler.Stop()
+	engine.SetInstanceID(cfg.InstanceID)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	// Initialize and start Redis subscriber
-	redisSub := engine.NewRedisSubscriber(eng, workflowRepo, configRepo, redisSubRepo)
-	if err := redisSub.Start(); err != nil {
-		log.Printf("Warning: Failed to start Redis subscriber: %v", err)
+	// Execution queue: trigger-started runs are handed to worker processes
+	switch cfg.ExecutionQueue {
+	case "none", "":
+	case "mysql":
+		eng.Queue = repository.NewExecutionQueueRepo(database)
+	case "redis":
+		eng.Queue = engine.NewRedisExecutionQueue(cfg.QueueRedisAddr, cfg.QueueRedisPassword, cfg.QueueRedisDB)
+	default:
+		log.Fatalf("Unknown EXECUTION_QUEUE %q (want none, mysql or redis)", cfg.ExecutionQueue)
 	}
-	defer redisSub.Stop()
+	eng.QueueMaxAttempts = cfg.QueueMaxAttempts
 
-	// Initialize and start Email poller
+	// Time- and poll-based triggers; started here or by the leader elector
+	scheduler := engine.NewScheduler(eng, workflowRepo, cronRepo, calendarRepo)
+	redisSub := engine.NewRedisSubscriber(eng, workflowRepo, configRepo, redisSubRepo)
+	redisStreams := engine.NewRedisStreamConsumer(eng, workflowRepo, configRepo, redisStreamRepo)
 	emailPoller := engine.NewEmailPoller(eng, workflowRepo, configRepo, emailTriggerRepo)
-	if err := emailPoller.Start(); err != nil {
-		log.Printf("Warning: Failed to start Email poller: %v", err)
+
+	// Leader election: only the lease holder runs cron, Redis and email triggers
+	var leaseStore engine.LeaseStore
+	switch cfg.LeaderElection {
+	case "mysql", "":
+		leaseStore = repository.NewLeaderLeaseRepo(database)
+	case "redis":
+		leaseStore = engine.NewRedisLeaseStore(cfg.LeaderRedisAddr, cfg.LeaderRedisPassword, cfg.LeaderRedisDB)
+	case "none":
+		if !cfg.HasRole("api") || !cfg.HasRole("scheduler") {
+			log.Println("Warning: LEADER_ELECTION=none with split roles; trigger changes made via the API reach the scheduler only after a restart")
+		}
+	default:
+		log.Fatalf("Unknown LEADER_ELECTION %q (want mysql, redis or none)", cfg.LeaderElection)
 	}
-	defer emailPoller.Stop()
 
-	// Setup router
-	router := api.NewRouter(workflowRepo, folderRepo, execRepo, execLogRepo, configRepo, configStoreRepo, cronRepo, redisSubRepo, emailTriggerRepo, httpTriggerRepo, kbArticleRepo, eng, scheduler, redisSub, emailPoller)
+	// The elector also relays trigger changes from API-only instances to the leader
+	var elector *engine.LeaderElector
+	if leaseStore != nil {
+		elector = engine.NewLeaderElector(leaseStore, cfg.LeaderElection, engine.TriggersLease, cfg.InstanceID,
+			time.Duration(cfg.LeaderLeaseTTLSec)*time.Second, scheduler, redisSub, redisStreams, emailPoller)
+		scheduler.SetChangeNotifier(elector.NotifyChanged)
+		redisSub.SetChangeNotifier(elector.NotifyChanged)
+		redisStreams.SetChangeNotifier(elector.NotifyChanged)
+		emailPoller.SetChangeNotifier(elector.NotifyChanged)
+	}
+
+	var background sync.WaitGroup
+	if cfg.HasRole("scheduler") {
+		if elector != nil {
+			background.Add(1)
+			go func() {
+				defer background.Done()
+				elector.Run(ctx)
+			}()
+		} else {
+			// Without leader election runs are only stopped by shutdown, which lets them finish
+			if err := scheduler.Start(context.Background()); err != nil {
+				log.Printf("Warning: Failed to start scheduler: %v", err)
+			}
+			defer scheduler.Stop()
+			if err := redisSub.Start(context.Background()); err != nil {
+				log.Printf("Warning: Failed to start Redis subscriber: %v", err)
+			}
+			defer redisSub.Stop()
+			if err := redisStreams.Start(context.Background()); err != nil {
+				log.Printf("Warning: Failed to start Redis stream consumer: %v", err)
+			}
+			defer redisStreams.Stop()
+			if err := emailPoller.Start(context.Background()); err != nil {
+				log.Printf("Warning: Failed to start Email poller: %v", err)
+			}
+			defer emailPoller.Stop()
+		}
+	}
 
-	addr := fmt.Sprintf(":%s", cfg.ServerPort)
-	log.Printf("Eflo workflow engine starting on %s", addr)
-	if err := http.ListenAndServe(addr, router); err != nil {
-		log.Fatalf("Server failed: %v", err)
+	if cfg.HasRole("worker") {
+		if eng.Queue == nil {
+			if len(cfg.Roles) == 1 {
+				log.Fatal("The worker role requires EXECUTION_QUEUE (mysql or redis)")
+			}
+		} else {
+			worker := engine.NewWorker(eng, workflowRepo, eng.Queue, cfg.InstanceID, cfg.WorkerConcurrency,
+				time.Duration(cfg.QueueVisibilitySec)*time.Second)
+			background.Add(1)
+			go func() {
+				defer background.Done()
+				worker.Run(ctx)
+			}()
+		}
 	}
+	log.Printf("Eflo roles: %s (execution queue: %s)", strings.Join(cfg.Roles, ","), cfg.ExecutionQueue)
+
+	// Embedded SMTP receiver: like HTTP-in, every API instance accepts mail for SMTP triggers
+	if cfg.HasRole("api") && cfg.SmtpReceiverPort != "" {
+		var tlsConfig *tls.Config
+		if cfg.SmtpReceiverTLSCert != "" && cfg.SmtpReceiverTLSKey != "" {
+			cert, err := tls.LoadX509KeyPair(cfg.SmtpReceiverTLSCert, cfg.SmtpReceiverTLSKey)
+			if err != nil {
+				log.Fatalf("Failed to load SMTP receiver certificate: %v", err)
+			}
+			tlsConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+		}
+		hostname := cfg.SmtpReceiverHostname
+		if hostname == "" {
+			hostname, _ = os.Hostname()
+		}
+		smtpReceiver := engine.NewSmtpReceiver(eng, workflowRepo, smtpTriggerRepo)
+		smtpReceiver.MaxBufferedBytes = cfg.SmtpReceiverMaxBuffered
+		smtpReceiver.Spool = repository.NewSmtpSpoolRepo(database)
+		if err := smtpReceiver.Start(":"+cfg.SmtpReceiverPort, hostname, cfg.SmtpReceiverMaxBytes, tlsConfig); err != nil {
+			log.Fatalf("Failed to start SMTP receiver: %v", err)
+		}
+		defer smtpReceiver.Stop()
+	}
+
+	if cfg.HasRole("api") {
+		// Setup router
+		router := api.NewRouter(workflowRepo, folderRepo, execRepo, execLogRepo, configRepo, configStoreRepo, cronRepo, redisSubRepo, redisStreamRepo, emailTriggerRepo, smtpTriggerRepo, httpTriggerRepo, kbArticleRepo, scriptLibRepo, calendarRepo, deadLetterRepo, eng, scheduler, redisSub, redisStreams, emailPoller, elector)
+
+		addr := fmt.Sprintf(":%s", cfg.ServerPort)
+		server := &http.Server{Addr: addr, Handler: router}
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			_ = server.Shutdown(shutdownCtx)
+		}()
+		log.Printf("Eflo workflow engine starting on %s", addr)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Server failed: %v", err)
+		}
+	} else {
+		<-ctx.Done()
+	}
+
+	log.Println("Shutting down")
+	stop()
+	background.Wait()
 }